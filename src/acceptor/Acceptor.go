@@ -0,0 +1,463 @@
+package acceptor
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Errors returned when a token is rejected. They can be matched with errors.Is.
+var (
+	ErrUnsupportedMechanism = errors.New("unsupported GSS-API mechanism")
+	ErrNoKey                = errors.New("no key in the keytab for the ticket")
+	ErrBadKeyVersion        = errors.New("no key with the version number of the ticket in the keytab")
+	ErrTicketNotYetValid    = errors.New("ticket is not yet valid")
+	ErrTicketExpired        = errors.New("ticket has expired")
+	ErrClockSkew            = errors.New("clock skew too great")
+	ErrReplay               = errors.New("request is a replay")
+	ErrBadMatch             = errors.New("authenticator does not match the ticket")
+	ErrWrongService         = errors.New("ticket is not for an accepted service")
+	ErrInvalidPAC           = errors.New("invalid PAC")
+)
+
+// GSS-API flags of the authenticator checksum, as defined in RFC 4121 section 4.1.1.1.
+const (
+	GSSFlag_Delegation = 0x01
+	GSSFlag_Mutual     = 0x02
+	GSSFlag_Replay     = 0x04
+	GSSFlag_Sequence   = 0x08
+	GSSFlag_Conf       = 0x10
+	GSSFlag_Integ      = 0x20
+)
+
+// CksumType_GSSAPI is the checksum type of the authenticator checksum of Kerberos GSS-API tokens.
+const CksumType_GSSAPI = 0x8003
+
+// DefaultMaxClockSkew is the default tolerated difference between the clocks of the client and the server.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// Acceptor validates Kerberos GSS-API and SPNEGO tokens with the keys of a keytab.
+//
+// Attributes:
+//   - Keytab (*keytab.Keytab): The keytab holding the long-term keys of the services.
+//   - ServicePrincipals ([]string): The service principals accepted, matched case-insensitively. Any principal of the keytab is accepted when empty.
+//   - MaxClockSkew (time.Duration): The tolerated difference between the clocks of the client and the server.
+//   - ReplayCache (*ReplayCache): The replay cache, shared by all the requests.
+//   - VerifyPAC (bool): Whether to verify the server signature of the PAC and its consistency with the ticket, when present.
+//   - RequirePAC (bool): Whether to reject tickets without a PAC. Implies VerifyPAC.
+//   - Now (func() time.Time): The clock used to validate times.
+type Acceptor struct {
+	Keytab            *keytab.Keytab
+	ServicePrincipals []string
+	MaxClockSkew      time.Duration
+	ReplayCache       *ReplayCache
+	VerifyPAC         bool
+	RequirePAC        bool
+	Now               func() time.Time
+}
+
+// Identity represents a client authenticated by an Acceptor.
+//
+// Attributes:
+//   - Client (messages.PrincipalName): The name of the client.
+//   - Realm (string): The realm of the client.
+//   - Principal (string): The client principal, as "name@REALM".
+//   - Service (string): The service principal of the ticket, as "name@REALM".
+//   - AuthTime (time.Time): The time of the initial authentication of the client.
+//   - EndTime (time.Time): The expiration time of the ticket.
+//   - TicketFlags (uint32): The flags of the ticket.
+//   - GSSFlags (uint32): The GSS-API flags requested by the client.
+//   - SessionKey (messages.EncryptionKey): The session key, or the subkey of the client if it sent one.
+//   - DelegatedCredential ([]byte): The KRB-CRED delegated by the client, nil if none.
+//   - PAC (*PAC): The PAC of the ticket, nil if absent or not verified.
+type Identity struct {
+	Client              messages.PrincipalName
+	Realm               string
+	Principal           string
+	Service             string
+	AuthTime            time.Time
+	EndTime             time.Time
+	TicketFlags         uint32
+	GSSFlags            uint32
+	SessionKey          messages.EncryptionKey
+	DelegatedCredential []byte
+	PAC                 *PAC
+	// Internal
+	ticketSessionKey messages.EncryptionKey
+}
+
+// NewAcceptor creates an Acceptor with a replay cache and the default clock skew.
+//
+// Parameters:
+//   - kt (*keytab.Keytab): The keytab holding the long-term keys of the services.
+//
+// Returns:
+//   - *Acceptor: The acceptor.
+func NewAcceptor(kt *keytab.Keytab) *Acceptor {
+	return &Acceptor{
+		Keytab:       kt,
+		MaxClockSkew: DefaultMaxClockSkew,
+		ReplayCache:  NewReplayCache(),
+		Now:          time.Now,
+	}
+}
+
+// Accept validates a token sent by a client, either a SPNEGO NegTokenInit carrying a
+// Kerberos token (as sent in "Authorization: Negotiate" headers) or a raw Kerberos GSS-API token.
+//
+// Parameters:
+//   - token ([]byte): The token.
+//
+// Returns:
+//   - (*Identity, []byte, error): The authenticated client, the token to send back and an error if
+//     the token was rejected. For SPNEGO the response is a NegTokenResp, carrying the AP-REP when
+//     mutual authentication was requested; for raw Kerberos tokens it is the AP-REP token, or nil.
+func (a *Acceptor) Accept(token []byte) (*Identity, []byte, error) {
+	mech, _, err := UnwrapInitialContextToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if mech != OID_SPNEGO {
+		identity, apRep, err := a.acceptKerberosToken(token)
+		if err != nil || apRep == nil {
+			return identity, nil, err
+		}
+		response, err := WrapKerberosToken(mech, TokID_APRep, apRep)
+		return identity, response, err
+	}
+
+	negTokenInit := NegTokenInit{}
+	err = negTokenInit.FromBytes(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(negTokenInit.MechTypes) == 0 || len(negTokenInit.MechToken) == 0 {
+		return nil, nil, fmt.Errorf("%w: no optimistic mechanism token", ErrUnsupportedMechanism)
+	}
+	preferred := negTokenInit.MechTypes[0]
+	if preferred != OID_Kerberos5 && preferred != OID_MSKerberos5 {
+		if isNTLMToken(negTokenInit.MechToken) {
+			return nil, nil, fmt.Errorf("%w: NTLM", ErrUnsupportedMechanism)
+		}
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedMechanism, preferred)
+	}
+
+	identity, apRep, err := a.acceptKerberosToken(negTokenInit.MechToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	negTokenResp := NegTokenResp{NegState: NegState_AcceptCompleted, SupportedMech: preferred}
+	if apRep != nil {
+		negTokenResp.ResponseToken, err = WrapKerberosToken(preferred, TokID_APRep, apRep)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	response, err := negTokenResp.ToBytes()
+	return identity, response, err
+}
+
+// acceptKerberosToken validates a Kerberos GSS-API token carrying an AP-REQ.
+// It returns the DER encoded AP-REP when mutual authentication was requested.
+func (a *Acceptor) acceptKerberosToken(token []byte) (*Identity, []byte, error) {
+	_, tokID, message, err := UnwrapKerberosToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tokID != TokID_APReq {
+		return nil, nil, fmt.Errorf("kerberos token is not an AP-REQ (TOK_ID 0x%04x)", tokID)
+	}
+
+	apReq := messages.APReq{}
+	err = apReq.FromBytes(message)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identity, authenticator, err := a.AcceptAPReq(&apReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mutual := apReq.APOptions&messages.APOption_MutualRequired != 0 || identity.GSSFlags&GSSFlag_Mutual != 0
+	if !mutual {
+		return identity, nil, nil
+	}
+	apRep, err := a.newAPRep(identity, authenticator)
+	return identity, apRep, err
+}
+
+// AcceptAPReq validates an AP-REQ: it decrypts the ticket with the keytab, checks its
+// validity period, decrypts the authenticator with the session key, checks that it
+// matches the ticket, that it is recent and not a replay, and verifies the PAC if asked to.
+//
+// Parameters:
+//   - apReq (*messages.APReq): The AP-REQ.
+//
+// Returns:
+//   - (*Identity, *messages.Authenticator, error): The authenticated client, the decrypted authenticator and an error if the AP-REQ was rejected.
+func (a *Acceptor) AcceptAPReq(apReq *messages.APReq) (*Identity, *messages.Authenticator, error) {
+	now := a.now()
+	skew := a.MaxClockSkew
+	ticket := &apReq.Ticket
+	service := ticket.SName.String() + "@" + ticket.Realm
+
+	if !a.acceptsService(service) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrWrongService, service)
+	}
+
+	serviceKey, encTicketPart, err := a.decryptTicket(ticket)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	startTime := encTicketPart.StartTime
+	if startTime.IsZero() {
+		startTime = encTicketPart.AuthTime
+	}
+	if startTime.After(now.Add(skew)) {
+		return nil, nil, fmt.Errorf("%w: valid from %s", ErrTicketNotYetValid, startTime.UTC().Format(time.RFC3339))
+	}
+	if encTicketPart.EndTime.Before(now.Add(-skew)) {
+		return nil, nil, fmt.Errorf("%w: expired at %s", ErrTicketExpired, encTicketPart.EndTime.UTC().Format(time.RFC3339))
+	}
+
+	sessionKey := encTicketPart.Key
+	plaintext, err := crypto.Decrypt(apReq.Authenticator.EType, sessionKey.KeyValue, messages.KeyUsage_AP_REQ_AUTHENTICATOR, apReq.Authenticator.Cipher)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decrypting authenticator: %w", err)
+	}
+	authenticator := &messages.Authenticator{}
+	err = authenticator.FromBytes(plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !authenticator.CName.Equal(encTicketPart.CName) || authenticator.CRealm != encTicketPart.CRealm {
+		return nil, nil, fmt.Errorf("%w: %s@%s is not %s@%s", ErrBadMatch,
+			authenticator.CName.String(), authenticator.CRealm, encTicketPart.CName.String(), encTicketPart.CRealm)
+	}
+
+	if authenticator.CTime.Before(now.Add(-skew)) || authenticator.CTime.After(now.Add(skew)) {
+		return nil, nil, fmt.Errorf("%w: client time %s", ErrClockSkew, authenticator.CTime.UTC().Format(time.RFC3339))
+	}
+
+	client := encTicketPart.CName.String() + "@" + encTicketPart.CRealm
+
+	identity := &Identity{
+		Client:      encTicketPart.CName,
+		Realm:       encTicketPart.CRealm,
+		Principal:   client,
+		Service:     service,
+		AuthTime:    encTicketPart.AuthTime,
+		EndTime:     encTicketPart.EndTime,
+		TicketFlags: encTicketPart.Flags,
+		SessionKey:  sessionKey,
+
+		ticketSessionKey: sessionKey,
+	}
+	if authenticator.SubKey != nil {
+		identity.SessionKey = *authenticator.SubKey
+	}
+	if authenticator.Cksum != nil && authenticator.Cksum.CksumType == CksumType_GSSAPI {
+		identity.GSSFlags, identity.DelegatedCredential, err = parseGSSChecksum(authenticator.Cksum.Checksum)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if a.VerifyPAC || a.RequirePAC {
+		identity.PAC, err = a.verifyPAC(encTicketPart, serviceKey)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Only authenticators that passed every other check are recorded, so that a rejected
+	// request cannot make a later valid one look like a replay
+	if a.ReplayCache != nil {
+		id := fmt.Sprintf("%s|%s|%d|%d", client, service, authenticator.CTime.Unix(), authenticator.Cusec)
+		if a.ReplayCache.Check(id, authenticator.CTime.Add(skew), now) {
+			return nil, nil, ErrReplay
+		}
+	}
+
+	return identity, authenticator, nil
+}
+
+// decryptTicket decrypts a ticket with the matching key of the keytab.
+func (a *Acceptor) decryptTicket(ticket *messages.Ticket) (messages.EncryptionKey, *messages.EncTicketPart, error) {
+	service := ticket.SName.String() + "@" + ticket.Realm
+	etype := ticket.EncPart.EType
+
+	found := false
+	candidates := make([]*keytab.KeytabEntry, 0)
+	for i := range a.Keytab.Entries {
+		entry := &a.Keytab.Entries[i]
+		if !strings.EqualFold(entry.Principal(), service) || int32(entry.Key.Type) != etype {
+			continue
+		}
+		found = true
+		if ticket.EncPart.HasKvno && entry.KVNO() != ticket.EncPart.Kvno {
+			continue
+		}
+		candidates = append(candidates, entry)
+	}
+	if !found {
+		return messages.EncryptionKey{}, nil, fmt.Errorf("%w: %s (%s)", ErrNoKey, service, keytab.EncryptionType(etype).String())
+	}
+	if len(candidates) == 0 {
+		return messages.EncryptionKey{}, nil, fmt.Errorf("%w: %s (kvno %d)", ErrBadKeyVersion, service, ticket.EncPart.Kvno)
+	}
+
+	var err error
+	for _, entry := range candidates {
+		var plaintext []byte
+		plaintext, err = crypto.Decrypt(etype, entry.Key.Key.Data, messages.KeyUsage_KDC_REP_TICKET, ticket.EncPart.Cipher)
+		if err != nil {
+			continue
+		}
+		encTicketPart := &messages.EncTicketPart{}
+		err = encTicketPart.FromBytes(plaintext)
+		if err != nil {
+			return messages.EncryptionKey{}, nil, err
+		}
+		key := messages.EncryptionKey{KeyType: etype, KeyValue: entry.Key.Key.Data}
+		return key, encTicketPart, nil
+	}
+	return messages.EncryptionKey{}, nil, fmt.Errorf("error decrypting ticket: %w", err)
+}
+
+// verifyPAC finds the PAC of a ticket, verifies its server signature and checks that
+// its client information matches the ticket.
+func (a *Acceptor) verifyPAC(encTicketPart *messages.EncTicketPart, serviceKey messages.EncryptionKey) (*PAC, error) {
+	data, err := findPAC(encTicketPart.AuthorizationData)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		if a.RequirePAC {
+			return nil, fmt.Errorf("%w: ticket has no PAC", ErrInvalidPAC)
+		}
+		return nil, nil
+	}
+
+	pac := &PAC{}
+	err = pac.FromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPAC, err)
+	}
+	err = pac.VerifyServerChecksum(serviceKey)
+	if err != nil {
+		return nil, err
+	}
+	if pac.GetBuffer(PACType_ClientInfo) == nil {
+		return nil, fmt.Errorf("%w: no client info", ErrInvalidPAC)
+	}
+	if !strings.EqualFold(pac.ClientName, encTicketPart.CName.String()) {
+		return nil, fmt.Errorf("%w: client name %s does not match the ticket", ErrInvalidPAC, pac.ClientName)
+	}
+	if !pac.ClientID.Equal(encTicketPart.AuthTime.Truncate(time.Second)) {
+		return nil, fmt.Errorf("%w: client info time does not match the ticket", ErrInvalidPAC)
+	}
+
+	return pac, nil
+}
+
+// newAPRep builds the DER encoded AP-REP answering an authenticator, encrypted in the session key of the ticket.
+func (a *Acceptor) newAPRep(identity *Identity, authenticator *messages.Authenticator) ([]byte, error) {
+	sequence, err := rand.Int(rand.Reader, big.NewInt(1<<31))
+	if err != nil {
+		return nil, err
+	}
+	encPart := messages.EncAPRepPart{
+		CTime:        authenticator.CTime,
+		Cusec:        authenticator.Cusec,
+		SeqNumber:    uint32(sequence.Int64()),
+		HasSeqNumber: true,
+	}
+	plaintext, err := encPart.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// The AP-REP is encrypted in the ticket session key, even when the client sent a subkey
+	ticketKey := identity.ticketSessionKey
+	cipher, err := crypto.Encrypt(ticketKey.KeyType, ticketKey.KeyValue, messages.KeyUsage_AP_REP_ENCPART, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	apRep := messages.APRep{
+		Pvno:    messages.PVNO,
+		MsgType: messages.MsgType_AP_REP,
+		EncPart: messages.EncryptedData{EType: ticketKey.KeyType, Cipher: cipher},
+	}
+	return apRep.ToBytes()
+}
+
+// acceptsService returns true if the service principal is one of the accepted ones.
+func (a *Acceptor) acceptsService(service string) bool {
+	if len(a.ServicePrincipals) == 0 {
+		return true
+	}
+	for _, accepted := range a.ServicePrincipals {
+		if strings.EqualFold(accepted, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// now returns the current time of the acceptor clock.
+func (a *Acceptor) now() time.Time {
+	if a.Now == nil {
+		return time.Now()
+	}
+	return a.Now()
+}
+
+// findPAC returns the PAC of a ticket, found in an AD-IF-RELEVANT element, or nil if absent.
+func findPAC(authorizationData []messages.AuthorizationDataEntry) ([]byte, error) {
+	for _, entry := range authorizationData {
+		if entry.ADType != messages.ADType_IF_RELEVANT {
+			continue
+		}
+		inner, err := messages.ParseAuthorizationData(entry.ADData)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPAC, err)
+		}
+		for _, element := range inner {
+			if element.ADType == messages.ADType_WIN2K_PAC {
+				return element.ADData, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// parseGSSChecksum parses the authenticator checksum of a Kerberos GSS-API token, as defined
+// in RFC 4121 section 4.1.1, returning the flags and the delegated credential if any.
+func parseGSSChecksum(data []byte) (uint32, []byte, error) {
+	if len(data) < 24 {
+		return 0, nil, fmt.Errorf("gss-api authenticator checksum is too short")
+	}
+	flags := binary.LittleEndian.Uint32(data[20:24])
+	if flags&GSSFlag_Delegation == 0 || len(data) < 28 {
+		return flags, nil, nil
+	}
+	length := int(binary.LittleEndian.Uint16(data[26:28]))
+	if 28+length > len(data) {
+		return 0, nil, fmt.Errorf("gss-api delegated credential is truncated")
+	}
+	return flags, data[28 : 28+length], nil
+}
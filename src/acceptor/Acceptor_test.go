@@ -0,0 +1,284 @@
+package acceptor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testRealm = "TESTSEGMENT.LOCAL"
+
+var (
+	testNow        = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	testServiceKey = messages.EncryptionKey{KeyType: crypto.ETypeAES256CTSHMACSHA196, KeyValue: bytes.Repeat([]byte{0x02}, 32)}
+	testKDCKey     = messages.EncryptionKey{KeyType: crypto.ETypeAES256CTSHMACSHA196, KeyValue: bytes.Repeat([]byte{0x01}, 32)}
+	testSessionKey = messages.EncryptionKey{KeyType: crypto.ETypeAES256CTSHMACSHA196, KeyValue: bytes.Repeat([]byte{0x05}, 32)}
+)
+
+// newTestKeytab returns a keytab holding the key of HTTP/web01.testsegment.local with kvno 3.
+func newTestKeytab(t *testing.T) *keytab.Keytab {
+	entry, err := keytab.NewKeytabEntry(testRealm, []string{"HTTP", "web01.testsegment.local"}, messages.NameType_PRINCIPAL, 3, keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, testServiceKey.KeyValue)
+	if err != nil {
+		t.Fatalf("Error creating keytab entry: %v", err)
+	}
+	return &keytab.Keytab{FileFormatVersion: 0x502, Entries: []keytab.KeytabEntry{entry}}
+}
+
+// newTestInitiator returns an initiator holding a ticket of "user" for HTTP/web01.testsegment.local.
+func newTestInitiator(t *testing.T, kvno uint32, authorizationData []messages.AuthorizationDataEntry) *Initiator {
+	client := messages.NewPrincipalName(messages.NameType_PRINCIPAL, "user")
+	encTicketPart := messages.EncTicketPart{
+		Key:               testSessionKey,
+		CRealm:            testRealm,
+		CName:             client,
+		AuthTime:          testNow,
+		EndTime:           testNow.Add(10 * time.Hour),
+		AuthorizationData: authorizationData,
+	}
+	plaintext, err := encTicketPart.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding EncTicketPart: %v", err)
+	}
+	cipher, err := crypto.Encrypt(testServiceKey.KeyType, testServiceKey.KeyValue, messages.KeyUsage_KDC_REP_TICKET, plaintext)
+	if err != nil {
+		t.Fatalf("Error encrypting ticket: %v", err)
+	}
+
+	return &Initiator{
+		Ticket: messages.Ticket{
+			TktVno:  messages.PVNO,
+			Realm:   testRealm,
+			SName:   messages.NewPrincipalName(messages.NameType_SRV_INST, "HTTP", "web01.testsegment.local"),
+			EncPart: messages.EncryptedData{EType: testServiceKey.KeyType, Kvno: kvno, HasKvno: true, Cipher: cipher},
+		},
+		SessionKey: testSessionKey,
+		Client:     client,
+		Realm:      testRealm,
+		Now:        func() time.Time { return testNow },
+	}
+}
+
+// newTestAcceptor returns an acceptor using the test keytab and a fixed clock.
+func newTestAcceptor(t *testing.T, now time.Time) *Acceptor {
+	a := NewAcceptor(newTestKeytab(t))
+	a.Now = func() time.Time { return now }
+	return a
+}
+
+func Test_Acceptor_Accept(t *testing.T) {
+	initiator := newTestInitiator(t, 3, nil)
+	token, err := initiator.NegotiateToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+
+	identity, response, err := newTestAcceptor(t, testNow).Accept(token)
+	if err != nil {
+		t.Fatalf("Error accepting token: %v", err)
+	}
+	if identity.Principal != "user@"+testRealm {
+		t.Errorf("Expected principal user@%s, got %s", testRealm, identity.Principal)
+	}
+	if identity.Service != "HTTP/web01.testsegment.local@"+testRealm {
+		t.Errorf("Unexpected service %s", identity.Service)
+	}
+	if identity.GSSFlags&GSSFlag_Mutual != 0 {
+		t.Errorf("Mutual authentication was not requested")
+	}
+	if !bytes.Equal(identity.SessionKey.KeyValue, testSessionKey.KeyValue) {
+		t.Errorf("Unexpected session key")
+	}
+
+	negTokenResp := NegTokenResp{}
+	err = negTokenResp.FromBytes(response)
+	if err != nil {
+		t.Fatalf("Error parsing response: %v", err)
+	}
+	if negTokenResp.NegState != NegState_AcceptCompleted || negTokenResp.ResponseToken != nil {
+		t.Errorf("Expected a completed negotiation without AP-REP, got state %d", negTokenResp.NegState)
+	}
+}
+
+func Test_Acceptor_Accept_Mutual(t *testing.T) {
+	initiator := newTestInitiator(t, 3, nil)
+	initiator.Mutual = true
+	token, err := initiator.NegotiateToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+
+	_, response, err := newTestAcceptor(t, testNow).Accept(token)
+	if err != nil {
+		t.Fatalf("Error accepting token: %v", err)
+	}
+	err = initiator.VerifyResponse(response)
+	if err != nil {
+		t.Errorf("Error verifying AP-REP: %v", err)
+	}
+
+	// Raw Kerberos tokens are answered with a raw AP-REP token
+	token, err = initiator.KerberosToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+	_, response, err = newTestAcceptor(t, testNow).Accept(token)
+	if err != nil {
+		t.Fatalf("Error accepting token: %v", err)
+	}
+	err = initiator.VerifyResponse(response)
+	if err != nil {
+		t.Errorf("Error verifying raw AP-REP: %v", err)
+	}
+}
+
+func Test_Acceptor_Accept_Rejections(t *testing.T) {
+	tests := []struct {
+		name     string
+		kvno     uint32
+		now      time.Time
+		services []string
+		expected error
+	}{
+		{"wrong kvno", 4, testNow, nil, ErrBadKeyVersion},
+		{"clock skew", 3, testNow.Add(10 * time.Minute), nil, ErrClockSkew},
+		{"expired", 3, testNow.Add(11 * time.Hour), nil, ErrTicketExpired},
+		{"not yet valid", 3, testNow.Add(-time.Hour), nil, ErrTicketNotYetValid},
+		{"wrong service", 3, testNow, []string{"HTTP/web02.testsegment.local@" + testRealm}, ErrWrongService},
+	}
+
+	for _, test := range tests {
+		token, err := newTestInitiator(t, test.kvno, nil).NegotiateToken()
+		if err != nil {
+			t.Fatalf("Error building token: %v", err)
+		}
+		a := newTestAcceptor(t, test.now)
+		a.ServicePrincipals = test.services
+		_, _, err = a.Accept(token)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func Test_Acceptor_Accept_Replay(t *testing.T) {
+	token, err := newTestInitiator(t, 3, nil).NegotiateToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+
+	a := newTestAcceptor(t, testNow)
+	if _, _, err = a.Accept(token); err != nil {
+		t.Fatalf("Error accepting token: %v", err)
+	}
+	if _, _, err = a.Accept(token); !errors.Is(err, ErrReplay) {
+		t.Errorf("Expected a replay error, got %v", err)
+	}
+
+	// Entries are forgotten once the authenticator would fail the clock skew check anyway
+	if purged := a.ReplayCache.Check("other", testNow, testNow.Add(time.Hour)); purged || a.ReplayCache.Len() != 1 {
+		t.Errorf("Expected the replay cache to be purged, got %d entries", a.ReplayCache.Len())
+	}
+}
+
+func Test_Acceptor_Accept_PAC(t *testing.T) {
+	pac, err := NewPAC("user", testNow, testServiceKey, testKDCKey)
+	if err != nil {
+		t.Fatalf("Error building PAC: %v", err)
+	}
+	ifRelevant := messages.MarshalAuthorizationData([]messages.AuthorizationDataEntry{{ADType: messages.ADType_WIN2K_PAC, ADData: pac}})
+	authorizationData := []messages.AuthorizationDataEntry{{ADType: messages.ADType_IF_RELEVANT, ADData: ifRelevant}}
+
+	token, err := newTestInitiator(t, 3, authorizationData).NegotiateToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+	a := newTestAcceptor(t, testNow)
+	a.RequirePAC = true
+	identity, _, err := a.Accept(token)
+	if err != nil {
+		t.Fatalf("Error accepting token: %v", err)
+	}
+	if identity.PAC == nil || identity.PAC.ClientName != "user" {
+		t.Errorf("Expected the PAC of user, got %+v", identity.PAC)
+	}
+
+	// A PAC signed with another key is rejected
+	forged, err := NewPAC("admin", testNow, testKDCKey, testKDCKey)
+	if err != nil {
+		t.Fatalf("Error building PAC: %v", err)
+	}
+	ifRelevant = messages.MarshalAuthorizationData([]messages.AuthorizationDataEntry{{ADType: messages.ADType_WIN2K_PAC, ADData: forged}})
+	authorizationData = []messages.AuthorizationDataEntry{{ADType: messages.ADType_IF_RELEVANT, ADData: ifRelevant}}
+	token, err = newTestInitiator(t, 3, authorizationData).NegotiateToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+	if _, _, err = a.Accept(token); !errors.Is(err, ErrInvalidPAC) {
+		t.Errorf("Expected an invalid PAC error, got %v", err)
+	}
+
+	// Tickets without PAC are rejected when a PAC is required
+	token, err = newTestInitiator(t, 3, nil).KerberosToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+	if _, _, err = a.Accept(token); !errors.Is(err, ErrInvalidPAC) {
+		t.Errorf("Expected an invalid PAC error, got %v", err)
+	}
+}
+
+func Test_Acceptor_Middleware(t *testing.T) {
+	var rejected error
+	a := newTestAcceptor(t, testNow)
+	handler := a.MiddlewareWithErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok {
+			t.Errorf("No identity in the request context")
+			return
+		}
+		w.Write([]byte(identity.Principal))
+	}), func(r *http.Request, err error) { rejected = err })
+
+	// Missing header
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusUnauthorized || recorder.Header().Get("WWW-Authenticate") != "Negotiate" {
+		t.Errorf("Expected a Negotiate challenge, got %d %q", recorder.Code, recorder.Header().Get("WWW-Authenticate"))
+	}
+
+	// Valid token with mutual authentication
+	initiator := newTestInitiator(t, 3, nil)
+	initiator.Mutual = true
+	token, err := initiator.NegotiateToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Authorization", "Negotiate "+base64.StdEncoding.EncodeToString(token))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "user@"+testRealm {
+		t.Fatalf("Expected the request to be authenticated, got %d %q", recorder.Code, recorder.Body.String())
+	}
+	response, err := base64.StdEncoding.DecodeString(recorder.Header().Get("WWW-Authenticate")[len("Negotiate "):])
+	if err != nil {
+		t.Fatalf("Error decoding response token: %v", err)
+	}
+	if err = initiator.VerifyResponse(response); err != nil {
+		t.Errorf("Error verifying AP-REP: %v", err)
+	}
+
+	// Replayed token
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized || !errors.Is(rejected, ErrReplay) {
+		t.Errorf("Expected the replay to be rejected, got %d (%v)", recorder.Code, rejected)
+	}
+}
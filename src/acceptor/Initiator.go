@@ -0,0 +1,166 @@
+package acceptor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"keytab/crypto"
+	"keytab/messages"
+	"time"
+)
+
+// Initiator builds the SPNEGO tokens of a client holding a service ticket, as sent in
+// "Authorization: Negotiate" headers. It is the counterpart of the Acceptor, mostly useful
+// to test services without a KDC.
+//
+// Attributes:
+//   - Ticket (messages.Ticket): The service ticket.
+//   - SessionKey (messages.EncryptionKey): The session key of the ticket.
+//   - Client (messages.PrincipalName): The name of the client.
+//   - Realm (string): The realm of the client.
+//   - Mutual (bool): Whether to request mutual authentication.
+//   - Now (func() time.Time): The clock used for the authenticator, time.Now when nil.
+type Initiator struct {
+	Ticket     messages.Ticket
+	SessionKey messages.EncryptionKey
+	Client     messages.PrincipalName
+	Realm      string
+	Mutual     bool
+	Now        func() time.Time
+	// Internal
+	ctime time.Time
+	cusec int32
+}
+
+// NegotiateToken builds a SPNEGO NegTokenInit carrying an AP-REQ for the ticket.
+//
+// Returns:
+//   - ([]byte, error): The token and an error if the authenticator could not be encrypted.
+func (i *Initiator) NegotiateToken() ([]byte, error) {
+	apReq, err := i.KerberosToken()
+	if err != nil {
+		return nil, err
+	}
+	negTokenInit := NegTokenInit{
+		MechTypes: []string{OID_Kerberos5, OID_MSKerberos5},
+		MechToken: apReq,
+	}
+	return negTokenInit.ToBytes()
+}
+
+// KerberosToken builds a raw Kerberos GSS-API token carrying an AP-REQ for the ticket.
+//
+// Returns:
+//   - ([]byte, error): The token and an error if the authenticator could not be encrypted.
+func (i *Initiator) KerberosToken() ([]byte, error) {
+	now := time.Now()
+	if i.Now != nil {
+		now = i.Now()
+	}
+	i.ctime = now.UTC().Truncate(time.Second)
+	i.cusec = int32(now.Nanosecond() / 1000)
+
+	// Authenticator checksum of RFC 4121 section 4.1.1, with zeroed channel bindings
+	flags := uint32(GSSFlag_Integ | GSSFlag_Conf)
+	if i.Mutual {
+		flags |= GSSFlag_Mutual
+	}
+	checksum := make([]byte, 24)
+	binary.LittleEndian.PutUint32(checksum[0:4], 16)
+	binary.LittleEndian.PutUint32(checksum[20:24], flags)
+
+	authenticator := messages.Authenticator{
+		AuthenticatorVno: messages.PVNO,
+		CRealm:           i.Realm,
+		CName:            i.Client,
+		Cksum:            &messages.Checksum{CksumType: CksumType_GSSAPI, Checksum: checksum},
+		Cusec:            i.cusec,
+		CTime:            i.ctime,
+	}
+	plaintext, err := authenticator.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := crypto.Encrypt(i.SessionKey.KeyType, i.SessionKey.KeyValue, messages.KeyUsage_AP_REQ_AUTHENTICATOR, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting authenticator: %w", err)
+	}
+
+	apReq := messages.APReq{
+		Pvno:          messages.PVNO,
+		MsgType:       messages.MsgType_AP_REQ,
+		Ticket:        i.Ticket,
+		Authenticator: messages.EncryptedData{EType: i.SessionKey.KeyType, Cipher: cipher},
+	}
+	if i.Mutual {
+		apReq.APOptions = messages.APOption_MutualRequired
+	}
+	message, err := apReq.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	return WrapKerberosToken(OID_Kerberos5, TokID_APReq, message)
+}
+
+// VerifyResponse verifies the response of an acceptor to the last token built, either a
+// SPNEGO NegTokenResp or a raw Kerberos AP-REP token, checking that the AP-REP echoes the
+// time of the authenticator.
+//
+// Parameters:
+//   - token ([]byte): The response token.
+//
+// Returns:
+//   - error: An error if the response is a rejection or does not authenticate the acceptor.
+func (i *Initiator) VerifyResponse(token []byte) error {
+	if len(token) != 0 && token[0] != 0x60 {
+		negTokenResp := NegTokenResp{}
+		err := negTokenResp.FromBytes(token)
+		if err != nil {
+			return err
+		}
+		if negTokenResp.NegState != NegState_AcceptCompleted {
+			return fmt.Errorf("negotiation was not completed (state %d)", negTokenResp.NegState)
+		}
+		token = negTokenResp.ResponseToken
+	}
+	if len(token) == 0 {
+		if i.Mutual {
+			return fmt.Errorf("no AP-REP in the response")
+		}
+		return nil
+	}
+
+	_, tokID, message, err := UnwrapKerberosToken(token)
+	if err != nil {
+		return err
+	}
+	if tokID == TokID_KRBError {
+		krbError := messages.KRBError{}
+		err = krbError.FromBytes(message)
+		if err != nil {
+			return err
+		}
+		return &krbError
+	}
+	if tokID != TokID_APRep {
+		return fmt.Errorf("kerberos token is not an AP-REP (TOK_ID 0x%04x)", tokID)
+	}
+
+	apRep := messages.APRep{}
+	err = apRep.FromBytes(message)
+	if err != nil {
+		return err
+	}
+	plaintext, err := crypto.Decrypt(apRep.EncPart.EType, i.SessionKey.KeyValue, messages.KeyUsage_AP_REP_ENCPART, apRep.EncPart.Cipher)
+	if err != nil {
+		return fmt.Errorf("error decrypting AP-REP: %w", err)
+	}
+	encPart := messages.EncAPRepPart{}
+	err = encPart.FromBytes(plaintext)
+	if err != nil {
+		return err
+	}
+	if !encPart.CTime.Equal(i.ctime) || encPart.Cusec != i.cusec {
+		return fmt.Errorf("%w: AP-REP does not echo the authenticator time", ErrBadMatch)
+	}
+	return nil
+}
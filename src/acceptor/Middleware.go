@@ -0,0 +1,86 @@
+package acceptor
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// contextKey is the type of the keys of the values stored by the middleware in request contexts.
+type contextKey int
+
+// identityKey is the context key of the authenticated Identity.
+const identityKey contextKey = 0
+
+// Middleware returns a net/http middleware authenticating requests with the
+// "Authorization: Negotiate" header of RFC 4559. Requests without a valid token are
+// answered with a 401 challenge; authenticated requests are passed to next with the
+// Identity of the client in their context, and the response token of the acceptor
+// is sent back in the "WWW-Authenticate" header.
+//
+// Parameters:
+//   - next (http.Handler): The handler of authenticated requests.
+//
+// Returns:
+//   - http.Handler: The authenticating handler.
+func (a *Acceptor) Middleware(next http.Handler) http.Handler {
+	return a.MiddlewareWithErrorHandler(next, nil)
+}
+
+// MiddlewareWithErrorHandler returns the same middleware as Middleware, calling onError
+// with the reason of each rejected authentication, for logging.
+//
+// Parameters:
+//   - next (http.Handler): The handler of authenticated requests.
+//   - onError (func(*http.Request, error)): Called when a token is rejected, may be nil.
+//
+// Returns:
+//   - http.Handler: The authenticating handler.
+func (a *Acceptor) MiddlewareWithErrorHandler(next http.Handler, onError func(*http.Request, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		scheme, encoded, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Negotiate") {
+			challenge(w)
+			return
+		}
+
+		token, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err == nil {
+			var identity *Identity
+			var response []byte
+			identity, response, err = a.Accept(token)
+			if err == nil {
+				if response != nil {
+					w.Header().Set("WWW-Authenticate", "Negotiate "+base64.StdEncoding.EncodeToString(response))
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey, identity)))
+				return
+			}
+		}
+
+		if onError != nil {
+			onError(r, err)
+		}
+		challenge(w)
+	})
+}
+
+// IdentityFromContext returns the Identity stored in a request context by the middleware.
+//
+// Parameters:
+//   - ctx (context.Context): The context of the request.
+//
+// Returns:
+//   - (*Identity, bool): The authenticated client and true if the request was authenticated.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey).(*Identity)
+	return identity, ok && identity != nil
+}
+
+// challenge answers a request with a 401 status and a Negotiate challenge.
+func challenge(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Negotiate")
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
@@ -0,0 +1,399 @@
+package acceptor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"keytab/crypto"
+	"keytab/messages"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// PAC buffer types, as defined in MS-PAC section 2.4.
+const (
+	PACType_LogonInfo             = 1
+	PACType_CredentialsInfo       = 2
+	PACType_ServerChecksum        = 6
+	PACType_PrivSvrChecksum       = 7
+	PACType_ClientInfo            = 10
+	PACType_ConstrainedDelegation = 11
+	PACType_UPNDNSInfo            = 12
+	PACType_ClientClaims          = 13
+	PACType_DeviceInfo            = 14
+	PACType_DeviceClaims          = 15
+	PACType_TicketChecksum        = 16
+	PACType_Attributes            = 17
+	PACType_Requestor             = 18
+	PACType_FullChecksum          = 19
+)
+
+// KeyUsage_PACChecksum is the key usage of the PAC signatures (KERB_NON_KERB_CKSUM_SALT).
+const KeyUsage_PACChecksum = 17
+
+// signatureSizes maps the checksum types used by PAC signatures to the size of the signature.
+var signatureSizes = map[int32]int{
+	crypto.CksumTypeHMACSHA196AES128:    12,
+	crypto.CksumTypeHMACSHA196AES256:    12,
+	crypto.CksumTypeHMACSHA256128AES128: 16,
+	crypto.CksumTypeHMACSHA384192AES256: 24,
+	crypto.CksumTypeHMACMD5:             16,
+}
+
+// PACInfoBuffer represents a buffer of a PAC, as defined in MS-PAC section 2.4.
+//
+// Attributes:
+//   - Type (uint32): The type of the buffer.
+//   - Size (uint32): The size of the buffer.
+//   - Offset (uint64): The offset of the buffer from the start of the PAC.
+//   - Data ([]byte): The content of the buffer.
+type PACInfoBuffer struct {
+	Type   uint32
+	Size   uint32
+	Offset uint64
+	Data   []byte
+}
+
+// PAC represents a Privilege Attribute Certificate, the authorization data issued by
+// Active Directory KDCs, as defined in MS-PAC section 2.3. Only the buffers needed to
+// verify the PAC and identify the client are decoded, the others are kept raw.
+//
+// Attributes:
+//   - Version (uint32): The version of the PAC, always 0.
+//   - Buffers ([]PACInfoBuffer): The buffers of the PAC.
+//   - ClientName (string): The name of the client, from the PAC_CLIENT_INFO buffer.
+//   - ClientID (time.Time): The authentication time of the client, from the PAC_CLIENT_INFO buffer.
+//   - UPN (string): The user principal name of the client, from the UPN_DNS_INFO buffer.
+//   - DNSDomainName (string): The DNS name of the domain of the client, from the UPN_DNS_INFO buffer.
+//   - SamAccountName (string): The sAMAccountName of the client, from the UPN_DNS_INFO buffer, may be empty.
+//   - SID (string): The SID of the client, from the UPN_DNS_INFO buffer, may be empty.
+//   - RawBytes ([]byte): The raw bytes of the PAC.
+type PAC struct {
+	Version        uint32
+	Buffers        []PACInfoBuffer
+	ClientName     string
+	ClientID       time.Time
+	UPN            string
+	DNSDomainName  string
+	SamAccountName string
+	SID            string
+	// Internal
+	RawBytes []byte
+}
+
+// FromBytes parses a PAC.
+//
+// Parameters:
+//   - data ([]byte): The PAC, as found in an AD-WIN2K-PAC authorization data element.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (p *PAC) FromBytes(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("pac is too short")
+	}
+	p.RawBytes = data
+	count := int(binary.LittleEndian.Uint32(data[0:4]))
+	p.Version = binary.LittleEndian.Uint32(data[4:8])
+	if count > (len(data)-8)/16 {
+		return fmt.Errorf("pac buffer count %d exceeds the pac size", count)
+	}
+
+	p.Buffers = make([]PACInfoBuffer, 0, count)
+	for i := 0; i < count; i++ {
+		header := data[8+16*i : 8+16*(i+1)]
+		buffer := PACInfoBuffer{
+			Type:   binary.LittleEndian.Uint32(header[0:4]),
+			Size:   binary.LittleEndian.Uint32(header[4:8]),
+			Offset: binary.LittleEndian.Uint64(header[8:16]),
+		}
+		if buffer.Offset > uint64(len(data)) || uint64(buffer.Size) > uint64(len(data))-buffer.Offset {
+			return fmt.Errorf("pac buffer of type %d is out of bounds", buffer.Type)
+		}
+		buffer.Data = data[buffer.Offset : buffer.Offset+uint64(buffer.Size)]
+		p.Buffers = append(p.Buffers, buffer)
+	}
+
+	if buffer := p.GetBuffer(PACType_ClientInfo); buffer != nil {
+		err := p.parseClientInfo(buffer.Data)
+		if err != nil {
+			return err
+		}
+	}
+	if buffer := p.GetBuffer(PACType_UPNDNSInfo); buffer != nil {
+		err := p.parseUPNDNSInfo(buffer.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetBuffer returns the first buffer of a type.
+//
+// Parameters:
+//   - bufferType (uint32): The type of the buffer.
+//
+// Returns:
+//   - *PACInfoBuffer: The buffer, or nil if the PAC has no buffer of this type.
+func (p *PAC) GetBuffer(bufferType uint32) *PACInfoBuffer {
+	for i := range p.Buffers {
+		if p.Buffers[i].Type == bufferType {
+			return &p.Buffers[i]
+		}
+	}
+	return nil
+}
+
+// VerifyServerChecksum verifies the server signature of the PAC, computed by the KDC
+// with the long-term key of the service over the PAC with both signatures zeroed.
+//
+// Parameters:
+//   - key (messages.EncryptionKey): The long-term key of the service.
+//
+// Returns:
+//   - error: An error if the signature is missing or invalid.
+func (p *PAC) VerifyServerChecksum(key messages.EncryptionKey) error {
+	server := p.GetBuffer(PACType_ServerChecksum)
+	if server == nil {
+		return fmt.Errorf("pac has no server signature")
+	}
+	cksumType, signature, err := parseSignature(server.Data)
+	if err != nil {
+		return err
+	}
+	expectedType, err := crypto.ChecksumTypeForEType(key.KeyType)
+	if err != nil {
+		return err
+	}
+	if cksumType != expectedType {
+		return fmt.Errorf("pac server signature type %d does not match the key type %d", cksumType, key.KeyType)
+	}
+
+	zeroed := append([]byte{}, p.RawBytes...)
+	for _, bufferType := range []uint32{PACType_ServerChecksum, PACType_PrivSvrChecksum} {
+		buffer := p.GetBuffer(bufferType)
+		if buffer == nil {
+			continue
+		}
+		_, sig, err := parseSignature(buffer.Data)
+		if err != nil {
+			return err
+		}
+		start := buffer.Offset + 4
+		for i := uint64(0); i < uint64(len(sig)); i++ {
+			zeroed[start+i] = 0
+		}
+	}
+
+	err = crypto.VerifyChecksum(cksumType, key.KeyValue, KeyUsage_PACChecksum, zeroed, signature)
+	if errors.Is(err, crypto.ErrIntegrity) {
+		return fmt.Errorf("%w: server signature mismatch", ErrInvalidPAC)
+	}
+	return err
+}
+
+// NewPAC builds a minimal PAC holding a PAC_CLIENT_INFO buffer and the server and KDC
+// signatures, as a KDC would issue it. It is meant for tests and stand-in KDCs.
+//
+// Parameters:
+//   - clientName (string): The name of the client, without realm.
+//   - authTime (time.Time): The authentication time of the ticket.
+//   - serverKey (messages.EncryptionKey): The long-term key of the service.
+//   - kdcKey (messages.EncryptionKey): The long-term key of the KDC (krbtgt).
+//
+// Returns:
+//   - ([]byte, error): The PAC and an error if a signature could not be computed.
+func NewPAC(clientName string, authTime time.Time, serverKey, kdcKey messages.EncryptionKey) ([]byte, error) {
+	serverType, err := crypto.ChecksumTypeForEType(serverKey.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	kdcType, err := crypto.ChecksumTypeForEType(kdcKey.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	name := encodeUTF16(clientName)
+	clientInfo := make([]byte, 10, 10+len(name))
+	binary.LittleEndian.PutUint64(clientInfo[0:8], timeToFiletime(authTime.Truncate(time.Second)))
+	binary.LittleEndian.PutUint16(clientInfo[8:10], uint16(len(name)))
+	clientInfo = append(clientInfo, name...)
+
+	newSignature := func(cksumType int32) []byte {
+		data := make([]byte, 4+signatureSizes[cksumType])
+		binary.LittleEndian.PutUint32(data[0:4], uint32(cksumType))
+		return data
+	}
+	contents := [][]byte{clientInfo, newSignature(serverType), newSignature(kdcType)}
+	types := []uint32{PACType_ClientInfo, PACType_ServerChecksum, PACType_PrivSvrChecksum}
+
+	// Buffers are aligned on 8 bytes boundaries
+	data := make([]byte, 8+16*len(contents))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(contents)))
+	offsets := make([]int, len(contents))
+	for i, content := range contents {
+		for len(data)%8 != 0 {
+			data = append(data, 0)
+		}
+		offsets[i] = len(data)
+		header := data[8+16*i : 8+16*(i+1)]
+		binary.LittleEndian.PutUint32(header[0:4], types[i])
+		binary.LittleEndian.PutUint32(header[4:8], uint32(len(content)))
+		binary.LittleEndian.PutUint64(header[8:16], uint64(len(data)))
+		data = append(data, content...)
+	}
+
+	serverSignature, err := crypto.Checksum(serverType, serverKey.KeyValue, KeyUsage_PACChecksum, data)
+	if err != nil {
+		return nil, err
+	}
+	copy(data[offsets[1]+4:], serverSignature)
+	kdcSignature, err := crypto.Checksum(kdcType, kdcKey.KeyValue, KeyUsage_PACChecksum, serverSignature)
+	if err != nil {
+		return nil, err
+	}
+	copy(data[offsets[2]+4:], kdcSignature)
+
+	return data, nil
+}
+
+// Describe prints a description of the PAC.
+//
+// Parameters:
+//   - indent (int): The indentation level for formatting the output.
+func (p *PAC) Describe(indent int) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+	fmt.Printf("%s<PAC>\n", indentPrompt)
+	fmt.Printf("%s │ \x1b[93mClientName\x1b[0m    : \x1b[96m%s\x1b[0m\n", indentPrompt, p.ClientName)
+	fmt.Printf("%s │ \x1b[93mClientID\x1b[0m      : \x1b[96m%s\x1b[0m\n", indentPrompt, p.ClientID.UTC().Format(time.RFC3339))
+	if len(p.UPN) != 0 {
+		fmt.Printf("%s │ \x1b[93mUPN\x1b[0m           : \x1b[96m%s\x1b[0m\n", indentPrompt, p.UPN)
+		fmt.Printf("%s │ \x1b[93mDNSDomainName\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, p.DNSDomainName)
+	}
+	if len(p.SID) != 0 {
+		fmt.Printf("%s │ \x1b[93mSID\x1b[0m           : \x1b[96m%s\x1b[0m\n", indentPrompt, p.SID)
+	}
+	fmt.Printf("%s │ \x1b[93mBuffers\x1b[0m       : \x1b[96m%d\x1b[0m\n", indentPrompt, len(p.Buffers))
+	fmt.Printf("%s └─\n", indentPrompt)
+}
+
+// parseClientInfo parses a PAC_CLIENT_INFO buffer, as defined in MS-PAC section 2.7.
+func (p *PAC) parseClientInfo(data []byte) error {
+	if len(data) < 10 {
+		return fmt.Errorf("pac client info is too short")
+	}
+	p.ClientID = filetimeToTime(binary.LittleEndian.Uint64(data[0:8]))
+	length := int(binary.LittleEndian.Uint16(data[8:10]))
+	if 10+length > len(data) {
+		return fmt.Errorf("pac client name is out of bounds")
+	}
+	p.ClientName = decodeUTF16(data[10 : 10+length])
+	return nil
+}
+
+// parseUPNDNSInfo parses an UPN_DNS_INFO buffer, as defined in MS-PAC section 2.10.
+func (p *PAC) parseUPNDNSInfo(data []byte) error {
+	if len(data) < 12 {
+		return fmt.Errorf("pac upn dns info is too short")
+	}
+	field := func(lengthOffset int) (string, []byte, error) {
+		length := int(binary.LittleEndian.Uint16(data[lengthOffset : lengthOffset+2]))
+		offset := int(binary.LittleEndian.Uint16(data[lengthOffset+2 : lengthOffset+4]))
+		if offset+length > len(data) {
+			return "", nil, fmt.Errorf("pac upn dns info field is out of bounds")
+		}
+		return decodeUTF16(data[offset : offset+length]), data[offset : offset+length], nil
+	}
+
+	var err error
+	if p.UPN, _, err = field(0); err != nil {
+		return err
+	}
+	if p.DNSDomainName, _, err = field(4); err != nil {
+		return err
+	}
+
+	// The S flag announces the sAMAccountName and SID extension
+	flags := binary.LittleEndian.Uint32(data[8:12])
+	if flags&0x2 != 0 && len(data) >= 20 {
+		if p.SamAccountName, _, err = field(12); err != nil {
+			return err
+		}
+		_, sid, err := field(16)
+		if err != nil {
+			return err
+		}
+		p.SID = sidToString(sid)
+	}
+	return nil
+}
+
+// parseSignature parses a PAC_SIGNATURE_DATA, as defined in MS-PAC section 2.8.
+func parseSignature(data []byte) (int32, []byte, error) {
+	if len(data) < 4 {
+		return 0, nil, fmt.Errorf("pac signature is too short")
+	}
+	cksumType := int32(binary.LittleEndian.Uint32(data[0:4]))
+	size, ok := signatureSizes[cksumType]
+	if !ok {
+		return 0, nil, fmt.Errorf("unsupported pac signature type %d", cksumType)
+	}
+	if 4+size > len(data) {
+		return 0, nil, fmt.Errorf("pac signature is truncated")
+	}
+	return cksumType, data[4 : 4+size], nil
+}
+
+// filetimeToTime converts a Windows FILETIME, in 100 nanoseconds intervals since 1601, to a time.
+func filetimeToTime(filetime uint64) time.Time {
+	const epochDifference = 116444736000000000
+	if filetime < epochDifference {
+		return time.Time{}
+	}
+	intervals := filetime - epochDifference
+	return time.Unix(int64(intervals/10000000), int64(intervals%10000000)*100).UTC()
+}
+
+// timeToFiletime converts a time to a Windows FILETIME.
+func timeToFiletime(t time.Time) uint64 {
+	const epochDifference = 116444736000000000
+	return uint64(t.UnixNano()/100) + epochDifference
+}
+
+// decodeUTF16 decodes a little endian UTF-16 string.
+func decodeUTF16(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[2*i : 2*i+2])
+	}
+	return string(utf16.Decode(units))
+}
+
+// encodeUTF16 encodes a string as little endian UTF-16.
+func encodeUTF16(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	data := make([]byte, 2*len(units))
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(data[2*i:2*i+2], unit)
+	}
+	return data
+}
+
+// sidToString converts a binary SID to its "S-1-5-21-..." form.
+func sidToString(sid []byte) string {
+	if len(sid) < 8 || len(sid) < 8+4*int(sid[1]) {
+		return ""
+	}
+	authority := uint64(0)
+	for _, b := range sid[2:8] {
+		authority = authority<<8 | uint64(b)
+	}
+	result := fmt.Sprintf("S-%d-%d", sid[0], authority)
+	for i := 0; i < int(sid[1]); i++ {
+		result += fmt.Sprintf("-%d", binary.LittleEndian.Uint32(sid[8+4*i:12+4*i]))
+	}
+	return result
+}
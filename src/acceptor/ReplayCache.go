@@ -0,0 +1,62 @@
+package acceptor
+
+import (
+	"sync"
+	"time"
+)
+
+// ReplayCache remembers the authenticators seen within the clock skew window, so that
+// a captured AP-REQ cannot be presented twice, as required by RFC 4120 section 3.2.3.
+// It is safe for concurrent use.
+//
+// Attributes:
+//   - entries (map[string]time.Time): The authenticators seen, mapped to the time after which they can be forgotten.
+type ReplayCache struct {
+	mutex   sync.Mutex
+	entries map[string]time.Time
+}
+
+// NewReplayCache creates an empty ReplayCache.
+//
+// Returns:
+//   - *ReplayCache: The replay cache.
+func NewReplayCache() *ReplayCache {
+	return &ReplayCache{entries: make(map[string]time.Time)}
+}
+
+// Check records an authenticator and reports whether it was already seen.
+// Expired entries are purged on each call.
+//
+// Parameters:
+//   - id (string): The identifier of the authenticator.
+//   - expires (time.Time): The time after which the authenticator would be rejected by the clock skew check anyway.
+//   - now (time.Time): The current time.
+//
+// Returns:
+//   - bool: True if the authenticator is a replay.
+func (r *ReplayCache) Check(id string, expires, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key, expiry := range r.entries {
+		if expiry.Before(now) {
+			delete(r.entries, key)
+		}
+	}
+
+	if _, ok := r.entries[id]; ok {
+		return true
+	}
+	r.entries[id] = expires
+	return false
+}
+
+// Len returns the number of authenticators currently remembered.
+//
+// Returns:
+//   - int: The number of entries.
+func (r *ReplayCache) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.entries)
+}
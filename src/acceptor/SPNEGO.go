@@ -0,0 +1,312 @@
+package acceptor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"keytab/der"
+)
+
+// Object identifiers of the GSS-API mechanisms.
+const (
+	OID_SPNEGO       = "1.3.6.1.5.5.2"
+	OID_Kerberos5    = "1.2.840.113554.1.2.2"
+	OID_MSKerberos5  = "1.2.840.48018.1.2.2"
+	OID_NTLMSSP      = "1.3.6.1.4.1.311.2.2.10"
+	OID_Kerberos5U2U = "1.2.840.113554.1.2.2.3"
+)
+
+// Token identifiers of the Kerberos GSS-API tokens, as defined in RFC 4121 section 4.1.
+const (
+	TokID_APReq    uint16 = 0x0100
+	TokID_APRep    uint16 = 0x0200
+	TokID_KRBError uint16 = 0x0300
+)
+
+// Negotiation states of a SPNEGO NegTokenResp, as defined in RFC 4178 section 4.2.2.
+const (
+	NegState_AcceptCompleted  = 0
+	NegState_AcceptIncomplete = 1
+	NegState_Reject           = 2
+	NegState_RequestMIC       = 3
+)
+
+// NegTokenInit represents the initial SPNEGO token sent by the initiator, as defined in RFC 4178 section 4.2.1.
+//
+// Attributes:
+//   - MechTypes ([]string): The object identifiers of the mechanisms proposed by the initiator, in order of preference.
+//   - MechToken ([]byte): The optimistic token of the preferred mechanism, may be nil.
+//   - MechListMIC ([]byte): The MIC over the mechanism list, may be nil.
+type NegTokenInit struct {
+	MechTypes   []string
+	MechToken   []byte
+	MechListMIC []byte
+}
+
+// NegTokenResp represents a SPNEGO response token, as defined in RFC 4178 section 4.2.2.
+//
+// Attributes:
+//   - NegState (int): The state of the negotiation.
+//   - SupportedMech (string): The object identifier of the selected mechanism, may be empty.
+//   - ResponseToken ([]byte): The token of the selected mechanism, may be nil.
+//   - MechListMIC ([]byte): The MIC over the mechanism list, may be nil.
+type NegTokenResp struct {
+	NegState      int
+	SupportedMech string
+	ResponseToken []byte
+	MechListMIC   []byte
+}
+
+// FromBytes parses a SPNEGO NegTokenInit, framed as a GSS-API InitialContextToken.
+//
+// Parameters:
+//   - data ([]byte): The token.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (n *NegTokenInit) FromBytes(data []byte) error {
+	oid, inner, err := UnwrapInitialContextToken(data)
+	if err != nil {
+		return err
+	}
+	if oid != OID_SPNEGO {
+		return fmt.Errorf("token is not a SPNEGO token but a %s token", oid)
+	}
+
+	element, _, err := der.Parse(inner)
+	if err != nil {
+		return fmt.Errorf("error parsing NegTokenInit: %w", err)
+	}
+	if !element.Is(der.ClassContext, 0) {
+		return fmt.Errorf("token is not a NegTokenInit")
+	}
+	element, err = element.Unwrap(der.ClassContext, 0)
+	if err != nil {
+		return err
+	}
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing NegTokenInit: %w", err)
+	}
+
+	n.MechTypes = nil
+	if mechTypes, ok := fields[0]; ok {
+		children, err := mechTypes.Children()
+		if err != nil {
+			return fmt.Errorf("error parsing mechTypes: %w", err)
+		}
+		for _, child := range children {
+			mech, err := child.OID()
+			if err != nil {
+				return fmt.Errorf("error parsing mechTypes: %w", err)
+			}
+			n.MechTypes = append(n.MechTypes, mech)
+		}
+	}
+
+	n.MechToken, n.MechListMIC = nil, nil
+	if mechToken, ok := fields[2]; ok {
+		if n.MechToken, err = mechToken.Bytes(); err != nil {
+			return fmt.Errorf("error parsing mechToken: %w", err)
+		}
+	}
+	if mic, ok := fields[3]; ok {
+		if n.MechListMIC, err = mic.Bytes(); err != nil {
+			return fmt.Errorf("error parsing mechListMIC: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ToBytes converts a NegTokenInit to a GSS-API InitialContextToken.
+//
+// Returns:
+//   - ([]byte, error): The token and an error if an object identifier is invalid.
+func (n *NegTokenInit) ToBytes() ([]byte, error) {
+	mechTypes := make([][]byte, 0, len(n.MechTypes))
+	for _, mech := range n.MechTypes {
+		encoded, err := der.ObjectIdentifier(mech)
+		if err != nil {
+			return nil, err
+		}
+		mechTypes = append(mechTypes, encoded)
+	}
+
+	var mechToken, mechListMIC []byte
+	if n.MechToken != nil {
+		mechToken = der.Explicit(2, der.OctetString(n.MechToken))
+	}
+	if n.MechListMIC != nil {
+		mechListMIC = der.Explicit(3, der.OctetString(n.MechListMIC))
+	}
+
+	inner := der.Explicit(0, der.Sequence(
+		der.Explicit(0, der.Sequence(mechTypes...)),
+		mechToken,
+		mechListMIC,
+	))
+	return WrapInitialContextToken(OID_SPNEGO, inner)
+}
+
+// FromBytes parses a SPNEGO NegTokenResp.
+//
+// Parameters:
+//   - data ([]byte): The token.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (n *NegTokenResp) FromBytes(data []byte) error {
+	element, _, err := der.Parse(data)
+	if err != nil {
+		return fmt.Errorf("error parsing NegTokenResp: %w", err)
+	}
+	element, err = element.Unwrap(der.ClassContext, 1)
+	if err != nil {
+		return fmt.Errorf("token is not a NegTokenResp: %w", err)
+	}
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing NegTokenResp: %w", err)
+	}
+
+	n.NegState = NegState_AcceptIncomplete
+	if negState, ok := fields[0]; ok {
+		value, err := negState.Int()
+		if err != nil {
+			return fmt.Errorf("error parsing negState: %w", err)
+		}
+		n.NegState = int(value)
+	}
+
+	n.SupportedMech, n.ResponseToken, n.MechListMIC = "", nil, nil
+	if mech, ok := fields[1]; ok {
+		if n.SupportedMech, err = mech.OID(); err != nil {
+			return fmt.Errorf("error parsing supportedMech: %w", err)
+		}
+	}
+	if token, ok := fields[2]; ok {
+		if n.ResponseToken, err = token.Bytes(); err != nil {
+			return fmt.Errorf("error parsing responseToken: %w", err)
+		}
+	}
+	if mic, ok := fields[3]; ok {
+		if n.MechListMIC, err = mic.Bytes(); err != nil {
+			return fmt.Errorf("error parsing mechListMIC: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ToBytes converts a NegTokenResp to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The token and an error if the supported mechanism is invalid.
+func (n *NegTokenResp) ToBytes() ([]byte, error) {
+	var supportedMech, responseToken, mechListMIC []byte
+	if len(n.SupportedMech) != 0 {
+		oid, err := der.ObjectIdentifier(n.SupportedMech)
+		if err != nil {
+			return nil, err
+		}
+		supportedMech = der.Explicit(1, oid)
+	}
+	if n.ResponseToken != nil {
+		responseToken = der.Explicit(2, der.OctetString(n.ResponseToken))
+	}
+	if n.MechListMIC != nil {
+		mechListMIC = der.Explicit(3, der.OctetString(n.MechListMIC))
+	}
+
+	return der.Explicit(1, der.Sequence(
+		der.Explicit(0, der.Enumerated(int64(n.NegState))),
+		supportedMech,
+		responseToken,
+		mechListMIC,
+	)), nil
+}
+
+// WrapInitialContextToken frames a token as a GSS-API InitialContextToken, as defined in RFC 2743 section 3.1.
+//
+// Parameters:
+//   - mech (string): The object identifier of the mechanism.
+//   - inner ([]byte): The mechanism specific token.
+//
+// Returns:
+//   - ([]byte, error): The framed token and an error if the object identifier is invalid.
+func WrapInitialContextToken(mech string, inner []byte) ([]byte, error) {
+	oid, err := der.ObjectIdentifier(mech)
+	if err != nil {
+		return nil, err
+	}
+	return der.Encode(der.ClassApplication, true, 0, append(oid, inner...)), nil
+}
+
+// UnwrapInitialContextToken removes the GSS-API InitialContextToken framing of a token.
+//
+// Parameters:
+//   - data ([]byte): The framed token.
+//
+// Returns:
+//   - (string, []byte, error): The object identifier of the mechanism, the mechanism specific token and an error if the framing is invalid.
+func UnwrapInitialContextToken(data []byte) (string, []byte, error) {
+	element, _, err := der.Parse(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing GSS-API token: %w", err)
+	}
+	if element.Class != der.ClassApplication || element.Tag != 0 || !element.Constructed {
+		return "", nil, fmt.Errorf("token is not a GSS-API InitialContextToken")
+	}
+	mech, inner, err := der.Parse(element.Content)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing GSS-API mechanism: %w", err)
+	}
+	oid, err := mech.OID()
+	if err != nil {
+		return "", nil, err
+	}
+	return oid, inner, nil
+}
+
+// WrapKerberosToken frames a Kerberos message as a Kerberos GSS-API token, as defined in RFC 4121 section 4.1.
+//
+// Parameters:
+//   - mech (string): The object identifier of the Kerberos mechanism, either OID_Kerberos5 or OID_MSKerberos5.
+//   - tokID (uint16): The token identifier.
+//   - message ([]byte): The DER encoded Kerberos message.
+//
+// Returns:
+//   - ([]byte, error): The framed token and an error if the object identifier is invalid.
+func WrapKerberosToken(mech string, tokID uint16, message []byte) ([]byte, error) {
+	inner := make([]byte, 2, 2+len(message))
+	binary.BigEndian.PutUint16(inner, tokID)
+	return WrapInitialContextToken(mech, append(inner, message...))
+}
+
+// UnwrapKerberosToken removes the framing of a Kerberos GSS-API token.
+//
+// Parameters:
+//   - data ([]byte): The framed token.
+//
+// Returns:
+//   - (string, uint16, []byte, error): The object identifier of the mechanism, the token identifier,
+//     the DER encoded Kerberos message and an error if the token is not a Kerberos token.
+func UnwrapKerberosToken(data []byte) (string, uint16, []byte, error) {
+	mech, inner, err := UnwrapInitialContextToken(data)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if mech != OID_Kerberos5 && mech != OID_MSKerberos5 {
+		return "", 0, nil, fmt.Errorf("%w: %s", ErrUnsupportedMechanism, mech)
+	}
+	if len(inner) < 2 {
+		return "", 0, nil, fmt.Errorf("kerberos token is truncated")
+	}
+	return mech, binary.BigEndian.Uint16(inner[0:2]), inner[2:], nil
+}
+
+// isNTLMToken returns true if a mechanism token is a raw NTLMSSP message.
+func isNTLMToken(token []byte) bool {
+	return bytes.HasPrefix(token, []byte("NTLMSSP\x00"))
+}
@@ -0,0 +1,178 @@
+package ad
+
+import (
+	"fmt"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"strings"
+)
+
+// Bits of the msDS-SupportedEncryptionTypes attribute, as defined in MS-KILE section 2.2.7.
+const (
+	SupportedEncryptionType_DES_CBC_CRC             = 0x01
+	SupportedEncryptionType_DES_CBC_MD5             = 0x02
+	SupportedEncryptionType_RC4_HMAC                = 0x04
+	SupportedEncryptionType_AES128_CTS_HMAC_SHA1_96 = 0x08
+	SupportedEncryptionType_AES256_CTS_HMAC_SHA1_96 = 0x10
+)
+
+// DefaultSupportedEncryptionTypes is the msDS-SupportedEncryptionTypes value of the computer
+// accounts joined by Windows, adcli and msktutil: RC4-HMAC, AES128 and AES256.
+const DefaultSupportedEncryptionTypes = SupportedEncryptionType_RC4_HMAC | SupportedEncryptionType_AES128_CTS_HMAC_SHA1_96 | SupportedEncryptionType_AES256_CTS_HMAC_SHA1_96
+
+// maxAccountNameLength is the maximum length of the sAMAccountName of a computer, without its trailing $.
+const maxAccountNameLength = 15
+
+// supportedEncryptionTypeBits maps the bits of msDS-SupportedEncryptionTypes to their encryption
+// types, the strongest first.
+var supportedEncryptionTypeBits = []struct {
+	bit   uint32
+	etype keytab.EncryptionType
+}{
+	{SupportedEncryptionType_AES256_CTS_HMAC_SHA1_96, keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96},
+	{SupportedEncryptionType_AES128_CTS_HMAC_SHA1_96, keytab.EncryptionType_AES128_CTS_HMAC_SHA1_96},
+	{SupportedEncryptionType_RC4_HMAC, keytab.EncryptionType_RC4_HMAC},
+	{SupportedEncryptionType_DES_CBC_MD5, keytab.EncryptionType_DES_CBC_MD5},
+	{SupportedEncryptionType_DES_CBC_CRC, keytab.EncryptionType_DES_CBC_CRC},
+}
+
+// SupportedEncryptionTypes returns the encryption types of a msDS-SupportedEncryptionTypes value,
+// the strongest first. The bits which are not encryption types, such as AES256-CTS-HMAC-SHA1-96-SK
+// (0x20) and the FAST and claims flags, are ignored.
+//
+// Parameters:
+//   - value (uint32): The msDS-SupportedEncryptionTypes value.
+//
+// Returns:
+//   - []keytab.EncryptionType: The encryption types.
+func SupportedEncryptionTypes(value uint32) []keytab.EncryptionType {
+	etypes := []keytab.EncryptionType{}
+	for _, bit := range supportedEncryptionTypeBits {
+		if value&bit.bit != 0 {
+			etypes = append(etypes, bit.etype)
+		}
+	}
+	return etypes
+}
+
+// MachineAccount is the computer account of a host joined to an Active Directory domain.
+//
+// Attributes:
+//   - Realm (string): The upper-case realm of the domain.
+//   - Hostname (string): The lower-case short host name.
+//   - FQDN (string): The lower-case fully qualified domain name of the host.
+//   - Account (string): The sAMAccountName of the computer, the upper-case short host name truncated to 15 characters followed by $.
+type MachineAccount struct {
+	Realm    string
+	Hostname string
+	FQDN     string
+	Account  string
+}
+
+// NewMachineAccount returns the computer account of a host, as adcli and msktutil name it.
+//
+// Parameters:
+//   - domain (string): The DNS name of the domain.
+//   - hostname (string): The short host name or the fully qualified domain name of the host.
+//
+// Returns:
+//   - (*MachineAccount, error): The account and an error if the domain or host name is empty or invalid.
+func NewMachineAccount(domain, hostname string) (*MachineAccount, error) {
+	domain = strings.Trim(domain, ".")
+	hostname = strings.ToLower(strings.Trim(hostname, "."))
+	if len(domain) == 0 {
+		return nil, fmt.Errorf("empty domain")
+	}
+	if len(hostname) == 0 || strings.ContainsAny(hostname, "/@$ ") {
+		return nil, fmt.Errorf("invalid host name %q", hostname)
+	}
+
+	account := &MachineAccount{Realm: strings.ToUpper(domain), FQDN: hostname}
+	if short, _, found := strings.Cut(hostname, "."); found {
+		account.Hostname = short
+	} else {
+		account.Hostname = hostname
+		account.FQDN = hostname + "." + strings.ToLower(domain)
+	}
+	name := strings.ToUpper(account.Hostname)
+	if len(name) > maxAccountNameLength {
+		name = name[:maxAccountNameLength]
+	}
+	account.Account = name + "$"
+	return account, nil
+}
+
+// Salt returns the salt of the AES keys of the account, derived from its sAMAccountName.
+//
+// Returns:
+//   - string: The salt, such as "EXAMPLE.COMhostweb01.example.com".
+func (m *MachineAccount) Salt() string {
+	return keytab.MachineSalt(m.Realm, m.Account)
+}
+
+// Principals returns the components of the principals of the account: its sAMAccountName, then
+// the host and RestrictedKrbHost service principal names registered when joining the domain, and
+// those of the additional service classes, each with the short and fully qualified host names.
+//
+// Parameters:
+//   - services ([]string): The additional service classes, such as HTTP, cifs or nfs.
+//
+// Returns:
+//   - [][]string: The components of the principals.
+func (m *MachineAccount) Principals(services []string) [][]string {
+	principals := [][]string{{m.Account}}
+	seen := make(map[string]bool)
+	for _, service := range append([]string{"host", "RestrictedKrbHost"}, services...) {
+		service = strings.TrimSpace(service)
+		if len(service) == 0 || seen[strings.ToLower(service)] {
+			continue
+		}
+		seen[strings.ToLower(service)] = true
+		principals = append(principals, []string{service, m.Hostname}, []string{service, m.FQDN})
+	}
+	return principals
+}
+
+// KeytabEntries derives the keys of the password of the account and returns the keytab entries
+// of all its principals, which share the keys of the account. The AES keys are salted with the
+// salt of the account.
+//
+// Parameters:
+//   - password (string): The password of the account.
+//   - kvno (uint32): The key version number, the msDS-KeyVersionNumber of the account.
+//   - etypes ([]keytab.EncryptionType): The encryption types of the keys.
+//   - services ([]string): The additional service classes.
+//
+// Returns:
+//   - ([]keytab.KeytabEntry, error): The entries and an error if an encryption type is not supported or an entry can not be encoded.
+func (m *MachineAccount) KeytabEntries(password string, kvno uint32, etypes []keytab.EncryptionType, services []string) ([]keytab.KeytabEntry, error) {
+	keys := make([][]byte, len(etypes))
+	defer func() {
+		for _, key := range keys {
+			clear(key)
+		}
+	}()
+	for i, etype := range etypes {
+		if !crypto.IsSupported(int32(etype)) {
+			return nil, fmt.Errorf("unsupported encryption type %s", etype.String())
+		}
+		key, err := crypto.StringToKey(int32(etype), password, m.Salt(), nil)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+
+	entries := []keytab.KeytabEntry{}
+	for _, components := range m.Principals(services) {
+		for i, etype := range etypes {
+			entry, err := keytab.NewKeytabEntry(m.Realm, components, messages.NameType_PRINCIPAL, kvno, etype, keys[i])
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
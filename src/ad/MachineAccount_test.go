@@ -0,0 +1,66 @@
+package ad
+
+import (
+	"bytes"
+	"keytab/crypto"
+	"keytab/keytab"
+	"reflect"
+	"testing"
+)
+
+func Test_SupportedEncryptionTypes(t *testing.T) {
+	etypes := SupportedEncryptionTypes(DefaultSupportedEncryptionTypes)
+	expected := []keytab.EncryptionType{keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, keytab.EncryptionType_AES128_CTS_HMAC_SHA1_96, keytab.EncryptionType_RC4_HMAC}
+	if !reflect.DeepEqual(etypes, expected) {
+		t.Errorf("Unexpected encryption types %v for 0x1c", etypes)
+	}
+	if etypes = SupportedEncryptionTypes(0x38); len(etypes) != 2 || etypes[1] != keytab.EncryptionType_AES128_CTS_HMAC_SHA1_96 {
+		t.Errorf("Expected AES256 and AES128 for 0x38, got %v", etypes)
+	}
+}
+
+func Test_MachineAccount_KeytabEntries(t *testing.T) {
+	account, err := NewMachineAccount("testsegment.local", "WEB01")
+	if err != nil {
+		t.Fatalf("Error creating machine account: %v", err)
+	}
+	if account.Realm != "TESTSEGMENT.LOCAL" || account.Hostname != "web01" || account.FQDN != "web01.testsegment.local" || account.Account != "WEB01$" {
+		t.Errorf("Unexpected machine account %+v", account)
+	}
+	if account.Salt() != "TESTSEGMENT.LOCALhostweb01.testsegment.local" {
+		t.Errorf("Unexpected salt %q", account.Salt())
+	}
+
+	principals := account.Principals([]string{"HTTP", "host", " cifs"})
+	expected := [][]string{
+		{"WEB01$"},
+		{"host", "web01"}, {"host", "web01.testsegment.local"},
+		{"RestrictedKrbHost", "web01"}, {"RestrictedKrbHost", "web01.testsegment.local"},
+		{"HTTP", "web01"}, {"HTTP", "web01.testsegment.local"},
+		{"cifs", "web01"}, {"cifs", "web01.testsegment.local"},
+	}
+	if !reflect.DeepEqual(principals, expected) {
+		t.Errorf("Unexpected principals %v", principals)
+	}
+
+	entries, err := account.KeytabEntries("Password123!", 4, SupportedEncryptionTypes(DefaultSupportedEncryptionTypes), nil)
+	if err != nil {
+		t.Fatalf("Error creating keytab entries: %v", err)
+	}
+	if len(entries) != 15 || entries[3].Principal() != "host/web01@TESTSEGMENT.LOCAL" || entries[14].KVNO() != 4 {
+		t.Fatalf("Expected 3 keys of kvno 4 for 5 principals, got %d entries", len(entries))
+	}
+	aes256, _ := crypto.StringToKey(crypto.ETypeAES256CTSHMACSHA196, "Password123!", "TESTSEGMENT.LOCALhostweb01.testsegment.local", nil)
+	if !bytes.Equal(entries[0].Key.Key.Data, aes256) || !bytes.Equal(entries[12].Key.Key.Data, aes256) {
+		t.Errorf("Expected the AES256 key salted with the machine salt for all the principals")
+	}
+
+	if _, err = account.KeytabEntries("Password123!", 4, SupportedEncryptionTypes(SupportedEncryptionType_DES_CBC_MD5), nil); err == nil {
+		t.Errorf("Expected an error for DES keys")
+	}
+
+	account, err = NewMachineAccount("TESTSEGMENT.LOCAL", "fileserver-production01.testsegment.local")
+	if err != nil || account.Account != "FILESERVER-PROD$" || account.FQDN != "fileserver-production01.testsegment.local" {
+		t.Errorf("Unexpected machine account %+v (%v)", account, err)
+	}
+}
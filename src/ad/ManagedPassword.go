@@ -0,0 +1,208 @@
+package ad
+
+import (
+	"encoding/binary"
+	"fmt"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"math"
+	"strings"
+	"time"
+)
+
+// ManagedPasswordVersion is the version of MSDS-MANAGEDPASSWORD_BLOB.
+const ManagedPasswordVersion = 1
+
+// managedPasswordHeaderSize is the size of the header of MSDS-MANAGEDPASSWORD_BLOB, up to its first field.
+const managedPasswordHeaderSize = 16
+
+// managedPasswordEncryptionTypes are the encryption types of the keys derived from managed passwords,
+// those Active Directory derives for group managed service accounts.
+var managedPasswordEncryptionTypes = []keytab.EncryptionType{
+	keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96,
+	keytab.EncryptionType_AES128_CTS_HMAC_SHA1_96,
+	keytab.EncryptionType_RC4_HMAC,
+}
+
+// ManagedPassword holds the passwords of a group managed service account, read from its
+// msDS-ManagedPassword attribute as a MSDS-MANAGEDPASSWORD_BLOB, as defined in MS-ADTS section 2.2.19.
+//
+// Attributes:
+//   - Version (uint16): The version of the blob, ManagedPasswordVersion.
+//   - CurrentPassword ([]byte): The current password, in UTF-16LE without its null terminator.
+//   - PreviousPassword ([]byte): The previous password, in UTF-16LE without its null terminator, empty if the password never changed.
+//   - QueryPasswordInterval (time.Duration): The time left before the password must be queried again.
+//   - UnchangedPasswordInterval (time.Duration): The time left during which the password does not change.
+type ManagedPassword struct {
+	Version                   uint16
+	CurrentPassword           []byte
+	PreviousPassword          []byte
+	QueryPasswordInterval     time.Duration
+	UnchangedPasswordInterval time.Duration
+}
+
+// ParseManagedPassword parses the msDS-ManagedPassword attribute of a group managed service account.
+//
+// Parameters:
+//   - data ([]byte): The MSDS-MANAGEDPASSWORD_BLOB.
+//
+// Returns:
+//   - (*ManagedPassword, error): The passwords and an error if the blob can not be parsed.
+func ParseManagedPassword(data []byte) (*ManagedPassword, error) {
+	password := &ManagedPassword{}
+	err := password.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return password, nil
+}
+
+// FromBytes parses a MSDS-MANAGEDPASSWORD_BLOB. The offsets of its fields are relative to the
+// start of the blob, and an offset of 0 marks an absent previous password.
+//
+// Parameters:
+//   - data ([]byte): The blob.
+//
+// Returns:
+//   - error: An error if the version is unknown or the data is truncated.
+func (p *ManagedPassword) FromBytes(data []byte) error {
+	if len(data) < managedPasswordHeaderSize {
+		return fmt.Errorf("truncated managed password blob")
+	}
+	p.Version = binary.LittleEndian.Uint16(data[0:2])
+	if p.Version != ManagedPasswordVersion {
+		return fmt.Errorf("unknown managed password blob version %d", p.Version)
+	}
+	length := binary.LittleEndian.Uint32(data[4:8])
+	if uint64(length) > uint64(len(data)) {
+		return fmt.Errorf("managed password blob of %d bytes exceeds the %d bytes of data", length, len(data))
+	}
+	data = data[:length]
+	currentOffset := int(binary.LittleEndian.Uint16(data[8:10]))
+	previousOffset := int(binary.LittleEndian.Uint16(data[10:12]))
+	queryOffset := int(binary.LittleEndian.Uint16(data[12:14]))
+	unchangedOffset := int(binary.LittleEndian.Uint16(data[14:16]))
+
+	// Each password ends at the next field, the last one being followed by the alignment padding of the intervals
+	var err error
+	currentEnd := queryOffset
+	if previousOffset != 0 {
+		currentEnd = previousOffset
+	}
+	if p.CurrentPassword, err = readManagedPassword(data, currentOffset, currentEnd, previousOffset == 0); err != nil {
+		return fmt.Errorf("current password: %v", err)
+	}
+	p.PreviousPassword = nil
+	if previousOffset != 0 {
+		if p.PreviousPassword, err = readManagedPassword(data, previousOffset, queryOffset, true); err != nil {
+			return fmt.Errorf("previous password: %v", err)
+		}
+	}
+	if p.QueryPasswordInterval, err = readInterval(data, queryOffset); err != nil {
+		return fmt.Errorf("query password interval: %v", err)
+	}
+	if p.UnchangedPasswordInterval, err = readInterval(data, unchangedOffset); err != nil {
+		return fmt.Errorf("unchanged password interval: %v", err)
+	}
+	return nil
+}
+
+// readManagedPassword returns a copy of the UTF-16LE password between an offset of the blob and the
+// offset of the next field, without its null terminator. As the password is random and may hold
+// null units, its end is given by the next field rather than by its first null unit, as impacket does.
+//
+// Parameters:
+//   - data ([]byte): The blob.
+//   - offset (int): The offset of the password.
+//   - next (int): The offset of the next field.
+//   - padded (bool): Whether the password is followed by the padding aligning the intervals on 8 bytes.
+//
+// Returns:
+//   - ([]byte, error): The password and an error if it is outside of the data or not null-terminated.
+func readManagedPassword(data []byte, offset, next int, padded bool) ([]byte, error) {
+	if offset < managedPasswordHeaderSize || next > len(data) || next < offset+2 {
+		return nil, fmt.Errorf("password from offset %d to %d outside of the %d bytes of data", offset, next, len(data))
+	}
+	end := next - 2
+	if padded {
+		// Up to 6 bytes of padding, as the password and its terminator have an even length
+		for end-2 >= offset && next-(end-2) <= 2+6 && data[end-2] == 0 && data[end-1] == 0 {
+			end -= 2
+		}
+	}
+	for _, b := range data[end:next] {
+		if b != 0 {
+			return nil, fmt.Errorf("missing null terminator")
+		}
+	}
+	return append([]byte{}, data[offset:end]...), nil
+}
+
+// readInterval returns the interval at an offset of the blob, a count of 100 nanoseconds.
+func readInterval(data []byte, offset int) (time.Duration, error) {
+	if offset < managedPasswordHeaderSize || offset+8 > len(data) {
+		return 0, fmt.Errorf("offset %d outside of the %d bytes of data", offset, len(data))
+	}
+	interval := binary.LittleEndian.Uint64(data[offset : offset+8])
+	if interval > math.MaxInt64/100 {
+		// Intervals which never expire
+		return time.Duration(math.MaxInt64), nil
+	}
+	return time.Duration(interval) * 100, nil
+}
+
+// KeytabEntries derives the AES256, AES128 and RC4 keys of the passwords, as Active Directory does
+// for the account, and returns their keytab entries for the "ACCOUNT$" principal. The AES keys are
+// salted like those of computer accounts. The keys of the current password get the given kvno, the
+// msDS-KeyVersionNumber of the account, and those of the previous password the previous kvno,
+// unless it would be 0.
+//
+// Parameters:
+//   - realm (string): The realm of the account.
+//   - account (string): The sAMAccountName of the account, with or without its trailing $.
+//   - kvno (uint32): The key version number of the current keys.
+//
+// Returns:
+//   - ([]keytab.KeytabEntry, error): The entries and an error if the kvno is 0 or a key can not be derived.
+func (p *ManagedPassword) KeytabEntries(realm string, account string, kvno uint32) ([]keytab.KeytabEntry, error) {
+	if kvno == 0 {
+		return nil, fmt.Errorf("the key version number of the current keys is needed")
+	}
+	realm = strings.ToUpper(realm)
+	name := strings.TrimSuffix(account, "$")
+	salt := keytab.MachineSalt(realm, name)
+
+	entries := []keytab.KeytabEntry{}
+	for age, password := range [][]byte{p.CurrentPassword, p.PreviousPassword} {
+		if len(password) == 0 || uint32(age) >= kvno {
+			continue
+		}
+		for _, etype := range managedPasswordEncryptionTypes {
+			var key []byte
+			var err error
+			if etype == keytab.EncryptionType_RC4_HMAC {
+				// The password is random and may not be valid UTF-16, its encoding is hashed as is
+				key = crypto.NTHash(password)
+			} else {
+				key, err = crypto.StringToKey(int32(etype), decodeUTF16(password), salt, nil)
+				if err != nil {
+					return nil, err
+				}
+			}
+			entry, err := keytab.NewKeytabEntry(realm, []string{name + "$"}, messages.NameType_PRINCIPAL, kvno-uint32(age), etype, key)
+			clear(key)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Wipe overwrites the passwords with zeros.
+func (p *ManagedPassword) Wipe() {
+	clear(p.CurrentPassword)
+	clear(p.PreviousPassword)
+}
@@ -0,0 +1,103 @@
+package ad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"keytab/crypto"
+	"keytab/keytab"
+	"testing"
+	"time"
+)
+
+// newManagedPassword encodes a MSDS-MANAGEDPASSWORD_BLOB, without a previous password when it is empty.
+func newManagedPassword(current, previous []byte, query, unchanged time.Duration) []byte {
+	body := []byte{}
+	currentOffset := managedPasswordHeaderSize
+	body = append(append(body, current...), 0, 0)
+	previousOffset := 0
+	if len(previous) != 0 {
+		previousOffset = managedPasswordHeaderSize + len(body)
+		body = append(append(body, previous...), 0, 0)
+	}
+	for len(body)%8 != 0 {
+		body = append(body, 0)
+	}
+	queryOffset := managedPasswordHeaderSize + len(body)
+	body = binary.LittleEndian.AppendUint64(body, uint64(query/100))
+	unchangedOffset := managedPasswordHeaderSize + len(body)
+	body = binary.LittleEndian.AppendUint64(body, uint64(unchanged/100))
+
+	data := binary.LittleEndian.AppendUint16(nil, ManagedPasswordVersion)
+	data = binary.LittleEndian.AppendUint16(data, 0)
+	data = binary.LittleEndian.AppendUint32(data, uint32(managedPasswordHeaderSize+len(body)))
+	for _, offset := range []int{currentOffset, previousOffset, queryOffset, unchangedOffset} {
+		data = binary.LittleEndian.AppendUint16(data, uint16(offset))
+	}
+	return append(data, body...)
+}
+
+func Test_ParseManagedPassword(t *testing.T) {
+	current, previous := encodeUTF16("Current-gMSA-Password!"), encodeUTF16("Previous-gMSA-Password!")
+	blob := newManagedPassword(current, previous, 24*time.Hour, 720*time.Hour)
+
+	password, err := ParseManagedPassword(blob)
+	if err != nil {
+		t.Fatalf("Error parsing managed password: %v", err)
+	}
+	if !bytes.Equal(password.CurrentPassword, current) || !bytes.Equal(password.PreviousPassword, previous) {
+		t.Errorf("Unexpected passwords %x and %x", password.CurrentPassword, password.PreviousPassword)
+	}
+	if password.QueryPasswordInterval != 24*time.Hour || password.UnchangedPasswordInterval != 720*time.Hour {
+		t.Errorf("Unexpected intervals %s and %s", password.QueryPasswordInterval, password.UnchangedPasswordInterval)
+	}
+
+	entries, err := password.KeytabEntries("testsegment.local", "svc_web$", 3)
+	if err != nil {
+		t.Fatalf("Error creating keytab entries: %v", err)
+	}
+	if len(entries) != 6 || entries[0].KVNO() != 3 || entries[5].KVNO() != 2 || entries[0].Principal() != "svc_web$@TESTSEGMENT.LOCAL" {
+		t.Fatalf("Expected 3 keys with kvno 3 and 3 keys with kvno 2, got %d entries", len(entries))
+	}
+	aes256, _ := crypto.StringToKey(crypto.ETypeAES256CTSHMACSHA196, "Current-gMSA-Password!", "TESTSEGMENT.LOCALhostsvc_web.testsegment.local", nil)
+	rc4, _ := crypto.StringToKey(crypto.ETypeRC4HMAC, "Previous-gMSA-Password!", "", nil)
+	if entries[0].Key.Type != keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96 || !bytes.Equal(entries[0].Key.Key.Data, aes256) {
+		t.Errorf("Unexpected AES256 key %x, expected %x", entries[0].Key.Key.Data, aes256)
+	}
+	if entries[5].Key.Type != keytab.EncryptionType_RC4_HMAC || !bytes.Equal(entries[5].Key.Key.Data, rc4) {
+		t.Errorf("Unexpected RC4 key %x, expected %x", entries[5].Key.Key.Data, rc4)
+	}
+
+	password, err = ParseManagedPassword(newManagedPassword(current, nil, time.Hour, time.Hour))
+	if err != nil || len(password.PreviousPassword) != 0 {
+		t.Fatalf("Unexpected managed password without a previous password %+v (%v)", password, err)
+	}
+	if entries, err = password.KeytabEntries("TESTSEGMENT.LOCAL", "svc_web", 1); err != nil || len(entries) != 3 {
+		t.Errorf("Expected the 3 current keys, got %d entries (%v)", len(entries), err)
+	}
+
+	for i := 1; i < len(blob); i += 5 {
+		if _, err = ParseManagedPassword(blob[:i]); err == nil {
+			t.Errorf("Expected an error for a blob truncated to %d bytes", i)
+		}
+	}
+}
+
+func Test_ManagedPassword_FromBytesNullUnit(t *testing.T) {
+	current := append(append(encodeUTF16("ab"), 0, 0), encodeUTF16("cd")...)
+	previous := append(append(encodeUTF16("efg"), 0, 0), encodeUTF16("h")...)
+
+	password := &ManagedPassword{}
+	if err := password.FromBytes(newManagedPassword(current, previous, time.Hour, time.Hour)); err != nil {
+		t.Fatalf("Error parsing managed password: %v", err)
+	}
+	if !bytes.Equal(password.CurrentPassword, current) || !bytes.Equal(password.PreviousPassword, previous) {
+		t.Errorf("Unexpected passwords %x and %x", password.CurrentPassword, password.PreviousPassword)
+	}
+
+	if err := password.FromBytes(newManagedPassword(current, nil, time.Hour, time.Hour)); err != nil {
+		t.Fatalf("Error parsing managed password: %v", err)
+	}
+	if !bytes.Equal(password.CurrentPassword, current) || password.PreviousPassword != nil {
+		t.Errorf("Unexpected passwords %x and %x", password.CurrentPassword, password.PreviousPassword)
+	}
+}
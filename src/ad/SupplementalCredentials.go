@@ -0,0 +1,260 @@
+package ad
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"keytab/keytab"
+	"unicode/utf16"
+)
+
+// Revisions of the stored Kerberos credentials, as defined in MS-SAMR section 2.2.10.
+const (
+	StoredCredentialRevision    = 3 // KERB_STORED_CREDENTIAL of the Primary:Kerberos property
+	StoredCredentialNewRevision = 4 // KERB_STORED_CREDENTIAL_NEW of the Primary:Kerberos-Newer-Keys property
+)
+
+// Names of the properties of USER_PROPERTIES holding Kerberos keys.
+const (
+	PropertyKerberos          = "Primary:Kerberos"
+	PropertyKerberosNewerKeys = "Primary:Kerberos-Newer-Keys"
+)
+
+// userPropertiesSignature is the PropertySignature of USER_PROPERTIES.
+const userPropertiesSignature = 0x50
+
+// userPropertiesHeaderSize is the size of the header of USER_PROPERTIES, up to its first property.
+const userPropertiesHeaderSize = 112
+
+// KerberosKey is a key of a stored credential, a KERB_KEY_DATA or KERB_KEY_DATA_NEW.
+//
+// Attributes:
+//   - EncryptionType (keytab.EncryptionType): The encryption type of the key.
+//   - IterationCount (uint32): The iteration count of the string-to-key function, 0 in KERB_KEY_DATA.
+//   - Key ([]byte): The key.
+type KerberosKey struct {
+	EncryptionType keytab.EncryptionType
+	IterationCount uint32
+	Key            []byte
+}
+
+// StoredCredential holds the Kerberos keys of an account, stored in its supplementalCredentials
+// attribute as a KERB_STORED_CREDENTIAL (revision 3) or a KERB_STORED_CREDENTIAL_NEW (revision 4),
+// as defined in MS-SAMR section 2.2.10.
+//
+// Attributes:
+//   - Revision (uint16): StoredCredentialRevision or StoredCredentialNewRevision.
+//   - Flags (uint16): The flags, always 0.
+//   - DefaultSalt (string): The salt of the keys.
+//   - DefaultIterationCount (uint32): The default iteration count, 0 in revision 3.
+//   - Credentials ([]KerberosKey): The keys of the current password.
+//   - ServiceCredentials ([]KerberosKey): The service keys, unused by Active Directory.
+//   - OldCredentials ([]KerberosKey): The keys of the previous password.
+//   - OlderCredentials ([]KerberosKey): The keys of the password before the previous one, absent in revision 3.
+type StoredCredential struct {
+	Revision              uint16
+	Flags                 uint16
+	DefaultSalt           string
+	DefaultIterationCount uint32
+	Credentials           []KerberosKey
+	ServiceCredentials    []KerberosKey
+	OldCredentials        []KerberosKey
+	OlderCredentials      []KerberosKey
+}
+
+// ParseSupplementalCredentials parses the Kerberos keys of a decrypted supplementalCredentials
+// attribute, either the whole USER_PROPERTIES structure, whose Primary:Kerberos-Newer-Keys
+// property is preferred to its Primary:Kerberos property, or a stored credential alone.
+//
+// Parameters:
+//   - data ([]byte): The USER_PROPERTIES, KERB_STORED_CREDENTIAL or KERB_STORED_CREDENTIAL_NEW structure.
+//
+// Returns:
+//   - (*StoredCredential, error): The stored credential and an error if the data can not be parsed or holds no Kerberos keys.
+func ParseSupplementalCredentials(data []byte) (*StoredCredential, error) {
+	if len(data) >= userPropertiesHeaderSize && binary.LittleEndian.Uint32(data[0:4]) == 0 && binary.LittleEndian.Uint16(data[108:110]) == userPropertiesSignature {
+		properties, err := parseUserProperties(data)
+		if err != nil {
+			return nil, err
+		}
+		value, found := properties[PropertyKerberosNewerKeys]
+		if !found {
+			value, found = properties[PropertyKerberos]
+		}
+		if !found {
+			return nil, fmt.Errorf("no Kerberos keys in the supplemental credentials")
+		}
+		data, err = hex.DecodeString(string(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kerberos property value: %v", err)
+		}
+	}
+
+	credential := &StoredCredential{}
+	err := credential.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// parseUserProperties returns the values of the properties of a USER_PROPERTIES structure, by name.
+func parseUserProperties(data []byte) (map[string][]byte, error) {
+	length := binary.LittleEndian.Uint32(data[4:8])
+	if uint64(length)+12 > uint64(len(data)) {
+		return nil, fmt.Errorf("user properties of %d bytes exceed the %d bytes of data", length, len(data)-12)
+	}
+	count := int(binary.LittleEndian.Uint16(data[110:112]))
+
+	properties := make(map[string][]byte)
+	offset := userPropertiesHeaderSize
+	for i := 0; i < count; i++ {
+		if offset+6 > len(data) {
+			return nil, fmt.Errorf("truncated user property #%d", i)
+		}
+		nameLength := int(binary.LittleEndian.Uint16(data[offset : offset+2]))
+		valueLength := int(binary.LittleEndian.Uint16(data[offset+2 : offset+4]))
+		offset += 6
+		if offset+nameLength+valueLength > len(data) {
+			return nil, fmt.Errorf("truncated user property #%d", i)
+		}
+		name := decodeUTF16(data[offset : offset+nameLength])
+		offset += nameLength
+		properties[name] = data[offset : offset+valueLength]
+		offset += valueLength
+	}
+	return properties, nil
+}
+
+// FromBytes parses a KERB_STORED_CREDENTIAL or a KERB_STORED_CREDENTIAL_NEW, depending on its revision.
+// The offsets of the salt and keys are relative to the start of the structure.
+//
+// Parameters:
+//   - data ([]byte): The structure.
+//
+// Returns:
+//   - error: An error if the revision is unknown or the data is truncated.
+func (c *StoredCredential) FromBytes(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("truncated stored credential")
+	}
+	c.Revision = binary.LittleEndian.Uint16(data[0:2])
+	c.Flags = binary.LittleEndian.Uint16(data[2:4])
+
+	var counts [4]int
+	var saltLength, saltOffset uint32
+	var offset, keySize int
+	switch c.Revision {
+	case StoredCredentialRevision:
+		if len(data) < 16 {
+			return fmt.Errorf("truncated stored credential header")
+		}
+		counts[0] = int(binary.LittleEndian.Uint16(data[4:6]))
+		counts[2] = int(binary.LittleEndian.Uint16(data[6:8]))
+		saltLength = uint32(binary.LittleEndian.Uint16(data[8:10]))
+		saltOffset = binary.LittleEndian.Uint32(data[12:16])
+		offset, keySize = 16, 20
+	case StoredCredentialNewRevision:
+		if len(data) < 24 {
+			return fmt.Errorf("truncated stored credential header")
+		}
+		for i := range counts {
+			counts[i] = int(binary.LittleEndian.Uint16(data[4+2*i : 6+2*i]))
+		}
+		saltLength = uint32(binary.LittleEndian.Uint16(data[12:14]))
+		saltOffset = binary.LittleEndian.Uint32(data[16:20])
+		c.DefaultIterationCount = binary.LittleEndian.Uint32(data[20:24])
+		offset, keySize = 24, 24
+	default:
+		return fmt.Errorf("unknown stored credential revision %d", c.Revision)
+	}
+
+	// The offsets are compared in 64 bits, as they overflow int on 32-bit platforms
+	if uint64(saltOffset)+uint64(saltLength) > uint64(len(data)) {
+		return fmt.Errorf("salt of %d bytes at offset %d exceeds the %d bytes of data", saltLength, saltOffset, len(data))
+	}
+	c.DefaultSalt = decodeUTF16(data[saltOffset : saltOffset+saltLength])
+
+	lists := []*[]KerberosKey{&c.Credentials, &c.ServiceCredentials, &c.OldCredentials, &c.OlderCredentials}
+	for list, count := range counts {
+		*lists[list] = nil
+		for i := 0; i < count; i++ {
+			if offset+keySize > len(data) {
+				return fmt.Errorf("truncated key data")
+			}
+			keyData := data[offset : offset+keySize]
+			offset += keySize
+
+			key := KerberosKey{}
+			if c.Revision == StoredCredentialNewRevision {
+				key.IterationCount = binary.LittleEndian.Uint32(keyData[8:12])
+				keyData = keyData[4:]
+			}
+			keyType := binary.LittleEndian.Uint32(keyData[8:12])
+			keyLength := binary.LittleEndian.Uint32(keyData[12:16])
+			keyOffset := binary.LittleEndian.Uint32(keyData[16:20])
+			if uint64(keyOffset)+uint64(keyLength) > uint64(len(data)) {
+				return fmt.Errorf("key of %d bytes at offset %d exceeds the %d bytes of data", keyLength, keyOffset, len(data))
+			}
+			if keyType > 0xffff {
+				// Key types outside of the IANA registry can not be stored in keytabs
+				continue
+			}
+			key.EncryptionType = keytab.EncryptionType(keyType)
+			key.Key = append([]byte{}, data[keyOffset:keyOffset+keyLength]...)
+			*lists[list] = append(*lists[list], key)
+		}
+	}
+	return nil
+}
+
+// KeytabEntries returns the keytab entries of the keys of the current, previous and older passwords.
+// The stored credentials do not hold key version numbers: the keys of the current password get
+// the given kvno, the msDS-KeyVersionNumber of the account, and the older ones the previous
+// kvnos. Older keys which would get a kvno below 1 are skipped.
+//
+// Parameters:
+//   - realm (string): The realm of the principal.
+//   - components ([]string): The components of the principal.
+//   - nameType (uint32): The name type of the principal.
+//   - kvno (uint32): The key version number of the current keys.
+//
+// Returns:
+//   - ([]keytab.KeytabEntry, error): The entries and an error if the kvno is 0 or an entry can not be encoded.
+func (c *StoredCredential) KeytabEntries(realm string, components []string, nameType uint32, kvno uint32) ([]keytab.KeytabEntry, error) {
+	if kvno == 0 {
+		return nil, fmt.Errorf("the key version number of the current keys is needed")
+	}
+	entries := []keytab.KeytabEntry{}
+	for age, keys := range [][]KerberosKey{c.Credentials, c.OldCredentials, c.OlderCredentials} {
+		if uint32(age) >= kvno {
+			break
+		}
+		for _, key := range keys {
+			entry, err := keytab.NewKeytabEntry(realm, components, nameType, kvno-uint32(age), key.EncryptionType, key.Key)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Wipe overwrites the keys with zeros.
+func (c *StoredCredential) Wipe() {
+	for _, keys := range [][]KerberosKey{c.Credentials, c.ServiceCredentials, c.OldCredentials, c.OlderCredentials} {
+		for _, key := range keys {
+			clear(key.Key)
+		}
+	}
+}
+
+// decodeUTF16 decodes a little-endian UTF-16 string, ignoring a trailing odd byte.
+func decodeUTF16(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,148 @@
+package ad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"keytab/keytab"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 encodes a string in little-endian UTF-16.
+func encodeUTF16(s string) []byte {
+	data := []byte{}
+	for _, unit := range utf16.Encode([]rune(s)) {
+		data = binary.LittleEndian.AppendUint16(data, unit)
+	}
+	return data
+}
+
+// newStoredCredential encodes a KERB_STORED_CREDENTIAL_NEW, or a KERB_STORED_CREDENTIAL when revision is 3.
+func newStoredCredential(revision uint16, salt string, lists [4][]KerberosKey) []byte {
+	headerSize, keySize := 24, 24
+	if revision == StoredCredentialRevision {
+		headerSize, keySize = 16, 20
+	}
+	count := 0
+	for _, keys := range lists {
+		count += len(keys)
+	}
+	saltData := encodeUTF16(salt)
+	saltOffset := headerSize + count*keySize
+	keyOffset := saltOffset + len(saltData)
+
+	header := binary.LittleEndian.AppendUint16(nil, revision)
+	header = binary.LittleEndian.AppendUint16(header, 0)
+	if revision == StoredCredentialRevision {
+		header = binary.LittleEndian.AppendUint16(header, uint16(len(lists[0])))
+		header = binary.LittleEndian.AppendUint16(header, uint16(len(lists[2])))
+	} else {
+		for _, keys := range lists {
+			header = binary.LittleEndian.AppendUint16(header, uint16(len(keys)))
+		}
+	}
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(saltData)))
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(saltData)))
+	header = binary.LittleEndian.AppendUint32(header, uint32(saltOffset))
+	if revision == StoredCredentialNewRevision {
+		header = binary.LittleEndian.AppendUint32(header, 4096)
+	}
+
+	keyData := []byte{}
+	for _, keys := range lists {
+		for _, key := range keys {
+			header = append(header, make([]byte, 8)...)
+			if revision == StoredCredentialNewRevision {
+				header = binary.LittleEndian.AppendUint32(header, key.IterationCount)
+			}
+			header = binary.LittleEndian.AppendUint32(header, uint32(key.EncryptionType))
+			header = binary.LittleEndian.AppendUint32(header, uint32(len(key.Key)))
+			header = binary.LittleEndian.AppendUint32(header, uint32(keyOffset+len(keyData)))
+			keyData = append(keyData, key.Key...)
+		}
+	}
+	return append(append(header, saltData...), keyData...)
+}
+
+// newUserProperties encodes a USER_PROPERTIES structure, with hex-encoded property values.
+func newUserProperties(properties map[string][]byte) []byte {
+	body := []byte{}
+	for name, value := range properties {
+		nameData := encodeUTF16(name)
+		valueData := []byte(strings.ToUpper(hex.EncodeToString(value)))
+		body = binary.LittleEndian.AppendUint16(body, uint16(len(nameData)))
+		body = binary.LittleEndian.AppendUint16(body, uint16(len(valueData)))
+		body = binary.LittleEndian.AppendUint16(body, 0)
+		body = append(append(body, nameData...), valueData...)
+	}
+	data := binary.LittleEndian.AppendUint32(nil, 0)
+	data = binary.LittleEndian.AppendUint32(data, uint32(100+len(body)))
+	data = append(data, make([]byte, 100)...)
+	data = binary.LittleEndian.AppendUint16(data, userPropertiesSignature)
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(properties)))
+	return append(data, body...)
+}
+
+func Test_ParseSupplementalCredentials(t *testing.T) {
+	aes256 := KerberosKey{EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, IterationCount: 4096, Key: bytes.Repeat([]byte{0x01}, 32)}
+	aes128 := KerberosKey{EncryptionType: keytab.EncryptionType_AES128_CTS_HMAC_SHA1_96, IterationCount: 4096, Key: bytes.Repeat([]byte{0x02}, 16)}
+	des := KerberosKey{EncryptionType: keytab.EncryptionType_DES_CBC_MD5, IterationCount: 4096, Key: bytes.Repeat([]byte{0x03}, 8)}
+	oldAES256 := KerberosKey{EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, IterationCount: 4096, Key: bytes.Repeat([]byte{0x04}, 32)}
+	olderAES256 := KerberosKey{EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, IterationCount: 4096, Key: bytes.Repeat([]byte{0x05}, 32)}
+	newer := newStoredCredential(StoredCredentialNewRevision, "TESTSEGMENT.LOCALsvc_web", [4][]KerberosKey{{aes256, aes128, des}, nil, {oldAES256}, {olderAES256}})
+	older := newStoredCredential(StoredCredentialRevision, "TESTSEGMENT.LOCALsvc_web", [4][]KerberosKey{{des}, nil, {des}, nil})
+
+	properties := newUserProperties(map[string][]byte{PropertyKerberos: older, PropertyKerberosNewerKeys: newer, "Packages": []byte("Kerberos")})
+	for name, data := range map[string][]byte{"user properties": properties, "stored credential": newer} {
+		credential, err := ParseSupplementalCredentials(data)
+		if err != nil {
+			t.Fatalf("Error parsing %s: %v", name, err)
+		}
+		if credential.Revision != StoredCredentialNewRevision || credential.DefaultSalt != "TESTSEGMENT.LOCALsvc_web" || credential.DefaultIterationCount != 4096 {
+			t.Errorf("Unexpected header in %s: %+v", name, credential)
+		}
+		if len(credential.Credentials) != 3 || len(credential.OldCredentials) != 1 || len(credential.OlderCredentials) != 1 ||
+			credential.Credentials[1].EncryptionType != aes128.EncryptionType || !bytes.Equal(credential.Credentials[1].Key, aes128.Key) ||
+			!bytes.Equal(credential.OlderCredentials[0].Key, olderAES256.Key) {
+			t.Errorf("Unexpected keys in %s: %+v", name, credential)
+		}
+	}
+
+	credential, err := ParseSupplementalCredentials(older)
+	if err != nil || credential.Revision != StoredCredentialRevision || len(credential.Credentials) != 1 || len(credential.OldCredentials) != 1 || credential.Credentials[0].IterationCount != 0 {
+		t.Errorf("Unexpected KERB_STORED_CREDENTIAL %+v (%v)", credential, err)
+	}
+
+	credential, _ = ParseSupplementalCredentials(newer)
+	entries, err := credential.KeytabEntries("TESTSEGMENT.LOCAL", []string{"svc_web"}, 1, 2)
+	if err != nil {
+		t.Fatalf("Error creating keytab entries: %v", err)
+	}
+	if len(entries) != 4 || entries[0].KVNO() != 2 || entries[3].KVNO() != 1 || entries[3].Principal() != "svc_web@TESTSEGMENT.LOCAL" || !bytes.Equal(entries[3].Key.Key.Data, oldAES256.Key) {
+		t.Errorf("Expected the 3 current keys with kvno 2 and the old key with kvno 1, got %d entries", len(entries))
+	}
+
+	for i := 1; i < len(newer); i += 7 {
+		if _, err = ParseSupplementalCredentials(newer[:i]); err == nil {
+			t.Errorf("Expected an error for a stored credential truncated to %d bytes", i)
+		}
+	}
+}
+
+func Test_StoredCredential_FromBytesLargeOffset(t *testing.T) {
+	aes256 := KerberosKey{EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, IterationCount: 4096, Key: bytes.Repeat([]byte{0x01}, 32)}
+	data := newStoredCredential(StoredCredentialNewRevision, "TESTSEGMENT.LOCALsvc_web", [4][]KerberosKey{{aes256}, nil, nil, nil})
+
+	// Offsets which overflow int on 32-bit platforms, at the salt offset and the key offset of the first KERB_KEY_DATA_NEW
+	for _, position := range []int{16, 24 + 20} {
+		for _, offset := range []uint32{0x80000000, 0xfffffff0} {
+			malformed := append([]byte{}, data...)
+			binary.LittleEndian.PutUint32(malformed[position:position+4], offset)
+			if err := (&StoredCredential{}).FromBytes(malformed); err == nil {
+				t.Errorf("Expected an error for the offset 0x%x at %d", offset, position)
+			}
+		}
+	}
+}
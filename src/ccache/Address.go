@@ -0,0 +1,58 @@
+package ccache
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Address represents a host address stored in a credential in a ccache file.
+//
+// Attributes:
+//   - AddrType (uint16): The type of the address.
+//   - Data (CountedOctetString): The address.
+//   - RawBytesSize (uint32): The size of the raw bytes of the address.
+type Address struct {
+	AddrType uint16
+	Data     CountedOctetString
+	// Internal
+	RawBytesSize uint32
+}
+
+// FromBytes parses a byte array into an Address.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (a *Address) FromBytes(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("address is truncated")
+	}
+	a.AddrType = binary.BigEndian.Uint16(data[0:2])
+	a.RawBytesSize = 2
+
+	err := a.Data.FromBytes(data[2:])
+	if err != nil {
+		return err
+	}
+	a.RawBytesSize += a.Data.RawBytesSize
+
+	return nil
+}
+
+// ToBytes converts an Address to a byte array.
+//
+// Returns:
+//   - ([]byte, error): The byte array and an error if the conversion failed.
+func (a *Address) ToBytes() ([]byte, error) {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, a.AddrType)
+
+	addressBytes, err := a.Data.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, addressBytes...), nil
+}
@@ -0,0 +1,58 @@
+package ccache
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// AuthData represents an authorization data element stored in a credential in a ccache file.
+//
+// Attributes:
+//   - ADType (uint16): The type of the authorization data.
+//   - Data (CountedOctetString): The authorization data.
+//   - RawBytesSize (uint32): The size of the raw bytes of the authorization data element.
+type AuthData struct {
+	ADType uint16
+	Data   CountedOctetString
+	// Internal
+	RawBytesSize uint32
+}
+
+// FromBytes parses a byte array into an AuthData.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (a *AuthData) FromBytes(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("authorization data is truncated")
+	}
+	a.ADType = binary.BigEndian.Uint16(data[0:2])
+	a.RawBytesSize = 2
+
+	err := a.Data.FromBytes(data[2:])
+	if err != nil {
+		return err
+	}
+	a.RawBytesSize += a.Data.RawBytesSize
+
+	return nil
+}
+
+// ToBytes converts an AuthData to a byte array.
+//
+// Returns:
+//   - ([]byte, error): The byte array and an error if the conversion failed.
+func (a *AuthData) ToBytes() ([]byte, error) {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, a.ADType)
+
+	adBytes, err := a.Data.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, adBytes...), nil
+}
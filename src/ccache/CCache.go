@@ -0,0 +1,240 @@
+package ccache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"keytab/keytab"
+	"os"
+	"strings"
+)
+
+const (
+	// FileFormatVersion3 is the version 0x0503 of the ccache file format.
+	FileFormatVersion3 uint16 = 0x0503
+	// FileFormatVersion4 is the version 0x0504 of the ccache file format, which adds a header.
+	FileFormatVersion4 uint16 = 0x0504
+)
+
+// CCache represents a file-based MIT credential cache.
+//
+// Attributes:
+//   - FileFormatVersion (uint16): The version of the ccache file format.
+//   - Header (Header): The header of the ccache, only present in version 0x0504.
+//   - DefaultPrincipal (Principal): The default client principal of the ccache.
+//   - Credentials ([]Credential): The credentials in the ccache.
+//   - RawBytesSize (uint32): The size of the raw bytes of the ccache.
+type CCache struct {
+	FileFormatVersion uint16
+	Header            Header
+	DefaultPrincipal  Principal
+	Credentials       []Credential
+	// Internal
+	RawBytesSize uint32
+}
+
+// FromBytes parses a byte array into a CCache.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (c *CCache) FromBytes(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("ccache is truncated")
+	}
+	c.RawBytesSize = 0
+
+	c.FileFormatVersion = binary.BigEndian.Uint16(data[0:2])
+	data = data[2:]
+	c.RawBytesSize += 2
+
+	switch c.FileFormatVersion {
+	case FileFormatVersion4:
+		err := c.Header.FromBytes(data)
+		if err != nil {
+			return fmt.Errorf("error parsing header: %w", err)
+		}
+		data = data[c.Header.RawBytesSize:]
+		c.RawBytesSize += c.Header.RawBytesSize
+	case FileFormatVersion3:
+		c.Header = Header{}
+	default:
+		return fmt.Errorf("unsupported ccache file format version 0x%04x", c.FileFormatVersion)
+	}
+
+	err := c.DefaultPrincipal.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("error parsing default principal: %w", err)
+	}
+	data = data[c.DefaultPrincipal.RawBytesSize:]
+	c.RawBytesSize += c.DefaultPrincipal.RawBytesSize
+
+	c.Credentials = make([]Credential, 0)
+	for len(data) != 0 {
+		credential := Credential{}
+		err := credential.FromBytes(data, c.FileFormatVersion)
+		if err != nil {
+			return fmt.Errorf("error parsing credential %d: %w", len(c.Credentials), err)
+		}
+		data = data[credential.RawBytesSize:]
+		c.RawBytesSize += credential.RawBytesSize
+		c.Credentials = append(c.Credentials, credential)
+	}
+
+	return nil
+}
+
+// ToBytes converts a CCache to a byte array.
+//
+// Returns:
+//   - ([]byte, error): The byte array and an error if the conversion failed.
+func (c *CCache) ToBytes() ([]byte, error) {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, c.FileFormatVersion)
+
+	switch c.FileFormatVersion {
+	case FileFormatVersion4:
+		headerBytes, err := c.Header.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, headerBytes...)
+	case FileFormatVersion3:
+	default:
+		return nil, fmt.Errorf("unsupported ccache file format version 0x%04x", c.FileFormatVersion)
+	}
+
+	principalBytes, err := c.DefaultPrincipal.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, principalBytes...)
+
+	for _, credential := range c.Credentials {
+		credentialBytes, err := credential.ToBytes(c.FileFormatVersion)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, credentialBytes...)
+	}
+
+	return data, nil
+}
+
+// LoadCCacheFromFile loads a CCache from a file.
+//
+// Parameters:
+//   - path (string): The path to the ccache file.
+//
+// Returns:
+//   - (*CCache, error): The CCache struct and an error if the file could not be read.
+func LoadCCacheFromFile(path string) (*CCache, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	ccache := &CCache{}
+	err = ccache.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return ccache, nil
+}
+
+// SaveToFile saves the CCache struct to a file. Credential caches hold session keys,
+// so the file is only readable by its owner.
+//
+// Parameters:
+//   - path (string): The path to the file to save the CCache struct to.
+//
+// Returns:
+//   - error: An error if the saving failed.
+func (c *CCache) SaveToFile(path string) error {
+	data, err := c.ToBytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// GetCredentials returns the credentials of the ccache, excluding configuration entries.
+//
+// Returns:
+//   - []Credential: The credentials holding actual tickets.
+func (c *CCache) GetCredentials() []Credential {
+	credentials := make([]Credential, 0)
+	for _, credential := range c.Credentials {
+		if !credential.IsConfigurationEntry() {
+			credentials = append(credentials, credential)
+		}
+	}
+	return credentials
+}
+
+// KeytabMarks returns the annotations describing how a credential relates to the principals of a keytab.
+//
+// Parameters:
+//   - credential (*Credential): The credential to check.
+//   - kt (*keytab.Keytab): The keytab to check against, may be nil.
+//
+// Returns:
+//   - []string: The annotations, empty if the credential has no principal in the keytab.
+func KeytabMarks(credential *Credential, kt *keytab.Keytab) []string {
+	marks := make([]string, 0)
+	if kt == nil || credential.IsConfigurationEntry() {
+		return marks
+	}
+
+	client := credential.Client.String()
+	server := credential.Server.String()
+	clientInKeytab, serverInKeytab := false, false
+	for i := range kt.Entries {
+		principal := kt.Entries[i].Principal()
+		if principal == client {
+			clientInKeytab = true
+		}
+		if principal == server {
+			serverInKeytab = true
+		}
+	}
+
+	if clientInKeytab {
+		marks = append(marks, "client in keytab")
+	}
+	if serverInKeytab {
+		marks = append(marks, "server key in keytab")
+	}
+	return marks
+}
+
+// Describe prints a detailed description of the CCache struct,
+// including its attributes formatted with indentation for clarity.
+//
+// Parameters:
+//   - indent (int): The indentation level for formatting the output.
+//   - kt (*keytab.Keytab): An optional keytab, credentials whose principals are in it are marked. May be nil.
+//   - showKeys (bool): Whether the session keys are shown, they are masked otherwise.
+func (c *CCache) Describe(indent int, kt *keytab.Keytab, showKeys bool) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+	fmt.Printf("%s<CCache>\n", indentPrompt)
+	fmt.Printf("%s │ \x1b[93mFileFormatVersion\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, c.FileFormatVersion, c.FileFormatVersion)
+	if c.FileFormatVersion == FileFormatVersion4 {
+		c.Header.Describe(indent + 1)
+	}
+	fmt.Printf("%s │ \x1b[93mDefaultPrincipal\x1b[0m  : \x1b[96m%s\x1b[0m\n", indentPrompt, c.DefaultPrincipal.String())
+	fmt.Printf("%s │ \x1b[93mCredentials\x1b[0m       : \x1b[96m%d\x1b[0m\n", indentPrompt, len(c.Credentials))
+	for i := range c.Credentials {
+		c.Credentials[i].Describe(indent+1, i, KeytabMarks(&c.Credentials[i], kt), showKeys)
+	}
+	fmt.Printf("%s └─\n", indentPrompt)
+}
@@ -0,0 +1,104 @@
+package ccache
+
+import (
+	"bytes"
+	"keytab/keytab"
+	"testing"
+)
+
+func newTestCCache(version uint16) *CCache {
+	client := NewPrincipal(1, "TESTSEGMENT.LOCAL", []string{"user"})
+	c := &CCache{
+		FileFormatVersion: version,
+		DefaultPrincipal:  client,
+		Credentials: []Credential{
+			{
+				Client: client,
+				Server: NewPrincipal(2, "TESTSEGMENT.LOCAL", []string{"krbtgt", "TESTSEGMENT.LOCAL"}),
+				Key: KeyBlock{
+					Type: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96,
+					Key:  NewCountedOctetString(bytes.Repeat([]byte{0x18}, 32)),
+				},
+				AuthTime:    1700000000,
+				StartTime:   1700000000,
+				EndTime:     1700036000,
+				RenewTill:   1700604800,
+				TicketFlags: TicketFlag_Forwardable | TicketFlag_Initial | TicketFlag_PreAuthent,
+				Addresses:   []Address{{AddrType: 2, Data: NewCountedOctetString([]byte{127, 0, 0, 1})}},
+				AuthData:    []AuthData{},
+				Ticket:      NewCountedOctetString([]byte{0x61, 0x03, 0x02, 0x01, 0x05}),
+			},
+		},
+	}
+	if version == FileFormatVersion4 {
+		c.Header.SetKDCTimeOffset(-42, 1000)
+	}
+	return c
+}
+
+func Test_CCache_ToBytesFromBytesInvolution(t *testing.T) {
+	for _, version := range []uint16{FileFormatVersion3, FileFormatVersion4} {
+		c1 := newTestCCache(version)
+		c1Bytes, err := c1.ToBytes()
+		if err != nil {
+			t.Fatalf("Error converting ccache 0x%04x to bytes: %v", version, err)
+		}
+
+		c2 := CCache{}
+		err = c2.FromBytes(c1Bytes)
+		if err != nil {
+			t.Fatalf("Error parsing ccache 0x%04x: %v", version, err)
+		}
+
+		c2Bytes, err := c2.ToBytes()
+		if err != nil {
+			t.Fatalf("Error converting ccache 0x%04x to bytes: %v", version, err)
+		}
+		if !bytes.Equal(c1Bytes, c2Bytes) {
+			t.Errorf("ccache 0x%04x mismatch after round trip", version)
+		}
+		if !c2.Credentials[0].Key.Equal(c1.Credentials[0].Key) {
+			t.Errorf("Key mismatch for ccache 0x%04x", version)
+		}
+		if c2.Credentials[0].Server.String() != "krbtgt/TESTSEGMENT.LOCAL@TESTSEGMENT.LOCAL" {
+			t.Errorf("Unexpected server principal %s", c2.Credentials[0].Server.String())
+		}
+	}
+}
+
+func Test_CCache_KDCTimeOffset(t *testing.T) {
+	c1 := newTestCCache(FileFormatVersion4)
+	c1Bytes, _ := c1.ToBytes()
+
+	c2 := CCache{}
+	if err := c2.FromBytes(c1Bytes); err != nil {
+		t.Fatalf("Error parsing ccache: %v", err)
+	}
+
+	seconds, microseconds, ok := c2.Header.GetKDCTimeOffset()
+	if !ok || seconds != -42 || microseconds != 1000 {
+		t.Errorf("Expected KDC time offset -42s 1000us, got %ds %dus (present: %v)", seconds, microseconds, ok)
+	}
+}
+
+func Test_CCache_KeytabMarks(t *testing.T) {
+	c := newTestCCache(FileFormatVersion4)
+	kt := &keytab.Keytab{
+		FileFormatVersion: 0x502,
+		Entries: []keytab.KeytabEntry{
+			{
+				NumComponents: 2,
+				Realm:         keytab.CountedOctetString{Length: 17, Data: []byte("TESTSEGMENT.LOCAL")},
+				Components: []keytab.CountedOctetString{
+					{Length: 6, Data: []byte("krbtgt")},
+					{Length: 17, Data: []byte("TESTSEGMENT.LOCAL")},
+				},
+			},
+		},
+	}
+
+	marks := KeytabMarks(&c.Credentials[0], kt)
+	if len(marks) != 1 || marks[0] != "server key in keytab" {
+		t.Errorf("Expected [server key in keytab], got %v", marks)
+	}
+}
@@ -0,0 +1,101 @@
+package ccache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"keytab/utils"
+	"strings"
+)
+
+// CountedOctetString represents a counted octet string in a credential cache file.
+// Unlike the keytab format, the length of a counted octet string in a ccache is a 32-bit value.
+//
+// Attributes:
+//   - Length (uint32): The length of the counted octet string.
+//   - Data ([]byte): The data of the counted octet string.
+//   - RawBytesSize (uint32): The size of the raw bytes of the counted octet string.
+type CountedOctetString struct {
+	Length uint32
+	Data   []byte
+	// Internal
+	RawBytesSize uint32
+}
+
+// NewCountedOctetString creates a CountedOctetString holding a copy of the given data.
+//
+// Parameters:
+//   - data ([]byte): The data of the counted octet string.
+//
+// Returns:
+//   - CountedOctetString: The counted octet string.
+func NewCountedOctetString(data []byte) CountedOctetString {
+	return CountedOctetString{
+		Length: uint32(len(data)),
+		Data:   append([]byte{}, data...),
+	}
+}
+
+// FromBytes parses a byte array into a CountedOctetString.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - error: An error if the parsing fails.
+func (c *CountedOctetString) FromBytes(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("counted octet string is truncated")
+	}
+
+	c.Length = binary.BigEndian.Uint32(data[0:4])
+	data = data[4:]
+
+	if uint32(len(data)) < c.Length {
+		return fmt.Errorf("counted octet string data is truncated: expected %d bytes, got %d", c.Length, len(data))
+	}
+	c.Data = append([]byte{}, data[:c.Length]...)
+
+	c.RawBytesSize = 4 + c.Length
+
+	return nil
+}
+
+// ToBytes converts a CountedOctetString to a byte array.
+//
+// Returns:
+//   - []byte: The byte array representation of the CountedOctetString.
+//   - error: An error if the conversion fails.
+func (c *CountedOctetString) ToBytes() ([]byte, error) {
+	if c.Length != uint32(len(c.Data)) {
+		return nil, fmt.Errorf("length of data is not equal to the length of the counted octet string")
+	}
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, c.Length)
+	data = append(data, c.Data...)
+
+	return data, nil
+}
+
+// Describe prints the CountedOctetString to the console.
+//
+// Parameters:
+//   - indent (int): The indentation level.
+//   - id (int): The ID of the CountedOctetString.
+func (c *CountedOctetString) Describe(indent, id int) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+	fmt.Printf("%s<CountedOctetString #%d>\n", indentPrompt, id)
+	fmt.Printf("%s │ \x1b[93mLength\x1b[0m : \x1b[96m0x%08x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, c.Length, c.Length)
+	fmt.Printf("%s │ \x1b[93mData\x1b[0m:\n", indentPrompt)
+	fmt.Printf("%s │  │ \x1b[93mHex\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, hex.EncodeToString(c.Data))
+	fmt.Printf("%s │  │ \x1b[93mRaw\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, utils.BytesToPrintableString(c.Data))
+	fmt.Printf("%s │  └─\n", indentPrompt)
+	fmt.Printf("%s └─\n", indentPrompt)
+}
+
+// Equal checks if two CountedOctetString are equal.
+func (c *CountedOctetString) Equal(c2 CountedOctetString) bool {
+	return c.Length == c2.Length && bytes.Equal(c.Data, c2.Data)
+}
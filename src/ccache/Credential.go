@@ -0,0 +1,271 @@
+package ccache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Credential represents a single credential in a ccache file.
+//
+// Attributes:
+//   - Client (Principal): The client principal the ticket was issued to.
+//   - Server (Principal): The server principal the ticket was issued for.
+//   - Key (KeyBlock): The session key of the ticket.
+//   - AuthTime (uint32): The time of the initial authentication.
+//   - StartTime (uint32): The time after which the ticket is valid.
+//   - EndTime (uint32): The time after which the ticket is no longer valid.
+//   - RenewTill (uint32): The maximum end time of the ticket when renewing it.
+//   - IsSKey (uint8): Whether the ticket is encrypted in the session key of a second ticket.
+//   - TicketFlags (uint32): The flags of the ticket.
+//   - Addresses ([]Address): The addresses the ticket is valid for.
+//   - AuthData ([]AuthData): The authorization data of the ticket.
+//   - Ticket (CountedOctetString): The DER encoded ticket.
+//   - SecondTicket (CountedOctetString): The DER encoded second ticket, if any.
+//   - RawBytesSize (uint32): The size of the raw bytes of the credential.
+type Credential struct {
+	Client       Principal
+	Server       Principal
+	Key          KeyBlock
+	AuthTime     uint32
+	StartTime    uint32
+	EndTime      uint32
+	RenewTill    uint32
+	IsSKey       uint8
+	TicketFlags  uint32
+	Addresses    []Address
+	AuthData     []AuthData
+	Ticket       CountedOctetString
+	SecondTicket CountedOctetString
+	// Internal
+	RawBytesSize uint32
+}
+
+// FromBytes parses a byte array into a Credential.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//   - version (uint16): The file format version of the ccache.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (c *Credential) FromBytes(data []byte, version uint16) error {
+	c.RawBytesSize = 0
+
+	// Client
+	err := c.Client.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("error parsing client principal: %w", err)
+	}
+	data = data[c.Client.RawBytesSize:]
+	c.RawBytesSize += c.Client.RawBytesSize
+
+	// Server
+	err = c.Server.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("error parsing server principal: %w", err)
+	}
+	data = data[c.Server.RawBytesSize:]
+	c.RawBytesSize += c.Server.RawBytesSize
+
+	// Key
+	err = c.Key.FromBytes(data, version)
+	if err != nil {
+		return fmt.Errorf("error parsing keyblock: %w", err)
+	}
+	data = data[c.Key.RawBytesSize:]
+	c.RawBytesSize += c.Key.RawBytesSize
+
+	// Times, IsSKey and TicketFlags
+	if len(data) < 25 {
+		return fmt.Errorf("credential is truncated")
+	}
+	c.AuthTime = binary.BigEndian.Uint32(data[0:4])
+	c.StartTime = binary.BigEndian.Uint32(data[4:8])
+	c.EndTime = binary.BigEndian.Uint32(data[8:12])
+	c.RenewTill = binary.BigEndian.Uint32(data[12:16])
+	c.IsSKey = data[16]
+	c.TicketFlags = binary.BigEndian.Uint32(data[17:21])
+	data = data[21:]
+	c.RawBytesSize += 21
+
+	// Addresses
+	numAddresses := binary.BigEndian.Uint32(data[0:4])
+	data = data[4:]
+	c.RawBytesSize += 4
+	c.Addresses = make([]Address, 0)
+	for i := uint32(0); i < numAddresses; i++ {
+		address := Address{}
+		err = address.FromBytes(data)
+		if err != nil {
+			return fmt.Errorf("error parsing address %d: %w", i, err)
+		}
+		data = data[address.RawBytesSize:]
+		c.RawBytesSize += address.RawBytesSize
+		c.Addresses = append(c.Addresses, address)
+	}
+
+	// AuthData
+	if len(data) < 4 {
+		return fmt.Errorf("credential is truncated")
+	}
+	numAuthData := binary.BigEndian.Uint32(data[0:4])
+	data = data[4:]
+	c.RawBytesSize += 4
+	c.AuthData = make([]AuthData, 0)
+	for i := uint32(0); i < numAuthData; i++ {
+		authData := AuthData{}
+		err = authData.FromBytes(data)
+		if err != nil {
+			return fmt.Errorf("error parsing authorization data %d: %w", i, err)
+		}
+		data = data[authData.RawBytesSize:]
+		c.RawBytesSize += authData.RawBytesSize
+		c.AuthData = append(c.AuthData, authData)
+	}
+
+	// Ticket
+	err = c.Ticket.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("error parsing ticket: %w", err)
+	}
+	data = data[c.Ticket.RawBytesSize:]
+	c.RawBytesSize += c.Ticket.RawBytesSize
+
+	// SecondTicket
+	err = c.SecondTicket.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("error parsing second ticket: %w", err)
+	}
+	c.RawBytesSize += c.SecondTicket.RawBytesSize
+
+	return nil
+}
+
+// ToBytes converts a Credential to a byte array.
+//
+// Parameters:
+//   - version (uint16): The file format version of the ccache.
+//
+// Returns:
+//   - ([]byte, error): The byte array and an error if the conversion failed.
+func (c *Credential) ToBytes(version uint16) ([]byte, error) {
+	data := make([]byte, 0)
+
+	clientBytes, err := c.Client.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, clientBytes...)
+
+	serverBytes, err := c.Server.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, serverBytes...)
+
+	keyBytes, err := c.Key.ToBytes(version)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, keyBytes...)
+
+	buffer := make([]byte, 21)
+	binary.BigEndian.PutUint32(buffer[0:4], c.AuthTime)
+	binary.BigEndian.PutUint32(buffer[4:8], c.StartTime)
+	binary.BigEndian.PutUint32(buffer[8:12], c.EndTime)
+	binary.BigEndian.PutUint32(buffer[12:16], c.RenewTill)
+	buffer[16] = c.IsSKey
+	binary.BigEndian.PutUint32(buffer[17:21], c.TicketFlags)
+	data = append(data, buffer...)
+
+	buffer4 := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer4, uint32(len(c.Addresses)))
+	data = append(data, buffer4...)
+	for _, address := range c.Addresses {
+		addressBytes, err := address.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, addressBytes...)
+	}
+
+	binary.BigEndian.PutUint32(buffer4, uint32(len(c.AuthData)))
+	data = append(data, buffer4...)
+	for _, authData := range c.AuthData {
+		authDataBytes, err := authData.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, authDataBytes...)
+	}
+
+	ticketBytes, err := c.Ticket.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, ticketBytes...)
+
+	secondTicketBytes, err := c.SecondTicket.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, secondTicketBytes...)
+
+	return data, nil
+}
+
+// IsConfigurationEntry returns true if the Credential is a ccache configuration entry
+// rather than an actual ticket.
+//
+// Returns:
+//   - bool: True if the credential is a configuration entry, false otherwise.
+func (c *Credential) IsConfigurationEntry() bool {
+	return c.Server.IsConfigurationEntry()
+}
+
+// Describe prints a detailed description of the Credential struct,
+// including its attributes formatted with indentation for clarity.
+//
+// Parameters:
+//   - indent (int): The indentation level for formatting the output.
+//   - id (int): The ID of the Credential.
+//   - marks ([]string): Annotations to display next to the credential header, may be empty.
+//   - showKeys (bool): Whether the session key is shown, it is masked otherwise.
+func (c *Credential) Describe(indent, id int, marks []string, showKeys bool) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+	if len(marks) != 0 {
+		fmt.Printf("%s<Credential #%d> \x1b[92m[%s]\x1b[0m\n", indentPrompt, id, strings.Join(marks, ", "))
+	} else {
+		fmt.Printf("%s<Credential #%d>\n", indentPrompt, id)
+	}
+	fmt.Printf("%s │ \x1b[93mClient\x1b[0m       : \x1b[96m%s\x1b[0m (\x1b[94mNameType %d\x1b[0m)\n", indentPrompt, c.Client.String(), c.Client.NameType)
+	fmt.Printf("%s │ \x1b[93mServer\x1b[0m       : \x1b[96m%s\x1b[0m (\x1b[94mNameType %d\x1b[0m)\n", indentPrompt, c.Server.String(), c.Server.NameType)
+	if c.IsConfigurationEntry() {
+		fmt.Printf("%s │ \x1b[93mConfigValue\x1b[0m  : \x1b[96m%s\x1b[0m\n", indentPrompt, c.Ticket.Data)
+		fmt.Printf("%s └─\n", indentPrompt)
+		return
+	}
+	fmt.Printf("%s │ \x1b[93mKey\x1b[0m          : \n", indentPrompt)
+	c.Key.Describe(indent+2, showKeys)
+	fmt.Printf("%s │ \x1b[93mAuthTime\x1b[0m     : \x1b[96m0x%08x\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, c.AuthTime, formatTime(c.AuthTime))
+	fmt.Printf("%s │ \x1b[93mStartTime\x1b[0m    : \x1b[96m0x%08x\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, c.StartTime, formatTime(c.StartTime))
+	fmt.Printf("%s │ \x1b[93mEndTime\x1b[0m      : \x1b[96m0x%08x\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, c.EndTime, formatTime(c.EndTime))
+	fmt.Printf("%s │ \x1b[93mRenewTill\x1b[0m    : \x1b[96m0x%08x\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, c.RenewTill, formatTime(c.RenewTill))
+	fmt.Printf("%s │ \x1b[93mIsSKey\x1b[0m       : \x1b[96m%d\x1b[0m\n", indentPrompt, c.IsSKey)
+	fmt.Printf("%s │ \x1b[93mTicketFlags\x1b[0m  : \x1b[96m0x%08x\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, c.TicketFlags, TicketFlagsToString(c.TicketFlags))
+	fmt.Printf("%s │ \x1b[93mAddresses\x1b[0m    : \x1b[96m%d\x1b[0m\n", indentPrompt, len(c.Addresses))
+	fmt.Printf("%s │ \x1b[93mAuthData\x1b[0m     : \x1b[96m%d\x1b[0m\n", indentPrompt, len(c.AuthData))
+	fmt.Printf("%s │ \x1b[93mTicket\x1b[0m       : \x1b[96m%d bytes\x1b[0m\n", indentPrompt, c.Ticket.Length)
+	fmt.Printf("%s │ \x1b[93mSecondTicket\x1b[0m : \x1b[96m%d bytes\x1b[0m\n", indentPrompt, c.SecondTicket.Length)
+	fmt.Printf("%s └─\n", indentPrompt)
+}
+
+// formatTime formats a ccache timestamp, printing unset timestamps as "-".
+func formatTime(timestamp uint32) string {
+	if timestamp == 0 {
+		return "-"
+	}
+	return time.Unix(int64(timestamp), 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,162 @@
+package ccache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	// HeaderTag_KDCTimeOffset is the tag of the header field holding the
+	// offset between the KDC clock and the local clock.
+	HeaderTag_KDCTimeOffset uint16 = 0x0001
+)
+
+// HeaderTag represents a single tagged field of a version 4 ccache header.
+//
+// Attributes:
+//   - Tag (uint16): The tag of the field.
+//   - Length (uint16): The length of the value of the field.
+//   - Value ([]byte): The value of the field.
+type HeaderTag struct {
+	Tag    uint16
+	Length uint16
+	Value  []byte
+}
+
+// Header represents the header of a version 4 ccache file.
+//
+// Attributes:
+//   - Length (uint16): The length of the header, excluding this field.
+//   - Tags ([]HeaderTag): The tagged fields of the header.
+//   - RawBytesSize (uint32): The size of the raw bytes of the header.
+type Header struct {
+	Length uint16
+	Tags   []HeaderTag
+	// Internal
+	RawBytesSize uint32
+}
+
+// FromBytes parses a byte array into a Header.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (h *Header) FromBytes(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("header is truncated")
+	}
+	h.Length = binary.BigEndian.Uint16(data[0:2])
+	data = data[2:]
+	h.RawBytesSize = 2 + uint32(h.Length)
+
+	if len(data) < int(h.Length) {
+		return fmt.Errorf("header is truncated: expected %d bytes, got %d", h.Length, len(data))
+	}
+	data = data[:h.Length]
+
+	h.Tags = make([]HeaderTag, 0)
+	for len(data) != 0 {
+		if len(data) < 4 {
+			return fmt.Errorf("header tag is truncated")
+		}
+		tag := HeaderTag{
+			Tag:    binary.BigEndian.Uint16(data[0:2]),
+			Length: binary.BigEndian.Uint16(data[2:4]),
+		}
+		data = data[4:]
+		if len(data) < int(tag.Length) {
+			return fmt.Errorf("header tag 0x%04x is truncated", tag.Tag)
+		}
+		tag.Value = append([]byte{}, data[:tag.Length]...)
+		data = data[tag.Length:]
+		h.Tags = append(h.Tags, tag)
+	}
+
+	return nil
+}
+
+// ToBytes converts a Header to a byte array. The Length field is recomputed from the tags.
+//
+// Returns:
+//   - ([]byte, error): The byte array and an error if the conversion failed.
+func (h *Header) ToBytes() ([]byte, error) {
+	tags := make([]byte, 0)
+	for _, tag := range h.Tags {
+		if int(tag.Length) != len(tag.Value) {
+			return nil, fmt.Errorf("length of header tag 0x%04x is not equal to the length of its value", tag.Tag)
+		}
+		buffer := make([]byte, 4)
+		binary.BigEndian.PutUint16(buffer[0:2], tag.Tag)
+		binary.BigEndian.PutUint16(buffer[2:4], tag.Length)
+		tags = append(tags, buffer...)
+		tags = append(tags, tag.Value...)
+	}
+	if len(tags) > 0xffff {
+		return nil, fmt.Errorf("header is too large")
+	}
+	h.Length = uint16(len(tags))
+
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, h.Length)
+
+	return append(data, tags...), nil
+}
+
+// GetKDCTimeOffset returns the KDC time offset stored in the header, if any.
+//
+// Returns:
+//   - int32: The offset in seconds.
+//   - int32: The offset in microseconds.
+//   - bool: True if the header contains a KDC time offset, false otherwise.
+func (h *Header) GetKDCTimeOffset() (int32, int32, bool) {
+	for _, tag := range h.Tags {
+		if tag.Tag == HeaderTag_KDCTimeOffset && len(tag.Value) == 8 {
+			seconds := int32(binary.BigEndian.Uint32(tag.Value[0:4]))
+			microseconds := int32(binary.BigEndian.Uint32(tag.Value[4:8]))
+			return seconds, microseconds, true
+		}
+	}
+	return 0, 0, false
+}
+
+// SetKDCTimeOffset sets the KDC time offset stored in the header, replacing any existing one.
+//
+// Parameters:
+//   - seconds (int32): The offset in seconds.
+//   - microseconds (int32): The offset in microseconds.
+func (h *Header) SetKDCTimeOffset(seconds, microseconds int32) {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint32(value[0:4], uint32(seconds))
+	binary.BigEndian.PutUint32(value[4:8], uint32(microseconds))
+
+	for i := range h.Tags {
+		if h.Tags[i].Tag == HeaderTag_KDCTimeOffset {
+			h.Tags[i].Length = 8
+			h.Tags[i].Value = value
+			return
+		}
+	}
+	h.Tags = append(h.Tags, HeaderTag{Tag: HeaderTag_KDCTimeOffset, Length: 8, Value: value})
+}
+
+// Describe prints the Header to the console.
+//
+// Parameters:
+//   - indent (int): The indentation level.
+func (h *Header) Describe(indent int) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+	fmt.Printf("%s<Header>\n", indentPrompt)
+	fmt.Printf("%s │ \x1b[93mLength\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, h.Length, h.Length)
+	for _, tag := range h.Tags {
+		if tag.Tag == HeaderTag_KDCTimeOffset && len(tag.Value) == 8 {
+			seconds, microseconds, _ := h.GetKDCTimeOffset()
+			fmt.Printf("%s │ \x1b[93mKDCTimeOffset\x1b[0m : \x1b[96m%ds %dus\x1b[0m\n", indentPrompt, seconds, microseconds)
+		} else {
+			fmt.Printf("%s │ \x1b[93mTag 0x%04x\x1b[0m : \x1b[96m%x\x1b[0m\n", indentPrompt, tag.Tag, tag.Value)
+		}
+	}
+	fmt.Printf("%s └─\n", indentPrompt)
+}
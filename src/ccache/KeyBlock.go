@@ -0,0 +1,112 @@
+package ccache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"keytab/keytab"
+	"strings"
+)
+
+// KeyBlock represents a session key stored in a credential in a ccache file.
+//
+// Attributes:
+//   - Type (keytab.EncryptionType): The encryption type of the key.
+//   - Key (CountedOctetString): The key.
+//   - RawBytesSize (uint32): The size of the raw bytes of the keyblock.
+type KeyBlock struct {
+	Type keytab.EncryptionType
+	Key  CountedOctetString
+	// Internal
+	RawBytesSize uint32
+}
+
+// FromBytes parses a byte array into a KeyBlock.
+// In file format version 0x0503, the encryption type is stored twice.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//   - version (uint16): The file format version of the ccache.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *KeyBlock) FromBytes(data []byte, version uint16) error {
+	if len(data) < 2 {
+		return fmt.Errorf("keyblock is truncated")
+	}
+	k.Type = keytab.EncryptionType(binary.BigEndian.Uint16(data[0:2]))
+	data = data[2:]
+	k.RawBytesSize = 2
+
+	if version == FileFormatVersion3 {
+		if len(data) < 2 {
+			return fmt.Errorf("keyblock is truncated")
+		}
+		data = data[2:]
+		k.RawBytesSize += 2
+	}
+
+	err := k.Key.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("error parsing key: %w", err)
+	}
+	k.RawBytesSize += k.Key.RawBytesSize
+
+	return nil
+}
+
+// ToBytes converts a KeyBlock to a byte array.
+//
+// Parameters:
+//   - version (uint16): The file format version of the ccache.
+//
+// Returns:
+//   - ([]byte, error): The byte array and an error if the conversion failed.
+func (k *KeyBlock) ToBytes(version uint16) ([]byte, error) {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, uint16(k.Type))
+	if version == FileFormatVersion3 {
+		data = append(data, data[0], data[1])
+	}
+
+	keyBytes, err := k.Key.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, keyBytes...)
+
+	return data, nil
+}
+
+// Describe prints the KeyBlock to the console. The key is masked unless keys are shown, as
+// in the description of keytab files.
+//
+// Parameters:
+//   - indent (int): The indentation level.
+//   - showKeys (bool): Whether the key is shown.
+func (k *KeyBlock) Describe(indent int, showKeys bool) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+	fmt.Printf("%s<KeyBlock>\n", indentPrompt)
+	fmt.Printf("%s │ \x1b[93mType\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%s\x1b[0m) (\x1b[94m%d\x1b[0m)\n", indentPrompt, uint16(k.Type), k.Type.String(), uint16(k.Type))
+	fmt.Printf("%s │ \x1b[93mKey\x1b[0m  :\n", indentPrompt)
+	if showKeys {
+		k.Key.Describe(indent+2, 0)
+	} else {
+		keyPrompt := strings.Repeat(" │ ", indent+2)
+		fmt.Printf("%s<CountedOctetString #0>\n", keyPrompt)
+		fmt.Printf("%s │ \x1b[93mLength\x1b[0m : \x1b[96m0x%08x\x1b[0m (\x1b[94m%d\x1b[0m)\n", keyPrompt, k.Key.Length, k.Key.Length)
+		fmt.Printf("%s │ \x1b[93mData\x1b[0m   : \x1b[96m%s\x1b[0m (use --show-keys to reveal)\n", keyPrompt, keytab.NewKeyBlock(k.Type, k.Key.Data).Masked())
+		fmt.Printf("%s └─\n", keyPrompt)
+	}
+	fmt.Printf("%s └─\n", indentPrompt)
+}
+
+// Equal checks if two KeyBlock structs are equal.
+//
+// Parameters:
+//   - k2 (KeyBlock): The KeyBlock to compare to.
+//
+// Returns:
+//   - bool: True if the KeyBlock structs are equal, false otherwise.
+func (k *KeyBlock) Equal(k2 KeyBlock) bool {
+	return k.Type == k2.Type && k.Key.Equal(k2.Key)
+}
@@ -0,0 +1,171 @@
+package ccache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Principal represents a principal in a credential cache file.
+//
+// Attributes:
+//   - NameType (uint32): The name type of the principal.
+//   - NumComponents (uint32): The number of components of the principal.
+//   - Realm (CountedOctetString): The realm of the principal.
+//   - Components ([]CountedOctetString): The components of the principal.
+//   - RawBytesSize (uint32): The size of the raw bytes of the principal.
+type Principal struct {
+	NameType      uint32
+	NumComponents uint32
+	Realm         CountedOctetString
+	Components    []CountedOctetString
+	// Internal
+	RawBytesSize uint32
+}
+
+// NewPrincipal creates a Principal from a realm and a list of components.
+//
+// Parameters:
+//   - nameType (uint32): The name type of the principal.
+//   - realm (string): The realm of the principal.
+//   - components ([]string): The components of the principal.
+//
+// Returns:
+//   - Principal: The principal.
+func NewPrincipal(nameType uint32, realm string, components []string) Principal {
+	p := Principal{
+		NameType:      nameType,
+		NumComponents: uint32(len(components)),
+		Realm:         NewCountedOctetString([]byte(realm)),
+	}
+	for _, component := range components {
+		p.Components = append(p.Components, NewCountedOctetString([]byte(component)))
+	}
+	return p
+}
+
+// FromBytes parses a byte array into a Principal.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - error: An error if the parsing fails.
+func (p *Principal) FromBytes(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("principal is truncated")
+	}
+	p.RawBytesSize = 0
+
+	// NameType
+	p.NameType = binary.BigEndian.Uint32(data[0:4])
+	data = data[4:]
+	p.RawBytesSize += 4
+
+	// NumComponents
+	p.NumComponents = binary.BigEndian.Uint32(data[0:4])
+	data = data[4:]
+	p.RawBytesSize += 4
+
+	// Realm
+	p.Realm = CountedOctetString{}
+	err := p.Realm.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("error parsing realm: %w", err)
+	}
+	data = data[p.Realm.RawBytesSize:]
+	p.RawBytesSize += p.Realm.RawBytesSize
+
+	// Components
+	p.Components = make([]CountedOctetString, 0)
+	for i := uint32(0); i < p.NumComponents; i++ {
+		component := CountedOctetString{}
+		err := component.FromBytes(data)
+		if err != nil {
+			return fmt.Errorf("error parsing component %d: %w", i, err)
+		}
+		p.Components = append(p.Components, component)
+		data = data[component.RawBytesSize:]
+		p.RawBytesSize += component.RawBytesSize
+	}
+
+	return nil
+}
+
+// ToBytes converts a Principal to a byte array.
+//
+// Returns:
+//   - []byte: The byte array representation of the Principal.
+//   - error: An error if the conversion fails.
+func (p *Principal) ToBytes() ([]byte, error) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data[0:4], p.NameType)
+	binary.BigEndian.PutUint32(data[4:8], p.NumComponents)
+
+	realmBytes, err := p.Realm.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, realmBytes...)
+
+	for _, component := range p.Components {
+		componentBytes, err := component.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, componentBytes...)
+	}
+
+	return data, nil
+}
+
+// GetComponents returns the components of the Principal as strings.
+//
+// Returns:
+//   - []string: The components of the principal.
+func (p *Principal) GetComponents() []string {
+	components := make([]string, 0, len(p.Components))
+	for _, component := range p.Components {
+		components = append(components, string(component.Data))
+	}
+	return components
+}
+
+// String returns the string representation of the Principal, in the form "component1/component2@REALM".
+//
+// Returns:
+//   - string: The string representation of the Principal.
+func (p *Principal) String() string {
+	return strings.Join(p.GetComponents(), "/") + "@" + string(p.Realm.Data)
+}
+
+// IsConfigurationEntry returns true if the Principal is the server principal of a
+// ccache configuration entry, i.e. its realm is "X-CACHECONF:".
+//
+// Returns:
+//   - bool: True if the principal denotes a configuration entry, false otherwise.
+func (p *Principal) IsConfigurationEntry() bool {
+	return string(p.Realm.Data) == "X-CACHECONF:"
+}
+
+// Equal checks if two Principal structs are equal.
+//
+// Parameters:
+//   - p2 (Principal): The Principal to compare to.
+//
+// Returns:
+//   - bool: True if the Principal structs are equal, false otherwise.
+func (p *Principal) Equal(p2 Principal) bool {
+	if p.NameType != p2.NameType || p.NumComponents != p2.NumComponents || !p.Realm.Equal(p2.Realm) {
+		return false
+	}
+	if len(p.Components) != len(p2.Components) {
+		return false
+	}
+	for i := range p.Components {
+		if !p.Components[i].Equal(p2.Components[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package ccache
+
+import "strings"
+
+// Ticket flags, as defined in RFC 4120 section 5.3. In a ccache the flags are stored
+// in a 32-bit integer where flag number n is the bit 0x80000000 >> n.
+const (
+	TicketFlag_Reserved               uint32 = 0x80000000
+	TicketFlag_Forwardable            uint32 = 0x40000000
+	TicketFlag_Forwarded              uint32 = 0x20000000
+	TicketFlag_Proxiable              uint32 = 0x10000000
+	TicketFlag_Proxy                  uint32 = 0x08000000
+	TicketFlag_MayPostdate            uint32 = 0x04000000
+	TicketFlag_Postdated              uint32 = 0x02000000
+	TicketFlag_Invalid                uint32 = 0x01000000
+	TicketFlag_Renewable              uint32 = 0x00800000
+	TicketFlag_Initial                uint32 = 0x00400000
+	TicketFlag_PreAuthent             uint32 = 0x00200000
+	TicketFlag_HWAuthent              uint32 = 0x00100000
+	TicketFlag_TransitedPolicyChecked uint32 = 0x00080000
+	TicketFlag_OkAsDelegate           uint32 = 0x00040000
+	TicketFlag_EncPARep               uint32 = 0x00010000
+	TicketFlag_Anonymous              uint32 = 0x00008000
+)
+
+// ticketFlagNames lists the names of the ticket flags in bit order.
+var ticketFlagNames = []struct {
+	Flag uint32
+	Name string
+}{
+	{TicketFlag_Reserved, "reserved"},
+	{TicketFlag_Forwardable, "forwardable"},
+	{TicketFlag_Forwarded, "forwarded"},
+	{TicketFlag_Proxiable, "proxiable"},
+	{TicketFlag_Proxy, "proxy"},
+	{TicketFlag_MayPostdate, "may-postdate"},
+	{TicketFlag_Postdated, "postdated"},
+	{TicketFlag_Invalid, "invalid"},
+	{TicketFlag_Renewable, "renewable"},
+	{TicketFlag_Initial, "initial"},
+	{TicketFlag_PreAuthent, "pre-authent"},
+	{TicketFlag_HWAuthent, "hw-authent"},
+	{TicketFlag_TransitedPolicyChecked, "transited-policy-checked"},
+	{TicketFlag_OkAsDelegate, "ok-as-delegate"},
+	{TicketFlag_EncPARep, "enc-pa-rep"},
+	{TicketFlag_Anonymous, "anonymous"},
+}
+
+// TicketFlagsToString returns the comma separated names of the flags set in the given value.
+//
+// Parameters:
+//   - flags (uint32): The ticket flags.
+//
+// Returns:
+//   - string: The names of the flags.
+func TicketFlagsToString(flags uint32) string {
+	names := make([]string, 0)
+	for _, flag := range ticketFlagNames {
+		if flags&flag.Flag != 0 {
+			names = append(names, flag.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
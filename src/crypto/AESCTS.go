@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+// aesCTSEncrypt encrypts data with AES in CBC mode with ciphertext stealing, as specified in RFC 3962 section 5.
+//
+// Parameters:
+//   - key ([]byte): The AES key.
+//   - iv ([]byte): The initialization vector.
+//   - plaintext ([]byte): The data to encrypt, at least one block long.
+//
+// Returns:
+//   - ([]byte, error): The ciphertext, of the same length as the plaintext, and an error if the encryption failed.
+func aesCTSEncrypt(key, iv, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) < aes.BlockSize {
+		return nil, fmt.Errorf("plaintext is shorter than one block")
+	}
+
+	if len(plaintext) == aes.BlockSize {
+		ciphertext := make([]byte, aes.BlockSize)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+		return ciphertext, nil
+	}
+
+	// Pad the plaintext with zeros to a multiple of the block size and encrypt it in CBC mode
+	padded := make([]byte, (len(plaintext)+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	copy(padded, plaintext)
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	// Swap the last two blocks and truncate the output to the length of the plaintext
+	n := len(encrypted)
+	ciphertext := make([]byte, 0, len(plaintext))
+	ciphertext = append(ciphertext, encrypted[:n-2*aes.BlockSize]...)
+	ciphertext = append(ciphertext, encrypted[n-aes.BlockSize:]...)
+	ciphertext = append(ciphertext, encrypted[n-2*aes.BlockSize:n-aes.BlockSize]...)
+
+	return ciphertext[:len(plaintext)], nil
+}
+
+// aesCTSDecrypt decrypts data encrypted with AES in CBC mode with ciphertext stealing.
+//
+// Parameters:
+//   - key ([]byte): The AES key.
+//   - iv ([]byte): The initialization vector.
+//   - ciphertext ([]byte): The data to decrypt, at least one block long.
+//
+// Returns:
+//   - ([]byte, error): The plaintext and an error if the decryption failed.
+func aesCTSDecrypt(key, iv, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aes.BlockSize {
+		return nil, fmt.Errorf("ciphertext is shorter than one block")
+	}
+
+	if len(ciphertext) == aes.BlockSize {
+		plaintext := make([]byte, aes.BlockSize)
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+		return plaintext, nil
+	}
+
+	// Split into the leading full blocks, the second to last full block and the last (possibly partial) block
+	lastLength := len(ciphertext) % aes.BlockSize
+	if lastLength == 0 {
+		lastLength = aes.BlockSize
+	}
+	headLength := len(ciphertext) - lastLength - aes.BlockSize
+	head := ciphertext[:headLength]
+	secondToLast := ciphertext[headLength : headLength+aes.BlockSize]
+	last := ciphertext[headLength+aes.BlockSize:]
+
+	// Decrypt the leading blocks in CBC mode
+	plaintext := make([]byte, 0, len(ciphertext))
+	previous := iv
+	if headLength != 0 {
+		decrypted := make([]byte, headLength)
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, head)
+		plaintext = append(plaintext, decrypted...)
+		previous = head[headLength-aes.BlockSize:]
+	}
+
+	// Decrypt the second to last block in ECB mode, this yields the last plaintext block
+	// XORed with the zero padded last ciphertext block
+	intermediate := make([]byte, aes.BlockSize)
+	block.Decrypt(intermediate, secondToLast)
+
+	lastPlaintext := make([]byte, lastLength)
+	for i := 0; i < lastLength; i++ {
+		lastPlaintext[i] = intermediate[i] ^ last[i]
+	}
+
+	// Rebuild the full last ciphertext block to recover the second to last plaintext block
+	fullLast := make([]byte, aes.BlockSize)
+	copy(fullLast, last)
+	copy(fullLast[lastLength:], intermediate[lastLength:])
+	secondToLastPlaintext := make([]byte, aes.BlockSize)
+	block.Decrypt(secondToLastPlaintext, fullLast)
+	for i := 0; i < aes.BlockSize; i++ {
+		secondToLastPlaintext[i] ^= previous[i]
+	}
+
+	plaintext = append(plaintext, secondToLastPlaintext...)
+	plaintext = append(plaintext, lastPlaintext...)
+
+	return plaintext, nil
+}
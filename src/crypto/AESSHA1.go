@@ -0,0 +1,131 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+)
+
+// aesSHA1Profile implements the aes128-cts-hmac-sha1-96 and aes256-cts-hmac-sha1-96
+// encryption types of RFC 3962, built on the simplified profile of RFC 3961.
+//
+// Attributes:
+//   - size (int): The size of the keys in bytes.
+type aesSHA1Profile struct {
+	size int
+}
+
+// aesSHA1HMACSize is the size of the truncated HMAC-SHA1 appended to ciphertexts.
+const aesSHA1HMACSize = 12
+
+func (p aesSHA1Profile) keySize() int {
+	return p.size
+}
+
+func (p aesSHA1Profile) confounderSize() int {
+	return aes.BlockSize
+}
+
+// deriveKey implements DK(base-key, constant) of RFC 3961 section 5.1.
+func (p aesSHA1Profile) deriveKey(baseKey, constant []byte) ([]byte, error) {
+	block, err := aes.NewCipher(baseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	folded := nfold(constant, aes.BlockSize)
+	derived := make([]byte, 0, p.size+aes.BlockSize)
+	for len(derived) < p.size {
+		block.Encrypt(folded, folded)
+		derived = append(derived, folded...)
+	}
+
+	return derived[:p.size], nil
+}
+
+// usageKey derives the key for the given key usage and purpose (0x99 for checksums,
+// 0xAA for encryption and 0x55 for integrity).
+func (p aesSHA1Profile) usageKey(baseKey []byte, usage uint32, purpose byte) ([]byte, error) {
+	constant := make([]byte, 5)
+	binary.BigEndian.PutUint32(constant, usage)
+	constant[4] = purpose
+	return p.deriveKey(baseKey, constant)
+}
+
+func (p aesSHA1Profile) encrypt(key []byte, usage uint32, plaintext, confounder []byte) ([]byte, error) {
+	ke, err := p.usageKey(key, usage, 0xAA)
+	if err != nil {
+		return nil, err
+	}
+	ki, err := p.usageKey(key, usage, 0x55)
+	if err != nil {
+		return nil, err
+	}
+
+	data := append(append([]byte{}, confounder...), plaintext...)
+	ciphertext, err := aesCTSEncrypt(ke, make([]byte, aes.BlockSize), data)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha1.New, ki)
+	mac.Write(data)
+
+	return append(ciphertext, mac.Sum(nil)[:aesSHA1HMACSize]...), nil
+}
+
+func (p aesSHA1Profile) decrypt(key []byte, usage uint32, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize+aesSHA1HMACSize {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	ke, err := p.usageKey(key, usage, 0xAA)
+	if err != nil {
+		return nil, err
+	}
+	ki, err := p.usageKey(key, usage, 0x55)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := ciphertext[:len(ciphertext)-aesSHA1HMACSize]
+	expectedMAC := ciphertext[len(ciphertext)-aesSHA1HMACSize:]
+
+	data, err := aesCTSDecrypt(ke, make([]byte, aes.BlockSize), encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha1.New, ki)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil)[:aesSHA1HMACSize], expectedMAC) {
+		return nil, ErrIntegrity
+	}
+
+	return data[aes.BlockSize:], nil
+}
+
+// checksum computes the hmac-sha1-96-aes checksum of RFC 3962, keyed with Kc.
+func (p aesSHA1Profile) checksum(key []byte, usage uint32, data []byte) ([]byte, error) {
+	kc, err := p.usageKey(key, usage, 0x99)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha1.New, kc)
+	mac.Write(data)
+	return mac.Sum(nil)[:aesSHA1HMACSize], nil
+}
+
+// stringToKey implements the string-to-key function of RFC 3962 section 4, with a default
+// of 4096 iterations of PBKDF2-HMAC-SHA1.
+func (p aesSHA1Profile) stringToKey(password, salt string, params []byte) ([]byte, error) {
+	iterations, err := iterationCount(params, 4096)
+	if err != nil {
+		return nil, err
+	}
+
+	tkey := pbkdf2(sha1.New, []byte(password), []byte(salt), iterations, p.size)
+	return p.deriveKey(tkey, []byte("kerberos"))
+}
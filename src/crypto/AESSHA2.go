@@ -0,0 +1,121 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// aesSHA2Profile implements the aes128-cts-hmac-sha256-128 and aes256-cts-hmac-sha384-192
+// encryption types of RFC 8009.
+//
+// Attributes:
+//   - size (int): The size of the keys in bytes.
+//   - hmacSize (int): The size of the truncated HMAC appended to ciphertexts, in bytes.
+//   - hash (func() hash.Hash): The hash function used by the HMAC and the key derivation function.
+//   - name (string): The name of the encryption type, used in the salt of string-to-key.
+type aesSHA2Profile struct {
+	size     int
+	hmacSize int
+	hash     func() hash.Hash
+	name     string
+}
+
+func (p aesSHA2Profile) keySize() int {
+	return p.size
+}
+
+func (p aesSHA2Profile) confounderSize() int {
+	return aes.BlockSize
+}
+
+// kdf implements KDF-HMAC-SHA2(key, label, k) of RFC 8009 section 3.
+func (p aesSHA2Profile) kdf(key, label []byte, size int) []byte {
+	mac := hmac.New(p.hash, key)
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, 1)
+	mac.Write(buffer)
+	mac.Write(label)
+	mac.Write([]byte{0x00})
+	binary.BigEndian.PutUint32(buffer, uint32(size*8))
+	mac.Write(buffer)
+	return mac.Sum(nil)[:size]
+}
+
+// usageKey derives the key for the given key usage and purpose (0x99 for checksums,
+// 0xAA for encryption and 0x55 for integrity).
+func (p aesSHA2Profile) usageKey(baseKey []byte, usage uint32, purpose byte) []byte {
+	label := make([]byte, 5)
+	binary.BigEndian.PutUint32(label, usage)
+	label[4] = purpose
+	if purpose == 0xAA {
+		return p.kdf(baseKey, label, p.size)
+	}
+	return p.kdf(baseKey, label, p.hmacSize)
+}
+
+func (p aesSHA2Profile) encrypt(key []byte, usage uint32, plaintext, confounder []byte) ([]byte, error) {
+	ke := p.usageKey(key, usage, 0xAA)
+	ki := p.usageKey(key, usage, 0x55)
+
+	data := append(append([]byte{}, confounder...), plaintext...)
+	ciphertext, err := aesCTSEncrypt(ke, make([]byte, aes.BlockSize), data)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(p.hash, ki)
+	mac.Write(make([]byte, aes.BlockSize))
+	mac.Write(ciphertext)
+
+	return append(ciphertext, mac.Sum(nil)[:p.hmacSize]...), nil
+}
+
+func (p aesSHA2Profile) decrypt(key []byte, usage uint32, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize+p.hmacSize {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	ke := p.usageKey(key, usage, 0xAA)
+	ki := p.usageKey(key, usage, 0x55)
+
+	encrypted := ciphertext[:len(ciphertext)-p.hmacSize]
+	expectedMAC := ciphertext[len(ciphertext)-p.hmacSize:]
+
+	mac := hmac.New(p.hash, ki)
+	mac.Write(make([]byte, aes.BlockSize))
+	mac.Write(encrypted)
+	if !hmac.Equal(mac.Sum(nil)[:p.hmacSize], expectedMAC) {
+		return nil, ErrIntegrity
+	}
+
+	data, err := aesCTSDecrypt(ke, make([]byte, aes.BlockSize), encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return data[aes.BlockSize:], nil
+}
+
+// checksum computes the checksum of RFC 8009 section 5, keyed with Kc.
+func (p aesSHA2Profile) checksum(key []byte, usage uint32, data []byte) ([]byte, error) {
+	kc := p.usageKey(key, usage, 0x99)
+
+	mac := hmac.New(p.hash, kc)
+	mac.Write(data)
+	return mac.Sum(nil)[:p.hmacSize], nil
+}
+
+// stringToKey implements the string-to-key function of RFC 8009 section 4, where the salt
+// is prefixed with the name of the encryption type and the default is 32768 iterations.
+func (p aesSHA2Profile) stringToKey(password, salt string, params []byte) ([]byte, error) {
+	iterations, err := iterationCount(params, 32768)
+	if err != nil {
+		return nil, err
+	}
+
+	saltp := append(append([]byte(p.name), 0x00), salt...)
+	tkey := pbkdf2(p.hash, []byte(password), saltp, iterations, p.size)
+	return p.kdf(tkey, []byte("kerberos"), p.size), nil
+}
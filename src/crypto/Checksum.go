@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"fmt"
+)
+
+// Checksum type numbers of the keyed checksums supported by this package, as assigned by IANA.
+const (
+	CksumTypeHMACSHA196AES128    int32 = 15
+	CksumTypeHMACSHA196AES256    int32 = 16
+	CksumTypeHMACSHA256128AES128 int32 = 19
+	CksumTypeHMACSHA384192AES256 int32 = 20
+	CksumTypeHMACMD5             int32 = -138
+)
+
+// checksumETypes maps the supported checksum types to the encryption type of their key.
+var checksumETypes = map[int32]int32{
+	CksumTypeHMACSHA196AES128:    ETypeAES128CTSHMACSHA196,
+	CksumTypeHMACSHA196AES256:    ETypeAES256CTSHMACSHA196,
+	CksumTypeHMACSHA256128AES128: ETypeAES128CTSHMACSHA256128,
+	CksumTypeHMACSHA384192AES256: ETypeAES256CTSHMACSHA384192,
+	CksumTypeHMACMD5:             ETypeRC4HMAC,
+}
+
+// ChecksumTypeForEType returns the mandatory checksum type of an encryption type.
+//
+// Parameters:
+//   - etype (int32): The encryption type of the key.
+//
+// Returns:
+//   - (int32, error): The checksum type and an error if the encryption type is not supported.
+func ChecksumTypeForEType(etype int32) (int32, error) {
+	for cksumType, keyEType := range checksumETypes {
+		if keyEType == etype {
+			return cksumType, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported encryption type %d", etype)
+}
+
+// Checksum computes a keyed checksum over data.
+//
+// Parameters:
+//   - cksumType (int32): The checksum type.
+//   - key ([]byte): The key, of the encryption type associated with the checksum type.
+//   - usage (uint32): The key usage number.
+//   - data ([]byte): The data to checksum.
+//
+// Returns:
+//   - ([]byte, error): The checksum and an error if the checksum type is not supported.
+func Checksum(cksumType int32, key []byte, usage uint32, data []byte) ([]byte, error) {
+	etype, ok := checksumETypes[cksumType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported checksum type %d", cksumType)
+	}
+	profile, err := getProfile(etype)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != profile.keySize() {
+		return nil, fmt.Errorf("invalid key size %d for checksum type %d", len(key), cksumType)
+	}
+
+	return profile.checksum(key, usage, data)
+}
+
+// VerifyChecksum verifies a keyed checksum over data in constant time.
+//
+// Parameters:
+//   - cksumType (int32): The checksum type.
+//   - key ([]byte): The key, of the encryption type associated with the checksum type.
+//   - usage (uint32): The key usage number.
+//   - data ([]byte): The checksummed data.
+//   - checksum ([]byte): The expected checksum.
+//
+// Returns:
+//   - error: ErrIntegrity if the checksum does not match, or another error if it could not be computed.
+func VerifyChecksum(cksumType int32, key []byte, usage uint32, data, checksum []byte) error {
+	computed, err := Checksum(cksumType, key, usage, data)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(computed, checksum) {
+		return ErrIntegrity
+	}
+	return nil
+}
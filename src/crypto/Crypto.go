@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+)
+
+// Encryption type numbers supported by this package, as assigned by IANA.
+const (
+	ETypeAES128CTSHMACSHA196    int32 = 17
+	ETypeAES256CTSHMACSHA196    int32 = 18
+	ETypeAES128CTSHMACSHA256128 int32 = 19
+	ETypeAES256CTSHMACSHA384192 int32 = 20
+	ETypeRC4HMAC                int32 = 23
+)
+
+// ErrIntegrity is returned when the integrity check of a ciphertext fails,
+// which usually means the wrong key was used to decrypt it.
+var ErrIntegrity = errors.New("integrity check failed")
+
+// encryptionProfile is implemented by each supported family of encryption types.
+type encryptionProfile interface {
+	keySize() int
+	confounderSize() int
+	encrypt(key []byte, usage uint32, plaintext, confounder []byte) ([]byte, error)
+	decrypt(key []byte, usage uint32, ciphertext []byte) ([]byte, error)
+	checksum(key []byte, usage uint32, data []byte) ([]byte, error)
+	stringToKey(password, salt string, params []byte) ([]byte, error)
+}
+
+// profiles maps the supported encryption types to their implementation.
+var profiles = map[int32]encryptionProfile{
+	ETypeAES128CTSHMACSHA196:    aesSHA1Profile{size: 16},
+	ETypeAES256CTSHMACSHA196:    aesSHA1Profile{size: 32},
+	ETypeAES128CTSHMACSHA256128: aesSHA2Profile{size: 16, hmacSize: 16, hash: sha256.New, name: "aes128-cts-hmac-sha256-128"},
+	ETypeAES256CTSHMACSHA384192: aesSHA2Profile{size: 32, hmacSize: 24, hash: sha512.New384, name: "aes256-cts-hmac-sha384-192"},
+	ETypeRC4HMAC:                rc4HMACProfile{},
+}
+
+// getProfile returns the implementation of an encryption type.
+func getProfile(etype int32) (encryptionProfile, error) {
+	profile, ok := profiles[etype]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encryption type %d", etype)
+	}
+	return profile, nil
+}
+
+// IsSupported returns true if the encryption type is supported by this package.
+//
+// Parameters:
+//   - etype (int32): The encryption type.
+//
+// Returns:
+//   - bool: True if the encryption type is supported, false otherwise.
+func IsSupported(etype int32) bool {
+	_, ok := profiles[etype]
+	return ok
+}
+
+// KeySize returns the size in bytes of the keys of an encryption type.
+//
+// Parameters:
+//   - etype (int32): The encryption type.
+//
+// Returns:
+//   - (int, error): The size of the keys and an error if the encryption type is not supported.
+func KeySize(etype int32) (int, error) {
+	profile, err := getProfile(etype)
+	if err != nil {
+		return 0, err
+	}
+	return profile.keySize(), nil
+}
+
+// Encrypt encrypts data with a key for a given key usage, with a random confounder.
+//
+// Parameters:
+//   - etype (int32): The encryption type.
+//   - key ([]byte): The key.
+//   - usage (uint32): The key usage number.
+//   - plaintext ([]byte): The data to encrypt.
+//
+// Returns:
+//   - ([]byte, error): The ciphertext and an error if the encryption failed.
+func Encrypt(etype int32, key []byte, usage uint32, plaintext []byte) ([]byte, error) {
+	profile, err := getProfile(etype)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != profile.keySize() {
+		return nil, fmt.Errorf("invalid key size %d for encryption type %d", len(key), etype)
+	}
+
+	confounder := make([]byte, profile.confounderSize())
+	_, err = rand.Read(confounder)
+	if err != nil {
+		return nil, err
+	}
+
+	return profile.encrypt(key, usage, plaintext, confounder)
+}
+
+// Decrypt decrypts and checks the integrity of data encrypted with a key for a given key usage.
+//
+// Parameters:
+//   - etype (int32): The encryption type.
+//   - key ([]byte): The key.
+//   - usage (uint32): The key usage number.
+//   - ciphertext ([]byte): The data to decrypt.
+//
+// Returns:
+//   - ([]byte, error): The plaintext and an error if the decryption failed, ErrIntegrity if the key is wrong.
+func Decrypt(etype int32, key []byte, usage uint32, ciphertext []byte) ([]byte, error) {
+	profile, err := getProfile(etype)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != profile.keySize() {
+		return nil, fmt.Errorf("invalid key size %d for encryption type %d", len(key), etype)
+	}
+
+	return profile.decrypt(key, usage, ciphertext)
+}
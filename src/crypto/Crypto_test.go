@@ -0,0 +1,164 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Error decoding hex %s: %v", s, err)
+	}
+	return data
+}
+
+func Test_NFold(t *testing.T) {
+	// Test vectors from RFC 3961 appendix A.1
+	vectors := []struct {
+		input    string
+		size     int
+		expected string
+	}{
+		{"012345", 8, "be072631276b1955"},
+		{"password", 7, "78a07b6caf85fa"},
+		{"Rough Consensus, and Running Code", 8, "bb6ed30870b7f0e0"},
+		{"password", 21, "59e4a8ca7c0385c3c37b3f6d2000247cb6e6bd5b3e"},
+		{"MASSACHVSETTS INSTITVTE OF TECHNOLOGY", 24, "db3b0d8f0b061e603282b308a50841229ad798fab9540c1b"},
+		{"kerberos", 8, "6b65726265726f73"},
+		{"kerberos", 16, "6b65726265726f737b9b5b2b93132b93"},
+	}
+	for _, vector := range vectors {
+		result := hex.EncodeToString(nfold([]byte(vector.input), vector.size))
+		if result != vector.expected {
+			t.Errorf("%d-fold(%q): expected %s, got %s", vector.size*8, vector.input, vector.expected, result)
+		}
+	}
+}
+
+func Test_AESSHA2_DeriveKeys(t *testing.T) {
+	// Test vectors from RFC 8009 appendix A, key usage 2
+	p := profiles[ETypeAES256CTSHMACSHA384192].(aesSHA2Profile)
+	baseKey := mustDecodeHex(t, "6d404d37faf79f9df0d33568d320669800eb4836472ea8a026d16b7182460c52")
+
+	kc := hex.EncodeToString(p.usageKey(baseKey, 2, 0x99))
+	ke := hex.EncodeToString(p.usageKey(baseKey, 2, 0xAA))
+	ki := hex.EncodeToString(p.usageKey(baseKey, 2, 0x55))
+
+	if kc != "ef5718be86cc84963d8bbb5031e9f5c4ba41f28faf69e73d" {
+		t.Errorf("Unexpected Kc %s", kc)
+	}
+	if ke != "56ab22bee63d82d7bc5227f6773f8ea7a5eb1c825160c38312980c442e5c7e49" {
+		t.Errorf("Unexpected Ke %s", ke)
+	}
+	if ki != "69b16514e3cd8e56b82010d5c73012b622c4d00ffc23ed1f" {
+		t.Errorf("Unexpected Ki %s", ki)
+	}
+}
+
+func Test_AESSHA2_Encrypt(t *testing.T) {
+	// Test vector from RFC 8009 appendix A, empty plaintext
+	p := profiles[ETypeAES128CTSHMACSHA256128]
+	baseKey := mustDecodeHex(t, "3705d96080c17728a0e800eab6e0d23c")
+	confounder := mustDecodeHex(t, "7e5895eaf2672435bad817f545a37148")
+
+	ciphertext, err := p.encrypt(baseKey, 2, []byte{}, confounder)
+	if err != nil {
+		t.Fatalf("Error encrypting: %v", err)
+	}
+	expected := "ef85fb890bb8472f4dab20394dca781dad877eda39d50c870c0d5a0a8e48c718"
+	if hex.EncodeToString(ciphertext) != expected {
+		t.Errorf("Expected %s, got %x", expected, ciphertext)
+	}
+}
+
+func Test_Crypto_EncryptDecryptInvolution(t *testing.T) {
+	for etype := range profiles {
+		size, _ := KeySize(etype)
+		key := bytes.Repeat([]byte{0x42}, size)
+		for _, length := range []int{0, 1, 15, 16, 17, 32, 33, 100} {
+			plaintext := bytes.Repeat([]byte{0x61}, length)
+			ciphertext, err := Encrypt(etype, key, 11, plaintext)
+			if err != nil {
+				t.Fatalf("Error encrypting with etype %d: %v", etype, err)
+			}
+			decrypted, err := Decrypt(etype, key, 11, ciphertext)
+			if err != nil {
+				t.Fatalf("Error decrypting with etype %d: %v", etype, err)
+			}
+			if !bytes.Equal(plaintext, decrypted) {
+				t.Errorf("Plaintext mismatch for etype %d and length %d", etype, length)
+			}
+
+			_, err = Decrypt(etype, key, 12, ciphertext)
+			if err != ErrIntegrity {
+				t.Errorf("Expected integrity error with the wrong key usage for etype %d, got %v", etype, err)
+			}
+		}
+	}
+}
+
+func Test_Checksum_RFC8009(t *testing.T) {
+	// RFC 8009 appendix A, checksum of the 21 bytes 000102...14 with key usage 2
+	data := mustDecodeHex(t, "000102030405060708090A0B0C0D0E0F1011121314")
+	vectors := []struct {
+		cksumType int32
+		key       string
+		expected  string
+	}{
+		{CksumTypeHMACSHA256128AES128, "3705D96080C17728A0E800EAB6E0D23C", "D78367186643D67B411CBA9139FC1DEE"},
+		{CksumTypeHMACSHA384192AES256, "6D404D37FAF79F9DF0D33568D320669800EB4836472EA8A026D16B7182460C52", "45EE791567EEFCA37F4AC1E0222DE80D43C3BFA06699672A"},
+	}
+
+	for _, vector := range vectors {
+		key := mustDecodeHex(t, vector.key)
+		expected := mustDecodeHex(t, vector.expected)
+		checksum, err := Checksum(vector.cksumType, key, 2, data)
+		if err != nil {
+			t.Fatalf("Error computing checksum type %d: %v", vector.cksumType, err)
+		}
+		if !bytes.Equal(checksum, expected) {
+			t.Errorf("Checksum type %d mismatch: got %x, expected %x", vector.cksumType, checksum, expected)
+		}
+		if err := VerifyChecksum(vector.cksumType, key, 2, append(data, 0x00), expected); err != ErrIntegrity {
+			t.Errorf("Expected checksum type %d verification of modified data to fail", vector.cksumType)
+		}
+	}
+}
+
+func Test_StringToKey(t *testing.T) {
+	// RFC 3962 appendix B, RFC 8009 appendix A and the well-known NT hash of "password"
+	vectors := []struct {
+		etype    int32
+		salt     string
+		params   string
+		expected string
+	}{
+		{ETypeAES128CTSHMACSHA196, "ATHENA.MIT.EDUraeburn", "00000001", "42263C6E89F4FC28B8DF68EE09799F15"},
+		{ETypeAES256CTSHMACSHA196, "ATHENA.MIT.EDUraeburn", "00000001", "FE697B52BC0D3CE14432BA036A92E65BBB52280990A2FA27883998D72AF30161"},
+		{ETypeAES128CTSHMACSHA196, "ATHENA.MIT.EDUraeburn", "000004B0", "4C01CD46D632D01E6DBE230A01ED642A"},
+		{ETypeAES256CTSHMACSHA196, "ATHENA.MIT.EDUraeburn", "000004B0", "55A6AC740AD17B4846941051E1E8B0A7548D93B0AB30A8BC3FF16280382B8C2A"},
+		{ETypeAES128CTSHMACSHA256128, "\x10\xdf\x9d\xd7\x83\xe5\xbc\x8a\xce\xa1\x73\x0e\x74\x35\x5f\x61ATHENA.MIT.EDUraeburn", "", "089BCA48B105EA6EA77CA5D2F39DC5E7"},
+		{ETypeAES256CTSHMACSHA384192, "\x10\xdf\x9d\xd7\x83\xe5\xbc\x8a\xce\xa1\x73\x0e\x74\x35\x5f\x61ATHENA.MIT.EDUraeburn", "", "45BD806DBF6A833A9CFFC1C94589A222367A79BC21C413718906E9F578A78467"},
+		{ETypeRC4HMAC, "ignored", "", "8846F7EAEE8FB117AD06BDD830B7586C"},
+	}
+
+	for _, vector := range vectors {
+		var params []byte
+		if vector.params != "" {
+			params = mustDecodeHex(t, vector.params)
+		}
+		key, err := StringToKey(vector.etype, "password", vector.salt, params)
+		if err != nil {
+			t.Fatalf("Error deriving key of encryption type %d: %v", vector.etype, err)
+		}
+		if expected := mustDecodeHex(t, vector.expected); !bytes.Equal(key, expected) {
+			t.Errorf("Key of encryption type %d mismatch: got %x, expected %x", vector.etype, key, expected)
+		}
+	}
+
+	if _, err := StringToKey(ETypeAES128CTSHMACSHA196, "password", "salt", []byte{0x01}); err == nil {
+		t.Errorf("Expected an error for invalid string-to-key parameters")
+	}
+}
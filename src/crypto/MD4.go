@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// md4 computes the MD4 digest of RFC 1320, only needed for the rc4-hmac string-to-key.
+func md4(data []byte) []byte {
+	a, b, c, d := uint32(0x67452301), uint32(0xefcdab89), uint32(0x98badcfe), uint32(0x10325476)
+
+	length := uint64(len(data)) * 8
+	padded := append(append([]byte{}, data...), 0x80)
+	for len(padded)%64 != 56 {
+		padded = append(padded, 0x00)
+	}
+	padded = binary.LittleEndian.AppendUint64(padded, length)
+
+	x := make([]uint32, 16)
+	for offset := 0; offset < len(padded); offset += 64 {
+		for i := range x {
+			x[i] = binary.LittleEndian.Uint32(padded[offset+4*i:])
+		}
+		aa, bb, cc, dd := a, b, c, d
+
+		// Round 1: F(x, y, z) = (x & y) | (^x & z).
+		for _, i := range []int{0, 4, 8, 12} {
+			a = bits.RotateLeft32(a+((b&c)|(^b&d))+x[i], 3)
+			d = bits.RotateLeft32(d+((a&b)|(^a&c))+x[i+1], 7)
+			c = bits.RotateLeft32(c+((d&a)|(^d&b))+x[i+2], 11)
+			b = bits.RotateLeft32(b+((c&d)|(^c&a))+x[i+3], 19)
+		}
+		// Round 2: G(x, y, z) = (x & y) | (x & z) | (y & z).
+		for _, i := range []int{0, 1, 2, 3} {
+			a = bits.RotateLeft32(a+((b&c)|(b&d)|(c&d))+x[i]+0x5a827999, 3)
+			d = bits.RotateLeft32(d+((a&b)|(a&c)|(b&c))+x[i+4]+0x5a827999, 5)
+			c = bits.RotateLeft32(c+((d&a)|(d&b)|(a&b))+x[i+8]+0x5a827999, 9)
+			b = bits.RotateLeft32(b+((c&d)|(c&a)|(d&a))+x[i+12]+0x5a827999, 13)
+		}
+		// Round 3: H(x, y, z) = x ^ y ^ z.
+		for _, i := range []int{0, 2, 1, 3} {
+			a = bits.RotateLeft32(a+(b^c^d)+x[i]+0x6ed9eba1, 3)
+			d = bits.RotateLeft32(d+(a^b^c)+x[i+8]+0x6ed9eba1, 9)
+			c = bits.RotateLeft32(c+(d^a^b)+x[i+4]+0x6ed9eba1, 11)
+			b = bits.RotateLeft32(b+(c^d^a)+x[i+12]+0x6ed9eba1, 15)
+		}
+
+		a, b, c, d = a+aa, b+bb, c+cc, d+dd
+	}
+
+	digest := make([]byte, 0, 16)
+	for _, v := range []uint32{a, b, c, d} {
+		digest = binary.LittleEndian.AppendUint32(digest, v)
+	}
+	return digest
+}
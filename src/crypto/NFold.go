@@ -0,0 +1,71 @@
+package crypto
+
+// nfold implements the n-fold operation of RFC 3961 section 5.1, stretching or
+// folding the input to the requested number of bytes.
+//
+// Parameters:
+//   - input ([]byte): The input to fold.
+//   - n (int): The size of the output in bytes.
+//
+// Returns:
+//   - []byte: The n-folded input.
+func nfold(input []byte, n int) []byte {
+	inBits := len(input) * 8
+	outBits := n * 8
+	lcm := inBits * outBits / gcd(inBits, outBits)
+
+	// Concatenate lcm/inBits copies of the input, each rotated right by 13 bits more than the previous one
+	buffer := make([]byte, 0, lcm/8)
+	for i := 0; i < lcm/inBits; i++ {
+		buffer = append(buffer, rotateRight(input, 13*i)...)
+	}
+
+	// Add the n-byte chunks together using one's complement addition
+	output := make([]byte, n)
+	for i := 0; i < len(buffer); i += n {
+		output = onesComplementAdd(output, buffer[i:i+n])
+	}
+
+	return output
+}
+
+// rotateRight rotates a byte array right by the given number of bits.
+func rotateRight(input []byte, bits int) []byte {
+	totalBits := len(input) * 8
+	bits %= totalBits
+	output := make([]byte, len(input))
+	for i := 0; i < totalBits; i++ {
+		if input[i/8]&(0x80>>(i%8)) != 0 {
+			j := (i + bits) % totalBits
+			output[j/8] |= 0x80 >> (j % 8)
+		}
+	}
+	return output
+}
+
+// onesComplementAdd adds two big-endian byte arrays of the same size using one's complement addition.
+func onesComplementAdd(a, b []byte) []byte {
+	output := make([]byte, len(a))
+	carry := 0
+	for i := len(a) - 1; i >= 0; i-- {
+		sum := int(a[i]) + int(b[i]) + carry
+		output[i] = byte(sum)
+		carry = sum >> 8
+	}
+	for carry != 0 {
+		for i := len(output) - 1; i >= 0 && carry != 0; i-- {
+			sum := int(output[i]) + carry
+			output[i] = byte(sum)
+			carry = sum >> 8
+		}
+	}
+	return output
+}
+
+// gcd returns the greatest common divisor of two integers.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// rc4HMACProfile implements the rc4-hmac encryption type of RFC 4757.
+type rc4HMACProfile struct{}
+
+// rc4HMACChecksumSize is the size of the HMAC-MD5 prepended to ciphertexts.
+const rc4HMACChecksumSize = 16
+
+func (p rc4HMACProfile) keySize() int {
+	return 16
+}
+
+func (p rc4HMACProfile) confounderSize() int {
+	return 8
+}
+
+// translateUsage maps RFC 4120 key usage numbers to the message types used by RFC 4757.
+func (p rc4HMACProfile) translateUsage(usage uint32) uint32 {
+	switch usage {
+	case 3:
+		return 8
+	case 9:
+		return 8
+	case 23:
+		return 13
+	}
+	return usage
+}
+
+// usageKey computes K1 = HMAC-MD5(key, T) where T is the little-endian message type.
+func (p rc4HMACProfile) usageKey(key []byte, usage uint32) []byte {
+	buffer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buffer, p.translateUsage(usage))
+	mac := hmac.New(md5.New, key)
+	mac.Write(buffer)
+	return mac.Sum(nil)
+}
+
+func (p rc4HMACProfile) encrypt(key []byte, usage uint32, plaintext, confounder []byte) ([]byte, error) {
+	k1 := p.usageKey(key, usage)
+
+	data := append(append([]byte{}, confounder...), plaintext...)
+
+	mac := hmac.New(md5.New, k1)
+	mac.Write(data)
+	checksum := mac.Sum(nil)
+
+	mac = hmac.New(md5.New, k1)
+	mac.Write(checksum)
+	k3 := mac.Sum(nil)
+
+	stream, err := rc4.NewCipher(k3)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := make([]byte, len(data))
+	stream.XORKeyStream(encrypted, data)
+
+	return append(checksum, encrypted...), nil
+}
+
+func (p rc4HMACProfile) decrypt(key []byte, usage uint32, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < rc4HMACChecksumSize+p.confounderSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	k1 := p.usageKey(key, usage)
+
+	checksum := ciphertext[:rc4HMACChecksumSize]
+	encrypted := ciphertext[rc4HMACChecksumSize:]
+
+	mac := hmac.New(md5.New, k1)
+	mac.Write(checksum)
+	k3 := mac.Sum(nil)
+
+	stream, err := rc4.NewCipher(k3)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, len(encrypted))
+	stream.XORKeyStream(data, encrypted)
+
+	mac = hmac.New(md5.New, k1)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil), checksum) {
+		return nil, ErrIntegrity
+	}
+
+	return data[p.confounderSize():], nil
+}
+
+// checksum computes the hmac-md5 checksum of RFC 4757 section 4, keyed with
+// Ksign = HMAC-MD5(key, "signaturekey\0").
+func (p rc4HMACProfile) checksum(key []byte, usage uint32, data []byte) ([]byte, error) {
+	mac := hmac.New(md5.New, key)
+	mac.Write([]byte("signaturekey\x00"))
+	ksign := mac.Sum(nil)
+
+	buffer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buffer, p.translateUsage(usage))
+	digest := md5.New()
+	digest.Write(buffer)
+	digest.Write(data)
+
+	mac = hmac.New(md5.New, ksign)
+	mac.Write(digest.Sum(nil))
+	return mac.Sum(nil), nil
+}
+
+// stringToKey implements the string-to-key function of RFC 4757 section 5, the MD4 of the
+// UTF-16LE password (the NT hash). The salt is not used.
+func (p rc4HMACProfile) stringToKey(password, salt string, params []byte) ([]byte, error) {
+	encoded := make([]byte, 0, 2*len(password))
+	for _, unit := range utf16.Encode([]rune(password)) {
+		encoded = binary.LittleEndian.AppendUint16(encoded, unit)
+	}
+	return NTHash(encoded), nil
+}
+
+// NTHash returns the RC4-HMAC key of a password given as its UTF-16LE encoding, the MD4 of the
+// encoding. The encoding is hashed as is, even when it is not valid UTF-16, as the random
+// passwords of group managed service accounts may be.
+//
+// Parameters:
+//   - password ([]byte): The UTF-16LE encoding of the password.
+//
+// Returns:
+//   - []byte: The key.
+func NTHash(password []byte) []byte {
+	return md4(password)
+}
@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// StringToKey derives the key of a password for an encryption type, as done by the KDC
+// when the password of a principal is set.
+//
+// Parameters:
+//   - etype (int32): The encryption type.
+//   - password (string): The password.
+//   - salt (string): The salt, usually the realm followed by the components of the principal.
+//   - params ([]byte): The string-to-key parameters from ETYPE-INFO2, or nil for the defaults.
+//
+// Returns:
+//   - ([]byte, error): The key and an error if the derivation failed.
+func StringToKey(etype int32, password, salt string, params []byte) ([]byte, error) {
+	profile, err := getProfile(etype)
+	if err != nil {
+		return nil, err
+	}
+	return profile.stringToKey(password, salt, params)
+}
+
+// iterationCount parses the string-to-key parameters of the AES encryption types, a 32-bit
+// big-endian iteration count, and returns defaultCount when there are none.
+func iterationCount(params []byte, defaultCount uint32) (int, error) {
+	if len(params) == 0 {
+		return int(defaultCount), nil
+	}
+	if len(params) != 4 {
+		return 0, fmt.Errorf("invalid string-to-key parameters of %d bytes", len(params))
+	}
+	count := binary.BigEndian.Uint32(params)
+	if count == 0 || count > 1<<24 {
+		return 0, fmt.Errorf("invalid iteration count %d", count)
+	}
+	return int(count), nil
+}
+
+// pbkdf2 implements PBKDF2 of RFC 8018 section 5.2 with an HMAC as the pseudorandom function.
+func pbkdf2(h func() hash.Hash, password, salt []byte, iterations, size int) []byte {
+	mac := hmac.New(h, password)
+	counter := make([]byte, 4)
+	derived := make([]byte, 0, size+mac.Size())
+	for block := uint32(1); len(derived) < size; block++ {
+		binary.BigEndian.PutUint32(counter, block)
+		mac.Reset()
+		mac.Write(salt)
+		mac.Write(counter)
+		u := mac.Sum(nil)
+		t := append([]byte{}, u...)
+		for i := 1; i < iterations; i++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		derived = append(derived, t...)
+	}
+	return derived[:size]
+}
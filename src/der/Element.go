@@ -0,0 +1,332 @@
+package der
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Classes of ASN.1 tags.
+const (
+	ClassUniversal   = 0
+	ClassApplication = 1
+	ClassContext     = 2
+	ClassPrivate     = 3
+)
+
+// Universal ASN.1 tags used by Kerberos.
+const (
+	TagBoolean         = 1
+	TagInteger         = 2
+	TagBitString       = 3
+	TagOctetString     = 4
+	TagNull            = 5
+	TagOID             = 6
+	TagEnumerated      = 10
+	TagSequence        = 16
+	TagIA5String       = 22
+	TagGeneralizedTime = 24
+	TagGeneralString   = 27
+)
+
+// Element represents a single DER encoded TLV (tag, length, value).
+//
+// Attributes:
+//   - Class (int): The class of the tag.
+//   - Constructed (bool): Whether the element is constructed.
+//   - Tag (int): The tag number.
+//   - Content ([]byte): The content octets of the element.
+//   - RawBytes ([]byte): The raw bytes of the whole element.
+//   - RawBytesSize (uint32): The size of the raw bytes of the element.
+type Element struct {
+	Class       int
+	Constructed bool
+	Tag         int
+	Content     []byte
+	// Internal
+	RawBytes     []byte
+	RawBytesSize uint32
+}
+
+// FromBytes parses the first DER element of a byte array.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (e *Element) FromBytes(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("der element is truncated")
+	}
+	offset := 0
+
+	// Identifier octets
+	e.Class = int(data[0] >> 6)
+	e.Constructed = data[0]&0x20 != 0
+	e.Tag = int(data[0] & 0x1f)
+	offset++
+	if e.Tag == 0x1f {
+		e.Tag = 0
+		for {
+			if offset >= len(data) {
+				return fmt.Errorf("der element tag is truncated")
+			}
+			b := data[offset]
+			offset++
+			e.Tag = e.Tag<<7 | int(b&0x7f)
+			if e.Tag > 0xffffff {
+				return fmt.Errorf("der element tag is too large")
+			}
+			if b&0x80 == 0 {
+				break
+			}
+		}
+	}
+
+	// Length octets
+	if offset >= len(data) {
+		return fmt.Errorf("der element length is truncated")
+	}
+	length := int(data[offset])
+	offset++
+	if length&0x80 != 0 {
+		numBytes := length & 0x7f
+		if numBytes == 0 || numBytes > 4 {
+			return fmt.Errorf("unsupported der length encoding")
+		}
+		if offset+numBytes > len(data) {
+			return fmt.Errorf("der element length is truncated")
+		}
+		length = 0
+		for i := 0; i < numBytes; i++ {
+			length = length<<8 | int(data[offset])
+			offset++
+		}
+	}
+
+	if length < 0 || offset+length > len(data) {
+		return fmt.Errorf("der element content is truncated: expected %d bytes, got %d", length, len(data)-offset)
+	}
+
+	e.Content = data[offset : offset+length]
+	e.RawBytes = data[:offset+length]
+	e.RawBytesSize = uint32(offset + length)
+
+	return nil
+}
+
+// Parse parses the first DER element of a byte array.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - Element: The parsed element.
+//   - []byte: The remaining bytes after the element.
+//   - error: An error if the parsing failed.
+func Parse(data []byte) (Element, []byte, error) {
+	e := Element{}
+	err := e.FromBytes(data)
+	if err != nil {
+		return e, nil, err
+	}
+	return e, data[e.RawBytesSize:], nil
+}
+
+// Is checks the class and tag of the element.
+//
+// Parameters:
+//   - class (int): The expected class.
+//   - tag (int): The expected tag.
+//
+// Returns:
+//   - bool: True if the element has the given class and tag.
+func (e Element) Is(class, tag int) bool {
+	return e.Class == class && e.Tag == tag
+}
+
+// Children parses the content of a constructed element into its child elements.
+//
+// Returns:
+//   - ([]Element, error): The child elements and an error if the parsing failed.
+func (e Element) Children() ([]Element, error) {
+	if !e.Constructed {
+		return nil, fmt.Errorf("der element is not constructed")
+	}
+	children := make([]Element, 0)
+	data := e.Content
+	for len(data) != 0 {
+		child, rest, err := Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+		data = rest
+	}
+	return children, nil
+}
+
+// Unwrap returns the single element enclosed in an explicitly tagged element,
+// after checking the class and tag of the wrapper.
+//
+// Parameters:
+//   - class (int): The expected class of the wrapper.
+//   - tag (int): The expected tag of the wrapper.
+//
+// Returns:
+//   - (Element, error): The enclosed element and an error if the wrapper does not match.
+func (e Element) Unwrap(class, tag int) (Element, error) {
+	if !e.Is(class, tag) {
+		return Element{}, fmt.Errorf("unexpected der tag [%d:%d], expected [%d:%d]", e.Class, e.Tag, class, tag)
+	}
+	inner, _, err := Parse(e.Content)
+	return inner, err
+}
+
+// Fields parses a SEQUENCE whose components are all explicitly context tagged,
+// and returns the enclosed element of each component indexed by its tag number.
+//
+// Returns:
+//   - (map[int]Element, error): The components of the sequence and an error if the parsing failed.
+func (e Element) Fields() (map[int]Element, error) {
+	if !e.Is(ClassUniversal, TagSequence) {
+		return nil, fmt.Errorf("der element is not a sequence")
+	}
+	children, err := e.Children()
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[int]Element)
+	for _, child := range children {
+		if child.Class != ClassContext {
+			return nil, fmt.Errorf("unexpected non context tagged field in sequence")
+		}
+		inner, _, err := Parse(child.Content)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing field [%d]: %w", child.Tag, err)
+		}
+		fields[child.Tag] = inner
+	}
+	return fields, nil
+}
+
+// Int returns the value of an INTEGER or ENUMERATED element.
+//
+// Returns:
+//   - (int64, error): The value and an error if the element is not an integer.
+func (e Element) Int() (int64, error) {
+	if !e.Is(ClassUniversal, TagInteger) && !e.Is(ClassUniversal, TagEnumerated) {
+		return 0, fmt.Errorf("der element is not an integer")
+	}
+	if len(e.Content) == 0 || len(e.Content) > 8 {
+		return 0, fmt.Errorf("invalid der integer length %d", len(e.Content))
+	}
+	value := int64(int8(e.Content[0]))
+	for _, b := range e.Content[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
+
+// Bytes returns the content of an OCTET STRING element.
+//
+// Returns:
+//   - ([]byte, error): The content and an error if the element is not an octet string.
+func (e Element) Bytes() ([]byte, error) {
+	if !e.Is(ClassUniversal, TagOctetString) {
+		return nil, fmt.Errorf("der element is not an octet string")
+	}
+	return e.Content, nil
+}
+
+// Text returns the content of a GeneralString, IA5String or other character string element.
+//
+// Returns:
+//   - (string, error): The string and an error if the element is not a string.
+func (e Element) Text() (string, error) {
+	if e.Class != ClassUniversal || e.Constructed {
+		return "", fmt.Errorf("der element is not a string")
+	}
+	switch e.Tag {
+	case TagGeneralString, TagIA5String, 12, 19, 20, 26:
+		return string(e.Content), nil
+	}
+	return "", fmt.Errorf("der element with tag %d is not a string", e.Tag)
+}
+
+// Time returns the value of a GeneralizedTime element.
+//
+// Returns:
+//   - (time.Time, error): The time and an error if the element is not a valid GeneralizedTime.
+func (e Element) Time() (time.Time, error) {
+	if !e.Is(ClassUniversal, TagGeneralizedTime) {
+		return time.Time{}, fmt.Errorf("der element is not a generalized time")
+	}
+	value := string(e.Content)
+	if strings.Contains(value, ".") {
+		return time.Parse("20060102150405.999999999Z", value)
+	}
+	return time.Parse("20060102150405Z", value)
+}
+
+// BitString returns the first 32 bits of a BIT STRING element, as used by KerberosFlags.
+//
+// Returns:
+//   - (uint32, error): The bits and an error if the element is not a bit string.
+func (e Element) BitString() (uint32, error) {
+	if !e.Is(ClassUniversal, TagBitString) {
+		return 0, fmt.Errorf("der element is not a bit string")
+	}
+	if len(e.Content) == 0 {
+		return 0, fmt.Errorf("bit string is empty")
+	}
+	value := uint32(0)
+	for i := 0; i < 4; i++ {
+		value <<= 8
+		if 1+i < len(e.Content) {
+			value |= uint32(e.Content[1+i])
+		}
+	}
+	return value, nil
+}
+
+// Bool returns the value of a BOOLEAN element.
+//
+// Returns:
+//   - (bool, error): The value and an error if the element is not a boolean.
+func (e Element) Bool() (bool, error) {
+	if !e.Is(ClassUniversal, TagBoolean) || len(e.Content) != 1 {
+		return false, fmt.Errorf("der element is not a boolean")
+	}
+	return e.Content[0] != 0, nil
+}
+
+// OID returns the value of an OBJECT IDENTIFIER element in dotted notation.
+//
+// Returns:
+//   - (string, error): The object identifier and an error if the element is not an object identifier.
+func (e Element) OID() (string, error) {
+	if !e.Is(ClassUniversal, TagOID) || len(e.Content) == 0 {
+		return "", fmt.Errorf("der element is not an object identifier")
+	}
+	arcs := make([]string, 0)
+	value := 0
+	for i, b := range e.Content {
+		value = value<<7 | int(b&0x7f)
+		if b&0x80 != 0 {
+			if i == len(e.Content)-1 {
+				return "", fmt.Errorf("object identifier is truncated")
+			}
+			continue
+		}
+		if len(arcs) == 0 {
+			first := min(value/40, 2)
+			arcs = append(arcs, fmt.Sprintf("%d", first), fmt.Sprintf("%d", value-first*40))
+		} else {
+			arcs = append(arcs, fmt.Sprintf("%d", value))
+		}
+		value = 0
+	}
+	return strings.Join(arcs, "."), nil
+}
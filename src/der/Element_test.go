@@ -0,0 +1,85 @@
+package der
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func Test_Element_Integer(t *testing.T) {
+	for _, value := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 0x7fffffff, -0x80000000} {
+		e := Element{}
+		err := e.FromBytes(Integer(value))
+		if err != nil {
+			t.Fatalf("Error parsing integer %d: %v", value, err)
+		}
+		parsed, err := e.Int()
+		if err != nil || parsed != value {
+			t.Errorf("Expected %d, got %d (%v)", value, parsed, err)
+		}
+	}
+
+	if hex.EncodeToString(Integer(128)) != "02020080" {
+		t.Errorf("Unexpected encoding of 128: %x", Integer(128))
+	}
+}
+
+func Test_Element_Fields(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	data := Application(1, Sequence(
+		Explicit(0, Integer(5)),
+		Explicit(1, GeneralString("TESTSEGMENT.LOCAL")),
+		nil,
+		Explicit(3, GeneralizedTime(now)),
+		Explicit(4, BitString(0x40810000)),
+		Explicit(5, OctetString(bytes.Repeat([]byte{0x42}, 300))),
+	))
+
+	e := Element{}
+	if err := e.FromBytes(data); err != nil {
+		t.Fatalf("Error parsing element: %v", err)
+	}
+	seq, err := e.Unwrap(ClassApplication, 1)
+	if err != nil {
+		t.Fatalf("Error unwrapping element: %v", err)
+	}
+	fields, err := seq.Fields()
+	if err != nil {
+		t.Fatalf("Error parsing fields: %v", err)
+	}
+
+	if _, ok := fields[2]; ok {
+		t.Errorf("Absent optional field was encoded")
+	}
+	if v, _ := fields[0].Int(); v != 5 {
+		t.Errorf("Expected 5, got %d", v)
+	}
+	if s, _ := fields[1].Text(); s != "TESTSEGMENT.LOCAL" {
+		t.Errorf("Expected TESTSEGMENT.LOCAL, got %s", s)
+	}
+	if tm, _ := fields[3].Time(); !tm.Equal(now) {
+		t.Errorf("Expected %s, got %s", now, tm)
+	}
+	if f, _ := fields[4].BitString(); f != 0x40810000 {
+		t.Errorf("Expected 0x40810000, got 0x%08x", f)
+	}
+	if b, _ := fields[5].Bytes(); len(b) != 300 {
+		t.Errorf("Expected 300 bytes, got %d", len(b))
+	}
+}
+
+func Test_Element_ObjectIdentifier(t *testing.T) {
+	oid := "1.2.840.113554.1.2.2"
+	e := Element{}
+	if err := e.FromBytes(MustObjectIdentifier(oid)); err != nil {
+		t.Fatalf("Error parsing object identifier: %v", err)
+	}
+	parsed, err := e.OID()
+	if err != nil || parsed != oid {
+		t.Errorf("Expected %s, got %s (%v)", oid, parsed, err)
+	}
+	if hex.EncodeToString(MustObjectIdentifier(oid)) != "06092a864886f712010202" {
+		t.Errorf("Unexpected encoding of %s: %x", oid, MustObjectIdentifier(oid))
+	}
+}
@@ -0,0 +1,240 @@
+package der
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encode encodes a DER element from its tag and content.
+//
+// Parameters:
+//   - class (int): The class of the tag.
+//   - constructed (bool): Whether the element is constructed.
+//   - tag (int): The tag number.
+//   - content ([]byte): The content octets.
+//
+// Returns:
+//   - []byte: The encoded element.
+func Encode(class int, constructed bool, tag int, content []byte) []byte {
+	data := make([]byte, 0, len(content)+8)
+
+	identifier := byte(class << 6)
+	if constructed {
+		identifier |= 0x20
+	}
+	if tag < 0x1f {
+		data = append(data, identifier|byte(tag))
+	} else {
+		data = append(data, identifier|0x1f)
+		tagBytes := []byte{byte(tag & 0x7f)}
+		for t := tag >> 7; t > 0; t >>= 7 {
+			tagBytes = append([]byte{byte(t&0x7f) | 0x80}, tagBytes...)
+		}
+		data = append(data, tagBytes...)
+	}
+
+	length := len(content)
+	if length < 0x80 {
+		data = append(data, byte(length))
+	} else {
+		lengthBytes := make([]byte, 0)
+		for l := length; l > 0; l >>= 8 {
+			lengthBytes = append([]byte{byte(l)}, lengthBytes...)
+		}
+		data = append(data, 0x80|byte(len(lengthBytes)))
+		data = append(data, lengthBytes...)
+	}
+
+	return append(data, content...)
+}
+
+// Sequence encodes a SEQUENCE (or SEQUENCE OF) from already encoded components.
+// Nil components are skipped, which allows optional fields to be passed unconditionally.
+//
+// Parameters:
+//   - components (...[]byte): The encoded components.
+//
+// Returns:
+//   - []byte: The encoded sequence.
+func Sequence(components ...[]byte) []byte {
+	content := make([]byte, 0)
+	for _, component := range components {
+		content = append(content, component...)
+	}
+	return Encode(ClassUniversal, true, TagSequence, content)
+}
+
+// Explicit wraps an encoded element in an explicit context specific tag.
+// A nil element yields nil, so that absent optional fields stay absent.
+//
+// Parameters:
+//   - tag (int): The context specific tag number.
+//   - inner ([]byte): The encoded element.
+//
+// Returns:
+//   - []byte: The encoded tagged element.
+func Explicit(tag int, inner []byte) []byte {
+	if inner == nil {
+		return nil
+	}
+	return Encode(ClassContext, true, tag, inner)
+}
+
+// Application wraps an encoded element in an explicit application tag.
+//
+// Parameters:
+//   - tag (int): The application tag number.
+//   - inner ([]byte): The encoded element.
+//
+// Returns:
+//   - []byte: The encoded tagged element.
+func Application(tag int, inner []byte) []byte {
+	return Encode(ClassApplication, true, tag, inner)
+}
+
+// Integer encodes an INTEGER.
+//
+// Parameters:
+//   - value (int64): The value.
+//
+// Returns:
+//   - []byte: The encoded integer.
+func Integer(value int64) []byte {
+	return Encode(ClassUniversal, false, TagInteger, integerContent(value))
+}
+
+// Enumerated encodes an ENUMERATED value.
+//
+// Parameters:
+//   - value (int64): The value.
+//
+// Returns:
+//   - []byte: The encoded value.
+func Enumerated(value int64) []byte {
+	return Encode(ClassUniversal, false, TagEnumerated, integerContent(value))
+}
+
+// integerContent returns the minimal two's complement big-endian encoding of a value.
+func integerContent(value int64) []byte {
+	content := []byte{byte(value)}
+	for v := value >> 8; ; v >>= 8 {
+		if (v == 0 && content[0]&0x80 == 0) || (v == -1 && content[0]&0x80 != 0) {
+			break
+		}
+		content = append([]byte{byte(v)}, content...)
+	}
+	return content
+}
+
+// OctetString encodes an OCTET STRING.
+//
+// Parameters:
+//   - value ([]byte): The value.
+//
+// Returns:
+//   - []byte: The encoded octet string.
+func OctetString(value []byte) []byte {
+	return Encode(ClassUniversal, false, TagOctetString, value)
+}
+
+// GeneralString encodes a GeneralString, as used by KerberosString and Realm.
+//
+// Parameters:
+//   - value (string): The value.
+//
+// Returns:
+//   - []byte: The encoded string.
+func GeneralString(value string) []byte {
+	return Encode(ClassUniversal, false, TagGeneralString, []byte(value))
+}
+
+// GeneralizedTime encodes a GeneralizedTime in the UTC form without fractional seconds required by Kerberos.
+//
+// Parameters:
+//   - value (time.Time): The value.
+//
+// Returns:
+//   - []byte: The encoded time.
+func GeneralizedTime(value time.Time) []byte {
+	return Encode(ClassUniversal, false, TagGeneralizedTime, []byte(value.UTC().Format("20060102150405Z")))
+}
+
+// BitString encodes a 32-bit BIT STRING, as used by KerberosFlags.
+//
+// Parameters:
+//   - value (uint32): The bits, the first bit being the most significant bit.
+//
+// Returns:
+//   - []byte: The encoded bit string.
+func BitString(value uint32) []byte {
+	return Encode(ClassUniversal, false, TagBitString, []byte{0x00, byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)})
+}
+
+// Boolean encodes a BOOLEAN.
+//
+// Parameters:
+//   - value (bool): The value.
+//
+// Returns:
+//   - []byte: The encoded boolean.
+func Boolean(value bool) []byte {
+	if value {
+		return Encode(ClassUniversal, false, TagBoolean, []byte{0xff})
+	}
+	return Encode(ClassUniversal, false, TagBoolean, []byte{0x00})
+}
+
+// ObjectIdentifier encodes an OBJECT IDENTIFIER given in dotted notation.
+//
+// Parameters:
+//   - oid (string): The object identifier, for example "1.2.840.113554.1.2.2".
+//
+// Returns:
+//   - ([]byte, error): The encoded object identifier and an error if the notation is invalid.
+func ObjectIdentifier(oid string) ([]byte, error) {
+	parts := strings.Split(oid, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid object identifier %q", oid)
+	}
+	arcs := make([]int, 0, len(parts))
+	for _, part := range parts {
+		arc, err := strconv.Atoi(part)
+		if err != nil || arc < 0 {
+			return nil, fmt.Errorf("invalid object identifier %q", oid)
+		}
+		arcs = append(arcs, arc)
+	}
+
+	content := encodeBase128(arcs[0]*40 + arcs[1])
+	for _, arc := range arcs[2:] {
+		content = append(content, encodeBase128(arc)...)
+	}
+	return Encode(ClassUniversal, false, TagOID, content), nil
+}
+
+// MustObjectIdentifier encodes an OBJECT IDENTIFIER and panics if the notation is invalid.
+// It is intended for constant object identifiers.
+//
+// Parameters:
+//   - oid (string): The object identifier in dotted notation.
+//
+// Returns:
+//   - []byte: The encoded object identifier.
+func MustObjectIdentifier(oid string) []byte {
+	data, err := ObjectIdentifier(oid)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// encodeBase128 encodes an object identifier arc in base 128.
+func encodeBase128(value int) []byte {
+	data := []byte{byte(value & 0x7f)}
+	for v := value >> 7; v > 0; v >>= 7 {
+		data = append([]byte{byte(v&0x7f) | 0x80}, data...)
+	}
+	return data
+}
@@ -0,0 +1,576 @@
+package kdctest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"keytab/acceptor"
+	"keytab/ccache"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultETypes are the encryption types issued by a KDC when none are configured, in order of preference.
+var DefaultETypes = []int32{
+	crypto.ETypeAES256CTSHMACSHA196,
+	crypto.ETypeAES128CTSHMACSHA196,
+	crypto.ETypeAES256CTSHMACSHA384192,
+	crypto.ETypeAES128CTSHMACSHA256128,
+	crypto.ETypeRC4HMAC,
+}
+
+// DefaultTicketLifetime is the default maximum lifetime of the tickets issued.
+const DefaultTicketLifetime = 10 * time.Hour
+
+// KDC is a minimal Key Distribution Center, serving AS and TGS exchanges for the principals of
+// a keytab. It is meant for tests of Kerberized services and clients, either fully in memory
+// through HandleMessage or on a loopback port with Start.
+//
+// The principals are looked up in the keytab on every request, using the key with the highest
+// version number of each encryption type, so the keytab can be modified between requests as
+// long as no request is being served. The keys of krbtgt/REALM@REALM and kadmin/changepw@REALM
+// are taken from the keytab when present, and generated otherwise. A stand-in kpasswd service
+// changing the passwords of the principals of the keytab is served by HandleKpasswd.
+//
+// Attributes:
+//   - Realm (string): The realm of the KDC.
+//   - Keytab (*keytab.Keytab): The principal database.
+//   - ETypes ([]int32): The encryption types issued, in order of preference.
+//   - RequirePreAuth (bool): Whether AS-REQs without PA-ENC-TIMESTAMP are answered with KDC_ERR_PREAUTH_REQUIRED.
+//   - IssuePAC (bool): Whether to put a minimal PAC, signed with the service and krbtgt keys, in the tickets.
+//   - TicketLifetime (time.Duration): The maximum lifetime of the tickets issued.
+//   - MaxClockSkew (time.Duration): The tolerated difference between the clocks of the clients and the KDC.
+//   - MinPasswordLength (int): The minimum length of the passwords set through kpasswd.
+//   - Now (func() time.Time): The clock of the KDC.
+type KDC struct {
+	Realm             string
+	Keytab            *keytab.Keytab
+	ETypes            []int32
+	RequirePreAuth    bool
+	IssuePAC          bool
+	TicketLifetime    time.Duration
+	MaxClockSkew      time.Duration
+	MinPasswordLength int
+	Now               func() time.Time
+	// Internal
+	krbtgtKey     messages.EncryptionKey
+	kadminKey     messages.EncryptionKey
+	mutex         sync.Mutex
+	server        *server
+	kpasswdServer *server
+}
+
+// kdcError is a KRB-ERROR to send back to the client.
+type kdcError struct {
+	code  int32
+	text  string
+	edata []byte
+}
+
+// principalKey is a long-term key of a principal, as found in the keytab.
+type principalKey struct {
+	key  messages.EncryptionKey
+	kvno uint32
+	salt string
+}
+
+// NewKDC creates a KDC serving the principals of a keytab, requiring pre-authentication.
+//
+// Parameters:
+//   - realm (string): The realm of the KDC.
+//   - kt (*keytab.Keytab): The principal database.
+//
+// Returns:
+//   - (*KDC, error): The KDC and an error if the krbtgt key could not be generated.
+func NewKDC(realm string, kt *keytab.Keytab) (*KDC, error) {
+	krbtgtKey, err := randomKey(crypto.ETypeAES256CTSHMACSHA196)
+	if err != nil {
+		return nil, err
+	}
+	kadminKey, err := randomKey(crypto.ETypeAES256CTSHMACSHA196)
+	if err != nil {
+		return nil, err
+	}
+	return &KDC{
+		Realm:          realm,
+		Keytab:         kt,
+		ETypes:         DefaultETypes,
+		RequirePreAuth: true,
+		TicketLifetime: DefaultTicketLifetime,
+		MaxClockSkew:   acceptor.DefaultMaxClockSkew,
+		Now:            time.Now,
+		krbtgtKey:      krbtgtKey,
+		kadminKey:      kadminKey,
+	}, nil
+}
+
+// HandleMessage serves a single DER encoded AS-REQ or TGS-REQ, without transport framing.
+//
+// Parameters:
+//   - request ([]byte): The DER encoded request.
+//
+// Returns:
+//   - []byte: The DER encoded AS-REP, TGS-REP or KRB-ERROR.
+func (k *KDC) HandleMessage(request []byte) []byte {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	req := messages.KDCReq{}
+	err := req.FromBytes(request)
+	if err != nil {
+		return k.marshalError(nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_MSG_TYPE, text: err.Error()})
+	}
+
+	var reply []byte
+	var kerr *kdcError
+	if req.MsgType == messages.MsgType_AS_REQ {
+		reply, kerr = k.asExchange(&req)
+	} else {
+		reply, kerr = k.tgsExchange(&req)
+	}
+	if kerr != nil {
+		return k.marshalError(&req, kerr)
+	}
+	return reply
+}
+
+// asExchange serves an AS-REQ, as described in RFC 4120 section 3.1.
+func (k *KDC) asExchange(req *messages.KDCReq) ([]byte, *kdcError) {
+	now := k.now()
+	body := &req.ReqBody
+	if !strings.EqualFold(body.Realm, k.Realm) {
+		return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_WRONG_REALM}
+	}
+
+	clientKeys := k.principalKeys(body.CName)
+	if len(clientKeys) == 0 {
+		return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_C_PRINCIPAL_UNKNOWN}
+	}
+	serviceKey, serviceKvno, kerr := k.serviceKey(body.SName)
+	if kerr != nil {
+		return nil, kerr
+	}
+
+	// Encryption types of the client keys acceptable to both sides, in order of preference of the client
+	etypes := make([]int32, 0)
+	for _, etype := range body.EType {
+		if _, ok := clientKeys[etype]; ok && k.issues(etype) {
+			etypes = append(etypes, etype)
+		}
+	}
+	if len(etypes) == 0 {
+		return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_ETYPE_NOSUPP}
+	}
+	methodData := k.methodData(clientKeys, etypes)
+
+	replyKey := clientKeys[etypes[0]]
+	preAuthenticated := false
+	if pa := messages.FindPAData(req.PAData, messages.PADataType_ENC_TIMESTAMP); pa != nil {
+		encTimestamp := messages.EncryptedData{}
+		err := encTimestamp.FromBytes(pa.PADataValue)
+		if err != nil {
+			return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_PREAUTH_FAILED, text: err.Error(), edata: methodData}
+		}
+		clientKey, ok := clientKeys[encTimestamp.EType]
+		if !ok {
+			return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_ETYPE_NOSUPP, edata: methodData}
+		}
+		plaintext, err := crypto.Decrypt(encTimestamp.EType, clientKey.key.KeyValue, messages.KeyUsage_AS_REQ_PA_ENC_TIMESTAMP, encTimestamp.Cipher)
+		if err != nil {
+			return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_PREAUTH_FAILED, edata: methodData}
+		}
+		timestamp := messages.PAEncTSEnc{}
+		err = timestamp.FromBytes(plaintext)
+		if err != nil {
+			return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_PREAUTH_FAILED, text: err.Error(), edata: methodData}
+		}
+		if timestamp.PATimestamp.Before(now.Add(-k.MaxClockSkew)) || timestamp.PATimestamp.After(now.Add(k.MaxClockSkew)) {
+			return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_SKEW}
+		}
+		replyKey = clientKey
+		preAuthenticated = true
+	} else if k.RequirePreAuth {
+		return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_PREAUTH_REQUIRED, edata: methodData}
+	}
+
+	flags := ccache.TicketFlag_Initial
+	if preAuthenticated {
+		flags |= ccache.TicketFlag_PreAuthent
+	}
+	ticket, encPart, kerr := k.issueTicket(body, body.CName, body.Realm, now, now.Add(k.TicketLifetime), flags, serviceKey, serviceKvno)
+	if kerr != nil {
+		return nil, kerr
+	}
+
+	encPart.ApplicationTag = messages.ApplicationTag_EncASRepPart
+	cipher, kerr := encryptPart(encPart, replyKey.key, messages.KeyUsage_AS_REP_ENCPART)
+	if kerr != nil {
+		return nil, kerr
+	}
+
+	reply := messages.KDCRep{
+		Pvno:    messages.PVNO,
+		MsgType: messages.MsgType_AS_REP,
+		PAData: []messages.PAData{{
+			PADataType:  messages.PADataType_ETYPE_INFO2,
+			PADataValue: messages.MarshalETypeInfo2([]messages.ETypeInfo2Entry{etypeInfo2(replyKey)}),
+		}},
+		CRealm:  k.Realm,
+		CName:   body.CName,
+		Ticket:  *ticket,
+		EncPart: messages.EncryptedData{EType: replyKey.key.KeyType, Kvno: replyKey.kvno, HasKvno: true, Cipher: cipher},
+	}
+	return marshalReply(&reply)
+}
+
+// tgsExchange serves a TGS-REQ, as described in RFC 4120 section 3.3.
+func (k *KDC) tgsExchange(req *messages.KDCReq) ([]byte, *kdcError) {
+	now := k.now()
+	body := &req.ReqBody
+
+	pa := messages.FindPAData(req.PAData, messages.PADataType_TGS_REQ)
+	if pa == nil {
+		return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_PADATA_TYPE_NOSUPP, text: "missing PA-TGS-REQ"}
+	}
+	apReq := messages.APReq{}
+	err := apReq.FromBytes(pa.PADataValue)
+	if err != nil {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_MSG_TYPE, text: err.Error()}
+	}
+
+	// The TGT
+	tgt := &apReq.Ticket
+	if !isKrbtgt(tgt.SName, k.Realm) {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_NOT_US}
+	}
+	krbtgtKey, _, _ := k.serviceKey(tgt.SName)
+	if tgt.EncPart.EType != krbtgtKey.KeyType {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_BADKEYVER}
+	}
+	plaintext, err := crypto.Decrypt(tgt.EncPart.EType, krbtgtKey.KeyValue, messages.KeyUsage_KDC_REP_TICKET, tgt.EncPart.Cipher)
+	if err != nil {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_BAD_INTEGRITY}
+	}
+	tgtPart := messages.EncTicketPart{}
+	err = tgtPart.FromBytes(plaintext)
+	if err != nil {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_BAD_INTEGRITY, text: err.Error()}
+	}
+	if tgtPart.EndTime.Before(now) {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_TKT_EXPIRED}
+	}
+
+	// The authenticator
+	sessionKey := tgtPart.Key
+	plaintext, err = crypto.Decrypt(apReq.Authenticator.EType, sessionKey.KeyValue, messages.KeyUsage_TGS_REQ_PA_AUTHENTICATOR, apReq.Authenticator.Cipher)
+	if err != nil {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_BAD_INTEGRITY}
+	}
+	authenticator := messages.Authenticator{}
+	err = authenticator.FromBytes(plaintext)
+	if err != nil {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_BAD_INTEGRITY, text: err.Error()}
+	}
+	if !authenticator.CName.Equal(tgtPart.CName) || !strings.EqualFold(authenticator.CRealm, tgtPart.CRealm) {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_BADMATCH}
+	}
+	if authenticator.CTime.Before(now.Add(-k.MaxClockSkew)) || authenticator.CTime.After(now.Add(k.MaxClockSkew)) {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_SKEW}
+	}
+	if authenticator.Cksum != nil {
+		err = crypto.VerifyChecksum(authenticator.Cksum.CksumType, sessionKey.KeyValue, messages.KeyUsage_TGS_REQ_PA_AUTHENTICATOR_CKSUM, body.RawBytes, authenticator.Cksum.Checksum)
+		if err == crypto.ErrIntegrity {
+			return nil, &kdcError{code: messages.ErrorCode_KRB_AP_ERR_MODIFIED}
+		} else if err != nil {
+			return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_SUMTYPE_NOSUPP, text: err.Error()}
+		}
+	}
+
+	serviceKey, serviceKvno, kerr := k.serviceKey(body.SName)
+	if kerr != nil {
+		return nil, kerr
+	}
+	flags := tgtPart.Flags &^ ccache.TicketFlag_Initial
+	ticket, encPart, kerr := k.issueTicket(body, tgtPart.CName, tgtPart.CRealm, tgtPart.AuthTime, tgtPart.EndTime, flags, serviceKey, serviceKvno)
+	if kerr != nil {
+		return nil, kerr
+	}
+
+	encPart.ApplicationTag = messages.ApplicationTag_EncTGSRepPart
+	replyKey, usage := sessionKey, uint32(messages.KeyUsage_TGS_REP_ENCPART_SESSION_KEY)
+	if authenticator.SubKey != nil {
+		replyKey, usage = *authenticator.SubKey, messages.KeyUsage_TGS_REP_ENCPART_SUB_KEY
+	}
+	cipher, kerr := encryptPart(encPart, replyKey, usage)
+	if kerr != nil {
+		return nil, kerr
+	}
+
+	reply := messages.KDCRep{
+		Pvno:    messages.PVNO,
+		MsgType: messages.MsgType_TGS_REP,
+		CRealm:  tgtPart.CRealm,
+		CName:   tgtPart.CName,
+		Ticket:  *ticket,
+		EncPart: messages.EncryptedData{EType: replyKey.KeyType, Cipher: cipher},
+	}
+	return marshalReply(&reply)
+}
+
+// issueTicket issues a ticket for the service of a request, with a new session key of the
+// first encryption type of the request issued by the KDC, and returns it with the
+// corresponding encrypted part of the reply.
+func (k *KDC) issueTicket(body *messages.KDCReqBody, cname messages.PrincipalName, crealm string, authTime, maxEndTime time.Time, flags uint32, serviceKey messages.EncryptionKey, serviceKvno uint32) (*messages.Ticket, *messages.EncKDCRepPart, *kdcError) {
+	now := k.now()
+
+	sessionEType := int32(0)
+	for _, etype := range body.EType {
+		if k.issues(etype) {
+			sessionEType = etype
+			break
+		}
+	}
+	if sessionEType == 0 {
+		return nil, nil, &kdcError{code: messages.ErrorCode_KDC_ERR_ETYPE_NOSUPP}
+	}
+	sessionKey, err := randomKey(sessionEType)
+	if err != nil {
+		return nil, nil, &kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()}
+	}
+
+	endTime := now.Add(k.TicketLifetime)
+	if maxEndTime.Before(endTime) {
+		endTime = maxEndTime
+	}
+	if !body.Till.IsZero() && body.Till.Before(endTime) {
+		endTime = body.Till
+	}
+	if body.KDCOptions&messages.KDCOption_Forwardable != 0 {
+		flags |= ccache.TicketFlag_Forwardable
+	}
+	renewTill := time.Time{}
+	if body.KDCOptions&messages.KDCOption_Renewable != 0 && !body.RTime.IsZero() {
+		flags |= ccache.TicketFlag_Renewable
+		renewTill = body.RTime
+	}
+
+	encTicketPart := messages.EncTicketPart{
+		Flags:     flags,
+		Key:       sessionKey,
+		CRealm:    crealm,
+		CName:     cname,
+		AuthTime:  authTime,
+		StartTime: now,
+		EndTime:   endTime,
+		RenewTill: renewTill,
+	}
+	if k.IssuePAC {
+		pac, err := acceptor.NewPAC(cname.String(), authTime, serviceKey, k.krbtgtKey)
+		if err != nil {
+			return nil, nil, &kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()}
+		}
+		ifRelevant := messages.MarshalAuthorizationData([]messages.AuthorizationDataEntry{{ADType: messages.ADType_WIN2K_PAC, ADData: pac}})
+		encTicketPart.AuthorizationData = []messages.AuthorizationDataEntry{{ADType: messages.ADType_IF_RELEVANT, ADData: ifRelevant}}
+	}
+	plaintext, err := encTicketPart.ToBytes()
+	if err != nil {
+		return nil, nil, &kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()}
+	}
+	cipher, err := crypto.Encrypt(serviceKey.KeyType, serviceKey.KeyValue, messages.KeyUsage_KDC_REP_TICKET, plaintext)
+	if err != nil {
+		return nil, nil, &kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()}
+	}
+
+	ticket := &messages.Ticket{
+		TktVno:  messages.PVNO,
+		Realm:   k.Realm,
+		SName:   body.SName,
+		EncPart: messages.EncryptedData{EType: serviceKey.KeyType, Kvno: serviceKvno, HasKvno: true, Cipher: cipher},
+	}
+	encPart := &messages.EncKDCRepPart{
+		Key:       sessionKey,
+		LastReq:   []messages.LastReqEntry{{LRType: 0, LRValue: authTime}},
+		Nonce:     body.Nonce,
+		Flags:     flags,
+		AuthTime:  authTime,
+		StartTime: now,
+		EndTime:   endTime,
+		RenewTill: renewTill,
+		SRealm:    k.Realm,
+		SName:     body.SName,
+	}
+	return ticket, encPart, nil
+}
+
+// salt returns the salt of the keys of a principal, the realm followed by its components.
+func (k *KDC) salt(name messages.PrincipalName) string {
+	return strings.ToUpper(k.Realm) + strings.Join(name.NameString, "")
+}
+
+// principalKeys returns the keys of the highest version number of a principal of the realm, by encryption type.
+func (k *KDC) principalKeys(name messages.PrincipalName) map[int32]principalKey {
+	keys := make(map[int32]principalKey)
+	if k.Keytab == nil {
+		return keys
+	}
+	for i := range k.Keytab.Entries {
+		entry := &k.Keytab.Entries[i]
+		if !strings.EqualFold(string(entry.Realm.Data), k.Realm) || len(entry.Components) != len(name.NameString) {
+			continue
+		}
+		match := true
+		for j, component := range entry.Components {
+			if string(component.Data) != name.NameString[j] {
+				match = false
+				break
+			}
+		}
+		etype := int32(entry.Key.Type)
+		if !match || !crypto.IsSupported(etype) {
+			continue
+		}
+		kvno := entry.KVNO()
+		if existing, ok := keys[etype]; ok && existing.kvno >= kvno {
+			continue
+		}
+		keys[etype] = principalKey{
+			key:  messages.EncryptionKey{KeyType: etype, KeyValue: entry.Key.Key.Data},
+			kvno: kvno,
+			salt: k.salt(name),
+		}
+	}
+	return keys
+}
+
+// serviceKey returns the key of the highest version number of a service, of the first
+// encryption type issued by the KDC that the service has a key for.
+func (k *KDC) serviceKey(name messages.PrincipalName) (messages.EncryptionKey, uint32, *kdcError) {
+	keys := k.principalKeys(name)
+	for _, etype := range k.ETypes {
+		if key, ok := keys[etype]; ok {
+			return key.key, key.kvno, nil
+		}
+	}
+	if isKrbtgt(name, k.Realm) {
+		return k.krbtgtKey, 1, nil
+	}
+	if isKadmin(name) {
+		return k.kadminKey, 1, nil
+	}
+	if len(keys) != 0 {
+		return messages.EncryptionKey{}, 0, &kdcError{code: messages.ErrorCode_KDC_ERR_ETYPE_NOSUPP}
+	}
+	return messages.EncryptionKey{}, 0, &kdcError{code: messages.ErrorCode_KDC_ERR_S_PRINCIPAL_UNKNOWN}
+}
+
+// methodData returns the METHOD-DATA sent with pre-authentication errors: a PA-ETYPE-INFO2
+// describing the keys of the client, followed by an empty PA-ENC-TIMESTAMP.
+func (k *KDC) methodData(clientKeys map[int32]principalKey, etypes []int32) []byte {
+	entries := make([]messages.ETypeInfo2Entry, 0, len(etypes))
+	for _, etype := range etypes {
+		entries = append(entries, etypeInfo2(clientKeys[etype]))
+	}
+	return messages.MarshalMethodData([]messages.PAData{
+		{PADataType: messages.PADataType_ETYPE_INFO2, PADataValue: messages.MarshalETypeInfo2(entries)},
+		{PADataType: messages.PADataType_ENC_TIMESTAMP, PADataValue: []byte{}},
+	})
+}
+
+// issues returns true if the KDC issues keys of an encryption type.
+func (k *KDC) issues(etype int32) bool {
+	for _, issued := range k.ETypes {
+		if issued == etype {
+			return crypto.IsSupported(etype)
+		}
+	}
+	return false
+}
+
+// now returns the current time of the KDC clock, truncated to the precision of KerberosTime.
+func (k *KDC) now() time.Time {
+	now := time.Now()
+	if k.Now != nil {
+		now = k.Now()
+	}
+	return now.UTC().Truncate(time.Second)
+}
+
+// marshalError builds the KRB-ERROR answering a request.
+func (k *KDC) marshalError(req *messages.KDCReq, kerr *kdcError) []byte {
+	krbError := messages.KRBError{
+		Pvno:      messages.PVNO,
+		MsgType:   messages.MsgType_KRB_ERROR,
+		STime:     k.now(),
+		ErrorCode: kerr.code,
+		Realm:     k.Realm,
+		SName:     messages.NewPrincipalName(messages.NameType_SRV_INST, "krbtgt", k.Realm),
+		EText:     kerr.text,
+		EData:     kerr.edata,
+	}
+	if req != nil {
+		krbError.CRealm = req.ReqBody.Realm
+		krbError.CName = req.ReqBody.CName
+		if len(req.ReqBody.SName.NameString) != 0 {
+			krbError.SName = req.ReqBody.SName
+		}
+	}
+	data, _ := krbError.ToBytes()
+	return data
+}
+
+// etypeInfo2 returns the ETYPE-INFO2 entry describing a key. RC4 keys are not salted.
+func etypeInfo2(key principalKey) messages.ETypeInfo2Entry {
+	if key.key.KeyType == crypto.ETypeRC4HMAC {
+		return messages.ETypeInfo2Entry{EType: key.key.KeyType}
+	}
+	return messages.ETypeInfo2Entry{EType: key.key.KeyType, Salt: key.salt, HasSalt: true}
+}
+
+// encryptPart encrypts the encrypted part of a KDC reply.
+func encryptPart(encPart *messages.EncKDCRepPart, key messages.EncryptionKey, usage uint32) ([]byte, *kdcError) {
+	plaintext, err := encPart.ToBytes()
+	if err != nil {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()}
+	}
+	cipher, err := crypto.Encrypt(key.KeyType, key.KeyValue, usage, plaintext)
+	if err != nil {
+		return nil, &kdcError{code: messages.ErrorCode_KDC_ERR_ETYPE_NOSUPP, text: err.Error()}
+	}
+	return cipher, nil
+}
+
+// marshalReply returns the DER encoding of a KDC reply.
+func marshalReply(reply *messages.KDCRep) ([]byte, *kdcError) {
+	data, err := reply.ToBytes()
+	if err != nil {
+		return nil, &kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()}
+	}
+	return data, nil
+}
+
+// randomKey generates a random key of an encryption type.
+func randomKey(etype int32) (messages.EncryptionKey, error) {
+	size, err := crypto.KeySize(etype)
+	if err != nil {
+		return messages.EncryptionKey{}, err
+	}
+	key := make([]byte, size)
+	_, err = rand.Read(key)
+	if err != nil {
+		return messages.EncryptionKey{}, fmt.Errorf("error generating key: %w", err)
+	}
+	return messages.EncryptionKey{KeyType: etype, KeyValue: key}, nil
+}
+
+// isKrbtgt returns true if a principal name is the ticket granting service of a realm.
+func isKrbtgt(name messages.PrincipalName, realm string) bool {
+	return len(name.NameString) == 2 && name.NameString[0] == "krbtgt" && strings.EqualFold(name.NameString[1], realm)
+}
+
+// isKadmin returns true if a principal name is the kpasswd service.
+func isKadmin(name messages.PrincipalName) bool {
+	return len(name.NameString) == 2 && name.NameString[0] == "kadmin" && name.NameString[1] == "changepw"
+}
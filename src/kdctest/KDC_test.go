@@ -0,0 +1,284 @@
+package kdctest
+
+import (
+	"bytes"
+	"keytab/acceptor"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"net"
+	"testing"
+	"time"
+)
+
+const testRealm = "TESTSEGMENT.LOCAL"
+
+var (
+	testNow        = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	testUserKey    = bytes.Repeat([]byte{0x11}, 32)
+	testServiceKey = bytes.Repeat([]byte{0x22}, 32)
+)
+
+// newTestKDC returns an in-memory KDC knowing user@TESTSEGMENT.LOCAL and HTTP/web01.testsegment.local.
+func newTestKDC(t *testing.T) *KDC {
+	kt, err := NewTestKeytab(testRealm,
+		TestKey{Principal: "user", Kvno: 1, EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, Key: bytes.Repeat([]byte{0xff}, 32)},
+		TestKey{Principal: "user", Kvno: 2, EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, Key: testUserKey},
+		TestKey{Principal: "HTTP/web01.testsegment.local", Kvno: 5, EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, Key: testServiceKey},
+	)
+	if err != nil {
+		t.Fatalf("Error creating keytab: %v", err)
+	}
+	kdc, err := NewKDC(testRealm, kt)
+	if err != nil {
+		t.Fatalf("Error creating KDC: %v", err)
+	}
+	kdc.Now = func() time.Time { return testNow }
+	return kdc
+}
+
+// newASReq returns an AS-REQ of user for a TGT, with an encrypted timestamp if key is not nil.
+func newASReq(t *testing.T, key []byte, timestamp time.Time) []byte {
+	req := messages.KDCReq{
+		Pvno:    messages.PVNO,
+		MsgType: messages.MsgType_AS_REQ,
+		ReqBody: messages.KDCReqBody{
+			KDCOptions: messages.KDCOption_Forwardable,
+			CName:      messages.NewPrincipalName(messages.NameType_PRINCIPAL, "user"),
+			Realm:      testRealm,
+			SName:      messages.NewPrincipalName(messages.NameType_SRV_INST, "krbtgt", testRealm),
+			Till:       testNow.Add(24 * time.Hour),
+			Nonce:      1234,
+			EType:      []int32{crypto.ETypeAES256CTSHMACSHA384192, crypto.ETypeAES256CTSHMACSHA196},
+		},
+	}
+	if key != nil {
+		encTimestamp := messages.PAEncTSEnc{PATimestamp: timestamp}
+		plaintext, _ := encTimestamp.ToBytes()
+		cipher, err := crypto.Encrypt(crypto.ETypeAES256CTSHMACSHA196, key, messages.KeyUsage_AS_REQ_PA_ENC_TIMESTAMP, plaintext)
+		if err != nil {
+			t.Fatalf("Error encrypting timestamp: %v", err)
+		}
+		encryptedData := messages.EncryptedData{EType: crypto.ETypeAES256CTSHMACSHA196, Cipher: cipher}
+		value, _ := encryptedData.ToBytes()
+		req.PAData = []messages.PAData{{PADataType: messages.PADataType_ENC_TIMESTAMP, PADataValue: value}}
+	}
+	data, err := req.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding AS-REQ: %v", err)
+	}
+	return data
+}
+
+// parseError parses a KRB-ERROR, failing the test if the reply is not one.
+func parseError(t *testing.T, reply []byte) *messages.KRBError {
+	krbError := &messages.KRBError{}
+	if err := krbError.FromBytes(reply); err != nil {
+		t.Fatalf("Expected a KRB-ERROR: %v", err)
+	}
+	return krbError
+}
+
+// decryptReply parses a KDC reply and decrypts its encrypted part.
+func decryptReply(t *testing.T, reply []byte, key []byte, usage uint32) (*messages.KDCRep, *messages.EncKDCRepPart) {
+	rep := &messages.KDCRep{}
+	if err := rep.FromBytes(reply); err != nil {
+		t.Fatalf("Expected a KDC-REP, got %v", parseError(t, reply))
+	}
+	plaintext, err := crypto.Decrypt(rep.EncPart.EType, key, usage, rep.EncPart.Cipher)
+	if err != nil {
+		t.Fatalf("Error decrypting reply: %v", err)
+	}
+	encPart := &messages.EncKDCRepPart{}
+	if err = encPart.FromBytes(plaintext); err != nil {
+		t.Fatalf("Error parsing encrypted part: %v", err)
+	}
+	return rep, encPart
+}
+
+func Test_KDC_ASExchange_PreAuthRequired(t *testing.T) {
+	kdc := newTestKDC(t)
+	krbError := parseError(t, kdc.HandleMessage(newASReq(t, nil, time.Time{})))
+	if krbError.ErrorCode != messages.ErrorCode_KDC_ERR_PREAUTH_REQUIRED {
+		t.Fatalf("Expected KDC_ERR_PREAUTH_REQUIRED, got %v", krbError)
+	}
+
+	methodData, err := messages.ParseMethodData(krbError.EData)
+	if err != nil {
+		t.Fatalf("Error parsing METHOD-DATA: %v", err)
+	}
+	pa := messages.FindPAData(methodData, messages.PADataType_ETYPE_INFO2)
+	if pa == nil || messages.FindPAData(methodData, messages.PADataType_ENC_TIMESTAMP) == nil {
+		t.Fatalf("Expected PA-ETYPE-INFO2 and PA-ENC-TIMESTAMP in the METHOD-DATA")
+	}
+	entries, err := messages.ParseETypeInfo2(pa.PADataValue)
+	if err != nil {
+		t.Fatalf("Error parsing ETYPE-INFO2: %v", err)
+	}
+	// The client prefers aes256-sha2, which the user has no key for
+	if len(entries) != 1 || entries[0].EType != crypto.ETypeAES256CTSHMACSHA196 || entries[0].Salt != testRealm+"user" {
+		t.Errorf("Unexpected ETYPE-INFO2 %+v", entries)
+	}
+
+	// Wrong key, and the old key of the user
+	for _, key := range [][]byte{testServiceKey, bytes.Repeat([]byte{0xff}, 32)} {
+		krbError = parseError(t, kdc.HandleMessage(newASReq(t, key, testNow)))
+		if krbError.ErrorCode != messages.ErrorCode_KDC_ERR_PREAUTH_FAILED {
+			t.Errorf("Expected KDC_ERR_PREAUTH_FAILED, got %v", krbError)
+		}
+	}
+
+	krbError = parseError(t, kdc.HandleMessage(newASReq(t, testUserKey, testNow.Add(time.Hour))))
+	if krbError.ErrorCode != messages.ErrorCode_KRB_AP_ERR_SKEW {
+		t.Errorf("Expected KRB_AP_ERR_SKEW, got %v", krbError)
+	}
+}
+
+func Test_KDC_ASExchange(t *testing.T) {
+	kdc := newTestKDC(t)
+	rep, encPart := decryptReply(t, kdc.HandleMessage(newASReq(t, testUserKey, testNow)), testUserKey, messages.KeyUsage_AS_REP_ENCPART)
+
+	if rep.EncPart.Kvno != 2 || encPart.Nonce != 1234 {
+		t.Errorf("Expected the reply to use kvno 2 and nonce 1234, got %d and %d", rep.EncPart.Kvno, encPart.Nonce)
+	}
+	if encPart.Key.KeyType != crypto.ETypeAES256CTSHMACSHA384192 {
+		t.Errorf("Expected an aes256-sha2 session key, got etype %d", encPart.Key.KeyType)
+	}
+	if !encPart.EndTime.Equal(testNow.Add(DefaultTicketLifetime)) {
+		t.Errorf("Expected the ticket lifetime to be capped, got %s", encPart.EndTime)
+	}
+	if encPart.Flags != 0x40600000 {
+		t.Errorf("Expected forwardable, initial and pre-authent flags, got 0x%08x", encPart.Flags)
+	}
+	if rep.Ticket.SName.String() != "krbtgt/"+testRealm {
+		t.Errorf("Unexpected ticket service %s", rep.Ticket.SName.String())
+	}
+}
+
+func Test_KDC_TGSExchange(t *testing.T) {
+	kdc := newTestKDC(t)
+	kdc.IssuePAC = true
+	asRep, asPart := decryptReply(t, kdc.HandleMessage(newASReq(t, testUserKey, testNow)), testUserKey, messages.KeyUsage_AS_REP_ENCPART)
+
+	body := messages.KDCReqBody{
+		Realm: testRealm,
+		SName: messages.NewPrincipalName(messages.NameType_SRV_INST, "HTTP", "web01.testsegment.local"),
+		Till:  testNow.Add(time.Hour),
+		Nonce: 5678,
+		EType: []int32{crypto.ETypeAES256CTSHMACSHA196},
+	}
+	checksum, err := crypto.Checksum(crypto.CksumTypeHMACSHA384192AES256, asPart.Key.KeyValue, messages.KeyUsage_TGS_REQ_PA_AUTHENTICATOR_CKSUM, body.Marshal())
+	if err != nil {
+		t.Fatalf("Error computing checksum: %v", err)
+	}
+	authenticator := messages.Authenticator{
+		AuthenticatorVno: messages.PVNO,
+		CRealm:           testRealm,
+		CName:            asRep.CName,
+		Cksum:            &messages.Checksum{CksumType: crypto.CksumTypeHMACSHA384192AES256, Checksum: checksum},
+		CTime:            testNow,
+	}
+	plaintext, _ := authenticator.ToBytes()
+	cipher, err := crypto.Encrypt(asPart.Key.KeyType, asPart.Key.KeyValue, messages.KeyUsage_TGS_REQ_PA_AUTHENTICATOR, plaintext)
+	if err != nil {
+		t.Fatalf("Error encrypting authenticator: %v", err)
+	}
+	apReq := messages.APReq{
+		Pvno:          messages.PVNO,
+		MsgType:       messages.MsgType_AP_REQ,
+		Ticket:        asRep.Ticket,
+		Authenticator: messages.EncryptedData{EType: asPart.Key.KeyType, Cipher: cipher},
+	}
+	apReqBytes, _ := apReq.ToBytes()
+	req := messages.KDCReq{
+		Pvno:    messages.PVNO,
+		MsgType: messages.MsgType_TGS_REQ,
+		PAData:  []messages.PAData{{PADataType: messages.PADataType_TGS_REQ, PADataValue: apReqBytes}},
+		ReqBody: body,
+	}
+	reqBytes, _ := req.ToBytes()
+
+	tgsRep, tgsPart := decryptReply(t, kdc.HandleMessage(reqBytes), asPart.Key.KeyValue, messages.KeyUsage_TGS_REP_ENCPART_SESSION_KEY)
+	if tgsPart.Nonce != 5678 || tgsRep.Ticket.EncPart.Kvno != 5 {
+		t.Errorf("Expected nonce 5678 and kvno 5, got %d and %d", tgsPart.Nonce, tgsRep.Ticket.EncPart.Kvno)
+	}
+	if tgsPart.Flags&0x00400000 != 0 {
+		t.Errorf("Service tickets must not have the initial flag")
+	}
+
+	// The service ticket is accepted by a service using the same keytab
+	initiator := &acceptor.Initiator{
+		Ticket:     tgsRep.Ticket,
+		SessionKey: tgsPart.Key,
+		Client:     tgsRep.CName,
+		Realm:      tgsRep.CRealm,
+		Now:        kdc.Now,
+	}
+	token, err := initiator.NegotiateToken()
+	if err != nil {
+		t.Fatalf("Error building token: %v", err)
+	}
+	a := acceptor.NewAcceptor(kdc.Keytab)
+	a.Now = kdc.Now
+	a.RequirePAC = true
+	identity, _, err := a.Accept(token)
+	if err != nil {
+		t.Fatalf("Error accepting service ticket: %v", err)
+	}
+	if identity.Principal != "user@"+testRealm || identity.PAC == nil {
+		t.Errorf("Unexpected identity %s", identity.Principal)
+	}
+
+	// A tampered request body fails the authenticator checksum
+	req.ReqBody.Nonce = 1
+	reqBytes, _ = req.ToBytes()
+	if krbError := parseError(t, kdc.HandleMessage(reqBytes)); krbError.ErrorCode != messages.ErrorCode_KRB_AP_ERR_MODIFIED {
+		t.Errorf("Expected KRB_AP_ERR_MODIFIED, got %v", krbError)
+	}
+}
+
+func Test_KDC_Start(t *testing.T) {
+	kdc := newTestKDC(t)
+	if err := kdc.Start(); err != nil {
+		t.Fatalf("Error starting KDC: %v", err)
+	}
+	defer kdc.Close()
+
+	request := newASReq(t, nil, time.Time{})
+	for _, network := range []string{"tcp", "udp"} {
+		conn, err := net.DialTimeout(network, kdc.Addr(), time.Second)
+		if err != nil {
+			t.Fatalf("Error connecting to KDC over %s: %v", network, err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		var reply []byte
+		if network == "tcp" {
+			_, err = conn.Write(FrameTCPMessage(request))
+			if err == nil {
+				reply, err = ReadTCPMessage(conn)
+			}
+		} else {
+			_, err = conn.Write(request)
+			if err == nil {
+				buffer := make([]byte, 65535)
+				var n int
+				n, err = conn.Read(buffer)
+				reply = buffer[:n]
+			}
+		}
+		conn.Close()
+		if err != nil {
+			t.Fatalf("Error exchanging with KDC over %s: %v", network, err)
+		}
+
+		krbError := parseError(t, reply)
+		if krbError.ErrorCode != messages.ErrorCode_KDC_ERR_PREAUTH_REQUIRED {
+			t.Errorf("Expected KDC_ERR_PREAUTH_REQUIRED over %s, got %v", network, krbError)
+		}
+	}
+
+	if err := kdc.Close(); err != nil {
+		t.Errorf("Error closing KDC: %v", err)
+	}
+}
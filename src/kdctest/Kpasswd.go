@@ -0,0 +1,215 @@
+package kdctest
+
+import (
+	"keytab/ccache"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"strings"
+)
+
+// kpasswdResult is the result code and string of a kpasswd reply.
+type kpasswdResult struct {
+	code uint16
+	text string
+}
+
+// HandleKpasswd serves a single kpasswd change-password or set-password request of RFC 3244,
+// without transport framing. On success the keys of the new password are added to the keytab
+// with the next key version number, as Keytab.Rekey does, keeping the previous ones. The
+// ticket must be an initial kadmin/changepw ticket; set-password requests are accepted for
+// any principal of the keytab, without access control.
+//
+// Parameters:
+//   - request ([]byte): The kpasswd request.
+//
+// Returns:
+//   - []byte: The kpasswd reply, carrying either a result or a KRB-ERROR.
+func (k *KDC) HandleKpasswd(request []byte) []byte {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	now := k.now()
+
+	req := messages.KpasswdMessage{}
+	err := req.FromBytes(request)
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_MSG_TYPE, text: err.Error()})
+	}
+	apReq := messages.APReq{}
+	err = apReq.FromBytes(req.AP)
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_MSG_TYPE, text: err.Error()})
+	}
+
+	// The kadmin/changepw ticket
+	ticket := &apReq.Ticket
+	if !isKadmin(ticket.SName) || !strings.EqualFold(ticket.Realm, k.Realm) {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_NOT_US})
+	}
+	serviceKey, _, _ := k.serviceKey(ticket.SName)
+	plaintext, err := crypto.Decrypt(ticket.EncPart.EType, serviceKey.KeyValue, messages.KeyUsage_KDC_REP_TICKET, ticket.EncPart.Cipher)
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_BAD_INTEGRITY})
+	}
+	ticketPart := messages.EncTicketPart{}
+	err = ticketPart.FromBytes(plaintext)
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_BAD_INTEGRITY, text: err.Error()})
+	}
+	if ticketPart.EndTime.Before(now) {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_TKT_EXPIRED})
+	}
+
+	// The authenticator
+	sessionKey := ticketPart.Key
+	plaintext, err = crypto.Decrypt(apReq.Authenticator.EType, sessionKey.KeyValue, messages.KeyUsage_AP_REQ_AUTHENTICATOR, apReq.Authenticator.Cipher)
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_BAD_INTEGRITY})
+	}
+	authenticator := messages.Authenticator{}
+	err = authenticator.FromBytes(plaintext)
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_BAD_INTEGRITY, text: err.Error()})
+	}
+	if !authenticator.CName.Equal(ticketPart.CName) || !strings.EqualFold(authenticator.CRealm, ticketPart.CRealm) {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_BADMATCH})
+	}
+	if authenticator.CTime.Before(now.Add(-k.MaxClockSkew)) || authenticator.CTime.After(now.Add(k.MaxClockSkew)) {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_AP_ERR_SKEW})
+	}
+	privKey := sessionKey
+	if authenticator.SubKey != nil {
+		privKey = *authenticator.SubKey
+	}
+
+	// The AP-REP, encrypted in the ticket session key
+	apRepPart := messages.EncAPRepPart{CTime: authenticator.CTime, Cusec: authenticator.Cusec}
+	plaintext, err = apRepPart.ToBytes()
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()})
+	}
+	cipher, err := crypto.Encrypt(sessionKey.KeyType, sessionKey.KeyValue, messages.KeyUsage_AP_REP_ENCPART, plaintext)
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()})
+	}
+	apRep := messages.APRep{Pvno: messages.PVNO, MsgType: messages.MsgType_AP_REP, EncPart: messages.EncryptedData{EType: sessionKey.KeyType, Cipher: cipher}}
+	apRepBytes, err := apRep.ToBytes()
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()})
+	}
+
+	result := k.changePassword(&req, &ticketPart, privKey)
+	encPrivPart := messages.EncKrbPrivPart{
+		UserData:  messages.MarshalKpasswdResult(result.code, result.text),
+		Timestamp: now,
+	}
+	plaintext, err = encPrivPart.ToBytes()
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()})
+	}
+	cipher, err = crypto.Encrypt(privKey.KeyType, privKey.KeyValue, messages.KeyUsage_KRB_PRIV_ENCPART, plaintext)
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()})
+	}
+	priv := messages.KRBPriv{Pvno: messages.PVNO, MsgType: messages.MsgType_KRB_PRIV, EncPart: messages.EncryptedData{EType: privKey.KeyType, Cipher: cipher}}
+	privBytes, err := priv.ToBytes()
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()})
+	}
+
+	reply := messages.KpasswdMessage{Version: messages.KpasswdVersion_ChangePassword, AP: apRepBytes, Data: privBytes}
+	data, err := reply.ToBytes()
+	if err != nil {
+		return k.kpasswdError(&kdcError{code: messages.ErrorCode_KRB_ERR_GENERIC, text: err.Error()})
+	}
+	return data
+}
+
+// changePassword decrypts the KRB-PRIV of an authenticated kpasswd request and changes the
+// password of its target in the keytab.
+func (k *KDC) changePassword(req *messages.KpasswdMessage, ticketPart *messages.EncTicketPart, privKey messages.EncryptionKey) kpasswdResult {
+	priv := messages.KRBPriv{}
+	err := priv.FromBytes(req.Data)
+	if err != nil {
+		return kpasswdResult{messages.KpasswdResult_MALFORMED, err.Error()}
+	}
+	plaintext, err := crypto.Decrypt(priv.EncPart.EType, privKey.KeyValue, messages.KeyUsage_KRB_PRIV_ENCPART, priv.EncPart.Cipher)
+	if err != nil {
+		return kpasswdResult{messages.KpasswdResult_AUTHERROR, "Failed decrypting request"}
+	}
+	encPrivPart := messages.EncKrbPrivPart{}
+	err = encPrivPart.FromBytes(plaintext)
+	if err != nil {
+		return kpasswdResult{messages.KpasswdResult_MALFORMED, err.Error()}
+	}
+
+	if ticketPart.Flags&ccache.TicketFlag_Initial == 0 {
+		return kpasswdResult{messages.KpasswdResult_INITIAL_FLAG_NEEDED, "Ticket must be derived from a password"}
+	}
+
+	target, realm := ticketPart.CName, ticketPart.CRealm
+	password := encPrivPart.UserData
+	switch req.Version {
+	case messages.KpasswdVersion_ChangePassword:
+	case messages.KpasswdVersion_SetPassword:
+		data := messages.ChangePasswdData{}
+		err = data.FromBytes(encPrivPart.UserData)
+		if err != nil {
+			return kpasswdResult{messages.KpasswdResult_MALFORMED, err.Error()}
+		}
+		password = data.NewPasswd
+		if data.TargName != nil {
+			target = *data.TargName
+		}
+		if len(data.TargRealm) != 0 {
+			realm = data.TargRealm
+		}
+	default:
+		return kpasswdResult{messages.KpasswdResult_BAD_VERSION, "Unsupported protocol version"}
+	}
+
+	if !strings.EqualFold(realm, k.Realm) {
+		return kpasswdResult{messages.KpasswdResult_HARDERROR, "Unknown realm " + realm}
+	}
+	if len(password) < k.MinPasswordLength {
+		return kpasswdResult{messages.KpasswdResult_SOFTERROR, "Password too short"}
+	}
+	entryRealm := ""
+	for i := 0; k.Keytab != nil && i < len(k.Keytab.Entries); i++ {
+		entry := &k.Keytab.Entries[i]
+		if strings.EqualFold(string(entry.Realm.Data), k.Realm) && entry.Principal() == target.String()+"@"+string(entry.Realm.Data) {
+			entryRealm = string(entry.Realm.Data)
+			break
+		}
+	}
+	if len(entryRealm) == 0 {
+		return kpasswdResult{messages.KpasswdResult_HARDERROR, "Unknown principal " + target.String()}
+	}
+
+	salts := make(map[keytab.EncryptionType]string)
+	for _, etype := range DefaultETypes {
+		salts[keytab.EncryptionType(etype)] = k.salt(target)
+	}
+	_, err = k.Keytab.Rekey(entryRealm, target.NameString, string(password), salts)
+	if err != nil {
+		return kpasswdResult{messages.KpasswdResult_HARDERROR, err.Error()}
+	}
+	return kpasswdResult{messages.KpasswdResult_SUCCESS, "Password changed"}
+}
+
+// kpasswdError builds a kpasswd reply carrying a KRB-ERROR, sent when the request could not be authenticated.
+func (k *KDC) kpasswdError(kerr *kdcError) []byte {
+	krbError := messages.KRBError{
+		Pvno:      messages.PVNO,
+		MsgType:   messages.MsgType_KRB_ERROR,
+		STime:     k.now(),
+		ErrorCode: kerr.code,
+		Realm:     k.Realm,
+		SName:     messages.NewPrincipalName(messages.NameType_SRV_INST, "kadmin", "changepw"),
+		EText:     kerr.text,
+	}
+	data, _ := krbError.ToBytes()
+	reply := messages.KpasswdMessage{Version: messages.KpasswdVersion_ChangePassword, Data: data}
+	message, _ := reply.ToBytes()
+	return message
+}
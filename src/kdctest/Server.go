@@ -0,0 +1,211 @@
+package kdctest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// maxMessageSize is the maximum size of a request accepted over TCP.
+const maxMessageSize = 1 << 20
+
+// server holds the loopback listeners of a started KDC.
+type server struct {
+	tcp     net.Listener
+	udp     net.PacketConn
+	handler func([]byte) []byte
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+	conns   map[net.Conn]struct{}
+}
+
+// Start serves the KDC on a random loopback port, and the kpasswd service on another one,
+// both over TCP and UDP.
+//
+// Returns:
+//   - error: An error if the KDC is already started or the ports could not be bound.
+func (k *KDC) Start() error {
+	if k.server != nil {
+		return fmt.Errorf("kdc is already started")
+	}
+	s, err := listen(k.HandleMessage)
+	if err != nil {
+		return err
+	}
+	kpasswd, err := listen(k.HandleKpasswd)
+	if err != nil {
+		s.close()
+		return err
+	}
+	k.server, k.kpasswdServer = s, kpasswd
+	return nil
+}
+
+// Addr returns the address the KDC is served on, as "127.0.0.1:port", or an empty string if it is not started.
+//
+// Returns:
+//   - string: The address of the KDC, valid for both TCP and UDP.
+func (k *KDC) Addr() string {
+	if k.server == nil {
+		return ""
+	}
+	return k.server.tcp.Addr().String()
+}
+
+// KpasswdAddr returns the address the kpasswd service is served on, as "127.0.0.1:port", or an empty string if it is not started.
+//
+// Returns:
+//   - string: The address of the kpasswd service, valid for both TCP and UDP.
+func (k *KDC) KpasswdAddr() string {
+	if k.kpasswdServer == nil {
+		return ""
+	}
+	return k.kpasswdServer.tcp.Addr().String()
+}
+
+// Close stops serving the KDC and the kpasswd service, and waits for the pending requests.
+//
+// Returns:
+//   - error: An error if closing the listeners failed.
+func (k *KDC) Close() error {
+	if k.server == nil {
+		return nil
+	}
+	err := k.server.close()
+	if kpasswdErr := k.kpasswdServer.close(); err == nil {
+		err = kpasswdErr
+	}
+	k.server, k.kpasswdServer = nil, nil
+	return err
+}
+
+// listen binds a TCP and a UDP socket on the same loopback port and serves them with a handler.
+func listen(handler func([]byte) []byte) (*server, error) {
+	var err error
+	for attempt := 0; attempt < 10; attempt++ {
+		var tcp net.Listener
+		tcp, err = net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		port := tcp.Addr().(*net.TCPAddr).Port
+		var udp net.PacketConn
+		udp, err = net.ListenPacket("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			tcp.Close()
+			continue
+		}
+
+		s := &server{tcp: tcp, udp: udp, handler: handler, conns: make(map[net.Conn]struct{})}
+		s.wg.Add(2)
+		go s.serveTCP()
+		go s.serveUDP()
+		return s, nil
+	}
+	return nil, fmt.Errorf("error binding a loopback port for both TCP and UDP: %w", err)
+}
+
+// serveTCP serves the TCP connections, where each message is preceded by its length on 4 bytes
+// in network order, as defined in RFC 4120 section 7.2.2.
+func (s *server) serveTCP() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conns[conn] = struct{}{}
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mutex.Lock()
+				delete(s.conns, conn)
+				s.mutex.Unlock()
+				conn.Close()
+			}()
+			for {
+				request, err := ReadTCPMessage(conn)
+				if err != nil {
+					return
+				}
+				if _, err = conn.Write(FrameTCPMessage(s.handler(request))); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// serveUDP serves the UDP datagrams, each holding a single message.
+func (s *server) serveUDP() {
+	defer s.wg.Done()
+	buffer := make([]byte, 65535)
+	for {
+		n, addr, err := s.udp.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		request := append([]byte{}, buffer[:n]...)
+		s.udp.WriteTo(s.handler(request), addr)
+	}
+}
+
+// close closes the listeners and the open connections, and waits for the serving goroutines to return.
+func (s *server) close() error {
+	err := s.tcp.Close()
+	if udpErr := s.udp.Close(); err == nil {
+		err = udpErr
+	}
+	s.mutex.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// FrameTCPMessage prefixes a Kerberos message with its length, as sent over TCP.
+//
+// Parameters:
+//   - message ([]byte): The DER encoded message.
+//
+// Returns:
+//   - []byte: The framed message.
+func FrameTCPMessage(message []byte) []byte {
+	framed := make([]byte, 4, 4+len(message))
+	binary.BigEndian.PutUint32(framed, uint32(len(message)))
+	return append(framed, message...)
+}
+
+// ReadTCPMessage reads a length prefixed Kerberos message from a TCP stream.
+//
+// Parameters:
+//   - r (io.Reader): The stream.
+//
+// Returns:
+//   - ([]byte, error): The DER encoded message and an error if the stream ended or the message is too large.
+func ReadTCPMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes is too large", length)
+	}
+	message := make([]byte, length)
+	_, err = io.ReadFull(r, message)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
@@ -0,0 +1,43 @@
+package kdctest
+
+import (
+	"keytab/keytab"
+	"keytab/messages"
+	"strings"
+)
+
+// TestKey is a key of a principal of the keytabs created by NewTestKeytab.
+//
+// Attributes:
+//   - Principal (string): The principal without its realm, such as "user" or "HTTP/web01.example.com".
+//   - Kvno (uint32): The key version number.
+//   - EncryptionType (keytab.EncryptionType): The encryption type of the key.
+//   - Key ([]byte): The key.
+type TestKey struct {
+	Principal      string
+	Kvno           uint32
+	EncryptionType keytab.EncryptionType
+	Key            []byte
+}
+
+// NewTestKeytab creates a keytab holding the keys of principals of a realm, to be used as the
+// principal database of a KDC or by the clients and services under test. The entries are
+// created with keytab.NewKeytabEntry, so that their kvno and size are set as in keytab files.
+//
+// Parameters:
+//   - realm (string): The realm of the principals.
+//   - keys (...TestKey): The keys of the principals.
+//
+// Returns:
+//   - (*keytab.Keytab, error): The keytab and an error if an entry can not be encoded.
+func NewTestKeytab(realm string, keys ...TestKey) (*keytab.Keytab, error) {
+	kt := &keytab.Keytab{FileFormatVersion: 0x502, Entries: make([]keytab.KeytabEntry, 0, len(keys))}
+	for _, key := range keys {
+		entry, err := keytab.NewKeytabEntry(realm, strings.Split(key.Principal, "/"), messages.NameType_PRINCIPAL, key.Kvno, key.EncryptionType, key.Key)
+		if err != nil {
+			return nil, err
+		}
+		kt.Entries = append(kt.Entries, entry)
+	}
+	return kt, nil
+}
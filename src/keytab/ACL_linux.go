@@ -0,0 +1,34 @@
+package keytab
+
+import (
+	"os"
+	"syscall"
+)
+
+// aclAttribute is the extended attribute holding the POSIX access ACL of a file on Linux.
+const aclAttribute = "system.posix_acl_access"
+
+// copyACL copies the POSIX access ACL of a file to another one. Files without ACL, and
+// file systems not supporting them, are ignored.
+//
+// Parameters:
+//   - source (*os.File): The file to copy the ACL of.
+//   - destination (*os.File): The file to copy the ACL to.
+//
+// Returns:
+//   - error: An error if the ACL could not be copied.
+func copyACL(source *os.File, destination *os.File) error {
+	size, err := syscall.Getxattr(source.Name(), aclAttribute, nil)
+	if err == syscall.ENODATA || err == syscall.ENOTSUP {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	acl := make([]byte, size)
+	size, err = syscall.Getxattr(source.Name(), aclAttribute, acl)
+	if err != nil {
+		return err
+	}
+	return syscall.Setxattr(destination.Name(), aclAttribute, acl[:size], 0)
+}
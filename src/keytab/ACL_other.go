@@ -0,0 +1,19 @@
+//go:build unix && !linux
+
+package keytab
+
+import (
+	"os"
+)
+
+// copyACL does nothing, POSIX ACLs are only copied on Linux.
+//
+// Parameters:
+//   - source (*os.File): The file to copy the ACL of.
+//   - destination (*os.File): The file to copy the ACL to.
+//
+// Returns:
+//   - error: Always nil.
+func copyACL(source *os.File, destination *os.File) error {
+	return nil
+}
@@ -0,0 +1,211 @@
+package keytab
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupTimeFormat is the layout of the UTC timestamps in the names of backups.
+const BackupTimeFormat = "20060102T150405"
+
+// BackupExtension is the extension of the names of backups.
+const BackupExtension = ".bak"
+
+// Backup is a timestamped copy of a keytab file, named as "host.keytab.20261017T120000.bak".
+// Backups made within the same second have a sequence number, as "host.keytab.20261017T120000.1.bak".
+//
+// Attributes:
+//   - Path (string): The path to the backup.
+//   - Time (time.Time): The time the backup was made, in UTC.
+//   - Sequence (int): The sequence number of the backup within its second.
+type Backup struct {
+	Path     string
+	Time     time.Time
+	Sequence int
+}
+
+// CreateBackup copies a keytab file to a new timestamped backup next to it, with the owner,
+// mode and ACLs of the file, and removes the oldest backups beyond the retention count.
+//
+// Parameters:
+//   - path (string): The path to the keytab file.
+//   - retention (int): The number of backups to keep, including the new one. 0 keeps all of them.
+//
+// Returns:
+//   - (string, error): The path to the backup and an error if the file could not be backed up.
+func CreateBackup(path string, retention int) (string, error) {
+	existing, info, err := lockFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer existing.Close()
+	return backupFile(existing, info, path, retention)
+}
+
+// ListBackups returns the backups of a keytab file.
+//
+// Parameters:
+//   - path (string): The path to the keytab file.
+//
+// Returns:
+//   - ([]Backup, error): The backups, from the newest to the oldest, and an error if the directory could not be read.
+func ListBackups(path string) ([]Backup, error) {
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(path) + "."
+	backups := make([]Backup, 0)
+	for _, file := range files {
+		name := file.Name()
+		if !file.Type().IsRegular() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, BackupExtension) || len(name) < len(prefix)+len(BackupExtension) {
+			continue
+		}
+		stamp, sequence, hasSequence := strings.Cut(name[len(prefix):len(name)-len(BackupExtension)], ".")
+		backup := Backup{Path: filepath.Join(filepath.Dir(path), name)}
+		backup.Time, err = time.Parse(BackupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		if hasSequence {
+			backup.Sequence, err = strconv.Atoi(sequence)
+			if err != nil || backup.Sequence <= 0 {
+				continue
+			}
+		}
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.After(backups[j].Time)
+		}
+		return backups[i].Sequence > backups[j].Sequence
+	})
+	return backups, nil
+}
+
+// RestoreBackup replaces a keytab file with one of its backups, atomically and under lock as
+// SaveToFileWithOptions does. The backup is copied as is, after checking that it is a valid keytab.
+//
+// Parameters:
+//   - path (string): The path to the keytab file.
+//   - backup (string): The path to the backup.
+//   - options (SaveOptions): The options of the save, whose Backups can be set to back up the replaced file.
+//
+// Returns:
+//   - error: An error if the backup could not be restored.
+func RestoreBackup(path string, backup string, options SaveOptions) error {
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return err
+	}
+	defer clear(data)
+	kt := &Keytab{}
+	defer kt.Close()
+	if err = kt.FromBytes(data); err != nil {
+		return fmt.Errorf("invalid backup %s: %v", backup, err)
+	}
+	return writeFile(path, data, options)
+}
+
+// Diff compares the entries of the keytab with the ones of another keytab.
+//
+// Parameters:
+//   - other (*Keytab): The other keytab.
+//
+// Returns:
+//   - ([]KeytabEntry, []KeytabEntry): The entries only in the keytab, and the entries only in the other keytab.
+func (k *Keytab) Diff(other *Keytab) ([]KeytabEntry, []KeytabEntry) {
+	matched := make([]bool, len(other.Entries))
+	removed := make([]KeytabEntry, 0)
+	for _, entry := range k.Entries {
+		found := false
+		for i, otherEntry := range other.Entries {
+			if !matched[i] && entry.Equal(otherEntry) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, entry)
+		}
+	}
+
+	added := make([]KeytabEntry, 0)
+	for i, otherEntry := range other.Entries {
+		if !matched[i] {
+			added = append(added, otherEntry)
+		}
+	}
+	return removed, added
+}
+
+// backupFile copies an open keytab file to a new backup and removes the oldest backups beyond the retention count.
+func backupFile(existing *os.File, info os.FileInfo, path string, retention int) (string, error) {
+	data, err := io.ReadAll(io.NewSectionReader(existing, 0, info.Size()))
+	if err != nil {
+		return "", err
+	}
+	defer clear(data)
+
+	// Backups of the same second are numbered after the existing ones, even if older ones were removed
+	now := time.Now().UTC().Truncate(time.Second)
+	stamp := now.Format(BackupTimeFormat)
+	backups, err := ListBackups(path)
+	if err != nil {
+		return "", err
+	}
+	sequence := 0
+	if len(backups) != 0 && backups[0].Time.Equal(now) {
+		sequence = backups[0].Sequence + 1
+	}
+
+	var backup *os.File
+	for ; backup == nil; sequence++ {
+		name := path + "." + stamp
+		if sequence != 0 {
+			name += "." + strconv.Itoa(sequence)
+		}
+		backup, err = os.OpenFile(name+BackupExtension, os.O_WRONLY|os.O_CREATE|os.O_EXCL, DefaultFileMode)
+		if err != nil && !os.IsExist(err) {
+			return "", err
+		}
+	}
+
+	_, err = backup.Write(data)
+	if err == nil {
+		err = backup.Sync()
+	}
+	if err == nil {
+		err = copyAttributes(existing, info, backup)
+	}
+	if closeErr := backup.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(backup.Name())
+		return "", err
+	}
+
+	if retention > 0 {
+		backups, err = ListBackups(path)
+		if err != nil {
+			return backup.Name(), err
+		}
+		for _, old := range backups[min(retention, len(backups)):] {
+			if err = os.Remove(old.Path); err != nil {
+				return backup.Name(), err
+			}
+		}
+	}
+	return backup.Name(), nil
+}
@@ -0,0 +1,93 @@
+package keytab
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Keytab_Backup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "host.keytab")
+	kt := newTestKeytab()
+	for _, name := range []string{"svc_web", "svc_sql", "svc_dns", "svc_ftp"} {
+		kt.Entries = append(kt.Entries, newTestEntry(name, EncryptionType_RC4_HMAC, 1, 16))
+		if err := kt.SaveToFileWithOptions(path, SaveOptions{Backups: 2}); err != nil {
+			t.Fatalf("Error saving keytab: %v", err)
+		}
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("Expected 2 backups to be kept, got %d (%v)", len(backups), err)
+	}
+	if !strings.HasPrefix(filepath.Base(backups[1].Path), "host.keytab."+backups[1].Time.Format(BackupTimeFormat)) {
+		t.Errorf("Unexpected backup name %s", backups[1].Path)
+	}
+	oldest, err := LoadKeytabFromFile(backups[1].Path)
+	if err != nil || len(oldest.Entries) != 2 {
+		t.Fatalf("Expected the oldest backup kept to hold 2 entries (%v)", err)
+	}
+
+	current, err := LoadKeytabFromFile(path)
+	if err != nil {
+		t.Fatalf("Error loading keytab: %v", err)
+	}
+	removed, added := current.Diff(oldest)
+	if len(removed) != 2 || removed[0].Principal() != "svc_dns@TESTSEGMENT.LOCAL" || len(added) != 0 {
+		t.Errorf("Expected svc_dns and svc_ftp to be removed by the backup, got %d removed and %d added", len(removed), len(added))
+	}
+
+	if err = RestoreBackup(path, backups[1].Path, SaveOptions{Backups: 2}); err != nil {
+		t.Fatalf("Error restoring backup: %v", err)
+	}
+	restored, err := LoadKeytabFromFile(path)
+	if err != nil || !restored.Equal(oldest) {
+		t.Errorf("Expected the keytab to be restored from the backup (%v)", err)
+	}
+	if backups, _ = ListBackups(path); len(backups) != 2 {
+		t.Errorf("Expected the restored file to be backed up, got %d backups", len(backups))
+	}
+	if latest, err := LoadKeytabFromFile(backups[0].Path); err != nil || !latest.Equal(current) {
+		t.Errorf("Expected the newest backup to hold the replaced keytab (%v)", err)
+	}
+}
+
+func Test_RestoreBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "host.keytab")
+	kt := newTestKeytab(newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16))
+	if err := kt.SaveToFile(path); err != nil {
+		t.Fatalf("Error saving keytab: %v", err)
+	}
+	saved, err := LoadKeytabFromFile(path)
+	if err != nil {
+		t.Fatalf("Error loading keytab: %v", err)
+	}
+	backup, err := CreateBackup(path, 1)
+	if err != nil {
+		t.Fatalf("Error backing up keytab: %v", err)
+	}
+
+	// A truncated keytab file
+	if err = os.WriteFile(path, []byte{0x05, 0x02, 0x00, 0x00, 0x00, 0x40}, 0600); err != nil {
+		t.Fatalf("Error corrupting keytab: %v", err)
+	}
+	if _, err = LoadKeytabFromFile(path); err == nil {
+		t.Fatalf("Expected an error loading the corrupt keytab")
+	}
+	if err = RestoreBackup(path, backup, SaveOptions{}); err != nil {
+		t.Fatalf("Error restoring backup over a corrupt keytab: %v", err)
+	}
+	if restored, err := LoadKeytabFromFile(path); err != nil || !restored.Equal(saved) {
+		t.Errorf("Expected the corrupt keytab to be restored from the backup (%v)", err)
+	}
+
+	// A deleted keytab file
+	os.Remove(path)
+	if err = RestoreBackup(path, backup, SaveOptions{}); err != nil {
+		t.Fatalf("Error restoring backup over a deleted keytab: %v", err)
+	}
+	if restored, err := LoadKeytabFromFile(path); err != nil || !restored.Equal(saved) {
+		t.Errorf("Expected the deleted keytab to be restored from the backup (%v)", err)
+	}
+}
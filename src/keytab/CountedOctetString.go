@@ -5,7 +5,9 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"keytab/render"
 	"keytab/utils"
+	"os"
 	"strings"
 )
 
@@ -32,14 +34,23 @@ type CountedOctetString struct {
 // Returns:
 //   - error: An error if the parsing fails.
 func (c *CountedOctetString) FromBytes(data []byte) error {
-	c.RawBytes = data
-
+	if len(data) < 2 {
+		return fmt.Errorf("truncated length")
+	}
 	c.Length = binary.BigEndian.Uint16(data[0:2])
-	data = data[2:]
+	c.RawBytesSize = 2 + uint32(c.Length)
+	if uint64(len(data)) < uint64(c.RawBytesSize) {
+		return fmt.Errorf("length %d exceeds the %d remaining bytes", c.Length, len(data)-2)
+	}
 
-	c.Data = data[:c.Length]
+	// The data is copied so that it does not alias the parsed buffer, and keys can be wiped
+	c.Data = make([]byte, c.Length)
+	copy(c.Data, data[2:c.RawBytesSize])
 
-	c.RawBytesSize = 2 + uint32(c.Length)
+	c.RawBytes = nil
+	if KeepRawBytes {
+		c.RawBytes = data[:c.RawBytesSize]
+	}
 
 	return nil
 }
@@ -71,14 +82,24 @@ func (c *CountedOctetString) ToBytes() ([]byte, error) {
 //   - indent (int): The indentation level.
 //   - id (int): The ID of the CountedOctetString.
 func (c *CountedOctetString) Describe(indent, id int) {
+	c.DescribeTo(render.NewRenderer(os.Stdout, false), indent, id)
+}
+
+// DescribeTo writes the description of the CountedOctetString to a Renderer, see Describe.
+//
+// Parameters:
+//   - r (*render.Renderer): The renderer the description is written to.
+//   - indent (int): The indentation level.
+//   - id (int): The ID of the CountedOctetString.
+func (c *CountedOctetString) DescribeTo(r *render.Renderer, indent, id int) {
 	indentPrompt := strings.Repeat(" │ ", indent)
-	fmt.Printf("%s<CountedOctetString #%d>\n", indentPrompt, id)
-	fmt.Printf("%s │ \x1b[93mLength\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, c.Length, c.Length)
-	fmt.Printf("%s │ \x1b[93mData\x1b[0m:\n", indentPrompt)
-	fmt.Printf("%s │  │ \x1b[93mHex\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, hex.EncodeToString(c.Data))
-	fmt.Printf("%s │  │ \x1b[93mRaw\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, utils.BytesToPrintableString(c.Data))
-	fmt.Printf("%s │  └─\n", indentPrompt)
-	fmt.Printf("%s └─\n", indentPrompt)
+	r.Printf("%s<CountedOctetString #%d>\n", indentPrompt, id)
+	r.Printf("%s │ \x1b[93mLength\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, c.Length, c.Length)
+	r.Printf("%s │ \x1b[93mData\x1b[0m:\n", indentPrompt)
+	r.Printf("%s │  │ \x1b[93mHex\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, hex.EncodeToString(c.Data))
+	r.Printf("%s │  │ \x1b[93mRaw\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, utils.BytesToPrintableString(c.Data))
+	r.Printf("%s │  └─\n", indentPrompt)
+	r.Printf("%s └─\n", indentPrompt)
 }
 
 // Equal checks if two CountedOctetString are equal.
@@ -26,3 +26,12 @@ func Test_CountedOctetString_FromBytes(t *testing.T) {
 		t.Errorf("Data mismatch: expected %v, got %v", c1.Data, c2.Data)
 	}
 }
+
+func Test_CountedOctetString_FromBytesTruncated(t *testing.T) {
+	c := CountedOctetString{}
+	for _, data := range [][]byte{{}, {0x00}, {0x00, 0x04, 0x01, 0x02, 0x03}, {0xff, 0xff}} {
+		if err := c.FromBytes(data); err == nil {
+			t.Errorf("Expected an error parsing %x", data)
+		}
+	}
+}
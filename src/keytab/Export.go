@@ -0,0 +1,143 @@
+package keytab
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExportFormat is an output format of Export.
+type ExportFormat string
+
+// Export formats.
+const (
+	ExportFormat_JSON      ExportFormat = "json"
+	ExportFormat_TXT       ExportFormat = "txt"
+	ExportFormat_CSV       ExportFormat = "csv"
+	ExportFormat_Impacket  ExportFormat = "impacket"
+	ExportFormat_Rubeus    ExportFormat = "rubeus"
+	ExportFormat_Wireshark ExportFormat = "wireshark"
+	ExportFormat_Generic   ExportFormat = "generic"
+)
+
+// ExportFormats is the list of the export formats.
+var ExportFormats = []ExportFormat{
+	ExportFormat_JSON, ExportFormat_TXT, ExportFormat_CSV,
+	ExportFormat_Impacket, ExportFormat_Rubeus, ExportFormat_Wireshark, ExportFormat_Generic,
+}
+
+// ParseExportFormat returns the ExportFormat of a name.
+//
+// Parameters:
+//   - name (string): The name of the format, as "json", "txt", "csv", "impacket", "rubeus", "wireshark" or "generic".
+//
+// Returns:
+//   - (ExportFormat, error): The ExportFormat and an error if the name is unknown.
+func ParseExportFormat(name string) (ExportFormat, error) {
+	for _, format := range ExportFormats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q, expected json, txt, csv, impacket, rubeus, wireshark or generic", name)
+}
+
+// HoldsKeys returns true if the format can only be written with the keys in clear.
+//
+// Returns:
+//   - bool: True for the impacket, rubeus, wireshark and generic formats.
+func (f ExportFormat) HoldsKeys() bool {
+	return f == ExportFormat_Impacket || f == ExportFormat_Rubeus || f == ExportFormat_Wireshark || f == ExportFormat_Generic
+}
+
+// impacketArguments returns the arguments of the impacket scripts for a key: -aesKey for AES keys
+// and -hashes with an empty LM hash for RC4 keys.
+var impacketArguments = map[EncryptionType]string{
+	EncryptionType_AES128_CTS_HMAC_SHA1_96: "-aesKey ",
+	EncryptionType_AES256_CTS_HMAC_SHA1_96: "-aesKey ",
+	EncryptionType_RC4_HMAC:                "-hashes :",
+}
+
+// rubeusArguments returns the arguments of Rubeus for a key.
+var rubeusArguments = map[EncryptionType]string{
+	EncryptionType_DES_CBC_MD5:             "/des:",
+	EncryptionType_AES128_CTS_HMAC_SHA1_96: "/aes128:",
+	EncryptionType_AES256_CTS_HMAC_SHA1_96: "/aes256:",
+	EncryptionType_RC4_HMAC:                "/rc4:",
+}
+
+// Subset returns a keytab holding copies of the entries matching any of the filters, in the order
+// of the keytab, such as the keys of the principals of a capture to give to Wireshark.
+//
+// Parameters:
+//   - filters ([]Filter): The filters, or none to copy all the entries.
+//
+// Returns:
+//   - *Keytab: The new keytab.
+func (k *Keytab) Subset(filters []Filter) *Keytab {
+	matched := make(map[*KeytabEntry]bool)
+	for _, filter := range filters {
+		for _, entry := range k.Find(filter) {
+			matched[entry] = true
+		}
+	}
+
+	subset := &Keytab{FileFormatVersion: k.FileFormatVersion, IgnoreRealmCase: k.IgnoreRealmCase}
+	for i := range k.Entries {
+		if len(filters) != 0 && !matched[&k.Entries[i]] {
+			continue
+		}
+		entry := k.Entries[i]
+		entry.Key.Key.Data = bytes.Clone(entry.Key.Key.Data)
+		entry.RawBytes = nil
+		subset.Entries = append(subset.Entries, entry)
+	}
+	return subset
+}
+
+// exportToolLines writes a line per entry in the format of a tool: the impacket target and arguments
+// ("REALM/name -aesKey <hex>"), the Rubeus arguments ("/user:name /domain:REALM /aes256:<hex>") or
+// the generic "principal:enctype:hex". Entries whose encryption type the tool does not support are skipped.
+//
+// Parameters:
+//   - writer (io.Writer): The writer.
+//   - format (ExportFormat): The format, ExportFormat_Impacket, ExportFormat_Rubeus or ExportFormat_Generic.
+//
+// Returns:
+//   - error: An error if the writing failed.
+func (k *Keytab) exportToolLines(writer io.Writer, format ExportFormat) error {
+	for i := range k.Entries {
+		entry := &k.Entries[i]
+		components := make([]string, 0, len(entry.Components))
+		for _, component := range entry.Components {
+			components = append(components, string(component.Data))
+		}
+		name, realm := strings.Join(components, "/"), string(entry.Realm.Data)
+		key := hex.EncodeToString(entry.Key.Key.Data)
+
+		var line string
+		switch format {
+		case ExportFormat_Impacket:
+			if argument, supported := impacketArguments[entry.Key.Type]; supported {
+				line = fmt.Sprintf("%s/%s %s%s", realm, name, argument, key)
+			}
+		case ExportFormat_Rubeus:
+			if argument, supported := rubeusArguments[entry.Key.Type]; supported {
+				line = fmt.Sprintf("/user:%s /domain:%s %s%s", name, realm, argument, key)
+			}
+		case ExportFormat_Generic:
+			line = fmt.Sprintf("%s:%s:%s", entry.Principal(), strings.ToLower(entry.Key.Type.String()), key)
+		default:
+			return fmt.Errorf("%s is not a line format", format)
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package keytab
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_Keytab_ExportTo(t *testing.T) {
+	kt := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 2, 32),
+		newTestEntry("svc_web", EncryptionType_RC4_HMAC, 2, 16),
+		newTestEntry("svc_db", EncryptionType_AES128_CTS_HMAC_SHA1_96, 1, 16),
+		newTestEntry("svc_db", EncryptionType_DES_CBC_CRC, 1, 8),
+	)
+	aes256, rc4, aes128 := strings.Repeat("00", 32), strings.Repeat("00", 16), strings.Repeat("00", 16)
+
+	expected := map[ExportFormat]string{
+		ExportFormat_Impacket: "TESTSEGMENT.LOCAL/svc_web -aesKey " + aes256 + "\n" +
+			"TESTSEGMENT.LOCAL/svc_web -hashes :" + rc4 + "\n" +
+			"TESTSEGMENT.LOCAL/svc_db -aesKey " + aes128 + "\n",
+		ExportFormat_Rubeus: "/user:svc_web /domain:TESTSEGMENT.LOCAL /aes256:" + aes256 + "\n" +
+			"/user:svc_web /domain:TESTSEGMENT.LOCAL /rc4:" + rc4 + "\n" +
+			"/user:svc_db /domain:TESTSEGMENT.LOCAL /aes128:" + aes128 + "\n",
+		ExportFormat_Generic: "svc_web@TESTSEGMENT.LOCAL:aes256-cts-hmac-sha1-96:" + aes256 + "\n" +
+			"svc_web@TESTSEGMENT.LOCAL:rc4-hmac:" + rc4 + "\n" +
+			"svc_db@TESTSEGMENT.LOCAL:aes128-cts-hmac-sha1-96:" + aes128 + "\n" +
+			"svc_db@TESTSEGMENT.LOCAL:des-cbc-crc:" + strings.Repeat("00", 8) + "\n",
+	}
+	for format, lines := range expected {
+		buffer := &bytes.Buffer{}
+		if err := kt.ExportTo(buffer, format, false); err == nil {
+			t.Errorf("Expected an error exporting the %s format without showKeys", format)
+		}
+		if err := kt.ExportTo(buffer, format, true); err != nil || buffer.String() != lines {
+			t.Errorf("Unexpected %s export (%v):\n%s", format, err, buffer.String())
+		}
+	}
+
+	kt.Entries[2].Key.Key.Data[0] = 0x01
+	subset := kt.Subset([]Filter{{Principal: "svc_db", EncryptionType: EncryptionType_AES128_CTS_HMAC_SHA1_96}, {Principal: "svc_web@TESTSEGMENT.LOCAL", Kvno: 2}})
+	if len(subset.Entries) != 3 || subset.Entries[2].Principal() != "svc_db@TESTSEGMENT.LOCAL" {
+		t.Fatalf("Expected 3 entries in the subset, got %d", len(subset.Entries))
+	}
+	subset.Close()
+	if kt.Entries[2].Key.Key.Data[0] != 0x01 {
+		t.Errorf("Expected closing the subset to leave the keys of the keytab")
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := kt.ExportTo(buffer, ExportFormat_Wireshark, false); err == nil || buffer.Len() != 0 {
+		t.Errorf("Expected the Wireshark keytab to be refused without showKeys, got %d bytes", buffer.Len())
+	}
+	if err := kt.Subset([]Filter{{Principal: "svc_db"}}).ExportTo(buffer, ExportFormat_Wireshark, true); err != nil {
+		t.Fatalf("Error exporting keytab for Wireshark: %v", err)
+	}
+	parsed := &Keytab{}
+	if err := parsed.FromBytes(buffer.Bytes()); err != nil || len(parsed.Entries) != 2 {
+		t.Errorf("Expected a keytab of 2 entries for Wireshark, got %d (%v)", len(parsed.Entries), err)
+	}
+}
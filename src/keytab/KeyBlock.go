@@ -1,8 +1,13 @@
 package keytab
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"keytab/render"
+	"os"
 	"strings"
 )
 
@@ -21,6 +26,19 @@ type KeyBlock struct {
 	RawBytesSize uint32
 }
 
+// NewKeyBlock creates a KeyBlock holding a key, without copying it, such as a session key
+// to be shown with KeyString.
+//
+// Parameters:
+//   - etype (EncryptionType): The encryption type of the key.
+//   - key ([]byte): The key.
+//
+// Returns:
+//   - *KeyBlock: The key block.
+func NewKeyBlock(etype EncryptionType, key []byte) *KeyBlock {
+	return &KeyBlock{Type: etype, Key: CountedOctetString{Length: uint16(len(key)), Data: key}}
+}
+
 // FromBytes parses a byte array into a KeyBlock.
 //
 // Parameters:
@@ -29,10 +47,15 @@ type KeyBlock struct {
 // Returns:
 //   - error: An error if the parsing failed.
 func (k *KeyBlock) FromBytes(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("truncated encryption type")
+	}
 	k.Type = EncryptionType(binary.BigEndian.Uint16(data[0:2]))
 	k.RawBytesSize = 2
 
-	k.Key.FromBytes(data[2:])
+	if err := k.Key.FromBytes(data[2:]); err != nil {
+		return err
+	}
 	k.RawBytesSize += k.Key.RawBytesSize
 
 	return nil
@@ -58,17 +81,96 @@ func (k *KeyBlock) ToBytes() ([]byte, error) {
 	return data, nil
 }
 
+// FingerprintPepper is a secret of an organization mixed in the fingerprints of keys when set,
+// so that fingerprints shared in tickets or chat cannot be matched against keys from elsewhere.
+var FingerprintPepper []byte
+
+// Fingerprint returns a short fingerprint identifying the key without revealing it, with the
+// FingerprintPepper if set. See FingerprintWithPepper.
+//
+// Returns:
+//   - string: The fingerprint, in hexadecimal.
+func (k *KeyBlock) Fingerprint() string {
+	return k.FingerprintWithPepper(FingerprintPepper)
+}
+
+// FingerprintWithPepper returns a short fingerprint identifying the key without revealing it:
+// the first 8 bytes of the SHA-256 of the encryption type, on 2 bytes, followed by the key,
+// or of their HMAC-SHA256 keyed with the pepper if not empty.
+//
+// Parameters:
+//   - pepper ([]byte): The pepper, or nil for a plain SHA-256.
+//
+// Returns:
+//   - string: The fingerprint, in hexadecimal.
+func (k *KeyBlock) FingerprintWithPepper(pepper []byte) string {
+	buffer := make([]byte, 2, 2+len(k.Key.Data))
+	binary.BigEndian.PutUint16(buffer, uint16(k.Type))
+	buffer = append(buffer, k.Key.Data...)
+	defer clear(buffer)
+
+	var digest []byte
+	if len(pepper) == 0 {
+		sum := sha256.Sum256(buffer)
+		digest = sum[:]
+	} else {
+		mac := hmac.New(sha256.New, pepper)
+		mac.Write(buffer)
+		digest = mac.Sum(nil)
+	}
+	return hex.EncodeToString(digest[:8])
+}
+
+// Masked returns the representation of the key shown instead of it in human-readable outputs.
+//
+// Returns:
+//   - string: The length and the fingerprint of the key.
+func (k *KeyBlock) Masked() string {
+	return fmt.Sprintf("hidden, %d bytes, fingerprint %s", len(k.Key.Data), k.Fingerprint())
+}
+
+// KeyString returns the key in hexadecimal if keys are shown, or its masked representation otherwise.
+//
+// Parameters:
+//   - showKeys (bool): Whether the key is shown.
+//
+// Returns:
+//   - string: The key or its masked representation.
+func (k *KeyBlock) KeyString(showKeys bool) string {
+	if showKeys {
+		return hex.EncodeToString(k.Key.Data)
+	}
+	return k.Masked()
+}
+
 // Describe prints the KeyBlock to the console.
 //
 // Parameters:
 //   - indent (int): The indentation level.
 func (k *KeyBlock) Describe(indent int) {
+	k.DescribeTo(render.NewRenderer(os.Stdout, false), indent)
+}
+
+// DescribeTo writes the description of the KeyBlock to a Renderer, see Describe.
+//
+// Parameters:
+//   - r (*render.Renderer): The renderer the description is written to.
+//   - indent (int): The indentation level.
+func (k *KeyBlock) DescribeTo(r *render.Renderer, indent int) {
 	indentPrompt := strings.Repeat(" │ ", indent)
-	fmt.Printf("%s<KeyBlock>\n", indentPrompt)
-	fmt.Printf("%s │ \x1b[93mType\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%s\x1b[0m) (\x1b[94m%d\x1b[0m)\n", indentPrompt, uint16(k.Type), k.Type.String(), uint16(k.Type))
-	fmt.Printf("%s │ \x1b[93mKey\x1b[0m  :\n", indentPrompt)
-	k.Key.Describe(indent+2, 0)
-	fmt.Printf("%s └─\n", indentPrompt)
+	r.Printf("%s<KeyBlock>\n", indentPrompt)
+	r.Printf("%s │ \x1b[93mType\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%s\x1b[0m) (\x1b[94m%d\x1b[0m)\n", indentPrompt, uint16(k.Type), k.Type.String(), uint16(k.Type))
+	r.Printf("%s │ \x1b[93mKey\x1b[0m  :\n", indentPrompt)
+	if r.ShowKeys {
+		k.Key.DescribeTo(r, indent+2, 0)
+	} else {
+		keyPrompt := strings.Repeat(" │ ", indent+2)
+		r.Printf("%s<CountedOctetString #0>\n", keyPrompt)
+		r.Printf("%s │ \x1b[93mLength\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", keyPrompt, k.Key.Length, k.Key.Length)
+		r.Printf("%s │ \x1b[93mData\x1b[0m   : \x1b[96m%s\x1b[0m (use --show-keys to reveal)\n", keyPrompt, k.Masked())
+		r.Printf("%s └─\n", keyPrompt)
+	}
+	r.Printf("%s └─\n", indentPrompt)
 }
 
 // Equal checks if two KeyBlock structs are equal.
@@ -1,5 +1,11 @@
 package keytab
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type EncryptionType uint16
 
 const (
@@ -56,3 +62,26 @@ var EncryptionTypeMap = map[EncryptionType]string{
 func (k EncryptionType) String() string {
 	return EncryptionTypeMap[k]
 }
+
+// ParseEncryptionType parses an encryption type from its name, case insensitively, or its number.
+// The name may omit its dashes or use underscores, as in "aes256_cts_hmac_sha1_96" or "rc4hmac".
+//
+// Parameters:
+//   - name (string): The name or number of the encryption type.
+//
+// Returns:
+//   - (EncryptionType, error): The encryption type and an error if it is unknown.
+func ParseEncryptionType(name string) (EncryptionType, error) {
+	if number, err := strconv.ParseUint(name, 0, 16); err == nil {
+		return EncryptionType(number), nil
+	}
+	normalize := func(s string) string {
+		return strings.NewReplacer("-", "", "_", "").Replace(strings.ToUpper(s))
+	}
+	for etype, etypeName := range EncryptionTypeMap {
+		if normalize(etypeName) == normalize(name) {
+			return etype, nil
+		}
+	}
+	return EncryptionType_NULL, fmt.Errorf("unknown encryption type %q", name)
+}
@@ -8,3 +8,21 @@ func Test_KeyBlockType_String(t *testing.T) {
 		t.Errorf("Expected AES256-CTS-HMAC-SHA1-96, got %s", k.String())
 	}
 }
+
+func Test_KeyBlockType_ParseEncryptionType(t *testing.T) {
+	for name, expected := range map[string]EncryptionType{
+		"aes256-cts-hmac-sha1-96": EncryptionType_AES256_CTS_HMAC_SHA1_96,
+		"AES128_CTS_HMAC_SHA1_96": EncryptionType_AES128_CTS_HMAC_SHA1_96,
+		"rc4-hmac":                EncryptionType_RC4_HMAC,
+		"23":                      EncryptionType_RC4_HMAC,
+		"0x12":                    EncryptionType_AES256_CTS_HMAC_SHA1_96,
+	} {
+		etype, err := ParseEncryptionType(name)
+		if err != nil || etype != expected {
+			t.Errorf("Expected %s for %q, got %s (%v)", expected, name, etype, err)
+		}
+	}
+	if _, err := ParseEncryptionType("des-cbc-sha1"); err == nil {
+		t.Errorf("Expected an error for an unknown encryption type")
+	}
+}
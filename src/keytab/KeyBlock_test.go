@@ -2,6 +2,7 @@ package keytab
 
 import (
 	"encoding/hex"
+	"strings"
 	"testing"
 )
 
@@ -29,3 +30,30 @@ func Test_KeyBlock_ToBytesFromBytesInvolution(t *testing.T) {
 		t.Errorf("Data mismatch: expected %v, got %v", k1.Key.Data, k2.Key.Data)
 	}
 }
+
+func Test_KeyBlock_Fingerprint(t *testing.T) {
+	aes := newTestEntry("svc_web", EncryptionType_AES128_CTS_HMAC_SHA1_96, 1, 16)
+	rc4 := newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16)
+	if fingerprint := aes.Key.Fingerprint(); len(fingerprint) != 16 || fingerprint != aes.Key.Fingerprint() {
+		t.Errorf("Expected a stable fingerprint of 16 hexadecimal digits, got %s", fingerprint)
+	}
+	if aes.Key.Fingerprint() == rc4.Key.Fingerprint() {
+		t.Errorf("Expected the fingerprint to depend on the encryption type")
+	}
+	if masked := rc4.Key.KeyString(false); strings.Contains(masked, strings.Repeat("00", 16)) {
+		t.Errorf("Expected the key to be masked, got %s", masked)
+	}
+
+	if fingerprint := rc4.Key.Fingerprint(); fingerprint != "41a4e53626525347" {
+		t.Errorf("Unexpected fingerprint %s", fingerprint)
+	}
+	if fingerprint := rc4.Key.FingerprintWithPepper([]byte("org-pepper")); fingerprint != "207c3e0b1004d46b" {
+		t.Errorf("Unexpected fingerprint with pepper %s", fingerprint)
+	}
+	FingerprintPepper = []byte("org-pepper")
+	defer func() { FingerprintPepper = nil }()
+	kt := newTestKeytab(rc4)
+	if fingerprints := kt.Fingerprints(); len(fingerprints) != 1 || fingerprints[0].Fingerprint != "207c3e0b1004d46b" || fingerprints[0].EncryptionType != "rc4-hmac" {
+		t.Errorf("Unexpected fingerprints %+v", fingerprints)
+	}
+}
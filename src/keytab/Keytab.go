@@ -1,9 +1,12 @@
 package keytab
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/csv"
 	"fmt"
 	"io"
+	"keytab/render"
 	"os"
 	"strings"
 )
@@ -13,27 +16,34 @@ import (
 // Attributes:
 //   - FileFormatVersion (uint16): The version of the keytab file format.
 //   - Entries ([]KeytabEntry): The entries in the keytab file.
+//   - IgnoreRealmCase (bool): Whether Lookup and Find compare realms case-insensitively, as Active Directory does.
 //   - RawBytes ([]byte): The raw bytes of the keytab file.
 //   - RawBytesSize (uint32): The size of the raw bytes of the keytab file.
 type Keytab struct {
 	FileFormatVersion uint16
 	Entries           []KeytabEntry
+	IgnoreRealmCase   bool
 	// Internal
 	RawBytes     []byte
 	RawBytesSize uint32
+	index        *keytabIndex
 }
 
-// FromBytes parses a byte array into a Keytab.
+// FromBytes parses a byte array into a Keytab. The size of each entry gives the offset of the
+// next one, and the holes left by entries deleted by MIT Kerberos, of negative sizes, are skipped.
 //
 // Parameters:
 //   - data ([]byte): The byte array to parse.
 //
 // Returns:
-//   - error: An error if the parsing failed.
+//   - error: An error if the data is not a keytab file, or a length or size exceeds the data.
 func (k *Keytab) FromBytes(data []byte) error {
-	k.RawBytes = data
+	raw := data
 	k.RawBytesSize = 0
 
+	if len(data) < 2 || data[0] != 0x05 {
+		return fmt.Errorf("not a keytab file")
+	}
 	k.FileFormatVersion = binary.BigEndian.Uint16(data[0:2])
 	data = data[2:]
 	k.RawBytesSize += 2
@@ -41,14 +51,35 @@ func (k *Keytab) FromBytes(data []byte) error {
 	k.Entries = make([]KeytabEntry, 0)
 
 	for len(data) != 0 {
+		if len(data) < 4 {
+			return fmt.Errorf("truncated entry #%d", len(k.Entries))
+		}
+		// Entries deleted by MIT Kerberos leave holes of the opposite of their size
+		if size := int32(binary.BigEndian.Uint32(data[0:4])); size < 0 {
+			hole := 4 - int64(size)
+			if int64(len(data)) < hole {
+				return fmt.Errorf("truncated hole after entry #%d", len(k.Entries))
+			}
+			data = data[hole:]
+			k.RawBytesSize += uint32(hole)
+			continue
+		}
+
 		entry := KeytabEntry{}
-		entry.FromBytes(data)
-		data = data[entry.RawBytesSize:]
+		err := entry.FromBytes(data)
+		if err != nil {
+			return fmt.Errorf("entry #%d: %v", len(k.Entries), err)
+		}
+		data = data[4+entry.Size:]
 		k.Entries = append(k.Entries, entry)
-		k.RawBytesSize += entry.RawBytesSize
+		k.RawBytesSize += 4 + entry.Size
 	}
 
-	k.RawBytes = k.RawBytes[:k.RawBytesSize]
+	k.RawBytes = nil
+	if KeepRawBytes {
+		k.RawBytes = raw[:k.RawBytesSize]
+	}
+	k.InvalidateIndex()
 
 	return nil
 }
@@ -135,6 +166,10 @@ func LoadKeytabFromFile(path string) (*Keytab, error) {
 
 	keytab := &Keytab{}
 	err = keytab.FromBytes(data)
+	if !KeepRawBytes {
+		// The keys were copied out of the file contents
+		clear(data)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -144,22 +179,35 @@ func LoadKeytabFromFile(path string) (*Keytab, error) {
 
 // Describe prints a detailed description of the Keytab struct,
 // including its attributes formatted with indentation for clarity.
+// Colors are used when the standard output is a terminal and NO_COLOR is not set.
 //
 // Parameters:
 //   - indent (int): The indentation level for formatting the output. Each level increases
 //     the indentation depth, allowing for a hierarchical display of the entry's components.
 func (k *Keytab) Describe(indent int) {
+	k.DescribeTo(render.NewRenderer(os.Stdout, false), indent)
+}
+
+// DescribeTo writes the description of the Keytab struct to a Renderer, see Describe.
+//
+// Parameters:
+//   - r (*render.Renderer): The renderer the description is written to.
+//   - indent (int): The indentation level for formatting the output. Each level increases
+//     the indentation depth, allowing for a hierarchical display of the entry's components.
+func (k *Keytab) DescribeTo(r *render.Renderer, indent int) {
 	indentPrompt := strings.Repeat(" │ ", indent)
-	fmt.Printf("%s<Keytab>\n", indentPrompt)
-	fmt.Printf("%s │ \x1b[93mFileFormatVersion\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.FileFormatVersion, k.FileFormatVersion)
-	fmt.Printf("%s │ \x1b[93mEntries\x1b[0m           : \x1b[96m%d\x1b[0m\n", indentPrompt, len(k.Entries))
+	r.Printf("%s<Keytab>\n", indentPrompt)
+	r.Printf("%s │ \x1b[93mFileFormatVersion\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.FileFormatVersion, k.FileFormatVersion)
+	r.Printf("%s │ \x1b[93mEntries\x1b[0m           : \x1b[96m%d\x1b[0m\n", indentPrompt, len(k.Entries))
 	for i, entry := range k.Entries {
-		entry.Describe(indent+1, i)
+		entry.DescribeTo(r, indent+1, i)
 	}
-	fmt.Printf("%s └─\n", indentPrompt)
+	r.Printf("%s └─\n", indentPrompt)
 }
 
-// SaveToFile saves the Keytab struct to a file.
+// SaveToFile saves the Keytab struct to a file with the default SaveOptions: the file is
+// replaced atomically under an advisory lock, keeping its owner, mode and ACLs, and the
+// save is refused if the path is a symbolic link. See SaveToFileWithOptions.
 //
 // Parameters:
 //   - path (string): The path to the file to save the Keytab struct to.
@@ -167,11 +215,7 @@ func (k *Keytab) Describe(indent int) {
 // Returns:
 //   - error: An error if the saving failed.
 func (k *Keytab) SaveToFile(path string) error {
-	data, err := k.ToBytes()
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, data, 0644)
+	return k.SaveToFileWithOptions(path, SaveOptions{})
 }
 
 // AddKey adds a new key to the keytab file.
@@ -186,43 +230,94 @@ func (k *Keytab) AddKey(principal string, key string, password string) {
 	// k.Entries = append(k.Entries, entry)
 }
 
-// DeleteKey deletes a key from the keytab file.
+// DeleteKey deletes the keys of a principal from the keytab file, matching key version
+// numbers with the effective kvno of the entries.
 //
 // Parameters:
-//   - principal (string): The principal to delete.
-func (k *Keytab) DeleteKey(principal string) {
-	// for i, entry := range k.Entries {
-	// 	if entry.Principal == principal {
-	// 		k.Entries = append(k.Entries[:i], k.Entries[i+1:]...)
-	// 		break
-	// 	}
-	// }
+//   - principal (string): The principal to delete, as "name@REALM". The realm of the first matching entry is used when absent.
+//   - kvno (uint32): The key version number to delete, or 0 to delete all of them.
+//
+// Returns:
+//   - int: The number of entries deleted.
+func (k *Keytab) DeleteKey(principal string, kvno uint32) int {
+	realm, components, err := k.resolvePrincipal(principal)
+	if err != nil {
+		return 0
+	}
+	entries := make([]KeytabEntry, 0, len(k.Entries))
+	for _, entry := range k.Entries {
+		if entry.matches(realm, components) && (kvno == 0 || entry.KVNO() == kvno) {
+			entry.Wipe()
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	deleted := len(k.Entries) - len(entries)
+	k.Entries = entries
+	k.InvalidateIndex()
+	return deleted
 }
 
-// Export exports the keytab file to a file.
+// Export exports the entries of the keytab to a file, only readable by its owner as it may hold
+// keys. The format is described in ExportTo.
 //
 // Parameters:
-//   - path (string): The path to the file to save the keytab file to.
-//   - jsonOutput (bool): Whether to export the keytab file to a JSON file.
-//   - txtOutput (bool): Whether to export the keytab file to a TXT file.
-//   - csvOutput (bool): Whether to export the keytab file to a CSV file.
-func (k *Keytab) Export(path string, jsonOutput, txtOutput, csvOutput bool) error {
-	data, err := k.ToBytes()
+//   - path (string): The path to the output file.
+//   - format (ExportFormat): The format.
+//   - showKeys (bool): Whether the keys are exported.
+//
+// Returns:
+//   - error: An error if the export failed.
+func (k *Keytab) Export(path string, format ExportFormat, showKeys bool) error {
+	buffer := &bytes.Buffer{}
+	defer func() { clear(buffer.Bytes()) }()
+	err := k.ExportTo(buffer, format, showKeys)
 	if err != nil {
 		return err
 	}
+	return os.WriteFile(path, buffer.Bytes(), 0600)
+}
 
-	if jsonOutput {
-		os.WriteFile(path, data, 0644)
-	}
-
-	if txtOutput {
-		os.WriteFile(path, data, 0644)
+// ExportTo writes the entries of the keytab to a writer, as JSON (the KeytabView), as a TXT table,
+// as CSV, as the arguments of impacket or Rubeus, as "principal:enctype:hex" lines (the generic
+// format) or as a keytab file for Wireshark. Keys are masked, showing their length and fingerprint,
+// unless showKeys is set, which the impacket, Rubeus, Wireshark and generic formats require as
+// they hold the keys in clear.
+//
+// Parameters:
+//   - writer (io.Writer): The writer.
+//   - format (ExportFormat): The format.
+//   - showKeys (bool): Whether the keys are exported.
+//
+// Returns:
+//   - error: An error if the export failed.
+func (k *Keytab) ExportTo(writer io.Writer, format ExportFormat, showKeys bool) error {
+	if format.HoldsKeys() && !showKeys {
+		return fmt.Errorf("the %s format holds the keys, which must be shown explicitly", format)
 	}
+	r := &render.Renderer{Writer: writer, ShowKeys: showKeys}
 
-	if csvOutput {
-		os.WriteFile(path, data, 0644)
+	switch format {
+	case ExportFormat_JSON:
+		return r.JSON(k.View(showKeys))
+	case ExportFormat_TXT:
+		r.Table(tableHeaders, k.tableRows(showKeys))
+		return nil
+	case ExportFormat_CSV:
+		csvWriter := csv.NewWriter(writer)
+		csvWriter.Write(tableHeaders)
+		csvWriter.WriteAll(k.tableRows(showKeys))
+		return csvWriter.Error()
+	case ExportFormat_Impacket, ExportFormat_Rubeus, ExportFormat_Generic:
+		return k.exportToolLines(writer, format)
+	case ExportFormat_Wireshark:
+		data, err := k.ToBytes()
+		if err != nil {
+			return err
+		}
+		defer clear(data)
+		_, err = writer.Write(data)
+		return err
 	}
-
-	return nil
+	return fmt.Errorf("unknown export format %q", format)
 }
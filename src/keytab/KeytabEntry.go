@@ -1,8 +1,11 @@
 package keytab
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"keytab/render"
+	"os"
 	"strings"
 	"time"
 )
@@ -32,35 +35,52 @@ type KeytabEntry struct {
 //   - error: An error if the parsing fails.
 func (k *KeytabEntry) FromBytes(data []byte) error {
 	k.RawBytesSize = 0
-	k.RawBytes = data
+	raw := data
 
 	// Size
+	if len(data) < 4 {
+		return fmt.Errorf("truncated entry size")
+	}
 	k.Size = binary.BigEndian.Uint32(data[0:4])
 	data = data[4:]
+	if uint64(k.Size) > uint64(len(data)) {
+		return fmt.Errorf("entry size %d exceeds the %d remaining bytes", k.Size, len(data))
+	}
 	data = data[:k.Size]
 	k.RawBytesSize += 4
 
 	// NumComponents
+	if len(data) < 2 {
+		return fmt.Errorf("truncated number of components")
+	}
 	k.NumComponents = binary.BigEndian.Uint16(data[0:2])
 	data = data[2:]
 	k.RawBytesSize += 2
 
 	// Realm
 	k.Realm = CountedOctetString{}
-	k.Realm.FromBytes(data)
+	if err := k.Realm.FromBytes(data); err != nil {
+		return fmt.Errorf("realm: %v", err)
+	}
 	data = data[k.Realm.RawBytesSize:]
 	k.RawBytesSize += k.Realm.RawBytesSize
 
 	// Components
+	k.Components = nil
 	for i := uint16(0); i < k.NumComponents; i++ {
 		component := CountedOctetString{}
-		component.FromBytes(data)
+		if err := component.FromBytes(data); err != nil {
+			return fmt.Errorf("component #%d: %v", i, err)
+		}
 		k.Components = append(k.Components, component)
 		data = data[component.RawBytesSize:]
 		k.RawBytesSize += component.RawBytesSize
 	}
 
 	// NameType
+	if len(data) < 9 {
+		return fmt.Errorf("truncated name type, timestamp or kvno")
+	}
 	k.NameType = binary.BigEndian.Uint32(data[0:4])
 	data = data[4:]
 	k.RawBytesSize += 4
@@ -76,7 +96,9 @@ func (k *KeytabEntry) FromBytes(data []byte) error {
 	k.RawBytesSize += 1
 
 	// Key
-	k.Key.FromBytes(data)
+	if err := k.Key.FromBytes(data); err != nil {
+		return fmt.Errorf("key: %v", err)
+	}
 	data = data[k.Key.RawBytesSize:]
 	k.RawBytesSize += k.Key.RawBytesSize
 
@@ -89,7 +111,10 @@ func (k *KeytabEntry) FromBytes(data []byte) error {
 		k.Vno = 0
 	}
 
-	k.RawBytes = k.RawBytes[:k.RawBytesSize]
+	k.RawBytes = nil
+	if KeepRawBytes {
+		k.RawBytes = raw[:k.RawBytesSize]
+	}
 
 	return nil
 }
@@ -154,6 +179,37 @@ func (k *KeytabEntry) ToBytes() ([]byte, error) {
 	return data, nil
 }
 
+// NewKeytabEntry creates a KeytabEntry holding a copy of a key, timestamped now, with its size set.
+//
+// Parameters:
+//   - realm (string): The realm of the principal.
+//   - components ([]string): The components of the principal.
+//   - nameType (uint32): The name type of the principal, such as NT-PRINCIPAL (1) or NT-SRV-INST (2).
+//   - kvno (uint32): The key version number.
+//   - etype (EncryptionType): The encryption type of the key.
+//   - key ([]byte): The key.
+//
+// Returns:
+//   - (KeytabEntry, error): The entry and an error if it can not be encoded.
+func NewKeytabEntry(realm string, components []string, nameType uint32, kvno uint32, etype EncryptionType, key []byte) (KeytabEntry, error) {
+	entry := KeytabEntry{
+		NumComponents: uint16(len(components)),
+		Realm:         CountedOctetString{Length: uint16(len(realm)), Data: []byte(realm)},
+		NameType:      nameType,
+		Timestamp:     uint32(time.Now().Unix()),
+		Key:           KeyBlock{Type: etype, Key: CountedOctetString{Length: uint16(len(key)), Data: bytes.Clone(key)}},
+	}
+	for _, component := range components {
+		entry.Components = append(entry.Components, CountedOctetString{Length: uint16(len(component)), Data: []byte(component)})
+	}
+	entry.SetKVNO(kvno)
+	err := entry.UpdateSize()
+	if err != nil {
+		return KeytabEntry{}, err
+	}
+	return entry, nil
+}
+
 // UpdateSize updates the size of the KeytabEntry.
 //
 // Returns:
@@ -170,6 +226,41 @@ func (k *KeytabEntry) UpdateSize() error {
 	return nil
 }
 
+// Principal returns the string representation of the principal of the KeytabEntry,
+// in the form "component1/component2@REALM".
+//
+// Returns:
+//   - string: The principal of the KeytabEntry.
+func (k *KeytabEntry) Principal() string {
+	components := make([]string, 0, len(k.Components))
+	for _, component := range k.Components {
+		components = append(components, string(component.Data))
+	}
+	return strings.Join(components, "/") + "@" + string(k.Realm.Data)
+}
+
+// KVNO returns the effective key version number of the KeytabEntry, following the rules of
+// MIT Kerberos: the 32-bit Vno wins when present and non-zero, Vno8 is used otherwise.
+//
+// Returns:
+//   - uint32: The key version number.
+func (k *KeytabEntry) KVNO() uint32 {
+	if k.Vno != 0 {
+		return k.Vno
+	}
+	return uint32(k.Vno8)
+}
+
+// SetKVNO sets the key version number of the KeytabEntry, storing it in the 32-bit Vno and
+// its value modulo 256 in Vno8, as MIT Kerberos writes them.
+//
+// Parameters:
+//   - kvno (uint32): The key version number.
+func (k *KeytabEntry) SetKVNO(kvno uint32) {
+	k.Vno = kvno
+	k.Vno8 = uint8(kvno % 256)
+}
+
 // Describe prints a detailed description of the KeytabEntry struct,
 // including its attributes formatted with indentation for clarity.
 //
@@ -177,25 +268,36 @@ func (k *KeytabEntry) UpdateSize() error {
 //   - indent (int): The indentation level for formatting the output. Each level increases
 //     the indentation depth, allowing for a hierarchical display of the entry's components.
 func (k *KeytabEntry) Describe(indent, id int) {
+	k.DescribeTo(render.NewRenderer(os.Stdout, false), indent, id)
+}
+
+// DescribeTo writes the description of the KeytabEntry to a Renderer, see Describe.
+//
+// Parameters:
+//   - r (*render.Renderer): The renderer the description is written to.
+//   - indent (int): The indentation level for formatting the output. Each level increases
+//     the indentation depth, allowing for a hierarchical display of the entry's components.
+func (k *KeytabEntry) DescribeTo(r *render.Renderer, indent, id int) {
 	indentPrompt := strings.Repeat(" │ ", indent)
-	fmt.Printf("%s<KeytabEntry #%d>\n", indentPrompt, id)
-	fmt.Printf("%s │ \x1b[93mSize\x1b[0m          : \x1b[96m0x%08x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.Size, k.Size)
-	fmt.Printf("%s │ \x1b[93mNumComponents\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.NumComponents, k.NumComponents)
-	fmt.Printf("%s │ \x1b[93mRealm\x1b[0m         : \x1b[96m%s\x1b[0m\n", indentPrompt, k.Realm.Data)
+	r.Printf("%s<KeytabEntry #%d>\n", indentPrompt, id)
+	r.Printf("%s │ \x1b[93mSize\x1b[0m          : \x1b[96m0x%08x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.Size, k.Size)
+	r.Printf("%s │ \x1b[93mNumComponents\x1b[0m : \x1b[96m0x%04x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.NumComponents, k.NumComponents)
+	r.Printf("%s │ \x1b[93mRealm\x1b[0m         : \x1b[96m%s\x1b[0m\n", indentPrompt, k.Realm.Data)
 
-	fmt.Printf("%s │ \x1b[93mComponents\x1b[0m    : \n", indentPrompt)
+	r.Printf("%s │ \x1b[93mComponents\x1b[0m    : \n", indentPrompt)
 	for i, component := range k.Components {
-		component.Describe(indent+2, i)
-		fmt.Printf("%s │  └─\n", indentPrompt)
+		component.DescribeTo(r, indent+2, i)
+		r.Printf("%s │  └─\n", indentPrompt)
 	}
 
-	fmt.Printf("%s │ \x1b[93mNameType\x1b[0m      : \x1b[96m0x%08x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.NameType, k.NameType)
-	fmt.Printf("%s │ \x1b[93mTimestamp\x1b[0m     : \x1b[96m0x%08x\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, k.Timestamp, time.Unix(int64(k.Timestamp), 0).Format(time.RFC3339))
-	fmt.Printf("%s │ \x1b[93mVno8\x1b[0m          : \x1b[96m0x%02x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.Vno8, k.Vno8)
-	fmt.Printf("%s │ \x1b[93mKey\x1b[0m           : \n", indentPrompt)
-	k.Key.Describe(indent + 2)
-	fmt.Printf("%s │ \x1b[93mVno\x1b[0m           : \x1b[96m0x%08x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.Vno, k.Vno)
-	fmt.Printf("%s └─\n", indentPrompt)
+	r.Printf("%s │ \x1b[93mNameType\x1b[0m      : \x1b[96m0x%08x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.NameType, k.NameType)
+	r.Printf("%s │ \x1b[93mTimestamp\x1b[0m     : \x1b[96m0x%08x\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, k.Timestamp, time.Unix(int64(k.Timestamp), 0).Format(time.RFC3339))
+	r.Printf("%s │ \x1b[93mVno8\x1b[0m          : \x1b[96m0x%02x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.Vno8, k.Vno8)
+	r.Printf("%s │ \x1b[93mKey\x1b[0m           : \n", indentPrompt)
+	k.Key.DescribeTo(r, indent+2)
+	r.Printf("%s │ \x1b[93mVno\x1b[0m           : \x1b[96m0x%08x\x1b[0m (\x1b[94m%d\x1b[0m)\n", indentPrompt, k.Vno, k.Vno)
+	r.Printf("%s │ \x1b[93mKVNO\x1b[0m          : \x1b[94m%d\x1b[0m\n", indentPrompt, k.KVNO())
+	r.Printf("%s └─\n", indentPrompt)
 }
 
 // Equal checks if two KeytabEntry structs are equal.
@@ -206,6 +308,14 @@ func (k *KeytabEntry) Describe(indent, id int) {
 // Returns:
 //   - bool: True if the KeytabEntry structs are equal, false otherwise.
 func (k *KeytabEntry) Equal(k2 KeytabEntry) bool {
+	if len(k.Components) != len(k2.Components) {
+		return false
+	}
+	for i := range k.Components {
+		if !k.Components[i].Equal(k2.Components[i]) {
+			return false
+		}
+	}
 	return k.Size == k2.Size &&
 		k.NumComponents == k2.NumComponents &&
 		k.Realm.Equal(k2.Realm) &&
@@ -2,11 +2,29 @@ package keytab
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"testing"
 )
 
+// newTestEntry returns an entry of a principal of TESTSEGMENT.LOCAL with a zero key.
+func newTestEntry(name string, etype EncryptionType, kvno uint8, size int) KeytabEntry {
+	return KeytabEntry{
+		NumComponents: 1,
+		Realm:         CountedOctetString{Length: 17, Data: []byte("TESTSEGMENT.LOCAL")},
+		Components:    []CountedOctetString{{Length: uint16(len(name)), Data: []byte(name)}},
+		NameType:      1,
+		Vno8:          kvno,
+		Key:           KeyBlock{Type: etype, Key: CountedOctetString{Length: uint16(size), Data: make([]byte, size)}},
+	}
+}
+
+// newTestKeytab returns a keytab of version 0x502 holding entries, such as those of newTestEntry.
+func newTestKeytab(entries ...KeytabEntry) *Keytab {
+	return &Keytab{FileFormatVersion: 0x502, Entries: entries}
+}
+
 func Test_KeytabEntry_ToBytesFromBytesInvolution(t *testing.T) {
 	entry1 := KeytabEntry{
 		Size:          0,
@@ -108,3 +126,65 @@ func Test_KeytabEntry_Equal(t *testing.T) {
 		t.Errorf("entry1.Equal(entry2) is not true.")
 	}
 }
+
+func Test_KeytabEntry_KVNO(t *testing.T) {
+	entry1 := KeytabEntry{Vno8: 5}
+	if entry1.KVNO() != 5 {
+		t.Errorf("Expected Vno8 to be used without 32-bit kvno, got %d", entry1.KVNO())
+	}
+
+	entry1.Vno = 300
+	if entry1.KVNO() != 300 {
+		t.Errorf("Expected the 32-bit kvno to win, got %d", entry1.KVNO())
+	}
+
+	entry1.SetKVNO(256)
+	if entry1.Vno8 != 0 || entry1.Vno != 256 {
+		t.Errorf("Expected Vno8 0 and Vno 256, got %d and %d", entry1.Vno8, entry1.Vno)
+	}
+
+	entry1Bytes, _ := entry1.ToBytes()
+	entry2 := KeytabEntry{}
+	entry2.FromBytes(entry1Bytes)
+	if entry2.KVNO() != 256 {
+		t.Errorf("Expected kvno 256 after encoding and parsing, got %d", entry2.KVNO())
+	}
+}
+
+func Test_KeytabEntry_FromBytesMalformed(t *testing.T) {
+	entry := KeytabEntry{
+		NumComponents: 2,
+		Realm:         CountedOctetString{Length: 17, Data: []byte("TESTSEGMENT.LOCAL")},
+		Components:    []CountedOctetString{{Length: 4, Data: []byte("HTTP")}, {Length: 5, Data: []byte("web01")}},
+		NameType:      1,
+		Vno8:          1,
+		Key:           KeyBlock{Type: EncryptionType_RC4_HMAC, Key: CountedOctetString{Length: 16, Data: make([]byte, 16)}},
+	}
+	entry.UpdateSize()
+	data, _ := entry.ToBytes()
+	parsed := KeytabEntry{}
+	if err := parsed.FromBytes(data); err != nil || len(parsed.Components) != 2 {
+		t.Fatalf("Error parsing entry: %v", err)
+	}
+
+	// The length of the second component, after the size, the number of components, the realm and the first component
+	offset := 4 + 2 + 2 + 17 + 2 + 4
+	for _, length := range []uint16{0x00ff, 0xffff} {
+		lying := bytes.Clone(data)
+		binary.BigEndian.PutUint16(lying[offset:offset+2], length)
+		if err := parsed.FromBytes(lying); err == nil {
+			t.Errorf("Expected an error for a component length of %d", length)
+		}
+	}
+	lying := bytes.Clone(data)
+	binary.BigEndian.PutUint16(lying[4:6], 40)
+	if err := parsed.FromBytes(lying); err == nil {
+		t.Errorf("Expected an error for 40 components")
+	}
+	// The length of the key, before its 16 bytes and the 32-bit kvno
+	lying = bytes.Clone(data)
+	binary.BigEndian.PutUint16(lying[len(lying)-22:len(lying)-20], 32)
+	if err := parsed.FromBytes(lying); err == nil {
+		t.Errorf("Expected an error for a key length beyond the entry")
+	}
+}
@@ -1,6 +1,10 @@
 package keytab
 
 import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -77,3 +81,97 @@ func Test_Keytab_ToBytesFromBytesInvolution(t *testing.T) {
 		t.Errorf("Keytab mismatch: expected %+v, got %+v", kt1, kt2)
 	}
 }
+
+func Test_Keytab_FromBytesMalformed(t *testing.T) {
+	entry := newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16)
+	entry.UpdateSize()
+	data, err := newTestKeytab(entry).ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding keytab: %v", err)
+	}
+	for i := 0; i < len(data); i++ {
+		if i == 2 {
+			continue
+		}
+		if err = (&Keytab{}).FromBytes(data[:i]); err == nil {
+			t.Errorf("Expected an error for a keytab truncated to %d bytes", i)
+		}
+	}
+
+	// A hole left by a deleted entry is skipped, and the entry after it is parsed
+	hole := append([]byte{0x05, 0x02, 0xff, 0xff, 0xff, 0xfc, 0, 0, 0, 0}, data[2:]...)
+	kt := &Keytab{}
+	if err = kt.FromBytes(hole); err != nil || len(kt.Entries) != 1 || kt.Entries[0].Principal() != "svc_web@TESTSEGMENT.LOCAL" {
+		t.Errorf("Expected the hole to be skipped, got %d entries (%v)", len(kt.Entries), err)
+	}
+	if err = kt.FromBytes([]byte{0x05, 0x02, 0x80, 0x00, 0x00, 0x00}); err == nil {
+		t.Errorf("Expected an error for a hole larger than the file")
+	}
+
+	wrongVersion := append([]byte{0x04, 0x02}, data[2:]...)
+	if err = kt.FromBytes(wrongVersion); err == nil {
+		t.Errorf("Expected an error for the file format version 0x0402")
+	}
+
+	// The size of an entry is trusted to find the next entry, but not beyond the file
+	padded := append([]byte{}, data...)
+	binary.BigEndian.PutUint32(padded[2:6], entry.Size+2)
+	padded = append(padded, 0, 0)
+	padded = append(padded, data[2:]...)
+	if err = kt.FromBytes(padded); err != nil || len(kt.Entries) != 2 || kt.Entries[1].Key.Type != EncryptionType_RC4_HMAC {
+		t.Errorf("Expected the padding of the first entry to be skipped, got %d entries (%v)", len(kt.Entries), err)
+	}
+	// Sizes beyond the file, cutting the key, and read as a hole beyond the file
+	for _, size := range []uint32{entry.Size + 1, entry.Size - 10, 0xffffff00} {
+		lying := append([]byte{}, data...)
+		binary.BigEndian.PutUint32(lying[2:6], size)
+		if err = kt.FromBytes(lying); err == nil {
+			t.Errorf("Expected an error for an entry of %d bytes with a size of %d", entry.Size, size)
+		}
+	}
+}
+
+func Test_Keytab_DeleteKey(t *testing.T) {
+	kt := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 0, 32),
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 1, 32),
+		newTestEntry("svc_sql", EncryptionType_AES256_CTS_HMAC_SHA1_96, 0, 32),
+	)
+	kt.Entries[0].SetKVNO(256)
+	kt.Entries[2].SetKVNO(256)
+
+	// Vno8 of the kvno 256 entry is 0, it must not match kvno 0 nor 1
+	if deleted := kt.DeleteKey("svc_web", 256); deleted != 1 || len(kt.Entries) != 2 || kt.Entries[0].KVNO() != 1 {
+		t.Errorf("Expected the kvno 256 entry of svc_web to be deleted, got %d deleted", deleted)
+	}
+	if deleted := kt.DeleteKey("svc_sql@TESTSEGMENT.LOCAL", 0); deleted != 1 || kt.Entries[0].Principal() != "svc_web@TESTSEGMENT.LOCAL" {
+		t.Errorf("Expected all the entries of svc_sql to be deleted, got %d deleted", deleted)
+	}
+	if deleted := kt.DeleteKey("nobody", 0); deleted != 0 {
+		t.Errorf("Expected no entry to be deleted for a missing principal, got %d", deleted)
+	}
+}
+
+func Test_Keytab_Export(t *testing.T) {
+	kt := newTestKeytab(newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16))
+	kt.Entries[0].Key.Key.Data[0] = 0xab
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	if err := kt.Export(path, ExportFormat_CSV, false); err != nil {
+		t.Fatalf("Error exporting keytab: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || strings.Contains(lines[1], "ab00") || !strings.HasPrefix(lines[1], "0,svc_web@TESTSEGMENT.LOCAL,1,rc4-hmac,") {
+		t.Errorf("Unexpected CSV export with masked keys:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the export to be only readable by its owner (%v)", err)
+	}
+
+	if err := kt.Export(path, ExportFormat_TXT, true); err != nil {
+		t.Fatalf("Error exporting keytab: %v", err)
+	}
+	if data, _ = os.ReadFile(path); !strings.Contains(string(data), "ab"+strings.Repeat("00", 15)) {
+		t.Errorf("Expected the keys in the TXT export with showKeys:\n%s", data)
+	}
+}
@@ -0,0 +1,279 @@
+package keytab
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity is the severity of a lint finding.
+type Severity int
+
+// Severities of lint findings, from the least to the most severe.
+const (
+	Severity_Info Severity = iota
+	Severity_Warning
+	Severity_Error
+)
+
+// SeverityMap is a map of Severity to its string representation.
+var SeverityMap = map[Severity]string{
+	Severity_Info:    "info",
+	Severity_Warning: "warning",
+	Severity_Error:   "error",
+}
+
+// String returns the string representation of the Severity.
+//
+// Returns:
+//   - string: The string representation of the Severity.
+func (s Severity) String() string {
+	return SeverityMap[s]
+}
+
+// MarshalText encodes the Severity as its string representation, in JSON.
+//
+// Returns:
+//   - ([]byte, error): The string representation of the Severity.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity returns the Severity of a string representation.
+//
+// Parameters:
+//   - name (string): The string representation, as "info", "warning" or "error".
+//
+// Returns:
+//   - (Severity, error): The Severity and an error if the name is unknown.
+func ParseSeverity(name string) (Severity, error) {
+	for severity, severityName := range SeverityMap {
+		if strings.EqualFold(name, severityName) {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q, expected info, warning or error", name)
+}
+
+// Codes of lint findings.
+const (
+	Lint_WeakEncryptionType    = "weak-enctype"
+	Lint_UnknownEncryptionType = "unknown-enctype"
+	Lint_KeyLengthMismatch     = "key-length-mismatch"
+	Lint_LengthMismatch        = "length-mismatch"
+	Lint_SizeMismatch          = "size-mismatch"
+	Lint_KvnoMismatch          = "kvno-mismatch"
+	Lint_DuplicateKey          = "duplicate-key"
+	Lint_DuplicateEntry        = "duplicate-entry"
+	Lint_ZeroTimestamp         = "zero-timestamp"
+	Lint_FutureTimestamp       = "future-timestamp"
+	Lint_EmptyRealm            = "empty-realm"
+	Lint_EmptyComponent        = "empty-component"
+	Lint_EncryptionTypesDrift  = "enctype-drift"
+)
+
+// KeySizes is a map of EncryptionType to the size of its keys in bytes.
+var KeySizes = map[EncryptionType]int{
+	EncryptionType_DES_CBC_CRC:                8,
+	EncryptionType_DES_CBC_MD4:                8,
+	EncryptionType_DES_CBC_MD5:                8,
+	EncryptionType_RESERVED_OLD_RC4_HMAC:      16,
+	EncryptionType_DES3_CBC_MD5:               24,
+	EncryptionType_DES3_CBC_SHA1:              24,
+	EncryptionType_RC4_HMAC:                   16,
+	EncryptionType_RC4_HMAC_EXP:               16,
+	EncryptionType_CAMELLIA128_CTS_CMAC:       16,
+	EncryptionType_CAMELLIA256_CTS_CMAC:       32,
+	EncryptionType_AES128_CTS_HMAC_SHA1_96:    16,
+	EncryptionType_AES256_CTS_HMAC_SHA1_96:    32,
+	EncryptionType_AES128_CTS_HMAC_SHA256_128: 16,
+	EncryptionType_AES256_CTS_HMAC_SHA384_192: 32,
+}
+
+// weakEncryptionTypes is a map of the deprecated encryption types to the severity of their use.
+var weakEncryptionTypes = map[EncryptionType]Severity{
+	EncryptionType_NULL:                  Severity_Error,
+	EncryptionType_DES_CBC_CRC:           Severity_Error,
+	EncryptionType_DES_CBC_MD4:           Severity_Error,
+	EncryptionType_DES_CBC_MD5:           Severity_Error,
+	EncryptionType_RESERVED_OLD_RC4_HMAC: Severity_Error,
+	EncryptionType_RC4_HMAC_EXP:          Severity_Error,
+	EncryptionType_DES3_CBC_MD5:          Severity_Warning,
+	EncryptionType_DES3_CBC_SHA1:         Severity_Warning,
+	EncryptionType_RC4_HMAC:              Severity_Warning,
+}
+
+// maxClockSkew is the tolerance of the check of future timestamps, the default clock skew of Kerberos.
+const maxClockSkew = 5 * time.Minute
+
+// Finding is a problem found in a keytab by Lint.
+//
+// Attributes:
+//   - Code (string): The machine-readable code of the finding, one of the Lint_ constants.
+//   - Severity (Severity): The severity of the finding.
+//   - Entry (int): The index of the entry the finding is about, or -1 if it is about a principal.
+//   - Principal (string): The principal the finding is about.
+//   - Fingerprint (string): The fingerprint of the key of the entry, empty if the finding is about a principal.
+//   - Message (string): The description of the finding.
+type Finding struct {
+	Code        string   `json:"code"`
+	Severity    Severity `json:"severity"`
+	Entry       int      `json:"entry"`
+	Principal   string   `json:"principal"`
+	Fingerprint string   `json:"fingerprint,omitempty"`
+	Message     string   `json:"message"`
+}
+
+// String returns the string representation of the Finding.
+//
+// Returns:
+//   - string: The finding, as "[severity] code: principal (entry #n, key fingerprint): message".
+func (f Finding) String() string {
+	location := f.Principal
+	if f.Entry >= 0 {
+		location += fmt.Sprintf(" (entry #%d, key %s)", f.Entry, f.Fingerprint)
+	}
+	return fmt.Sprintf("[%s] %s: %s: %s", f.Severity.String(), f.Code, location, f.Message)
+}
+
+// Lint audits the keytab for weak, inconsistent or corrupt content: deprecated encryption
+// types, keys whose length does not match their encryption type, sizes and lengths that do
+// not match the content, 8-bit and 32-bit kvnos that disagree, duplicate entries, zero or
+// future timestamps, empty realms or components, and principals whose encryption types
+// differ between kvnos.
+//
+// Returns:
+//   - []Finding: The findings, by entry and then by principal.
+func (k *Keytab) Lint() []Finding {
+	findings := make([]Finding, 0)
+	now := time.Now()
+
+	type tuple struct {
+		principal string
+		kvno      uint32
+		etype     EncryptionType
+	}
+	seen := make(map[tuple]int)
+	etypes := make(map[string]map[uint32][]EncryptionType)
+	principals := make([]string, 0)
+
+	for i := range k.Entries {
+		entry := &k.Entries[i]
+		principal := entry.Principal()
+		fingerprint := entry.Key.Fingerprint()
+		add := func(code string, severity Severity, format string, args ...interface{}) {
+			findings = append(findings, Finding{Code: code, Severity: severity, Entry: i, Principal: principal, Fingerprint: fingerprint, Message: fmt.Sprintf(format, args...)})
+		}
+
+		etype := entry.Key.Type
+		if severity, weak := weakEncryptionTypes[etype]; weak {
+			add(Lint_WeakEncryptionType, severity, "the encryption type %s (%d) is deprecated", etype.String(), etype)
+		}
+		if size, known := KeySizes[etype]; !known {
+			if _, weak := weakEncryptionTypes[etype]; !weak {
+				add(Lint_UnknownEncryptionType, Severity_Info, "the encryption type %d is unknown", etype)
+			}
+		} else if len(entry.Key.Key.Data) != size {
+			add(Lint_KeyLengthMismatch, Severity_Error, "the key is %d bytes long, %s keys are %d bytes long", len(entry.Key.Key.Data), etype.String(), size)
+		}
+
+		if int(entry.NumComponents) != len(entry.Components) {
+			add(Lint_LengthMismatch, Severity_Error, "the number of components is %d, %d components are present", entry.NumComponents, len(entry.Components))
+		}
+		if int(entry.Realm.Length) != len(entry.Realm.Data) {
+			add(Lint_LengthMismatch, Severity_Error, "the length of the realm is %d, %d bytes are present", entry.Realm.Length, len(entry.Realm.Data))
+		}
+		if int(entry.Key.Key.Length) != len(entry.Key.Key.Data) {
+			add(Lint_LengthMismatch, Severity_Error, "the length of the key is %d, %d bytes are present", entry.Key.Key.Length, len(entry.Key.Key.Data))
+		}
+		for j, component := range entry.Components {
+			if int(component.Length) != len(component.Data) {
+				add(Lint_LengthMismatch, Severity_Error, "the length of component #%d is %d, %d bytes are present", j, component.Length, len(component.Data))
+			}
+		}
+		if data, err := entry.ToBytes(); err == nil {
+			// Entries written without the 32-bit kvno are 4 bytes shorter
+			size := uint32(len(data) - 4)
+			if entry.Size != size && !(entry.Vno == 0 && entry.Size == size-4) {
+				add(Lint_SizeMismatch, Severity_Error, "the size of the entry is %d, its content is %d bytes long", entry.Size, size)
+			}
+		}
+
+		if entry.Vno != 0 && entry.Vno%256 != uint32(entry.Vno8) {
+			add(Lint_KvnoMismatch, Severity_Warning, "the 8-bit kvno %d is not the 32-bit kvno %d modulo 256", entry.Vno8, entry.Vno)
+		}
+
+		key := tuple{principal, entry.KVNO(), etype}
+		if first, duplicate := seen[key]; duplicate {
+			if entry.Key.Key.Equal(k.Entries[first].Key.Key) {
+				add(Lint_DuplicateEntry, Severity_Info, "entry #%d holds the same %s key of kvno %d", first, etype.String(), key.kvno)
+			} else {
+				add(Lint_DuplicateKey, Severity_Error, "entry #%d holds a different %s key of kvno %d (key %s)", first, etype.String(), key.kvno, k.Entries[first].Key.Fingerprint())
+			}
+		} else {
+			seen[key] = i
+		}
+
+		if entry.Timestamp == 0 {
+			add(Lint_ZeroTimestamp, Severity_Info, "the timestamp is zero")
+		} else if timestamp := time.Unix(int64(entry.Timestamp), 0); timestamp.After(now.Add(maxClockSkew)) {
+			add(Lint_FutureTimestamp, Severity_Warning, "the timestamp %s is in the future", timestamp.UTC().Format(time.RFC3339))
+		}
+
+		if len(entry.Realm.Data) == 0 {
+			add(Lint_EmptyRealm, Severity_Error, "the realm is empty")
+		}
+		if len(entry.Components) == 0 {
+			add(Lint_EmptyComponent, Severity_Error, "the principal has no components")
+		}
+		for j, component := range entry.Components {
+			if len(component.Data) == 0 {
+				add(Lint_EmptyComponent, Severity_Error, "component #%d is empty", j)
+			}
+		}
+
+		if _, exists := etypes[principal]; !exists {
+			etypes[principal] = make(map[uint32][]EncryptionType)
+			principals = append(principals, principal)
+		}
+		etypes[principal][key.kvno] = append(etypes[principal][key.kvno], etype)
+	}
+
+	for _, principal := range principals {
+		kvnos := make([]uint32, 0, len(etypes[principal]))
+		for kvno := range etypes[principal] {
+			kvnos = append(kvnos, kvno)
+		}
+		sort.Slice(kvnos, func(i, j int) bool { return kvnos[i] < kvnos[j] })
+
+		descriptions := make([]string, len(kvnos))
+		drift := false
+		first := ""
+		for i, kvno := range kvnos {
+			names := make([]string, 0)
+			for _, etype := range etypes[principal][kvno] {
+				names = append(names, etype.String())
+			}
+			sort.Strings(names)
+			set := strings.Join(names, ", ")
+			if i == 0 {
+				first = set
+			} else if set != first {
+				drift = true
+			}
+			descriptions[i] = fmt.Sprintf("kvno %d: %s", kvno, set)
+		}
+		if drift {
+			findings = append(findings, Finding{
+				Code:      Lint_EncryptionTypesDrift,
+				Severity:  Severity_Warning,
+				Entry:     -1,
+				Principal: principal,
+				Message:   "the encryption types differ between kvnos (" + strings.Join(descriptions, "; ") + ")",
+			})
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,55 @@
+package keytab
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Keytab_Lint(t *testing.T) {
+	kt := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 1, 32),
+		newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16),
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 2, 32),
+		newTestEntry("svc_sql", EncryptionType_AES128_CTS_HMAC_SHA1_96, 1, 32),
+		newTestEntry("svc_sql", EncryptionType_AES128_CTS_HMAC_SHA1_96, 1, 16),
+		newTestEntry("svc_dns", EncryptionType_DES_CBC_MD5, 1, 8),
+	)
+	for i := range kt.Entries {
+		kt.Entries[i].Timestamp = uint32(time.Now().Unix())
+	}
+	kt.Entries[2].Timestamp += 86400
+	kt.Entries[5].Vno = 300
+	kt.UpdateEntriesSizes()
+	kt.Entries[0].Size++
+
+	expected := []struct {
+		code     string
+		severity Severity
+		entry    int
+	}{
+		{Lint_SizeMismatch, Severity_Error, 0},
+		{Lint_WeakEncryptionType, Severity_Warning, 1},
+		{Lint_FutureTimestamp, Severity_Warning, 2},
+		{Lint_KeyLengthMismatch, Severity_Error, 3},
+		{Lint_DuplicateKey, Severity_Error, 4},
+		{Lint_WeakEncryptionType, Severity_Error, 5},
+		{Lint_KvnoMismatch, Severity_Warning, 5},
+		{Lint_EncryptionTypesDrift, Severity_Warning, -1},
+	}
+	findings := kt.Lint()
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+	for i, finding := range findings {
+		if finding.Code != expected[i].code || finding.Severity != expected[i].severity || finding.Entry != expected[i].entry {
+			t.Errorf("Expected finding #%d to be %s (%s) on entry %d, got %s", i, expected[i].code, expected[i].severity.String(), expected[i].entry, finding.String())
+		}
+	}
+	if findings[7].Principal != "svc_web@TESTSEGMENT.LOCAL" {
+		t.Errorf("Expected the enctype drift of svc_web, got %s", findings[7].String())
+	}
+
+	if severity, err := ParseSeverity("WARNING"); err != nil || severity != Severity_Warning {
+		t.Errorf("Expected the warning severity, got %v (%v)", severity, err)
+	}
+}
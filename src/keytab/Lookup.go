@@ -0,0 +1,179 @@
+package keytab
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Errors returned by Lookup. They can be matched with errors.Is.
+var (
+	ErrNotFound     = errors.New("no matching entry in the keytab")
+	ErrKvnoNotFound = errors.New("no entry with the requested kvno in the keytab")
+)
+
+// Filter selects keytab entries in Find. Zero fields match any entry.
+//
+// Attributes:
+//   - Principal (string): The principal, as "name@REALM". Entries of any realm match when the realm is absent.
+//   - Kvno (uint32): The effective key version number.
+//   - EncryptionType (EncryptionType): The encryption type of the key.
+type Filter struct {
+	Principal      string
+	Kvno           uint32
+	EncryptionType EncryptionType
+}
+
+// keytabIndex maps principals to the indexes of their entries, built lazily by Lookup and Find.
+// InvalidateIndex replaces it with an empty one, so that copies of a keytab do not share changes.
+//
+// Attributes:
+//   - mutex (sync.Mutex): The mutex guarding the build of the index.
+//   - built (bool): Whether the index was built.
+//   - ignoreRealmCase (bool): Whether the realms of the keys are upper-cased.
+//   - principals (map[string][]int): The indexes of the entries of each "name@REALM" principal.
+//   - names (map[string][]int): The indexes of the entries of each name, in any realm.
+type keytabIndex struct {
+	mutex           sync.Mutex
+	built           bool
+	ignoreRealmCase bool
+	principals      map[string][]int
+	names           map[string][]int
+}
+
+// Lookup returns the entry of a principal with the semantics of krb5_kt_get_entry of MIT
+// Kerberos: a kvno of 0 selects the highest key version number, and an encryption type of 0
+// any encryption type. When no entry has the exact kvno, an entry without 32-bit kvno whose
+// Vno8 matches the kvno modulo 256 is returned, as written by tools truncating kvnos.
+// Realms are compared case-sensitively unless IgnoreRealmCase is set.
+//
+// The lookup uses an index of the principals built on first use. The methods of Keytab adding,
+// removing or replacing entries discard it, but InvalidateIndex must be called after modifying
+// Entries directly. The returned entry points into Entries and is only valid until they change.
+//
+// Parameters:
+//   - principal (string): The principal, as "name@REALM". Entries of any realm match when the realm is absent.
+//   - kvno (uint32): The key version number, or 0 for the highest one.
+//   - enctype (EncryptionType): The encryption type, or 0 for any.
+//
+// Returns:
+//   - (*KeytabEntry, error): The entry and ErrNotFound or ErrKvnoNotFound if there is none.
+func (k *Keytab) Lookup(principal string, kvno uint32, enctype EncryptionType) (*KeytabEntry, error) {
+	var best, truncated *KeytabEntry
+	found := false
+	for _, i := range k.principalEntries(principal) {
+		entry := &k.Entries[i]
+		if enctype != 0 && entry.Key.Type != enctype {
+			continue
+		}
+		found = true
+		switch {
+		case kvno == 0:
+			if best == nil || entry.KVNO() > best.KVNO() {
+				best = entry
+			}
+		case entry.KVNO() == kvno:
+			if best == nil {
+				best = entry
+			}
+		case entry.Vno == 0 && uint32(entry.Vno8) == kvno%256:
+			if truncated == nil {
+				truncated = entry
+			}
+		}
+	}
+
+	if best != nil {
+		return best, nil
+	}
+	if truncated != nil {
+		return truncated, nil
+	}
+	description := principal
+	if enctype != 0 {
+		description += " (" + enctype.String() + ")"
+	}
+	if found {
+		return nil, fmt.Errorf("%w: %s kvno %d", ErrKvnoNotFound, description, kvno)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, description)
+}
+
+// Find returns the entries matching a filter, in the order of the keytab. Like Lookup, it uses
+// the index of the keytab when the filter has a principal. The returned entries point into
+// Entries and are only valid until they change.
+//
+// Parameters:
+//   - filter (Filter): The filter.
+//
+// Returns:
+//   - []*KeytabEntry: The matching entries.
+func (k *Keytab) Find(filter Filter) []*KeytabEntry {
+	var indexes []int
+	if len(filter.Principal) != 0 {
+		indexes = k.principalEntries(filter.Principal)
+	} else {
+		indexes = make([]int, len(k.Entries))
+		for i := range indexes {
+			indexes[i] = i
+		}
+	}
+
+	entries := make([]*KeytabEntry, 0)
+	for _, i := range indexes {
+		entry := &k.Entries[i]
+		if filter.Kvno != 0 && entry.KVNO() != filter.Kvno {
+			continue
+		}
+		if filter.EncryptionType != 0 && entry.Key.Type != filter.EncryptionType {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// InvalidateIndex discards the index used by Lookup and Find, which is rebuilt on next use.
+// It must be called after adding, removing or renaming entries without the methods of Keytab,
+// and not concurrently with Lookup and Find.
+func (k *Keytab) InvalidateIndex() {
+	k.index = &keytabIndex{}
+}
+
+// principalEntries returns the indexes of the entries of a principal, building the index on first use.
+func (k *Keytab) principalEntries(principal string) []int {
+	if k.index == nil {
+		k.InvalidateIndex()
+	}
+	index := k.index
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if !index.built || index.ignoreRealmCase != k.IgnoreRealmCase {
+		index.ignoreRealmCase = k.IgnoreRealmCase
+		index.principals = make(map[string][]int)
+		index.names = make(map[string][]int)
+		for i := range k.Entries {
+			name, realm, _ := strings.Cut(k.Entries[i].Principal(), "@")
+			key := name + "@" + k.indexRealm(realm)
+			index.principals[key] = append(index.principals[key], i)
+			index.names[name] = append(index.names[name], i)
+		}
+		index.built = true
+	}
+
+	name, realm, hasRealm := strings.Cut(principal, "@")
+	if !hasRealm || len(realm) == 0 {
+		return index.names[name]
+	}
+	return index.principals[name+"@"+k.indexRealm(realm)]
+}
+
+// indexRealm returns the realm as used in the keys of the index.
+func (k *Keytab) indexRealm(realm string) string {
+	if k.IgnoreRealmCase {
+		return strings.ToUpper(realm)
+	}
+	return realm
+}
@@ -0,0 +1,68 @@
+package keytab
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Keytab_Lookup(t *testing.T) {
+	kt := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 1, 32),
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 2, 32),
+		newTestEntry("svc_web", EncryptionType_RC4_HMAC, 2, 16),
+		newTestEntry("svc_sql", EncryptionType_AES256_CTS_HMAC_SHA1_96, 4, 32),
+	)
+	kt.Entries[1].SetKVNO(258)
+
+	entry, err := kt.Lookup("svc_web@TESTSEGMENT.LOCAL", 0, EncryptionType_AES256_CTS_HMAC_SHA1_96)
+	if err != nil || entry != &kt.Entries[1] {
+		t.Errorf("Expected the highest kvno entry #1, got %v %v", entry, err)
+	}
+	if entry, err = kt.Lookup("svc_web", 2, 0); err != nil || entry != &kt.Entries[2] {
+		t.Errorf("Expected the kvno 2 entry #2, got %v %v", entry, err)
+	}
+	if entry, err = kt.Lookup("svc_sql", 260, 0); err != nil || entry != &kt.Entries[3] {
+		t.Errorf("Expected the truncated kvno 4 entry #3, got %v %v", entry, err)
+	}
+	if _, err = kt.Lookup("svc_web@TESTSEGMENT.LOCAL", 3, 0); !errors.Is(err, ErrKvnoNotFound) {
+		t.Errorf("Expected ErrKvnoNotFound, got %v", err)
+	}
+	if _, err = kt.Lookup("svc_web@testsegment.local", 0, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a realm of a different case, got %v", err)
+	}
+
+	kt.IgnoreRealmCase = true
+	if entry, err = kt.Lookup("svc_web@testsegment.local", 0, EncryptionType_RC4_HMAC); err != nil || entry != &kt.Entries[2] {
+		t.Errorf("Expected the rc4 entry #2 ignoring the realm case, got %v %v", entry, err)
+	}
+
+	kt.Entries = append(kt.Entries, newTestEntry("svc_dns", EncryptionType_AES128_CTS_HMAC_SHA1_96, 1, 16))
+	kt.InvalidateIndex()
+	if entry, err = kt.Lookup("svc_dns", 0, 0); err != nil || entry != &kt.Entries[4] {
+		t.Errorf("Expected the added entry #4, got %v %v", entry, err)
+	}
+}
+
+func Test_Keytab_Find(t *testing.T) {
+	kt := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 1, 32),
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 2, 32),
+		newTestEntry("svc_sql", EncryptionType_AES256_CTS_HMAC_SHA1_96, 2, 32),
+		newTestEntry("svc_web", EncryptionType_RC4_HMAC, 2, 16),
+	)
+
+	if entries := kt.Find(Filter{Principal: "svc_web@TESTSEGMENT.LOCAL"}); len(entries) != 3 || entries[2] != &kt.Entries[3] {
+		t.Errorf("Expected the 3 entries of svc_web, got %d", len(entries))
+	}
+	if entries := kt.Find(Filter{Kvno: 2, EncryptionType: EncryptionType_AES256_CTS_HMAC_SHA1_96}); len(entries) != 2 || entries[1] != &kt.Entries[2] {
+		t.Errorf("Expected the 2 aes256 entries of kvno 2, got %d", len(entries))
+	}
+	if entries := kt.Find(Filter{Principal: "svc_dns"}); len(entries) != 0 {
+		t.Errorf("Expected no entries of svc_dns, got %d", len(entries))
+	}
+
+	kt.DeleteKey("svc_web", 1)
+	if entries := kt.Find(Filter{Principal: "svc_web"}); len(entries) != 2 || entries[0] != &kt.Entries[0] {
+		t.Errorf("Expected the 2 remaining entries of svc_web, got %d", len(entries))
+	}
+}
@@ -0,0 +1,146 @@
+package keytab
+
+import (
+	"fmt"
+	"keytab/crypto"
+	"strings"
+	"time"
+)
+
+// DefaultSalt returns the default salt of a principal, its realm followed by its components,
+// as defined in RFC 4120 section 4.
+//
+// Parameters:
+//   - realm (string): The realm of the principal.
+//   - components ([]string): The components of the principal.
+//
+// Returns:
+//   - string: The salt.
+func DefaultSalt(realm string, components []string) string {
+	return realm + strings.Join(components, "")
+}
+
+// MachineSalt returns the salt of the keys of an Active Directory computer account, the
+// upper-case realm followed by "host", the lower-case host name and the lower-case realm, as in
+// "EXAMPLE.COMhostweb01.example.com".
+//
+// Parameters:
+//   - realm (string): The realm of the account.
+//   - hostname (string): The short host name of the computer, its sAMAccountName without the trailing $.
+//
+// Returns:
+//   - string: The salt.
+func MachineSalt(realm, hostname string) string {
+	return strings.ToUpper(realm) + "host" + strings.ToLower(strings.TrimSuffix(hostname, "$")) + "." + strings.ToLower(realm)
+}
+
+// CandidateSalts returns the salts the keys of the entry may have been derived with, the most
+// likely first. The default salt comes first, followed by the salt of an Active Directory
+// computer account for "NAME$" principals and for services of a host, such as "host/web01.example.com".
+//
+// Returns:
+//   - []string: The candidate salts.
+func (k *KeytabEntry) CandidateSalts() []string {
+	realm := string(k.Realm.Data)
+	components := make([]string, len(k.Components))
+	for i, component := range k.Components {
+		components[i] = string(component.Data)
+	}
+	salts := []string{DefaultSalt(realm, components)}
+	if len(components) == 1 && strings.HasSuffix(components[0], "$") {
+		salts = append(salts, MachineSalt(realm, components[0]))
+	} else if len(components) == 2 {
+		hostname, _, _ := strings.Cut(components[1], ".")
+		salts = append(salts, MachineSalt(realm, hostname))
+	}
+	return salts
+}
+
+// Rekey adds entries for the next key version number of a principal, holding the keys of a
+// new password in every encryption type the principal has at its current highest key version
+// number. The entries of the previous key versions are kept, so that tickets issued before the
+// change can still be decrypted. Encryption types without a string-to-key function are skipped.
+//
+// Parameters:
+//   - realm (string): The realm of the principal.
+//   - components ([]string): The components of the principal.
+//   - password (string): The new password.
+//   - salts (map[EncryptionType]string): The salt of each encryption type, as sent by the KDC in ETYPE-INFO2. DefaultSalt is used for missing ones.
+//
+// Returns:
+//   - (uint32, error): The new key version number and an error if no key could be derived.
+func (k *Keytab) Rekey(realm string, components []string, password string, salts map[EncryptionType]string) (uint32, error) {
+	var current uint32
+	var templates []*KeytabEntry
+	for i := range k.Entries {
+		entry := &k.Entries[i]
+		if !entry.matches(realm, components) {
+			continue
+		}
+		kvno := entry.KVNO()
+		if kvno > current {
+			current, templates = kvno, nil
+		}
+		if kvno == current {
+			templates = append(templates, entry)
+		}
+	}
+	if len(templates) == 0 {
+		return 0, fmt.Errorf("principal %s@%s is not in the keytab", strings.Join(components, "/"), realm)
+	}
+
+	kvno := current + 1
+	timestamp := uint32(time.Now().Unix())
+	added := make(map[EncryptionType]bool)
+	entries := make([]KeytabEntry, 0, len(templates))
+	for _, template := range templates {
+		etype := template.Key.Type
+		if added[etype] || !crypto.IsSupported(int32(etype)) {
+			continue
+		}
+		salt, ok := salts[etype]
+		if !ok {
+			salt = DefaultSalt(realm, components)
+		}
+		key, err := crypto.StringToKey(int32(etype), password, salt, nil)
+		if err != nil {
+			return 0, fmt.Errorf("error deriving the %s key: %w", etype.String(), err)
+		}
+		added[etype] = true
+
+		entry := KeytabEntry{
+			NumComponents: template.NumComponents,
+			Realm:         template.Realm,
+			Components:    template.Components,
+			NameType:      template.NameType,
+			Timestamp:     timestamp,
+			Key:           KeyBlock{Type: etype, Key: CountedOctetString{Length: uint16(len(key)), Data: key}},
+		}
+		entry.SetKVNO(kvno)
+		err = entry.UpdateSize()
+		if err != nil {
+			return 0, err
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return 0, fmt.Errorf("no key of %s@%s has an encryption type supporting passwords", strings.Join(components, "/"), realm)
+	}
+
+	k.Entries = append(k.Entries, entries...)
+	k.InvalidateIndex()
+	return kvno, nil
+}
+
+// matches returns true if the entry belongs to the principal.
+func (k *KeytabEntry) matches(realm string, components []string) bool {
+	if string(k.Realm.Data) != realm || len(k.Components) != len(components) {
+		return false
+	}
+	for i, component := range k.Components {
+		if string(component.Data) != components[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package keytab
+
+import (
+	"bytes"
+	"keytab/crypto"
+	"testing"
+)
+
+func Test_Keytab_Rekey(t *testing.T) {
+	kt := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 2, 32),
+		newTestEntry("svc_web", EncryptionType_AES128_CTS_HMAC_SHA1_96, 1, 16),
+		newTestEntry("svc_web", EncryptionType_RC4_HMAC, 2, 16),
+	)
+
+	kvno, err := kt.Rekey("TESTSEGMENT.LOCAL", []string{"svc_web"}, "password", map[EncryptionType]string{EncryptionType_AES256_CTS_HMAC_SHA1_96: "CUSTOMSALT"})
+	if err != nil {
+		t.Fatalf("Error rekeying: %v", err)
+	}
+	if kvno != 3 || len(kt.Entries) != 5 {
+		t.Fatalf("Expected 2 entries of kvno 3 to be added, got kvno %d and %d entries", kvno, len(kt.Entries))
+	}
+
+	aes256, _ := crypto.StringToKey(crypto.ETypeAES256CTSHMACSHA196, "password", "CUSTOMSALT", nil)
+	rc4, _ := crypto.StringToKey(crypto.ETypeRC4HMAC, "password", "", nil)
+	if added := kt.Entries[3]; added.Vno != 3 || added.Vno8 != 3 || !bytes.Equal(added.Key.Key.Data, aes256) {
+		t.Errorf("Unexpected aes256 entry of kvno %d/%d with key %x", added.Vno, added.Vno8, added.Key.Key.Data)
+	}
+	if added := kt.Entries[4]; added.Key.Type != EncryptionType_RC4_HMAC || !bytes.Equal(added.Key.Key.Data, rc4) {
+		t.Errorf("Unexpected rc4 entry %s with key %x", added.Key.Type.String(), added.Key.Key.Data)
+	}
+
+	if _, err = kt.Rekey("TESTSEGMENT.LOCAL", []string{"nobody"}, "password", nil); err == nil {
+		t.Errorf("Expected an error for a principal missing from the keytab")
+	}
+}
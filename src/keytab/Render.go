@@ -0,0 +1,180 @@
+package keytab
+
+import (
+	"encoding/hex"
+	"fmt"
+	"keytab/render"
+	"strings"
+	"time"
+)
+
+// KeytabView is the representation of a keytab in the JSON and YAML output formats.
+//
+// Attributes:
+//   - FileFormatVersion (uint16): The version of the keytab file format.
+//   - Entries ([]EntryView): The entries of the keytab.
+type KeytabView struct {
+	FileFormatVersion uint16      `json:"file_format_version"`
+	Entries           []EntryView `json:"entries"`
+}
+
+// EntryView is the representation of a keytab entry in the JSON and YAML output formats.
+//
+// Attributes:
+//   - Principal (string): The principal, as "name@REALM".
+//   - Realm (string): The realm of the principal.
+//   - Components ([]string): The components of the name of the principal.
+//   - NameType (uint32): The name type of the principal.
+//   - Timestamp (time.Time): The time the entry was written.
+//   - Kvno (uint32): The effective key version number.
+//   - Vno8 (uint8): The 8-bit key version number.
+//   - Vno (uint32): The 32-bit key version number, 0 if absent.
+//   - EncryptionType (string): The name of the encryption type of the key.
+//   - EncryptionTypeID (uint16): The number of the encryption type of the key.
+//   - KeyLength (int): The length of the key in bytes.
+//   - Fingerprint (string): The fingerprint of the key, see KeyBlock.Fingerprint.
+//   - Key (string): The key, in hexadecimal, only when keys are shown.
+type EntryView struct {
+	Principal        string    `json:"principal"`
+	Realm            string    `json:"realm"`
+	Components       []string  `json:"components"`
+	NameType         uint32    `json:"name_type"`
+	Timestamp        time.Time `json:"timestamp"`
+	Kvno             uint32    `json:"kvno"`
+	Vno8             uint8     `json:"vno8"`
+	Vno              uint32    `json:"vno"`
+	EncryptionType   string    `json:"enctype"`
+	EncryptionTypeID uint16    `json:"enctype_id"`
+	KeyLength        int       `json:"key_length"`
+	Fingerprint      string    `json:"fingerprint"`
+	Key              string    `json:"key,omitempty"`
+}
+
+// View returns the representation of the keytab in the JSON and YAML output formats.
+//
+// Parameters:
+//   - showKeys (bool): Whether the keys are included, instead of only their length and fingerprint.
+//
+// Returns:
+//   - KeytabView: The representation of the keytab.
+func (k *Keytab) View(showKeys bool) KeytabView {
+	view := KeytabView{FileFormatVersion: k.FileFormatVersion, Entries: make([]EntryView, 0, len(k.Entries))}
+	for i := range k.Entries {
+		view.Entries = append(view.Entries, k.Entries[i].View(showKeys))
+	}
+	return view
+}
+
+// View returns the representation of the entry in the JSON and YAML output formats.
+//
+// Parameters:
+//   - showKeys (bool): Whether the key is included, instead of only its length and fingerprint.
+//
+// Returns:
+//   - EntryView: The representation of the entry.
+func (k *KeytabEntry) View(showKeys bool) EntryView {
+	components := make([]string, 0, len(k.Components))
+	for _, component := range k.Components {
+		components = append(components, string(component.Data))
+	}
+	view := EntryView{
+		Principal:        k.Principal(),
+		Realm:            string(k.Realm.Data),
+		Components:       components,
+		NameType:         k.NameType,
+		Timestamp:        time.Unix(int64(k.Timestamp), 0).UTC(),
+		Kvno:             k.KVNO(),
+		Vno8:             k.Vno8,
+		Vno:              k.Vno,
+		EncryptionType:   k.Key.Type.String(),
+		EncryptionTypeID: uint16(k.Key.Type),
+		KeyLength:        len(k.Key.Key.Data),
+		Fingerprint:      k.Key.Fingerprint(),
+	}
+	if showKeys {
+		view.Key = hex.EncodeToString(k.Key.Key.Data)
+	}
+	return view
+}
+
+// KeyFingerprint identifies the key of a keytab entry without revealing it.
+//
+// Attributes:
+//   - Principal (string): The principal, as "name@REALM".
+//   - Kvno (uint32): The effective key version number.
+//   - EncryptionType (string): The name of the encryption type of the key.
+//   - Fingerprint (string): The fingerprint of the key, see KeyBlock.Fingerprint.
+type KeyFingerprint struct {
+	Principal      string `json:"principal"`
+	Kvno           uint32 `json:"kvno"`
+	EncryptionType string `json:"enctype"`
+	Fingerprint    string `json:"fingerprint"`
+}
+
+// Fingerprints returns the fingerprints of the keys of the entries.
+//
+// Returns:
+//   - []KeyFingerprint: The fingerprints, in the order of the entries.
+func (k *Keytab) Fingerprints() []KeyFingerprint {
+	fingerprints := make([]KeyFingerprint, 0, len(k.Entries))
+	for i := range k.Entries {
+		entry := &k.Entries[i]
+		fingerprints = append(fingerprints, KeyFingerprint{
+			Principal:      entry.Principal(),
+			Kvno:           entry.KVNO(),
+			EncryptionType: strings.ToLower(entry.Key.Type.String()),
+			Fingerprint:    entry.Key.Fingerprint(),
+		})
+	}
+	return fingerprints
+}
+
+// Render writes the keytab to a Renderer in an output format: the tree of Describe, a
+// compact table with a row per entry, or the KeytabView in JSON or YAML. Keys are masked
+// unless the ShowKeys of the renderer is set.
+//
+// Parameters:
+//   - r (*render.Renderer): The renderer the keytab is written to.
+//   - format (render.Format): The output format.
+//
+// Returns:
+//   - error: An error if the format is unknown or the keytab could not be encoded.
+func (k *Keytab) Render(r *render.Renderer, format render.Format) error {
+	switch format {
+	case render.Format_Tree:
+		k.DescribeTo(r, 0)
+	case render.Format_Table:
+		r.Table(tableHeaders, k.tableRows(r.ShowKeys))
+	case render.Format_JSON:
+		return r.JSON(k.View(r.ShowKeys))
+	case render.Format_YAML:
+		return r.YAML(k.View(r.ShowKeys))
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	return nil
+}
+
+// tableHeaders are the headers of the table output format and of the TXT and CSV exports.
+var tableHeaders = []string{"#", "PRINCIPAL", "KVNO", "ENCTYPE", "TIMESTAMP (UTC)", "KEY"}
+
+// tableRows returns the rows of the table output format and of the TXT and CSV exports.
+func (k *Keytab) tableRows(showKeys bool) [][]string {
+	rows := make([][]string, 0, len(k.Entries))
+	for i := range k.Entries {
+		entry := &k.Entries[i]
+		etype := entry.Key.Type.String()
+		if len(etype) == 0 {
+			etype = fmt.Sprintf("%d", entry.Key.Type)
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", i),
+			entry.Principal(),
+			fmt.Sprintf("%d", entry.KVNO()),
+			strings.ToLower(etype),
+			time.Unix(int64(entry.Timestamp), 0).UTC().Format("2006-01-02 15:04:05"),
+			entry.Key.KeyString(showKeys),
+		})
+	}
+	return rows
+}
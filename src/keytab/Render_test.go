@@ -0,0 +1,54 @@
+package keytab
+
+import (
+	"bytes"
+	"encoding/json"
+	"keytab/render"
+	"strings"
+	"testing"
+)
+
+func Test_Keytab_Render(t *testing.T) {
+	kt := newTestKeytab(newTestEntry("svc_web", EncryptionType_AES128_CTS_HMAC_SHA1_96, 2, 16))
+	buffer := &bytes.Buffer{}
+	r := render.NewRenderer(buffer, true)
+
+	if err := kt.Render(r, render.Format_Tree); err != nil {
+		t.Fatalf("Error rendering tree: %v", err)
+	}
+	if strings.Contains(buffer.String(), "\x1b[") || !strings.Contains(buffer.String(), " │  │ Realm         : TESTSEGMENT.LOCAL\n") {
+		t.Errorf("Unexpected tree:\n%s", buffer.String())
+	}
+	if strings.Contains(buffer.String(), strings.Repeat("00", 16)) || !strings.Contains(buffer.String(), "hidden, 16 bytes, fingerprint "+kt.Entries[0].Key.Fingerprint()) {
+		t.Errorf("Expected the key to be masked in the tree:\n%s", buffer.String())
+	}
+
+	buffer.Reset()
+	if err := kt.Render(r, render.Format_JSON); err != nil {
+		t.Fatalf("Error rendering JSON: %v", err)
+	}
+	view := KeytabView{}
+	if err := json.Unmarshal(buffer.Bytes(), &view); err != nil || len(view.Entries) != 1 {
+		t.Fatalf("Error decoding JSON output: %v", err)
+	}
+	if entry := view.Entries[0]; entry.Principal != "svc_web@TESTSEGMENT.LOCAL" || entry.Kvno != 2 || entry.EncryptionTypeID != 17 || entry.KeyLength != 16 || len(entry.Key) != 0 {
+		t.Errorf("Unexpected JSON entry %+v", entry)
+	}
+
+	buffer.Reset()
+	r.ShowKeys = true
+	if err := kt.Render(r, render.Format_YAML); err != nil {
+		t.Fatalf("Error rendering YAML: %v", err)
+	}
+	if !strings.Contains(buffer.String(), "    key: \""+strings.Repeat("00", 16)+"\"\n") {
+		t.Errorf("Expected the key to be shown with ShowKeys:\n%s", buffer.String())
+	}
+
+	buffer.Reset()
+	if err := kt.Render(r, render.Format_Table); err != nil {
+		t.Fatalf("Error rendering table: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buffer.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "0  svc_web@TESTSEGMENT.LOCAL  2     aes128-cts-hmac-sha1-96") {
+		t.Errorf("Unexpected table:\n%s", buffer.String())
+	}
+}
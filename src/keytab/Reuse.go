@@ -0,0 +1,176 @@
+package keytab
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Kinds of key reuse.
+const (
+	// Reuse_SharedPassword is an unsalted key, such as an RC4 key, shared by several principals, which therefore share their password.
+	Reuse_SharedPassword = "shared-password"
+	// Reuse_SharedSalt is a salted key, such as an AES key, shared by several principals, which share their password and salt.
+	Reuse_SharedSalt = "misconfigured-salt"
+	// Reuse_CopiedKeytab is a key of a single principal found in several keytab files.
+	Reuse_CopiedKeytab = "copied-keytab"
+)
+
+// reuseKindOrder orders the kinds of key reuse from the most to the least severe.
+var reuseKindOrder = map[string]int{
+	Reuse_SharedPassword: 0,
+	Reuse_SharedSalt:     1,
+	Reuse_CopiedKeytab:   2,
+}
+
+// unsaltedEncryptionTypes is a set of the encryption types whose keys do not depend on a salt.
+var unsaltedEncryptionTypes = map[EncryptionType]bool{
+	EncryptionType_NULL:                  true,
+	EncryptionType_RESERVED_OLD_RC4_HMAC: true,
+	EncryptionType_RC4_HMAC:              true,
+	EncryptionType_RC4_HMAC_EXP:          true,
+}
+
+// KeyLocation locates a key in a keytab file.
+//
+// Attributes:
+//   - Path (string): The path of the keytab file.
+//   - Entry (int): The index of the entry in the keytab.
+//   - Principal (string): The principal of the entry, as "name@REALM".
+//   - Kvno (uint32): The effective key version number of the entry.
+type KeyLocation struct {
+	Path      string `json:"path"`
+	Entry     int    `json:"entry"`
+	Principal string `json:"principal"`
+	Kvno      uint32 `json:"kvno"`
+}
+
+// ReuseGroup is a key found in several places.
+//
+// Attributes:
+//   - Kind (string): The kind of reuse, Reuse_SharedPassword, Reuse_SharedSalt or Reuse_CopiedKeytab.
+//   - EncryptionType (string): The name of the encryption type of the key.
+//   - Fingerprint (string): The fingerprint of the key.
+//   - Principals ([]string): The distinct principals holding the key, sorted.
+//   - Paths ([]string): The distinct keytab files holding the key, sorted.
+//   - Locations ([]KeyLocation): The entries holding the key, in the order they were added.
+//   - etype (EncryptionType): The encryption type of the key.
+type ReuseGroup struct {
+	Kind           string        `json:"kind"`
+	EncryptionType string        `json:"enctype"`
+	Fingerprint    string        `json:"fingerprint"`
+	Principals     []string      `json:"principals"`
+	Paths          []string      `json:"paths"`
+	Locations      []KeyLocation `json:"locations"`
+
+	etype EncryptionType
+}
+
+// String returns a one-line description of the ReuseGroup.
+//
+// Returns:
+//   - string: The kind, key and extent of the reuse.
+func (g ReuseGroup) String() string {
+	return fmt.Sprintf("[%s] %s key %s shared by %d principals in %d files", g.Kind, g.EncryptionType, g.Fingerprint, len(g.Principals), len(g.Paths))
+}
+
+// ReuseAuditor finds the keys shared by several principals or keytab files. Only digests of the
+// keys are kept, so that keytabs can be closed once added.
+//
+// Attributes:
+//   - groups (map[[32]byte]*ReuseGroup): The groups of locations of each key, by SHA-256 digest of its encryption type and value.
+//   - order ([][32]byte): The digests of the keys in the order they were first added.
+type ReuseAuditor struct {
+	groups map[[32]byte]*ReuseGroup
+	order  [][32]byte
+}
+
+// NewReuseAuditor creates an empty ReuseAuditor.
+//
+// Returns:
+//   - *ReuseAuditor: The auditor.
+func NewReuseAuditor() *ReuseAuditor {
+	return &ReuseAuditor{groups: make(map[[32]byte]*ReuseGroup)}
+}
+
+// Add records the keys of a keytab.
+//
+// Parameters:
+//   - path (string): The path of the keytab file.
+//   - kt (*Keytab): The keytab.
+func (a *ReuseAuditor) Add(path string, kt *Keytab) {
+	for i := range kt.Entries {
+		entry := &kt.Entries[i]
+		if len(entry.Key.Key.Data) == 0 {
+			continue
+		}
+
+		hash := sha256.New()
+		binary.Write(hash, binary.BigEndian, uint16(entry.Key.Type))
+		hash.Write(entry.Key.Key.Data)
+		var digest [32]byte
+		hash.Sum(digest[:0])
+
+		group, exists := a.groups[digest]
+		if !exists {
+			group = &ReuseGroup{EncryptionType: entry.Key.Type.String(), Fingerprint: entry.Key.Fingerprint(), etype: entry.Key.Type}
+			a.groups[digest] = group
+			a.order = append(a.order, digest)
+		}
+		group.Locations = append(group.Locations, KeyLocation{Path: path, Entry: i, Principal: entry.Principal(), Kvno: entry.KVNO()})
+	}
+}
+
+// Groups returns the keys shared by several principals, or by a single principal in several keytab
+// files. A key shared by principals is a shared password when it is not salted, and a misconfigured
+// salt otherwise, since the salts of distinct principals should differ. Keys repeated within a
+// single file for a single principal are left to Lint.
+//
+// Returns:
+//   - []ReuseGroup: The groups, from the most to the least severe kind, then by decreasing number of principals and files.
+func (a *ReuseAuditor) Groups() []ReuseGroup {
+	groups := []ReuseGroup{}
+	for _, digest := range a.order {
+		group := *a.groups[digest]
+		group.Principals = distinct(group.Locations, func(l KeyLocation) string { return l.Principal })
+		group.Paths = distinct(group.Locations, func(l KeyLocation) string { return l.Path })
+
+		switch {
+		case len(group.Principals) > 1 && unsaltedEncryptionTypes[group.etype]:
+			group.Kind = Reuse_SharedPassword
+		case len(group.Principals) > 1:
+			group.Kind = Reuse_SharedSalt
+		case len(group.Paths) > 1:
+			group.Kind = Reuse_CopiedKeytab
+		default:
+			continue
+		}
+		groups = append(groups, group)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Kind != groups[j].Kind {
+			return reuseKindOrder[groups[i].Kind] < reuseKindOrder[groups[j].Kind]
+		}
+		if len(groups[i].Principals) != len(groups[j].Principals) {
+			return len(groups[i].Principals) > len(groups[j].Principals)
+		}
+		return len(groups[i].Paths) > len(groups[j].Paths)
+	})
+	return groups
+}
+
+// distinct returns the sorted distinct values of a field of key locations.
+func distinct(locations []KeyLocation, field func(KeyLocation) string) []string {
+	seen := make(map[string]bool)
+	values := []string{}
+	for _, location := range locations {
+		if value := field(location); !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
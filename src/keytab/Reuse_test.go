@@ -0,0 +1,45 @@
+package keytab
+
+import (
+	"testing"
+)
+
+func Test_ReuseAuditor(t *testing.T) {
+	web := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16),
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 1, 32),
+		newTestEntry("svc_web", EncryptionType_AES128_CTS_HMAC_SHA1_96, 1, 16),
+	)
+	db := newTestKeytab(
+		newTestEntry("svc_db", EncryptionType_RC4_HMAC, 1, 16),
+		newTestEntry("svc_db", EncryptionType_AES256_CTS_HMAC_SHA1_96, 1, 32),
+	)
+	db.Entries[1].Key.Key.Data[0] = 0x01
+	web.Entries[2].Key.Key.Data[0] = 0x02
+
+	auditor := NewReuseAuditor()
+	auditor.Add("web.keytab", web)
+	auditor.Add("db.keytab", db)
+	auditor.Add("web-copy.keytab", web)
+
+	groups := auditor.Groups()
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d: %+v", len(groups), groups)
+	}
+	if groups[0].Kind != Reuse_SharedPassword || groups[0].EncryptionType != "RC4-HMAC" || len(groups[0].Principals) != 2 || len(groups[0].Locations) != 3 {
+		t.Errorf("Expected the shared rc4 key first, got %s", groups[0].String())
+	}
+	for _, group := range groups[1:] {
+		if group.Kind != Reuse_CopiedKeytab || len(group.Principals) != 1 || len(group.Paths) != 2 {
+			t.Errorf("Expected keys of svc_web copied in 2 files, got %s", group.String())
+		}
+	}
+
+	db.Entries[1].Key.Key.Data[0] = 0x00
+	auditor = NewReuseAuditor()
+	auditor.Add("web.keytab", web)
+	auditor.Add("db.keytab", db)
+	if groups = auditor.Groups(); len(groups) != 2 || groups[1].Kind != Reuse_SharedSalt {
+		t.Errorf("Expected a shared aes256 key with a misconfigured salt, got %+v", groups)
+	}
+}
@@ -0,0 +1,82 @@
+package keytab
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rotate adds the keys of a new password for the next key version number of a principal, in
+// every encryption type it currently has, then removes its key versions older than the
+// retention count. Keeping at least the previous version lets services accept tickets issued
+// before the rotation until they expire.
+//
+// Parameters:
+//   - principal (string): The principal, as "name@REALM". The realm of the first matching entry is used when absent.
+//   - password (string): The new password.
+//   - keep (int): The number of key versions to keep, including the new one. Zero or less keeps all of them.
+//   - salts (map[EncryptionType]string): The salt of each encryption type. DefaultSalt is used for missing ones.
+//
+// Returns:
+//   - (uint32, []uint32, error): The new key version number, the key version numbers removed and an error if the rotation failed.
+func (k *Keytab) Rotate(principal string, password string, keep int, salts map[EncryptionType]string) (uint32, []uint32, error) {
+	realm, components, err := k.resolvePrincipal(principal)
+	if err != nil {
+		return 0, nil, err
+	}
+	kvno, err := k.Rekey(realm, components, password, salts)
+	if err != nil {
+		return 0, nil, err
+	}
+	if keep <= 0 {
+		return kvno, nil, nil
+	}
+
+	versions := make([]uint32, 0)
+	seen := make(map[uint32]bool)
+	for i := range k.Entries {
+		entryKvno := k.Entries[i].KVNO()
+		if k.Entries[i].matches(realm, components) && !seen[entryKvno] {
+			seen[entryKvno] = true
+			versions = append(versions, entryKvno)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	if len(versions) <= keep {
+		return kvno, nil, nil
+	}
+	removed := versions[keep:]
+	kept := versions[:keep]
+
+	entries := make([]KeytabEntry, 0, len(k.Entries))
+	for _, entry := range k.Entries {
+		if entry.matches(realm, components) && entry.KVNO() < kept[len(kept)-1] {
+			entry.Wipe()
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	k.Entries = entries
+	k.InvalidateIndex()
+	return kvno, removed, nil
+}
+
+// resolvePrincipal splits a principal into its realm and components, taking the realm from
+// the first matching entry when absent.
+func (k *Keytab) resolvePrincipal(principal string) (string, []string, error) {
+	name, realm, _ := strings.Cut(principal, "@")
+	if len(name) == 0 {
+		return "", nil, fmt.Errorf("invalid principal %q", principal)
+	}
+	components := strings.Split(name, "/")
+	if len(realm) != 0 {
+		return realm, components, nil
+	}
+	for i := range k.Entries {
+		entryRealm := string(k.Entries[i].Realm.Data)
+		if k.Entries[i].matches(entryRealm, components) {
+			return entryRealm, components, nil
+		}
+	}
+	return "", nil, fmt.Errorf("principal %s is not in the keytab", principal)
+}
@@ -0,0 +1,35 @@
+package keytab
+
+import (
+	"testing"
+)
+
+func Test_Keytab_Rotate(t *testing.T) {
+	kt := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 1, 32),
+		newTestEntry("svc_sql", EncryptionType_AES256_CTS_HMAC_SHA1_96, 1, 32),
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 2, 32),
+		newTestEntry("svc_web", EncryptionType_RC4_HMAC, 2, 16),
+	)
+
+	kvno, removed, err := kt.Rotate("svc_web", "password", 2, nil)
+	if err != nil {
+		t.Fatalf("Error rotating: %v", err)
+	}
+	if kvno != 3 || len(removed) != 1 || removed[0] != 1 {
+		t.Errorf("Expected kvno 3 and kvno 1 removed, got kvno %d and %v", kvno, removed)
+	}
+	if len(kt.Entries) != 5 || kt.Entries[0].Principal() != "svc_sql@TESTSEGMENT.LOCAL" {
+		t.Fatalf("Expected svc_sql and 2 entries of kvno 2 and 3 of svc_web, got %d entries", len(kt.Entries))
+	}
+
+	// Without retention, all the versions are kept
+	kvno, removed, err = kt.Rotate("svc_web@TESTSEGMENT.LOCAL", "password", 0, nil)
+	if err != nil || kvno != 4 || len(removed) != 0 || len(kt.Entries) != 7 {
+		t.Errorf("Expected kvno 4 to be added without removal, got kvno %d, %v and %d entries (%v)", kvno, removed, len(kt.Entries), err)
+	}
+
+	if _, _, err = kt.Rotate("svc_web@OTHER.LOCAL", "password", 2, nil); err == nil {
+		t.Errorf("Expected an error for a principal of another realm")
+	}
+}
@@ -0,0 +1,129 @@
+package keytab
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultFileMode is the mode of the keytab files created by SaveToFile, which hold long-term keys.
+const DefaultFileMode = os.FileMode(0600)
+
+// SaveOptions are the options of SaveToFileWithOptions.
+//
+// Attributes:
+//   - FollowSymlinks (bool): Whether a symbolic link is resolved and its target replaced, instead of refusing to save.
+//   - Mode (os.FileMode): The mode of a new file. Defaults to DefaultFileMode. Existing files keep their mode.
+//   - Backups (int): The number of timestamped backups of the replaced file to keep, see CreateBackup. 0 disables backups.
+type SaveOptions struct {
+	FollowSymlinks bool
+	Mode           os.FileMode
+	Backups        int
+}
+
+// SaveToFileWithOptions saves the Keytab struct to a file without ever exposing a partially
+// written keytab. The keytab is written and synced to a temporary file in the same directory,
+// which gets the owner, mode and POSIX ACLs of the existing file, and is then renamed over it.
+//
+// While the file is replaced, an exclusive fcntl lock is held on the existing file, the lock
+// MIT Kerberos takes to read and write keytabs, so that concurrent readers and writers using
+// it never observe the replacement midway. On platforms without fcntl locks, the existing file
+// is only held open until it is replaced. When backups are enabled, the existing file is
+// copied to a backup while locked, before being replaced.
+//
+// Parameters:
+//   - path (string): The path to the file to save the Keytab struct to.
+//   - options (SaveOptions): The options of the save.
+//
+// Returns:
+//   - error: An error if the saving failed, in which case the existing file is unchanged.
+func (k *Keytab) SaveToFileWithOptions(path string, options SaveOptions) error {
+	data, err := k.ToBytes()
+	if err != nil {
+		return err
+	}
+	defer clear(data)
+	return writeFile(path, data, options)
+}
+
+// writeFile replaces a keytab file with data, as described in SaveToFileWithOptions.
+//
+// Parameters:
+//   - path (string): The path to the file.
+//   - data ([]byte): The content of the file.
+//   - options (SaveOptions): The options of the save.
+//
+// Returns:
+//   - error: An error if the writing failed, in which case the existing file is unchanged.
+func writeFile(path string, data []byte, options SaveOptions) error {
+	info, err := os.Lstat(path)
+	if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if !options.FollowSymlinks {
+			return fmt.Errorf("refusing to replace %s, which is a symbolic link", path)
+		}
+		path, err = filepath.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+		info, err = os.Lstat(path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var existing *os.File
+	if err == nil {
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("refusing to replace %s, which is not a regular file", path)
+		}
+		existing, info, err = lockFile(path)
+		if err != nil {
+			return fmt.Errorf("error locking %s: %v", path, err)
+		}
+		// Closing the file releases the lock, after the rename where the platform allows it
+		defer existing.Close()
+
+		if options.Backups > 0 {
+			_, err = backupFile(existing, info, path, options.Backups)
+			if err != nil {
+				return fmt.Errorf("error backing up %s: %v", path, err)
+			}
+		}
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		if existing != nil {
+			err = copyAttributes(existing, info, file)
+		} else if options.Mode != 0 {
+			err = file.Chmod(options.Mode.Perm())
+		} else {
+			err = file.Chmod(DefaultFileMode)
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		releaseBeforeRename(existing)
+	}
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return err
+	}
+	syncDirectory(filepath.Dir(path))
+	return nil
+}
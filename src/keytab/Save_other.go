@@ -0,0 +1,56 @@
+//go:build !unix
+
+package keytab
+
+import (
+	"os"
+)
+
+// lockFile opens an existing keytab file. fcntl locks are not available on this platform,
+// so the file is not locked.
+//
+// Parameters:
+//   - path (string): The path to the keytab file.
+//
+// Returns:
+//   - (*os.File, os.FileInfo, error): The file, its information, and an error if it could not be opened.
+func lockFile(path string) (*os.File, os.FileInfo, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// copyAttributes gives a file the mode of another one.
+//
+// Parameters:
+//   - source (*os.File): The file to copy the attributes of.
+//   - info (os.FileInfo): The information of the source file.
+//   - destination (*os.File): The file to copy the attributes to.
+//
+// Returns:
+//   - error: An error if an attribute could not be copied.
+func copyAttributes(source *os.File, info os.FileInfo, destination *os.File) error {
+	return destination.Chmod(info.Mode().Perm())
+}
+
+// syncDirectory does nothing, directories cannot be synced on this platform.
+//
+// Parameters:
+//   - path (string): The path to the directory.
+func syncDirectory(path string) {}
+
+// releaseBeforeRename closes an existing keytab file before it is replaced, as open files can
+// not be renamed over on this platform.
+//
+// Parameters:
+//   - file (*os.File): The existing file.
+func releaseBeforeRename(file *os.File) {
+	file.Close()
+}
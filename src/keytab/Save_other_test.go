@@ -0,0 +1,35 @@
+//go:build !unix
+
+package keytab
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_releaseBeforeRename(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.keytab")
+	if err := os.WriteFile(path, []byte{0x05, 0x02}, 0600); err != nil {
+		t.Fatalf("Error creating keytab: %v", err)
+	}
+	existing, _, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("Error locking keytab: %v", err)
+	}
+	releaseBeforeRename(existing)
+	if err = existing.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected the existing file to be closed before the rename, got %v", err)
+	}
+
+	kt := newTestKeytab(newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16))
+	for i := 0; i < 2; i++ {
+		if err = kt.SaveToFileWithOptions(path, SaveOptions{Backups: 1}); err != nil {
+			t.Fatalf("Error replacing keytab: %v", err)
+		}
+	}
+	if loaded, err := LoadKeytabFromFile(path); err != nil || !kt.Equal(loaded) {
+		t.Errorf("Keytab mismatch after replacing it (%v)", err)
+	}
+}
@@ -0,0 +1,72 @@
+package keytab
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Keytab_SaveToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.keytab")
+	err := os.WriteFile(path, []byte{0x05, 0x02}, 0600)
+	if err != nil {
+		t.Fatalf("Error creating keytab: %v", err)
+	}
+
+	kt1 := newTestKeytab()
+	if err = kt1.SaveToFile(path); err != nil {
+		t.Fatalf("Error saving keytab: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the mode 0600 of the existing file to be kept, got %v (%v)", info.Mode().Perm(), err)
+	}
+	files, _ := os.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("Expected the temporary file to be renamed, found %d files", len(files))
+	}
+
+	kt2, err := LoadKeytabFromFile(path)
+	if err != nil || !kt1.Equal(kt2) {
+		t.Errorf("Keytab mismatch after saving and loading (%v)", err)
+	}
+}
+
+func Test_Keytab_SaveToFileWithOptions(t *testing.T) {
+	directory := t.TempDir()
+	kt := newTestKeytab(newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16))
+
+	path := filepath.Join(directory, "new.keytab")
+	if err := kt.SaveToFile(path); err != nil {
+		t.Fatalf("Error saving keytab: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != DefaultFileMode {
+		t.Errorf("Expected a new file to have the mode %v, got %v (%v)", DefaultFileMode, info.Mode().Perm(), err)
+	}
+	other := filepath.Join(directory, "other.keytab")
+	if err := kt.SaveToFileWithOptions(other, SaveOptions{Mode: 0640}); err != nil {
+		t.Fatalf("Error saving keytab: %v", err)
+	}
+	if info, err := os.Stat(other); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Expected a new file to have the requested mode 0640, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	link := filepath.Join(directory, "link.keytab")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+	kt.Entries = append(kt.Entries, newTestEntry("svc_sql", EncryptionType_RC4_HMAC, 1, 16))
+	if err := kt.SaveToFile(link); err == nil {
+		t.Errorf("Expected an error saving to a symbolic link")
+	}
+	if err := kt.SaveToFileWithOptions(link, SaveOptions{FollowSymlinks: true}); err != nil {
+		t.Fatalf("Error saving keytab through a symbolic link: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the symbolic link to be kept (%v)", err)
+	}
+	saved, err := LoadKeytabFromFile(path)
+	if err != nil || len(saved.Entries) != 2 {
+		t.Errorf("Expected the target of the symbolic link to be replaced (%v)", err)
+	}
+}
@@ -0,0 +1,107 @@
+//go:build unix
+
+package keytab
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// maxLockAttempts is the number of times lockFile retries when the file is replaced while it waits for the lock.
+const maxLockAttempts = 10
+
+// lockFile opens an existing keytab file and takes an exclusive fcntl lock on all of it, as
+// krb5_lock_file of MIT Kerberos does, waiting for other readers and writers to release theirs.
+// As saves replace the file, the lock is retried on the new file if the path was renamed over
+// while waiting. The lock is released when the returned file is closed.
+//
+// Parameters:
+//   - path (string): The path to the keytab file.
+//
+// Returns:
+//   - (*os.File, os.FileInfo, error): The locked file, its information, and an error if it could not be locked.
+func lockFile(path string) (*os.File, os.FileInfo, error) {
+	for attempt := 0; attempt < maxLockAttempts; attempt++ {
+		file, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOFOLLOW, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lock := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart}
+		for {
+			err = syscall.FcntlFlock(file.Fd(), syscall.F_SETLKW, &lock)
+			if err != syscall.EINTR {
+				break
+			}
+		}
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		current, err := os.Lstat(path)
+		if err == nil && os.SameFile(info, current) {
+			return file, info, nil
+		}
+		file.Close()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, fmt.Errorf("the file kept being replaced while waiting for the lock")
+}
+
+// releaseBeforeRename does nothing, the lock on the existing file is held until it is replaced.
+//
+// Parameters:
+//   - file (*os.File): The existing file.
+func releaseBeforeRename(file *os.File) {}
+
+// copyAttributes gives a file the owner, mode and POSIX ACLs of another one.
+//
+// Parameters:
+//   - source (*os.File): The file to copy the attributes of.
+//   - info (os.FileInfo): The information of the source file.
+//   - destination (*os.File): The file to copy the attributes to.
+//
+// Returns:
+//   - error: An error if an attribute could not be copied.
+func copyAttributes(source *os.File, info os.FileInfo, destination *os.File) error {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		destinationInfo, err := destination.Stat()
+		if err != nil {
+			return err
+		}
+		destinationStat, ok := destinationInfo.Sys().(*syscall.Stat_t)
+		if !ok || destinationStat.Uid != stat.Uid || destinationStat.Gid != stat.Gid {
+			if err = destination.Chown(int(stat.Uid), int(stat.Gid)); err != nil {
+				return fmt.Errorf("error keeping the owner %d:%d of the file: %v", stat.Uid, stat.Gid, err)
+			}
+		}
+	}
+	if err := destination.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	return copyACL(source, destination)
+}
+
+// syncDirectory syncs a directory so that a rename in it is durable. Errors are ignored, as
+// some file systems do not support syncing directories and the rename already happened.
+//
+// Parameters:
+//   - path (string): The path to the directory.
+func syncDirectory(path string) {
+	directory, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	directory.Sync()
+	directory.Close()
+}
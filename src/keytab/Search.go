@@ -0,0 +1,118 @@
+package keytab
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SearchKey returns the entries holding a key. The keys are compared in constant time and all the
+// entries are compared, so that the time taken only depends on the number of entries and the
+// lengths of their keys.
+//
+// Parameters:
+//   - key ([]byte): The key to search for.
+//   - enctype (EncryptionType): The encryption type of the key, or 0 for any.
+//
+// Returns:
+//   - []*KeytabEntry: The matching entries, pointing into Entries.
+func (k *Keytab) SearchKey(key []byte, enctype EncryptionType) []*KeytabEntry {
+	matches := []*KeytabEntry{}
+	for i := range k.Entries {
+		entry := &k.Entries[i]
+		match := subtle.ConstantTimeCompare(entry.Key.Key.Data, key) == 1
+		if match && (enctype == EncryptionType_NULL || entry.Key.Type == enctype) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// SearchFingerprint returns the entries whose key has a fingerprint, computed with FingerprintPepper.
+// The fingerprints are compared in constant time, case insensitively.
+//
+// Parameters:
+//   - fingerprint (string): The hexadecimal fingerprint to search for.
+//   - enctype (EncryptionType): The encryption type of the key, or 0 for any.
+//
+// Returns:
+//   - ([]*KeytabEntry, error): The matching entries, pointing into Entries, and an error if the fingerprint is not hexadecimal.
+func (k *Keytab) SearchFingerprint(fingerprint string, enctype EncryptionType) ([]*KeytabEntry, error) {
+	wanted, err := hex.DecodeString(fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprint %q: %v", fingerprint, err)
+	}
+	matches := []*KeytabEntry{}
+	for i := range k.Entries {
+		entry := &k.Entries[i]
+		actual, _ := hex.DecodeString(entry.Key.Fingerprint())
+		match := subtle.ConstantTimeCompare(actual, wanted) == 1
+		if match && (enctype == EncryptionType_NULL || entry.Key.Type == enctype) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+// WalkKeytabs loads the keytab files given as paths and those found recursively in the given
+// directories, and calls a function with each of them. Files found in directories that do not
+// start with the keytab magic byte are skipped silently; other files that fail to load are passed
+// to the function with their error. Each keytab is closed, wiping its keys, when the function returns.
+//
+// Parameters:
+//   - paths ([]string): The paths of keytab files and directories.
+//   - walkFn (func(string, *Keytab, error) error): The function called with the path of each keytab and the keytab or an error. Returning an error stops the walk.
+//
+// Returns:
+//   - error: The error returned by walkFn or an error if a path can not be walked.
+func WalkKeytabs(paths []string, walkFn func(path string, kt *Keytab, err error) error) error {
+	visit := func(path string, explicit bool) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return walkFn(path, nil, err)
+		}
+		defer clear(data)
+		if !explicit && (len(data) == 0 || data[0] != 0x05) {
+			return nil
+		}
+		kt := &Keytab{}
+		err = kt.FromBytes(data)
+		if err != nil {
+			return walkFn(path, nil, err)
+		}
+		defer kt.Close()
+		return walkFn(path, kt, nil)
+	}
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			if err = walkFn(root, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if !info.IsDir() {
+			if err = visit(root, true); err != nil {
+				return err
+			}
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return walkFn(path, nil, err)
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			return visit(path, false)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
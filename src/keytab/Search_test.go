@@ -0,0 +1,48 @@
+package keytab
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Keytab_SearchKey(t *testing.T) {
+	kt := newTestKeytab(
+		newTestEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, 2, 32),
+		newTestEntry("svc_web", EncryptionType_RC4_HMAC, 2, 16),
+		newTestEntry("svc_db", EncryptionType_RC4_HMAC, 1, 16),
+	)
+	kt.Entries[2].Key.Key.Data[0] = 0x01
+
+	if matches := kt.SearchKey(make([]byte, 16), EncryptionType_NULL); len(matches) != 1 || matches[0] != &kt.Entries[1] {
+		t.Errorf("Expected entry #1 to match, got %d entries", len(matches))
+	}
+	if matches := kt.SearchKey(make([]byte, 32), EncryptionType_RC4_HMAC); len(matches) != 0 {
+		t.Errorf("Expected no match with another encryption type, got %d entries", len(matches))
+	}
+
+	matches, err := kt.SearchFingerprint("41A4E53626525347", EncryptionType_RC4_HMAC)
+	if err != nil || len(matches) != 1 || matches[0] != &kt.Entries[1] {
+		t.Errorf("Expected entry #1 to match the fingerprint, got %d entries (%v)", len(matches), err)
+	}
+	if _, err = kt.SearchFingerprint("not hex", EncryptionType_NULL); err == nil {
+		t.Errorf("Expected an error for an invalid fingerprint")
+	}
+
+	dir := t.TempDir()
+	if err = kt.SaveToFile(filepath.Join(dir, "svc.keytab")); err != nil {
+		t.Fatalf("Error saving keytab: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a keytab"), 0600)
+	found := []string{}
+	err = WalkKeytabs([]string{dir}, func(path string, kt *Keytab, err error) error {
+		if err != nil {
+			return err
+		}
+		found = append(found, filepath.Base(path))
+		return nil
+	})
+	if err != nil || len(found) != 1 || found[0] != "svc.keytab" {
+		t.Errorf("Expected to walk svc.keytab only, got %v (%v)", found, err)
+	}
+}
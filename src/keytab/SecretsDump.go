@@ -0,0 +1,119 @@
+package keytab
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"keytab/messages"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// secretsDumpSuffix matches the attributes appended to the lines by the -user-status and -pwd-last-set options of secretsdump.
+var secretsDumpSuffix = regexp.MustCompile(`(\s+\(\w+=[^)]*\))+$`)
+
+// secretsDumpHistory matches the accounts of the password history, dumped with the -history option of secretsdump.
+var secretsDumpHistory = regexp.MustCompile(`_history\d+$`)
+
+// ParseSecretsDump parses the keys printed by secretsdump or a DCSync, in the Kerberos key format
+// "DOMAIN\user:aes256-cts-hmac-sha1-96:<hex>" and the NTLM format "DOMAIN\user:rid:lmhash:nthash:::",
+// whose NT hash is the RC4-HMAC key. The krbtgt account becomes the krbtgt/REALM service principal,
+// other accounts NT-PRINCIPAL principals. Blank lines, log lines starting with "[" and comments are
+// ignored; lines that can not be imported, such as cleartext passwords and password history, are
+// skipped with a warning.
+//
+// Parameters:
+//   - reader (io.Reader): The output of secretsdump.
+//   - realm (string): The realm of the principals, or empty to use the upper-case domain of each line.
+//   - kvno (uint32): The key version number of the keys, which secretsdump does not print.
+//
+// Returns:
+//   - ([]KeytabEntry, []string, error): The entries, the warnings about skipped lines, and an error if the input can not be read or a line has no realm.
+func ParseSecretsDump(reader io.Reader, realm string, kvno uint32) ([]KeytabEntry, []string, error) {
+	entries := []KeytabEntry{}
+	warnings := []string{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = secretsDumpSuffix.ReplaceAllString(line, "")
+
+		fields := strings.Split(line, ":")
+		var etypeName, keyHex string
+		switch {
+		case len(fields) == 3:
+			// DOMAIN\user:aes256-cts-hmac-sha1-96:<hex>
+			etypeName, keyHex = fields[1], fields[2]
+		case len(fields) == 7 && fields[4] == "" && fields[5] == "" && fields[6] == "":
+			// DOMAIN\user:rid:lmhash:nthash:::
+			if _, err := strconv.ParseUint(fields[1], 10, 32); err != nil {
+				warnings = append(warnings, fmt.Sprintf("line %d: invalid RID %q", number, fields[1]))
+				continue
+			}
+			etypeName, keyHex = "rc4-hmac", fields[3]
+		case len(fields) == 6 && fields[3] == "" && fields[4] == "" && fields[5] == "":
+			// DOMAIN\WEB01$:lmhash:nthash::: from the LSA secrets
+			etypeName, keyHex = "rc4-hmac", fields[2]
+		default:
+			warnings = append(warnings, fmt.Sprintf("line %d: unknown format", number))
+			continue
+		}
+
+		domain, account, found := strings.Cut(fields[0], "\\")
+		if !found {
+			domain, account = "", fields[0]
+		}
+		if len(account) == 0 {
+			warnings = append(warnings, fmt.Sprintf("line %d: empty account name", number))
+			continue
+		}
+		if secretsDumpHistory.MatchString(account) {
+			warnings = append(warnings, fmt.Sprintf("line %d: skipping the password history of %s", number, account))
+			continue
+		}
+		etype, err := ParseEncryptionType(etypeName)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("line %d: skipping %s of %s", number, etypeName, account))
+			continue
+		}
+		key, err := hex.DecodeString(keyHex)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("line %d: invalid hexadecimal key: %v", number, err))
+			continue
+		}
+		if size, known := KeySizes[etype]; known && size != len(key) {
+			clear(key)
+			warnings = append(warnings, fmt.Sprintf("line %d: %s key of %d bytes instead of %d", number, etype.String(), len(key), size))
+			continue
+		}
+
+		entryRealm := strings.ToUpper(realm)
+		if len(entryRealm) == 0 {
+			entryRealm = strings.ToUpper(domain)
+		}
+		if len(entryRealm) == 0 {
+			clear(key)
+			return nil, nil, fmt.Errorf("line %d: no domain in the account name %q, the realm must be given", number, fields[0])
+		}
+		components, nameType := []string{account}, uint32(messages.NameType_PRINCIPAL)
+		if strings.EqualFold(account, "krbtgt") {
+			components, nameType = []string{"krbtgt", entryRealm}, messages.NameType_SRV_INST
+		}
+
+		entry, err := NewKeytabEntry(entryRealm, components, nameType, kvno, etype, key)
+		clear(key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", number, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return entries, warnings, nil
+}
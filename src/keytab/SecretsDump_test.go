@@ -0,0 +1,47 @@
+package keytab
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ParseSecretsDump(t *testing.T) {
+	dump := strings.Join([]string{
+		"[*] Dumping Domain Credentials (domain\\uid:rid:lmhash:nthash)",
+		"TESTSEGMENT\\krbtgt:502:aad3b435b51404eeaad3b435b51404ee:0123456789abcdef0123456789abcdef::: (status=Disabled)",
+		"TESTSEGMENT\\svc_web_history0:1104:aad3b435b51404eeaad3b435b51404ee:0123456789abcdef0123456789abcdef:::",
+		"svc_web:1104:aad3b435b51404eeaad3b435b51404ee:fedcba9876543210fedcba9876543210:::",
+		"TESTSEGMENT\\svc_web:aes256-cts-hmac-sha1-96:" + strings.Repeat("01", 32),
+		"TESTSEGMENT\\WEB01$:plain_password_hex:4100",
+		"TESTSEGMENT\\svc_db:aes128-cts-hmac-sha1-96:0102",
+	}, "\n")
+
+	entries, warnings, err := ParseSecretsDump(strings.NewReader(dump), "testsegment.local", 3)
+	if err != nil {
+		t.Fatalf("Error parsing secretsdump output: %v", err)
+	}
+	if len(entries) != 3 || len(warnings) != 3 {
+		t.Fatalf("Expected 3 entries and 3 warnings, got %d and %v", len(entries), warnings)
+	}
+	expected := []struct {
+		principal string
+		nameType  uint32
+		etype     EncryptionType
+	}{
+		{"krbtgt/TESTSEGMENT.LOCAL@TESTSEGMENT.LOCAL", 2, EncryptionType_RC4_HMAC},
+		{"svc_web@TESTSEGMENT.LOCAL", 1, EncryptionType_RC4_HMAC},
+		{"svc_web@TESTSEGMENT.LOCAL", 1, EncryptionType_AES256_CTS_HMAC_SHA1_96},
+	}
+	for i, entry := range entries {
+		if entry.Principal() != expected[i].principal || entry.NameType != expected[i].nameType || entry.Key.Type != expected[i].etype || entry.KVNO() != 3 {
+			t.Errorf("Expected %s (%d, %s, kvno 3), got %s (%d, %s, kvno %d)", expected[i].principal, expected[i].nameType, expected[i].etype, entry.Principal(), entry.NameType, entry.Key.Type, entry.KVNO())
+		}
+	}
+	if entries[1].Key.Key.Data[0] != 0xfe {
+		t.Errorf("Expected the NT hash as RC4 key, got %x", entries[1].Key.Key.Data)
+	}
+
+	if _, _, err = ParseSecretsDump(strings.NewReader(dump), "", 3); err == nil {
+		t.Errorf("Expected an error for an account without domain nor realm")
+	}
+}
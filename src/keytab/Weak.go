@@ -0,0 +1,438 @@
+package keytab
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"keytab/crypto"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultPasswords are common default passwords, tested by AuditWeak before the wordlist.
+var DefaultPasswords = []string{
+	"password", "Password1", "Password123", "P@ssw0rd", "P@ssw0rd123", "changeme", "changeit",
+	"welcome", "Welcome1", "Welcome123", "letmein", "secret", "admin", "Admin123", "123456", "12345678", "qwerty",
+}
+
+// Sources of the passwords found by AuditWeak.
+const (
+	WeakSource_Default   = "default"
+	WeakSource_Principal = "principal"
+	WeakSource_Wordlist  = "wordlist"
+)
+
+// Phases of AuditWeak. The wordlist is tested against the cheap unsalted keys first, then against
+// the salted keys of the principals whose password was not found.
+const (
+	WeakPhase_Unsalted = 1
+	WeakPhase_Salted   = 2
+	WeakPhase_Done     = 3
+)
+
+// weakChunkSize is the number of candidate passwords tested by each worker between checkpoints.
+const weakChunkSize = 256
+
+// WeakKey is a key derived from a password found by AuditWeak.
+//
+// Attributes:
+//   - Entry (int): The index of the entry in the keytab.
+//   - Principal (string): The principal of the entry, as "name@REALM".
+//   - Kvno (uint32): The effective key version number of the entry.
+//   - EncryptionType (string): The name of the encryption type of the key.
+//   - Salt (string): The salt the key was derived with, empty for unsalted encryption types.
+//   - Password (string): The password.
+//   - Source (string): Where the password comes from, WeakSource_Default, WeakSource_Principal or WeakSource_Wordlist.
+type WeakKey struct {
+	Entry          int    `json:"entry"`
+	Principal      string `json:"principal"`
+	Kvno           uint32 `json:"kvno"`
+	EncryptionType string `json:"enctype"`
+	Salt           string `json:"salt,omitempty"`
+	Password       string `json:"password"`
+	Source         string `json:"source"`
+}
+
+// WeakAuditState is the progress of AuditWeak, which can be saved to resume it later.
+//
+// Attributes:
+//   - Wordlist (string): The path of the wordlist.
+//   - Lines (int64): The number of lines of the wordlist.
+//   - Phase (int): The current phase, WeakPhase_Unsalted, WeakPhase_Salted or WeakPhase_Done.
+//   - Line (int64): The number of lines of the wordlist tested in the current phase.
+//   - Found ([]WeakKey): The keys whose password was found.
+type WeakAuditState struct {
+	Wordlist string    `json:"wordlist"`
+	Lines    int64     `json:"lines"`
+	Phase    int       `json:"phase"`
+	Line     int64     `json:"line"`
+	Found    []WeakKey `json:"found"`
+}
+
+// WeakAuditOptions are the options of AuditWeak.
+//
+// Attributes:
+//   - Workers (int): The number of passwords tested in parallel, or 0 for the number of CPUs.
+//   - Salt (string): The salt of the salted keys, instead of the candidate salts of each entry.
+//   - State (*WeakAuditState): The state of a previous run to resume, or nil to start over.
+//   - Progress (func(WeakAuditState)): A function called with the state after each batch of passwords, to report or save it.
+type WeakAuditOptions struct {
+	Workers  int
+	Salt     string
+	State    *WeakAuditState
+	Progress func(state WeakAuditState)
+}
+
+// weakCandidate is a password tested by AuditWeak.
+//
+// Attributes:
+//   - password (string): The password.
+//   - source (string): Where the password comes from.
+//   - principal (string): The only principal the password is tested against, or empty for all.
+type weakCandidate struct {
+	password  string
+	source    string
+	principal string
+}
+
+// weakTarget is a set of keys derived with the same encryption type and salt, so that each candidate
+// password is derived once for all of them.
+//
+// Attributes:
+//   - etype (EncryptionType): The encryption type of the keys.
+//   - salt (string): The salt of the keys, empty for unsalted encryption types.
+//   - entries ([]int): The indexes of the entries holding the keys.
+type weakTarget struct {
+	etype   EncryptionType
+	salt    string
+	entries []int
+}
+
+// AuditWeak finds the keys derived from default passwords, from passwords derived from the name of
+// their principal and from the passwords of a wordlist. The salted keys are tested with each of the
+// candidate salts of their entry. Keys whose encryption type has no string-to-key function are skipped.
+//
+// The wordlist is first tested against the unsalted keys, such as RC4 keys, which are cheap to
+// derive, then against the salted keys still unknown. Every password found is also tested against
+// all the other keys. The passwords are tested in parallel, and the state is reported after each
+// batch of passwords so that an interrupted audit can be resumed.
+//
+// Parameters:
+//   - wordlist (string): The path of the wordlist, holding one password per line.
+//   - options (WeakAuditOptions): The options of the audit.
+//
+// Returns:
+//   - ([]WeakKey, error): The keys whose password was found, by entry, and an error if the wordlist can not be read or the state does not match.
+func (k *Keytab) AuditWeak(wordlist string, options WeakAuditOptions) ([]WeakKey, error) {
+	if options.Workers <= 0 {
+		options.Workers = runtime.NumCPU()
+	}
+	lines, err := countLines(wordlist)
+	if err != nil {
+		return nil, err
+	}
+
+	state := WeakAuditState{Wordlist: wordlist, Lines: lines, Phase: WeakPhase_Unsalted}
+	if options.State != nil {
+		state = *options.State
+		state.Found = append([]WeakKey{}, options.State.Found...)
+		state.Wordlist = wordlist
+		if state.Lines != lines {
+			return nil, fmt.Errorf("the state was saved for a wordlist of %d lines, %s has %d", state.Lines, wordlist, lines)
+		}
+	}
+
+	pending := make(map[int]bool)
+	for i := range k.Entries {
+		if crypto.IsSupported(int32(k.Entries[i].Key.Type)) && len(k.Entries[i].Key.Key.Data) != 0 {
+			pending[i] = true
+		}
+	}
+	for _, found := range state.Found {
+		if found.Entry >= len(k.Entries) || k.Entries[found.Entry].Principal() != found.Principal || k.Entries[found.Entry].Key.Type.String() != found.EncryptionType {
+			return nil, fmt.Errorf("the state does not match the keytab at entry #%d", found.Entry)
+		}
+		delete(pending, found.Entry)
+	}
+
+	// Default passwords, passwords derived from the principals and passwords already found
+	candidates := []weakCandidate{}
+	for _, password := range DefaultPasswords {
+		candidates = append(candidates, weakCandidate{password: password, source: WeakSource_Default})
+	}
+	candidates = append(candidates, k.principalCandidates()...)
+	for _, found := range state.Found {
+		candidates = append(candidates, weakCandidate{password: found.Password, source: found.Source})
+	}
+	k.testCandidates(candidates, pending, &state, options, true, true)
+
+	for ; state.Phase < WeakPhase_Done; state.Phase, state.Line = state.Phase+1, 0 {
+		unsalted, salted := state.Phase == WeakPhase_Unsalted, state.Phase == WeakPhase_Salted
+		if len(k.weakTargets(pending, unsalted, salted, options.Salt)) != 0 {
+			err = k.testWordlist(pending, &state, options, unsalted, salted)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if options.Progress != nil {
+			options.Progress(state)
+		}
+	}
+
+	found := append([]WeakKey{}, state.Found...)
+	sort.Slice(found, func(i, j int) bool { return found[i].Entry < found[j].Entry })
+	return found, nil
+}
+
+// LoadWeakAuditState loads the state of an AuditWeak run from a JSON file.
+//
+// Parameters:
+//   - path (string): The path to the state file.
+//
+// Returns:
+//   - (*WeakAuditState, error): The state and an error if the file could not be read or decoded.
+func LoadWeakAuditState(path string) (*WeakAuditState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(data)
+
+	state := &WeakAuditState{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding state file %s: %v", path, err)
+	}
+	return state, nil
+}
+
+// SaveToFile saves the state to a JSON file, replaced atomically and only readable by its owner
+// when created, since it holds the passwords found.
+//
+// Parameters:
+//   - path (string): The path to the state file.
+//
+// Returns:
+//   - error: An error if the file could not be written.
+func (s WeakAuditState) SaveToFile(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	defer clear(data)
+	return writeFile(path, data, SaveOptions{})
+}
+
+// testWordlist tests the passwords of the wordlist from the line of the state, by batches.
+func (k *Keytab) testWordlist(pending map[int]bool, state *WeakAuditState, options WeakAuditOptions, unsalted, salted bool) error {
+	file, err := os.Open(state.Wordlist)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	chunk := make([]weakCandidate, 0, weakChunkSize*options.Workers)
+	line := int64(0)
+	for scanner.Scan() {
+		line++
+		if line <= state.Line {
+			continue
+		}
+		chunk = append(chunk, weakCandidate{password: strings.TrimSuffix(scanner.Text(), "\r"), source: WeakSource_Wordlist})
+		if len(chunk) == cap(chunk) {
+			k.testCandidates(chunk, pending, state, options, unsalted, salted)
+			state.Line = line
+			if options.Progress != nil {
+				options.Progress(*state)
+			}
+			chunk = chunk[:0]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("error reading the wordlist at line %d: %v", line+1, err)
+	}
+	k.testCandidates(chunk, pending, state, options, unsalted, salted)
+	state.Line = line
+	return nil
+}
+
+// testCandidates tests passwords in parallel against the pending keys of the given kinds, and
+// records the keys found in the state. The passwords found are then tested against all the keys.
+func (k *Keytab) testCandidates(candidates []weakCandidate, pending map[int]bool, state *WeakAuditState, options WeakAuditOptions, unsalted, salted bool) {
+	for len(candidates) != 0 {
+		targets := k.weakTargets(pending, unsalted, salted, options.Salt)
+		if len(targets) == 0 {
+			return
+		}
+
+		var mutex sync.Mutex
+		var wg sync.WaitGroup
+		found := []WeakKey{}
+		for worker := 0; worker < options.Workers; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				for c := worker; c < len(candidates); c += options.Workers {
+					for _, target := range targets {
+						matches := k.testCandidate(candidates[c], target)
+						if len(matches) != 0 {
+							mutex.Lock()
+							found = append(found, matches...)
+							mutex.Unlock()
+						}
+					}
+				}
+			}(worker)
+		}
+		wg.Wait()
+
+		candidates = nil
+		for _, key := range found {
+			if !pending[key.Entry] {
+				continue
+			}
+			delete(pending, key.Entry)
+			state.Found = append(state.Found, key)
+			candidates = append(candidates, weakCandidate{password: key.Password, source: key.Source})
+		}
+		unsalted, salted = true, true
+	}
+}
+
+// testCandidate derives the key of a password for a target and returns the entries holding it.
+func (k *Keytab) testCandidate(candidate weakCandidate, target *weakTarget) []WeakKey {
+	entries := target.entries
+	if len(candidate.principal) != 0 {
+		entries = []int{}
+		for _, i := range target.entries {
+			if k.Entries[i].Principal() == candidate.principal {
+				entries = append(entries, i)
+			}
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+	}
+
+	key, err := crypto.StringToKey(int32(target.etype), candidate.password, target.salt, nil)
+	if err != nil {
+		return nil
+	}
+	defer clear(key)
+
+	matches := []WeakKey{}
+	for _, i := range entries {
+		entry := &k.Entries[i]
+		if subtle.ConstantTimeCompare(entry.Key.Key.Data, key) == 1 {
+			matches = append(matches, WeakKey{
+				Entry:          i,
+				Principal:      entry.Principal(),
+				Kvno:           entry.KVNO(),
+				EncryptionType: entry.Key.Type.String(),
+				Salt:           target.salt,
+				Password:       candidate.password,
+				Source:         candidate.source,
+			})
+		}
+	}
+	return matches
+}
+
+// weakTargets groups the pending entries of the given kinds by encryption type and salt.
+func (k *Keytab) weakTargets(pending map[int]bool, unsalted, salted bool, salt string) []*weakTarget {
+	targets := []*weakTarget{}
+	index := make(map[string]*weakTarget)
+	add := func(etype EncryptionType, salt string, entry int) {
+		id := fmt.Sprintf("%d/%s", etype, salt)
+		target, exists := index[id]
+		if !exists {
+			target = &weakTarget{etype: etype, salt: salt}
+			index[id] = target
+			targets = append(targets, target)
+		}
+		target.entries = append(target.entries, entry)
+	}
+
+	for i := range k.Entries {
+		if !pending[i] {
+			continue
+		}
+		etype := k.Entries[i].Key.Type
+		if unsaltedEncryptionTypes[etype] {
+			if unsalted {
+				add(etype, "", i)
+			}
+		} else if salted && len(salt) != 0 {
+			add(etype, salt, i)
+		} else if salted {
+			for _, candidateSalt := range k.Entries[i].CandidateSalts() {
+				add(etype, candidateSalt, i)
+			}
+		}
+	}
+	return targets
+}
+
+// principalCandidates returns passwords derived from the first component of each principal,
+// tested against the keys of that principal only.
+func (k *Keytab) principalCandidates() []weakCandidate {
+	candidates := []weakCandidate{}
+	seen := make(map[string]bool)
+	for i := range k.Entries {
+		entry := &k.Entries[i]
+		if len(entry.Components) == 0 {
+			continue
+		}
+		name := strings.TrimSuffix(string(entry.Components[0].Data), "$")
+		if len(name) == 0 {
+			continue
+		}
+		lower := strings.ToLower(name)
+		for _, password := range []string{name, lower, strings.ToUpper(lower[:1]) + lower[1:], lower + "123", lower + "!"} {
+			id := entry.Principal() + "\x00" + password
+			if !seen[id] {
+				seen[id] = true
+				candidates = append(candidates, weakCandidate{password: password, source: WeakSource_Principal, principal: entry.Principal()})
+			}
+		}
+	}
+	return candidates
+}
+
+// countLines returns the number of lines of a file, counting a last line without a line feed.
+func countLines(path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	lines := int64(0)
+	last := byte('\n')
+	buffer := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buffer)
+		if n > 0 {
+			lines += int64(bytes.Count(buffer[:n], []byte{'\n'}))
+			last = buffer[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
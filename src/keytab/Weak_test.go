@@ -0,0 +1,73 @@
+package keytab
+
+import (
+	"keytab/crypto"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Keytab_AuditWeak(t *testing.T) {
+	newEntry := func(name string, etype EncryptionType, password, salt string) KeytabEntry {
+		entry := newTestEntry(name, etype, 1, 0)
+		key, err := crypto.StringToKey(int32(etype), password, salt, nil)
+		if err != nil {
+			t.Fatalf("Error deriving key: %v", err)
+		}
+		entry.Key.Key = CountedOctetString{Length: uint16(len(key)), Data: key}
+		return entry
+	}
+	kt := newTestKeytab(
+		newEntry("svc_web", EncryptionType_AES256_CTS_HMAC_SHA1_96, "Summer2024", "TESTSEGMENT.LOCALsvc_web"),
+		newEntry("svc_web", EncryptionType_RC4_HMAC, "Summer2024", ""),
+		newEntry("svc_db", EncryptionType_AES128_CTS_HMAC_SHA1_96, "svc_db123", "TESTSEGMENT.LOCALsvc_db"),
+		newEntry("web01$", EncryptionType_AES256_CTS_HMAC_SHA1_96, "Welcome1", MachineSalt("TESTSEGMENT.LOCAL", "web01")),
+		newEntry("svc_app", EncryptionType_AES256_CTS_HMAC_SHA1_96, "Autumn2024", "TESTSEGMENT.LOCALsvc_app"),
+		newEntry("svc_app", EncryptionType_RC4_HMAC, "not in the wordlist", ""),
+	)
+	wordlist := filepath.Join(t.TempDir(), "wordlist.txt")
+	os.WriteFile(wordlist, []byte("123\r\nWinter2023\nAutumn2024\nSummer2024"), 0600)
+
+	states := []WeakAuditState{}
+	found, err := kt.AuditWeak(wordlist, WeakAuditOptions{Workers: 2, Progress: func(state WeakAuditState) { states = append(states, state) }})
+	if err != nil {
+		t.Fatalf("Error auditing keytab: %v", err)
+	}
+	expected := []struct {
+		entry    int
+		password string
+		source   string
+	}{
+		{0, "Summer2024", WeakSource_Wordlist},
+		{1, "Summer2024", WeakSource_Wordlist},
+		{2, "svc_db123", WeakSource_Principal},
+		{3, "Welcome1", WeakSource_Default},
+		{4, "Autumn2024", WeakSource_Wordlist},
+	}
+	if len(found) != len(expected) {
+		t.Fatalf("Expected %d weak keys, got %+v", len(expected), found)
+	}
+	for i, key := range found {
+		if key.Entry != expected[i].entry || key.Password != expected[i].password || key.Source != expected[i].source {
+			t.Errorf("Expected entry #%d with %q from %s, got %+v", expected[i].entry, expected[i].password, expected[i].source, key)
+		}
+	}
+	if found[1].Salt != "" || found[3].Salt != "TESTSEGMENT.LOCALhostweb01.testsegment.local" {
+		t.Errorf("Unexpected salts %q and %q", found[1].Salt, found[3].Salt)
+	}
+	last := states[len(states)-1]
+	if last.Phase != WeakPhase_Salted || last.Lines != 4 || last.Line != 4 {
+		t.Errorf("Unexpected last state %+v", last)
+	}
+
+	// Resuming a finished audit returns its results without reading the wordlist again
+	last.Phase = WeakPhase_Done
+	resumed, err := kt.AuditWeak(wordlist, WeakAuditOptions{State: &last})
+	if err != nil || len(resumed) != len(expected) {
+		t.Errorf("Expected %d weak keys when resuming, got %d (%v)", len(expected), len(resumed), err)
+	}
+	last.Lines = 5
+	if _, err = kt.AuditWeak(wordlist, WeakAuditOptions{State: &last}); err == nil {
+		t.Errorf("Expected an error when resuming with another wordlist")
+	}
+}
@@ -0,0 +1,36 @@
+package keytab
+
+// KeepRawBytes makes FromBytes keep the RawBytes of the parsed structures, for debugging.
+// They alias the parsed buffer, keys included, which is then not wiped by LoadKeytabFromFile,
+// so they are left nil by default.
+var KeepRawBytes = false
+
+// Wipe overwrites the key with zeros. The key cannot be used afterwards.
+func (k *KeyBlock) Wipe() {
+	clear(k.Key.Data)
+	clear(k.Key.RawBytes)
+	clear(k.RawBytes)
+}
+
+// Wipe overwrites the key of the entry and its raw bytes with zeros.
+func (k *KeytabEntry) Wipe() {
+	k.Key.Wipe()
+	clear(k.RawBytes)
+}
+
+// Close wipes the keys of all the entries and the raw bytes of the keytab, and removes the
+// entries. The keytab is empty afterwards.
+//
+// Returns:
+//   - error: Always nil, Close implements io.Closer.
+func (k *Keytab) Close() error {
+	for i := range k.Entries {
+		k.Entries[i].Wipe()
+	}
+	clear(k.RawBytes)
+	k.Entries = nil
+	k.RawBytes = nil
+	k.RawBytesSize = 0
+	k.InvalidateIndex()
+	return nil
+}
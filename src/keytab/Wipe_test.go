@@ -0,0 +1,37 @@
+package keytab
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_Keytab_Close(t *testing.T) {
+	kt1 := newTestKeytab(newTestEntry("svc_web", EncryptionType_RC4_HMAC, 1, 16))
+	copy(kt1.Entries[0].Key.Key.Data, bytes.Repeat([]byte{0xab}, 16))
+	data, err := kt1.ToBytes()
+	if err != nil {
+		t.Fatalf("Error converting keytab to bytes: %v", err)
+	}
+
+	kt2 := &Keytab{}
+	if err = kt2.FromBytes(data); err != nil {
+		t.Fatalf("Error converting bytes to keytab: %v", err)
+	}
+	clear(data)
+	key := kt2.Entries[0].Key.Key.Data
+	if !bytes.Equal(key, bytes.Repeat([]byte{0xab}, 16)) || kt2.RawBytes != nil || kt2.Entries[0].Key.Key.RawBytes != nil {
+		t.Errorf("Expected the key to be copied out of the parsed buffer, and no raw bytes to be kept")
+	}
+
+	kt2.Close()
+	if !bytes.Equal(key, make([]byte, 16)) || len(kt2.Entries) != 0 {
+		t.Errorf("Expected the key to be wiped and the entries removed, got %x", key)
+	}
+
+	KeepRawBytes = true
+	defer func() { KeepRawBytes = false }()
+	data, _ = kt1.ToBytes()
+	if err = kt2.FromBytes(data); err != nil || len(kt2.RawBytes) != len(data) || len(kt2.Entries[0].RawBytes) != len(data)-2 {
+		t.Errorf("Expected the raw bytes to be kept with KeepRawBytes (%v)", err)
+	}
+}
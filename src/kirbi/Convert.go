@@ -0,0 +1,153 @@
+package kirbi
+
+import (
+	"fmt"
+	"keytab/ccache"
+	"keytab/keytab"
+	"keytab/messages"
+	"time"
+)
+
+// ToCCache converts a Kirbi into a version 0x0504 ccache holding one credential per ticket.
+// The enc-part of the Kirbi must have been decrypted.
+//
+// Returns:
+//   - (*ccache.CCache, error): The ccache and an error if the conversion failed.
+func (k *Kirbi) ToCCache() (*ccache.CCache, error) {
+	if !k.Decrypted {
+		return nil, fmt.Errorf("the enc-part of the kirbi is encrypted, a keytab holding the service key is needed")
+	}
+	if len(k.EncPart.TicketInfo) != len(k.Cred.Tickets) {
+		return nil, fmt.Errorf("the kirbi holds %d tickets but %d ticket infos", len(k.Cred.Tickets), len(k.EncPart.TicketInfo))
+	}
+
+	cc := &ccache.CCache{
+		FileFormatVersion: ccache.FileFormatVersion4,
+		Credentials:       make([]ccache.Credential, 0, len(k.Cred.Tickets)),
+	}
+
+	for i := range k.Cred.Tickets {
+		ticket := &k.Cred.Tickets[i]
+		info := &k.EncPart.TicketInfo[i]
+
+		ticketBytes, err := ticket.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		serverName, serverRealm := info.SName, info.SRealm
+		if len(serverName.NameString) == 0 {
+			serverName = ticket.SName
+		}
+		if len(serverRealm) == 0 {
+			serverRealm = ticket.Realm
+		}
+
+		credential := ccache.Credential{
+			Client: ccache.NewPrincipal(uint32(info.PName.NameType), info.PRealm, info.PName.NameString),
+			Server: ccache.NewPrincipal(uint32(serverName.NameType), serverRealm, serverName.NameString),
+			Key: ccache.KeyBlock{
+				Type: keytab.EncryptionType(info.Key.KeyType),
+				Key:  ccache.NewCountedOctetString(info.Key.KeyValue),
+			},
+			AuthTime:    toTimestamp(info.AuthTime),
+			StartTime:   toTimestamp(info.StartTime),
+			EndTime:     toTimestamp(info.EndTime),
+			RenewTill:   toTimestamp(info.RenewTill),
+			TicketFlags: info.Flags,
+			Addresses:   make([]ccache.Address, 0),
+			AuthData:    make([]ccache.AuthData, 0),
+			Ticket:      ccache.NewCountedOctetString(ticketBytes),
+		}
+		for _, address := range info.CAddr {
+			credential.Addresses = append(credential.Addresses, ccache.Address{
+				AddrType: uint16(address.AddrType),
+				Data:     ccache.NewCountedOctetString(address.Address),
+			})
+		}
+
+		if i == 0 {
+			cc.DefaultPrincipal = credential.Client
+		}
+		cc.Credentials = append(cc.Credentials, credential)
+	}
+
+	return cc, nil
+}
+
+// FromCCache creates a Kirbi holding every ticket of a ccache, skipping configuration entries.
+// The enc-part of the resulting KRB-CRED message is not encrypted.
+//
+// Parameters:
+//   - cc (*ccache.CCache): The ccache to convert.
+//
+// Returns:
+//   - (*Kirbi, error): The Kirbi and an error if the conversion failed.
+func FromCCache(cc *ccache.CCache) (*Kirbi, error) {
+	k := &Kirbi{
+		Cred: messages.KRBCred{
+			Pvno:    messages.PVNO,
+			MsgType: messages.MsgType_KRB_CRED,
+			Tickets: make([]messages.Ticket, 0),
+		},
+		EncPart: messages.EncKrbCredPart{
+			TicketInfo: make([]messages.KrbCredInfo, 0),
+		},
+		Decrypted: true,
+	}
+
+	for _, credential := range cc.GetCredentials() {
+		ticket := messages.Ticket{}
+		err := ticket.FromBytes(credential.Ticket.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ticket of %s: %w", credential.Server.String(), err)
+		}
+
+		info := messages.KrbCredInfo{
+			Key: messages.EncryptionKey{
+				KeyType:  int32(credential.Key.Type),
+				KeyValue: append([]byte{}, credential.Key.Key.Data...),
+			},
+			PRealm:    string(credential.Client.Realm.Data),
+			PName:     messages.NewPrincipalName(int32(credential.Client.NameType), credential.Client.GetComponents()...),
+			Flags:     credential.TicketFlags,
+			AuthTime:  fromTimestamp(credential.AuthTime),
+			StartTime: fromTimestamp(credential.StartTime),
+			EndTime:   fromTimestamp(credential.EndTime),
+			RenewTill: fromTimestamp(credential.RenewTill),
+			SRealm:    string(credential.Server.Realm.Data),
+			SName:     messages.NewPrincipalName(int32(credential.Server.NameType), credential.Server.GetComponents()...),
+		}
+		for _, address := range credential.Addresses {
+			info.CAddr = append(info.CAddr, messages.HostAddress{
+				AddrType: int32(address.AddrType),
+				Address:  append([]byte{}, address.Data.Data...),
+			})
+		}
+
+		k.Cred.Tickets = append(k.Cred.Tickets, ticket)
+		k.EncPart.TicketInfo = append(k.EncPart.TicketInfo, info)
+	}
+
+	if len(k.Cred.Tickets) == 0 {
+		return nil, fmt.Errorf("the ccache holds no tickets")
+	}
+
+	return k, nil
+}
+
+// toTimestamp converts an optional KerberosTime into a ccache timestamp.
+func toTimestamp(t time.Time) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint32(t.Unix())
+}
+
+// fromTimestamp converts a ccache timestamp into an optional KerberosTime.
+func fromTimestamp(timestamp uint32) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(timestamp), 0).UTC()
+}
@@ -0,0 +1,230 @@
+package kirbi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"keytab/ccache"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"os"
+	"strings"
+	"time"
+)
+
+// Kirbi represents a .kirbi file, a DER encoded KRB-CRED message.
+//
+// Attributes:
+//   - Cred (messages.KRBCred): The KRB-CRED message.
+//   - EncPart (messages.EncKrbCredPart): The decrypted part of the message, holding the session keys.
+//   - Decrypted (bool): Whether EncPart holds the decrypted part of the message.
+type Kirbi struct {
+	Cred      messages.KRBCred
+	EncPart   messages.EncKrbCredPart
+	Decrypted bool
+}
+
+// FromBytes parses a byte array into a Kirbi. When the enc-part of the message is not
+// encrypted (encryption type 0), as is the case for files produced by Rubeus and Mimikatz,
+// it is decoded right away.
+//
+// Parameters:
+//   - data ([]byte): The byte array to parse.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *Kirbi) FromBytes(data []byte) error {
+	err := k.Cred.FromBytes(data)
+	if err != nil {
+		return err
+	}
+
+	k.Decrypted = false
+	if k.Cred.EncPart.EType == 0 {
+		err = k.EncPart.FromBytes(k.Cred.EncPart.Cipher)
+		if err != nil {
+			return fmt.Errorf("error parsing unencrypted enc-part: %w", err)
+		}
+		k.Decrypted = true
+	}
+
+	return nil
+}
+
+// ToBytes converts a Kirbi to a byte array. If the enc-part is decrypted, it is
+// re-encoded without encryption, as expected by Windows tooling.
+//
+// Returns:
+//   - ([]byte, error): The byte array and an error if the conversion failed.
+func (k *Kirbi) ToBytes() ([]byte, error) {
+	if k.Decrypted {
+		encPartBytes, err := k.EncPart.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		k.Cred.EncPart = messages.EncryptedData{EType: 0, Cipher: encPartBytes}
+	}
+	return k.Cred.ToBytes()
+}
+
+// LoadKirbiFromFile loads a Kirbi from a file.
+//
+// Parameters:
+//   - path (string): The path to the kirbi file.
+//
+// Returns:
+//   - (*Kirbi, error): The Kirbi struct and an error if the file could not be read.
+func LoadKirbiFromFile(path string) (*Kirbi, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	kirbi := &Kirbi{}
+	err = kirbi.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return kirbi, nil
+}
+
+// SaveToFile saves the Kirbi struct to a file. Kirbi files hold session keys,
+// so the file is only readable by its owner.
+//
+// Parameters:
+//   - path (string): The path to the file to save the Kirbi struct to.
+//
+// Returns:
+//   - error: An error if the saving failed.
+func (k *Kirbi) SaveToFile(path string) error {
+	data, err := k.ToBytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Decrypt decrypts the enc-part of the KRB-CRED message using the keys of a keytab.
+// The keys of the server principal of the tickets are tried first, then every key of
+// the matching encryption type.
+//
+// Parameters:
+//   - kt (*keytab.Keytab): The keytab holding the service key.
+//
+// Returns:
+//   - error: An error if no key of the keytab could decrypt the enc-part.
+func (k *Kirbi) Decrypt(kt *keytab.Keytab) error {
+	if k.Decrypted {
+		return nil
+	}
+
+	etype := k.Cred.EncPart.EType
+	candidates := make([]*keytab.KeytabEntry, 0)
+	others := make([]*keytab.KeytabEntry, 0)
+	for i := range kt.Entries {
+		entry := &kt.Entries[i]
+		if int32(entry.Key.Type) != etype {
+			continue
+		}
+		if k.isTicketServer(entry) {
+			candidates = append(candidates, entry)
+		} else {
+			others = append(others, entry)
+		}
+	}
+	candidates = append(candidates, others...)
+
+	if len(candidates) == 0 {
+		return fmt.Errorf("no key of encryption type %s in the keytab", keytab.EncryptionType(etype).String())
+	}
+
+	for _, entry := range candidates {
+		plaintext, err := crypto.Decrypt(etype, entry.Key.Key.Data, messages.KeyUsage_KRB_CRED_ENCPART, k.Cred.EncPart.Cipher)
+		if errors.Is(err, crypto.ErrIntegrity) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		err = k.EncPart.FromBytes(plaintext)
+		if err != nil {
+			return fmt.Errorf("error parsing decrypted enc-part: %w", err)
+		}
+		k.Decrypted = true
+		return nil
+	}
+
+	return fmt.Errorf("no key of the keytab could decrypt the enc-part")
+}
+
+// isTicketServer returns true if the keytab entry is the key of the server of one of the tickets.
+func (k *Kirbi) isTicketServer(entry *keytab.KeytabEntry) bool {
+	principal := entry.Principal()
+	for i := range k.Cred.Tickets {
+		ticket := &k.Cred.Tickets[i]
+		if ticket.SName.String()+"@"+ticket.Realm == principal {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe prints a detailed description of the Kirbi struct,
+// including its attributes formatted with indentation for clarity.
+//
+// Parameters:
+//   - indent (int): The indentation level for formatting the output.
+//   - showKeys (bool): Whether the session keys are shown, they are masked otherwise.
+func (k *Kirbi) Describe(indent int, showKeys bool) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+	fmt.Printf("%s<Kirbi>\n", indentPrompt)
+	fmt.Printf("%s │ \x1b[93mPvno\x1b[0m    : \x1b[96m%d\x1b[0m\n", indentPrompt, k.Cred.Pvno)
+	fmt.Printf("%s │ \x1b[93mMsgType\x1b[0m : \x1b[96m%d\x1b[0m (\x1b[94mKRB-CRED\x1b[0m)\n", indentPrompt, k.Cred.MsgType)
+	fmt.Printf("%s │ \x1b[93mEncPart\x1b[0m : \x1b[96m%s\x1b[0m", indentPrompt, keytab.EncryptionType(k.Cred.EncPart.EType).String())
+	if k.Decrypted {
+		fmt.Printf(" (\x1b[92mdecrypted\x1b[0m)\n")
+	} else {
+		fmt.Printf(" (\x1b[91mencrypted\x1b[0m)\n")
+	}
+	fmt.Printf("%s │ \x1b[93mTickets\x1b[0m : \x1b[96m%d\x1b[0m\n", indentPrompt, len(k.Cred.Tickets))
+
+	for i := range k.Cred.Tickets {
+		ticket := &k.Cred.Tickets[i]
+		fmt.Printf("%s │ <Ticket #%d>\n", indentPrompt, i)
+		fmt.Printf("%s │  │ \x1b[93mServer\x1b[0m      : \x1b[96m%s@%s\x1b[0m\n", indentPrompt, ticket.SName.String(), ticket.Realm)
+		fmt.Printf("%s │  │ \x1b[93mTicketEType\x1b[0m : \x1b[96m%s\x1b[0m", indentPrompt, keytab.EncryptionType(ticket.EncPart.EType).String())
+		if ticket.EncPart.HasKvno {
+			fmt.Printf(" (\x1b[94mkvno %d\x1b[0m)", ticket.EncPart.Kvno)
+		}
+		fmt.Printf("\n")
+
+		if k.Decrypted && i < len(k.EncPart.TicketInfo) {
+			info := &k.EncPart.TicketInfo[i]
+			fmt.Printf("%s │  │ \x1b[93mClient\x1b[0m      : \x1b[96m%s@%s\x1b[0m\n", indentPrompt, info.PName.String(), info.PRealm)
+			fmt.Printf("%s │  │ \x1b[93mSessionKey\x1b[0m  : \x1b[96m%s\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, keytab.EncryptionType(info.Key.KeyType).String(), keytab.NewKeyBlock(keytab.EncryptionType(info.Key.KeyType), info.Key.KeyValue).KeyString(showKeys))
+			fmt.Printf("%s │  │ \x1b[93mFlags\x1b[0m       : \x1b[96m0x%08x\x1b[0m (\x1b[94m%s\x1b[0m)\n", indentPrompt, info.Flags, ccache.TicketFlagsToString(info.Flags))
+			fmt.Printf("%s │  │ \x1b[93mAuthTime\x1b[0m    : \x1b[96m%s\x1b[0m\n", indentPrompt, formatTime(info.AuthTime))
+			fmt.Printf("%s │  │ \x1b[93mStartTime\x1b[0m   : \x1b[96m%s\x1b[0m\n", indentPrompt, formatTime(info.StartTime))
+			fmt.Printf("%s │  │ \x1b[93mEndTime\x1b[0m     : \x1b[96m%s\x1b[0m\n", indentPrompt, formatTime(info.EndTime))
+			fmt.Printf("%s │  │ \x1b[93mRenewTill\x1b[0m   : \x1b[96m%s\x1b[0m\n", indentPrompt, formatTime(info.RenewTill))
+		}
+		fmt.Printf("%s │  └─\n", indentPrompt)
+	}
+	fmt.Printf("%s └─\n", indentPrompt)
+}
+
+// formatTime formats an optional KerberosTime, printing unset times as "-".
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
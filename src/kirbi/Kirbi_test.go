@@ -0,0 +1,129 @@
+package kirbi
+
+import (
+	"bytes"
+	"keytab/ccache"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"testing"
+)
+
+func newTestCCache(t *testing.T) *ccache.CCache {
+	ticket := messages.Ticket{
+		TktVno:  messages.PVNO,
+		Realm:   "TESTSEGMENT.LOCAL",
+		SName:   messages.NewPrincipalName(messages.NameType_SRV_INST, "HTTP", "web01.testsegment.local"),
+		EncPart: messages.EncryptedData{EType: 18, Kvno: 3, HasKvno: true, Cipher: bytes.Repeat([]byte{0x99}, 64)},
+	}
+	ticketBytes, err := ticket.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding ticket: %v", err)
+	}
+
+	client := ccache.NewPrincipal(messages.NameType_PRINCIPAL, "TESTSEGMENT.LOCAL", []string{"user"})
+	return &ccache.CCache{
+		FileFormatVersion: ccache.FileFormatVersion4,
+		DefaultPrincipal:  client,
+		Credentials: []ccache.Credential{
+			{
+				Client: client,
+				Server: ccache.NewPrincipal(messages.NameType_SRV_INST, "TESTSEGMENT.LOCAL", []string{"HTTP", "web01.testsegment.local"}),
+				Key: ccache.KeyBlock{
+					Type: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96,
+					Key:  ccache.NewCountedOctetString(bytes.Repeat([]byte{0x18}, 32)),
+				},
+				AuthTime:    1700000000,
+				StartTime:   1700000000,
+				EndTime:     1700036000,
+				RenewTill:   1700604800,
+				TicketFlags: ccache.TicketFlag_Forwardable | ccache.TicketFlag_PreAuthent,
+				Addresses:   []ccache.Address{},
+				AuthData:    []ccache.AuthData{},
+				Ticket:      ccache.NewCountedOctetString(ticketBytes),
+			},
+		},
+	}
+}
+
+func Test_Kirbi_CCacheConversionInvolution(t *testing.T) {
+	cc1 := newTestCCache(t)
+
+	k1, err := FromCCache(cc1)
+	if err != nil {
+		t.Fatalf("Error converting ccache to kirbi: %v", err)
+	}
+	k1Bytes, err := k1.ToBytes()
+	if err != nil {
+		t.Fatalf("Error converting kirbi to bytes: %v", err)
+	}
+
+	k2 := Kirbi{}
+	err = k2.FromBytes(k1Bytes)
+	if err != nil {
+		t.Fatalf("Error parsing kirbi: %v", err)
+	}
+	if !k2.Decrypted {
+		t.Fatalf("Expected the unencrypted enc-part to be decoded")
+	}
+
+	cc2, err := k2.ToCCache()
+	if err != nil {
+		t.Fatalf("Error converting kirbi to ccache: %v", err)
+	}
+
+	cc1Bytes, _ := cc1.ToBytes()
+	cc2Bytes, _ := cc2.ToBytes()
+	if !bytes.Equal(cc1Bytes, cc2Bytes) {
+		t.Errorf("ccache mismatch after conversion to kirbi and back")
+	}
+}
+
+func Test_Kirbi_DecryptWithKeytab(t *testing.T) {
+	k1, err := FromCCache(newTestCCache(t))
+	if err != nil {
+		t.Fatalf("Error converting ccache to kirbi: %v", err)
+	}
+
+	serviceKey := bytes.Repeat([]byte{0x23}, 32)
+	encPartBytes, _ := k1.EncPart.ToBytes()
+	cipher, err := crypto.Encrypt(18, serviceKey, messages.KeyUsage_KRB_CRED_ENCPART, encPartBytes)
+	if err != nil {
+		t.Fatalf("Error encrypting enc-part: %v", err)
+	}
+	k1.Cred.EncPart = messages.EncryptedData{EType: 18, Cipher: cipher}
+	kirbiBytes, _ := k1.Cred.ToBytes()
+
+	k2 := Kirbi{}
+	if err := k2.FromBytes(kirbiBytes); err != nil {
+		t.Fatalf("Error parsing kirbi: %v", err)
+	}
+	if k2.Decrypted {
+		t.Fatalf("Expected the enc-part to be encrypted")
+	}
+
+	kt := &keytab.Keytab{
+		FileFormatVersion: 0x502,
+		Entries: []keytab.KeytabEntry{
+			{
+				NumComponents: 2,
+				Realm:         keytab.CountedOctetString{Length: 17, Data: []byte("TESTSEGMENT.LOCAL")},
+				Components: []keytab.CountedOctetString{
+					{Length: 4, Data: []byte("HTTP")},
+					{Length: 23, Data: []byte("web01.testsegment.local")},
+				},
+				Key: keytab.KeyBlock{
+					Type: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96,
+					Key:  keytab.CountedOctetString{Length: 32, Data: serviceKey},
+				},
+			},
+		},
+	}
+
+	if err := k2.Decrypt(kt); err != nil {
+		t.Fatalf("Error decrypting kirbi: %v", err)
+	}
+	if !bytes.Equal(k2.EncPart.TicketInfo[0].Key.KeyValue, bytes.Repeat([]byte{0x18}, 32)) {
+		t.Errorf("Session key mismatch after decryption")
+	}
+}
@@ -0,0 +1,372 @@
+package kpasswd
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/login"
+	"keytab/messages"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultPort is the port of the kpasswd service.
+const DefaultPort = "464"
+
+// Service is the service the ticket of kpasswd requests is issued for.
+const Service = "kadmin/changepw"
+
+// Options configures a password change.
+//
+// Attributes:
+//   - Login (login.Options): The options of the AS exchange of the kadmin/changepw ticket. Its transport and timeout are also used for the kpasswd exchange.
+//   - Server (string): The address of the kpasswd server, as "host" or "host:port". The host of Login.KDC is used when empty.
+//   - Target (string): The principal whose password is set, as "name@REALM". Implies SetPassword. The authenticated principal when empty.
+//   - SetPassword (bool): Whether to use the set-password request of RFC 3244 instead of the original change-password one.
+//   - Send (func([]byte) ([]byte, error)): Sends a kpasswd message and returns the reply, replacing the network transport when set.
+type Options struct {
+	Login       login.Options
+	Server      string
+	Target      string
+	SetPassword bool
+	Send        func([]byte) ([]byte, error)
+}
+
+// Result holds the outcome of a successful password change.
+//
+// Attributes:
+//   - Login (*login.Result): The AS exchange of the kadmin/changepw ticket, with the keytab entry used.
+//   - Principal (string): The principal whose password was changed, as "name@REALM".
+//   - Text (string): The result string sent by the server, may be empty.
+type Result struct {
+	Login     *login.Result
+	Principal string
+	Text      string
+}
+
+// Error is a failure reported by a kpasswd server in its result code.
+//
+// Attributes:
+//   - Code (uint16): The result code.
+//   - Text ([]byte): The result string, either text or the binary password policy of Active Directory.
+type Error struct {
+	Code uint16
+	Text []byte
+}
+
+// Error returns the name of the result code followed by the result string.
+//
+// Returns:
+//   - string: The description of the error.
+func (e *Error) Error() string {
+	description := fmt.Sprintf("%s (%d)", messages.KpasswdResultToString(e.Code), e.Code)
+	if policy := describePolicy(e.Text); len(policy) != 0 {
+		return description + ": " + policy
+	}
+	if len(e.Text) != 0 && utf8.Valid(e.Text) {
+		return description + ": " + string(e.Text)
+	}
+	return description
+}
+
+// ChangePassword changes the password of a principal with the kpasswd protocol of RFC 3244,
+// authenticating with a kadmin/changepw ticket requested with the keys of a keytab. The keytab
+// is not modified; Keytab.Rekey adds the keys of the new password once the change succeeded.
+//
+// Parameters:
+//   - kt (*keytab.Keytab): The keytab holding the current keys of the principal.
+//   - principal (string): The principal to authenticate as, as "name@REALM". The realm of the keytab entry is used when absent.
+//   - newPassword (string): The new password.
+//   - options (Options): The options of the exchanges.
+//
+// Returns:
+//   - (*Result, error): The outcome of the change and an error if it failed. Failures reported by the
+//     server can be matched with errors.As to an *Error, and KRB-ERRORs to a *messages.KRBError.
+func ChangePassword(kt *keytab.Keytab, principal, newPassword string, options Options) (*Result, error) {
+	loginOptions := options.Login
+	loginOptions.Service = Service
+	loginResult, err := login.Login(kt, principal, loginOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting a %s ticket: %w", Service, err)
+	}
+
+	client := loginResult.Reply.CName
+	realm := loginResult.Reply.CRealm
+	result := &Result{Login: loginResult, Principal: client.String() + "@" + realm}
+
+	version := uint16(messages.KpasswdVersion_ChangePassword)
+	userData := []byte(newPassword)
+	if options.SetPassword || len(options.Target) != 0 {
+		version = messages.KpasswdVersion_SetPassword
+		request := messages.ChangePasswdData{NewPasswd: []byte(newPassword)}
+		if len(options.Target) != 0 {
+			name, targetRealm, _ := strings.Cut(options.Target, "@")
+			if len(targetRealm) == 0 {
+				targetRealm = realm
+			}
+			target := messages.NewPrincipalName(messages.NameType_PRINCIPAL, strings.Split(name, "/")...)
+			request.TargName, request.TargRealm = &target, targetRealm
+			result.Principal = target.String() + "@" + targetRealm
+		}
+		userData, err = request.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sessionKey := loginResult.EncPart.Key
+	subkey, err := randomKey(sessionKey.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	sequence := make([]byte, 4)
+	_, err = rand.Read(sequence)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if options.Login.Now != nil {
+		now = options.Login.Now()
+	}
+
+	authenticator := messages.Authenticator{
+		AuthenticatorVno: messages.PVNO,
+		CRealm:           realm,
+		CName:            client,
+		Cusec:            int32(now.Nanosecond() / 1000),
+		CTime:            now.UTC().Truncate(time.Second),
+		SubKey:           &subkey,
+		SeqNumber:        binary.BigEndian.Uint32(sequence) & 0x3fffffff,
+		HasSeqNumber:     true,
+	}
+	apReq, err := newAPReq(loginResult, &authenticator)
+	if err != nil {
+		return nil, err
+	}
+
+	server := options.server()
+	encPrivPart := messages.EncKrbPrivPart{
+		UserData:     userData,
+		Timestamp:    authenticator.CTime,
+		Usec:         authenticator.Cusec,
+		SeqNumber:    authenticator.SeqNumber,
+		HasSeqNumber: true,
+		SAddress:     localAddress(server),
+	}
+	priv, err := sealPriv(&encPrivPart, subkey)
+	if err != nil {
+		return nil, err
+	}
+
+	request := messages.KpasswdMessage{Version: version, AP: apReq, Data: priv}
+	message, err := request.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if options.Send != nil {
+		data, err = options.Send(message)
+	} else if len(server) == 0 {
+		err = fmt.Errorf("no kpasswd server address")
+	} else {
+		timeout := options.Login.Timeout
+		if timeout == 0 {
+			timeout = login.DefaultTimeout
+		}
+		data, err = login.Exchange(server, DefaultPort, options.Login.Transport, timeout, message)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging with the kpasswd server: %w", err)
+	}
+
+	code, text, err := parseReply(data, sessionKey, subkey, &authenticator)
+	if err != nil {
+		return nil, err
+	}
+	if code != messages.KpasswdResult_SUCCESS {
+		return nil, &Error{Code: code, Text: text}
+	}
+	result.Text = string(text)
+	return result, nil
+}
+
+// Salts returns the salts the KDC told the client to use during the AS exchange, to derive
+// the keys of the new password with Keytab.Rekey. They only apply to the authenticated principal.
+//
+// Returns:
+//   - map[keytab.EncryptionType]string: The salt of each encryption type the KDC sent one for.
+func (r *Result) Salts() map[keytab.EncryptionType]string {
+	salts := make(map[keytab.EncryptionType]string)
+	for _, info := range r.Login.ETypeInfo {
+		if info.HasSalt {
+			salts[keytab.EncryptionType(info.EType)] = info.Salt
+		}
+	}
+	return salts
+}
+
+// server returns the address of the kpasswd server, defaulting to the host of the KDC.
+func (o *Options) server() string {
+	if len(o.Server) != 0 || len(o.Login.KDC) == 0 {
+		return o.Server
+	}
+	if host, _, err := net.SplitHostPort(o.Login.KDC); err == nil {
+		return host
+	}
+	return o.Login.KDC
+}
+
+// newAPReq returns the DER encoded AP-REQ carrying an authenticator for the kadmin/changepw ticket.
+func newAPReq(loginResult *login.Result, authenticator *messages.Authenticator) ([]byte, error) {
+	plaintext, err := authenticator.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	sessionKey := loginResult.EncPart.Key
+	cipher, err := crypto.Encrypt(sessionKey.KeyType, sessionKey.KeyValue, messages.KeyUsage_AP_REQ_AUTHENTICATOR, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting authenticator: %w", err)
+	}
+	apReq := messages.APReq{
+		Pvno:          messages.PVNO,
+		MsgType:       messages.MsgType_AP_REQ,
+		Ticket:        loginResult.Reply.Ticket,
+		Authenticator: messages.EncryptedData{EType: sessionKey.KeyType, Cipher: cipher},
+	}
+	return apReq.ToBytes()
+}
+
+// parseReply authenticates a kpasswd reply and returns its result code and string.
+func parseReply(data []byte, sessionKey, subkey messages.EncryptionKey, authenticator *messages.Authenticator) (uint16, []byte, error) {
+	reply := messages.KpasswdMessage{}
+	err := reply.FromBytes(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(reply.AP) == 0 {
+		krbError := &messages.KRBError{}
+		err = krbError.FromBytes(reply.Data)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error parsing the KRB-ERROR of the kpasswd reply: %w", err)
+		}
+		return 0, nil, krbError
+	}
+
+	apRep := messages.APRep{}
+	err = apRep.FromBytes(reply.AP)
+	if err != nil {
+		return 0, nil, err
+	}
+	plaintext, err := crypto.Decrypt(apRep.EncPart.EType, sessionKey.KeyValue, messages.KeyUsage_AP_REP_ENCPART, apRep.EncPart.Cipher)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error decrypting AP-REP: %w", err)
+	}
+	encAPRepPart := messages.EncAPRepPart{}
+	err = encAPRepPart.FromBytes(plaintext)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !encAPRepPart.CTime.Equal(authenticator.CTime) || encAPRepPart.Cusec != authenticator.Cusec {
+		return 0, nil, fmt.Errorf("the AP-REP of the kpasswd server does not echo the authenticator time")
+	}
+	if encAPRepPart.SubKey != nil {
+		subkey = *encAPRepPart.SubKey
+	}
+
+	priv := messages.KRBPriv{}
+	err = priv.FromBytes(reply.Data)
+	if err != nil {
+		return 0, nil, err
+	}
+	plaintext, err = crypto.Decrypt(priv.EncPart.EType, subkey.KeyValue, messages.KeyUsage_KRB_PRIV_ENCPART, priv.EncPart.Cipher)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error decrypting KRB-PRIV: %w", err)
+	}
+	encPrivPart := messages.EncKrbPrivPart{}
+	err = encPrivPart.FromBytes(plaintext)
+	if err != nil {
+		return 0, nil, err
+	}
+	return messages.ParseKpasswdResult(encPrivPart.UserData)
+}
+
+// sealPriv returns the DER encoded KRB-PRIV carrying an encrypted part.
+func sealPriv(encPrivPart *messages.EncKrbPrivPart, key messages.EncryptionKey) ([]byte, error) {
+	plaintext, err := encPrivPart.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := crypto.Encrypt(key.KeyType, key.KeyValue, messages.KeyUsage_KRB_PRIV_ENCPART, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting KRB-PRIV: %w", err)
+	}
+	priv := messages.KRBPriv{
+		Pvno:    messages.PVNO,
+		MsgType: messages.MsgType_KRB_PRIV,
+		EncPart: messages.EncryptedData{EType: key.KeyType, Cipher: cipher},
+	}
+	return priv.ToBytes()
+}
+
+// localAddress returns the local address used to reach a server, sent as the sender address
+// of the KRB-PRIV, or nil if it cannot be determined. No packet is sent.
+func localAddress(server string) *messages.HostAddress {
+	if len(server) == 0 {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, DefaultPort)
+	}
+	conn, err := net.Dial("udp", server)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	ip := conn.LocalAddr().(*net.UDPAddr).IP
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return &messages.HostAddress{AddrType: messages.AddrType_IPv4, Address: ipv4}
+	}
+	return &messages.HostAddress{AddrType: messages.AddrType_IPv6, Address: ip.To16()}
+}
+
+// randomKey generates a random subkey of an encryption type.
+func randomKey(etype int32) (messages.EncryptionKey, error) {
+	size, err := crypto.KeySize(etype)
+	if err != nil {
+		return messages.EncryptionKey{}, err
+	}
+	key := make([]byte, size)
+	_, err = rand.Read(key)
+	if err != nil {
+		return messages.EncryptionKey{}, fmt.Errorf("error generating key: %w", err)
+	}
+	return messages.EncryptionKey{KeyType: etype, KeyValue: key}, nil
+}
+
+// describePolicy describes the password policy sent by Active Directory in the result string
+// of a KpasswdResult_SOFTERROR, as documented in MS-KILE, or returns an empty string.
+func describePolicy(text []byte) string {
+	if len(text) != 30 || text[0] != 0 || text[1] != 0 {
+		return ""
+	}
+	minLength := binary.BigEndian.Uint32(text[2:6])
+	history := binary.BigEndian.Uint32(text[6:10])
+	properties := binary.BigEndian.Uint32(text[10:14])
+	minAge := time.Duration(binary.BigEndian.Uint64(text[22:30]) * 100)
+
+	requirements := []string{fmt.Sprintf("at least %d characters", minLength)}
+	if properties&0x01 != 0 {
+		requirements = append(requirements, "complexity requirements")
+	}
+	if history != 0 {
+		requirements = append(requirements, fmt.Sprintf("not one of the last %d passwords", history))
+	}
+	if minAge != 0 {
+		requirements = append(requirements, fmt.Sprintf("not changed within %s of the previous change", minAge))
+	}
+	return "the password does not meet the policy (" + strings.Join(requirements, ", ") + ")"
+}
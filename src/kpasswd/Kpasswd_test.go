@@ -0,0 +1,161 @@
+package kpasswd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"keytab/crypto"
+	"keytab/kdctest"
+	"keytab/keytab"
+	"keytab/login"
+	"keytab/messages"
+	"strings"
+	"testing"
+)
+
+const testRealm = "TESTSEGMENT.LOCAL"
+
+// newTestKeytab returns a keytab holding the kvno 2 aes256 and rc4 keys of user and the kvno 2 aes256 key of svc_web.
+func newTestKeytab(t *testing.T) *keytab.Keytab {
+	kt, err := kdctest.NewTestKeytab(testRealm,
+		kdctest.TestKey{Principal: "user", Kvno: 2, EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, Key: bytes.Repeat([]byte{0x01}, 32)},
+		kdctest.TestKey{Principal: "user", Kvno: 2, EncryptionType: keytab.EncryptionType_RC4_HMAC, Key: bytes.Repeat([]byte{0x02}, 16)},
+		kdctest.TestKey{Principal: "svc_web", Kvno: 2, EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, Key: bytes.Repeat([]byte{0x03}, 32)},
+	)
+	if err != nil {
+		t.Fatalf("Error creating keytab: %v", err)
+	}
+	return kt
+}
+
+// newTestKDC returns an in-memory KDC using a copy of the test keytab as its database,
+// and the options reaching it without network.
+func newTestKDC(t *testing.T) (*kdctest.KDC, Options) {
+	kdc, err := kdctest.NewKDC(testRealm, newTestKeytab(t))
+	if err != nil {
+		t.Fatalf("Error creating KDC: %v", err)
+	}
+	options := Options{
+		Login: login.Options{Send: func(m []byte) ([]byte, error) { return kdc.HandleMessage(m), nil }},
+		Send:  func(m []byte) ([]byte, error) { return kdc.HandleKpasswd(m), nil },
+	}
+	return kdc, options
+}
+
+func Test_ChangePassword(t *testing.T) {
+	kdc, options := newTestKDC(t)
+	kt := newTestKeytab(t)
+
+	result, err := ChangePassword(kt, "user", "N3w-P4ssw0rd", options)
+	if err != nil {
+		t.Fatalf("Error changing password: %v", err)
+	}
+	if result.Principal != "user@"+testRealm || result.Text != "Password changed" {
+		t.Errorf("Unexpected result for %s: %q", result.Principal, result.Text)
+	}
+	if len(kdc.Keytab.Entries) != 5 {
+		t.Fatalf("Expected the KDC to add 2 keys, got %d entries", len(kdc.Keytab.Entries))
+	}
+
+	kvno, err := kt.Rekey(testRealm, []string{"user"}, "N3w-P4ssw0rd", result.Salts())
+	if err != nil || kvno != 3 {
+		t.Fatalf("Error rekeying the keytab to kvno 3, got %d: %v", kvno, err)
+	}
+	for i := 3; i < 5; i++ {
+		if !bytes.Equal(kdc.Keytab.Entries[i].Key.Key.Data, kt.Entries[i].Key.Key.Data) {
+			t.Errorf("Key of entry #%d differs between the keytab and the KDC", i)
+		}
+	}
+	expected, _ := crypto.StringToKey(crypto.ETypeAES256CTSHMACSHA196, "N3w-P4ssw0rd", testRealm+"user", nil)
+	if !bytes.Equal(kt.Entries[3].Key.Key.Data, expected) {
+		t.Errorf("Unexpected aes256 key %x", kt.Entries[3].Key.Key.Data)
+	}
+
+	loginResult, err := login.Login(kt, "user", options.Login)
+	if err != nil {
+		t.Fatalf("Error logging in with the new keys: %v", err)
+	}
+	if loginResult.Entry.KVNO() != 3 {
+		t.Errorf("Expected the kvno 3 key to be used, got %d", loginResult.Entry.KVNO())
+	}
+}
+
+func Test_ChangePassword_SetPassword(t *testing.T) {
+	kdc, options := newTestKDC(t)
+	options.Target = "svc_web@" + testRealm
+
+	result, err := ChangePassword(newTestKeytab(t), "user", "N3w-P4ssw0rd", options)
+	if err != nil {
+		t.Fatalf("Error setting password: %v", err)
+	}
+	if result.Principal != "svc_web@"+testRealm || kdc.Keytab.Entries[3].Principal() != "svc_web@"+testRealm {
+		t.Errorf("Expected the password of svc_web to be set, got %s", result.Principal)
+	}
+
+	options.Target = "nobody"
+	_, err = ChangePassword(newTestKeytab(t), "user", "N3w-P4ssw0rd", options)
+	var kpasswdError *Error
+	if !errors.As(err, &kpasswdError) || kpasswdError.Code != messages.KpasswdResult_HARDERROR {
+		t.Errorf("Expected KRB5_KPASSWD_HARDERROR for an unknown principal, got %v", err)
+	}
+}
+
+func Test_ChangePassword_Rejected(t *testing.T) {
+	kdc, options := newTestKDC(t)
+	kdc.MinPasswordLength = 16
+
+	_, err := ChangePassword(newTestKeytab(t), "user", "short", options)
+	var kpasswdError *Error
+	if !errors.As(err, &kpasswdError) || kpasswdError.Code != messages.KpasswdResult_SOFTERROR {
+		t.Fatalf("Expected KRB5_KPASSWD_SOFTERROR, got %v", err)
+	}
+	if err.Error() != "KRB5_KPASSWD_SOFTERROR (4): Password too short" {
+		t.Errorf("Unexpected error string %q", err.Error())
+	}
+	if len(kdc.Keytab.Entries) != 3 {
+		t.Errorf("Expected the keys of the KDC to be unchanged")
+	}
+
+	kt := newTestKeytab(t)
+	kt.Entries[0].Key.Key.Data = bytes.Repeat([]byte{0x04}, 32)
+	if _, err = ChangePassword(kt, "user", "N3w-P4ssw0rd", options); err == nil {
+		t.Errorf("Expected an error when authenticating with a wrong key")
+	}
+}
+
+func Test_Error_Policy(t *testing.T) {
+	policy := make([]byte, 30)
+	binary.BigEndian.PutUint32(policy[2:6], 12)
+	binary.BigEndian.PutUint32(policy[6:10], 24)
+	binary.BigEndian.PutUint32(policy[10:14], 1)
+	binary.BigEndian.PutUint64(policy[22:30], 864000000000)
+
+	err := &Error{Code: messages.KpasswdResult_SOFTERROR, Text: policy}
+	expected := "KRB5_KPASSWD_SOFTERROR (4): the password does not meet the policy (at least 12 characters, complexity requirements, not one of the last 24 passwords, not changed within 24h0m0s of the previous change)"
+	if err.Error() != expected {
+		t.Errorf("Unexpected error string %q", err.Error())
+	}
+}
+
+func Test_ChangePassword_Network(t *testing.T) {
+	kdc, _ := newTestKDC(t)
+	if err := kdc.Start(); err != nil {
+		t.Fatalf("Error starting KDC: %v", err)
+	}
+	defer kdc.Close()
+
+	kt := newTestKeytab(t)
+	for _, transport := range []string{login.Transport_UDP, login.Transport_TCP} {
+		options := Options{Login: login.Options{KDC: kdc.Addr(), Transport: transport}, Server: kdc.KpasswdAddr()}
+		result, err := ChangePassword(kt, "user", "N3w-P4ssw0rd-"+transport, options)
+		if err != nil {
+			t.Fatalf("Error changing password over %s: %v", transport, err)
+		}
+		if _, err = kt.Rekey(testRealm, []string{"user"}, "N3w-P4ssw0rd-"+transport, result.Salts()); err != nil {
+			t.Fatalf("Error rekeying: %v", err)
+		}
+	}
+	if !strings.HasPrefix(kt.Entries[len(kt.Entries)-1].Principal(), "user@") || kt.Entries[len(kt.Entries)-1].KVNO() != 4 {
+		t.Errorf("Expected the keytab to hold the kvno 4 keys of user")
+	}
+}
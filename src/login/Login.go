@@ -0,0 +1,429 @@
+package login
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"keytab/ccache"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"strings"
+	"time"
+)
+
+// DefaultETypes are the encryption types requested when none are configured, in order of preference.
+var DefaultETypes = []int32{
+	crypto.ETypeAES256CTSHMACSHA196,
+	crypto.ETypeAES128CTSHMACSHA196,
+	crypto.ETypeAES256CTSHMACSHA384192,
+	crypto.ETypeAES128CTSHMACSHA256128,
+	crypto.ETypeRC4HMAC,
+}
+
+// DefaultLifetime is the default requested lifetime of the ticket.
+const DefaultLifetime = 10 * time.Hour
+
+// Options configures an AS exchange.
+//
+// Attributes:
+//   - KDC (string): The address of the KDC, as "host" or "host:port".
+//   - Transport (string): The transport, Transport_UDP, Transport_TCP or Transport_Auto.
+//   - Timeout (time.Duration): The timeout of each exchange with the KDC, DefaultTimeout when zero.
+//   - ETypes ([]int32): The encryption types to request, in order of preference. DefaultETypes when empty.
+//   - Service (string): The service to request a ticket for, krbtgt/REALM when empty.
+//   - Lifetime (time.Duration): The requested lifetime of the ticket, DefaultLifetime when zero.
+//   - Forwardable (bool): Whether to request a forwardable ticket.
+//   - Send (func([]byte) ([]byte, error)): Sends a message to the KDC and returns its reply, replacing the network transport when set.
+//   - Now (func() time.Time): The clock of the client, time.Now when nil.
+type Options struct {
+	KDC         string
+	Transport   string
+	Timeout     time.Duration
+	ETypes      []int32
+	Service     string
+	Lifetime    time.Duration
+	Forwardable bool
+	Send        func([]byte) ([]byte, error)
+	Now         func() time.Time
+}
+
+// Result holds the outcome of a successful AS exchange.
+//
+// Attributes:
+//   - Entry (keytab.KeytabEntry): The keytab entry whose key decrypted the reply.
+//   - EntryIndex (int): The index of that entry in the keytab.
+//   - PreAuthenticated (bool): Whether encrypted timestamp pre-authentication was performed.
+//   - ETypeInfo ([]messages.ETypeInfo2Entry): The keys the KDC asked the client to use, in its order of preference.
+//   - Reply (messages.KDCRep): The AS-REP.
+//   - EncPart (messages.EncKDCRepPart): The decrypted part of the AS-REP.
+type Result struct {
+	Entry            keytab.KeytabEntry
+	EntryIndex       int
+	PreAuthenticated bool
+	ETypeInfo        []messages.ETypeInfo2Entry
+	Reply            messages.KDCRep
+	EncPart          messages.EncKDCRepPart
+}
+
+// Login performs an AS exchange with the long-term keys of a principal found in a keytab,
+// as "kinit -k" does. The request is first sent without pre-authentication; when the KDC
+// requires it, the encryption type is negotiated from the PA-ETYPE-INFO2 of the error and
+// an encrypted timestamp is sent with the matching key of the keytab.
+//
+// Parameters:
+//   - kt (*keytab.Keytab): The keytab.
+//   - principal (string): The client principal, as "name@REALM". The realm of the keytab entry is used when absent.
+//   - options (Options): The options of the exchange.
+//
+// Returns:
+//   - (*Result, error): The ticket and the keytab entry used, and an error if the exchange failed.
+//     Errors returned by the KDC can be matched with errors.As to a *messages.KRBError.
+func Login(kt *keytab.Keytab, principal string, options Options) (*Result, error) {
+	client, realm, err := resolvePrincipal(kt, principal)
+	if err != nil {
+		return nil, err
+	}
+	keys := principalEntries(kt, client, realm)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no key of a supported encryption type for %s@%s in the keytab", strings.Join(client.NameString, "/"), realm)
+	}
+
+	preferred := options.ETypes
+	if len(preferred) == 0 {
+		preferred = DefaultETypes
+	}
+	etypes := make([]int32, 0, len(preferred))
+	for _, etype := range preferred {
+		if _, ok := keys[etype]; ok {
+			etypes = append(etypes, etype)
+		}
+	}
+	if len(etypes) == 0 {
+		return nil, fmt.Errorf("the keytab has no key of the requested encryption types for %s@%s", strings.Join(client.NameString, "/"), realm)
+	}
+
+	service := messages.NewPrincipalName(messages.NameType_SRV_INST, "krbtgt", realm)
+	if len(options.Service) != 0 {
+		service = messages.NewPrincipalName(messages.NameType_SRV_INST, strings.Split(options.Service, "/")...)
+	}
+	lifetime := options.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultLifetime
+	}
+	kdcOptions := uint32(0)
+	if options.Forwardable {
+		kdcOptions |= messages.KDCOption_Forwardable
+	}
+
+	req := messages.KDCReq{
+		Pvno:    messages.PVNO,
+		MsgType: messages.MsgType_AS_REQ,
+		ReqBody: messages.KDCReqBody{
+			KDCOptions: kdcOptions,
+			CName:      client,
+			Realm:      realm,
+			SName:      service,
+			Till:       options.now().Add(lifetime),
+			EType:      etypes,
+		},
+	}
+
+	result := &Result{}
+	reply, err := options.exchange(&req)
+	var krbError *messages.KRBError
+	if errors.As(err, &krbError) && krbError.ErrorCode == messages.ErrorCode_KDC_ERR_PREAUTH_REQUIRED {
+		result.ETypeInfo, err = parseETypeInfo2(krbError.EData)
+		if err != nil {
+			return nil, err
+		}
+		entry := -1
+		for _, info := range result.ETypeInfo {
+			if index, ok := keys[info.EType]; ok {
+				entry = index
+				break
+			}
+		}
+		if entry < 0 {
+			return nil, fmt.Errorf("the KDC accepts none of the encryption types of the keytab for %s@%s (offered: %s)", strings.Join(client.NameString, "/"), realm, etypeNames(result.ETypeInfo))
+		}
+
+		var padata messages.PAData
+		padata, err = encryptedTimestamp(&kt.Entries[entry], options.now())
+		if err != nil {
+			return nil, err
+		}
+		req.PAData = []messages.PAData{padata}
+		result.PreAuthenticated = true
+		reply, err = options.exchange(&req)
+		if errors.As(err, &krbError) && krbError.ErrorCode == messages.ErrorCode_KDC_ERR_PREAUTH_FAILED {
+			return nil, fmt.Errorf("pre-authentication failed with keytab entry #%d (kvno %d, %s), the key does not match the one of the KDC: %w",
+				entry, kt.Entries[entry].KVNO(), kt.Entries[entry].Key.Type.String(), err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Reply = *reply
+	result.EntryIndex, err = decryptReply(kt, keys, result, client, realm)
+	if err != nil {
+		return nil, err
+	}
+	result.Entry = kt.Entries[result.EntryIndex]
+	if result.EncPart.Nonce != req.ReqBody.Nonce {
+		return nil, fmt.Errorf("the nonce of the AS-REP does not match the request")
+	}
+	return result, nil
+}
+
+// CCache returns a version 0x0504 ccache holding the ticket, with the client as default principal.
+//
+// Returns:
+//   - (*ccache.CCache, error): The ccache and an error if the ticket could not be encoded.
+func (r *Result) CCache() (*ccache.CCache, error) {
+	ticket, err := r.Reply.Ticket.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	client := ccache.NewPrincipal(uint32(r.Reply.CName.NameType), r.Reply.CRealm, r.Reply.CName.NameString)
+	credential := ccache.Credential{
+		Client: client,
+		Server: ccache.NewPrincipal(uint32(r.EncPart.SName.NameType), r.EncPart.SRealm, r.EncPart.SName.NameString),
+		Key: ccache.KeyBlock{
+			Type: keytab.EncryptionType(r.EncPart.Key.KeyType),
+			Key:  ccache.NewCountedOctetString(r.EncPart.Key.KeyValue),
+		},
+		AuthTime:    toTimestamp(r.EncPart.AuthTime),
+		StartTime:   toTimestamp(r.EncPart.StartTime),
+		EndTime:     toTimestamp(r.EncPart.EndTime),
+		RenewTill:   toTimestamp(r.EncPart.RenewTill),
+		TicketFlags: r.EncPart.Flags,
+		Addresses:   make([]ccache.Address, 0),
+		AuthData:    make([]ccache.AuthData, 0),
+		Ticket:      ccache.NewCountedOctetString(ticket),
+	}
+	return &ccache.CCache{
+		FileFormatVersion: ccache.FileFormatVersion4,
+		DefaultPrincipal:  client,
+		Credentials:       []ccache.Credential{credential},
+	}, nil
+}
+
+// exchange sends a KDC request with a new nonce and parses the reply, returning KRB-ERRORs as errors.
+func (o *Options) exchange(req *messages.KDCReq) (*messages.KDCRep, error) {
+	nonce := make([]byte, 4)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	req.ReqBody.Nonce = binary.BigEndian.Uint32(nonce) & 0x7fffffff
+	message, err := req.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if o.Send != nil {
+		data, err = o.Send(message)
+	} else {
+		data, err = o.send(message)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging with the KDC: %w", err)
+	}
+
+	messageType, err := messages.GetMessageType(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the reply of the KDC: %w", err)
+	}
+	if messageType == messages.MsgType_KRB_ERROR {
+		krbError := &messages.KRBError{}
+		err = krbError.FromBytes(data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, krbError
+	}
+	reply := &messages.KDCRep{}
+	err = reply.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if reply.MsgType != req.MsgType+1 {
+		return nil, fmt.Errorf("unexpected %s reply of the KDC", messages.MessageTypeToString(int(reply.MsgType)))
+	}
+	return reply, nil
+}
+
+// send sends a message to the KDC over the network, retrying over TCP when the reply is too big for UDP.
+func (o *Options) send(message []byte) ([]byte, error) {
+	if len(o.KDC) == 0 {
+		return nil, fmt.Errorf("no KDC address")
+	}
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	reply, err := Exchange(o.KDC, DefaultKDCPort, o.Transport, timeout, message)
+	if err != nil || o.Transport != Transport_Auto {
+		return reply, err
+	}
+	krbError := messages.KRBError{}
+	if krbError.FromBytes(reply) == nil && krbError.ErrorCode == messages.ErrorCode_KRB_ERR_RESPONSE_TOO_BIG {
+		return Exchange(o.KDC, DefaultKDCPort, Transport_TCP, timeout, message)
+	}
+	return reply, nil
+}
+
+// now returns the current time of the client clock.
+func (o *Options) now() time.Time {
+	if o.Now == nil {
+		return time.Now().UTC()
+	}
+	return o.Now().UTC()
+}
+
+// decryptReply decrypts the AS-REP with the key of the keytab matching its encryption type
+// and key version number, and returns the index of the keytab entry used.
+func decryptReply(kt *keytab.Keytab, keys map[int32]int, result *Result, client messages.PrincipalName, realm string) (int, error) {
+	encrypted := &result.Reply.EncPart
+	index, ok := keys[encrypted.EType]
+	if !ok {
+		return -1, fmt.Errorf("the AS-REP is encrypted with %s, which the keytab has no key for", keytab.EncryptionType(encrypted.EType).String())
+	}
+	if encrypted.HasKvno && kt.Entries[index].KVNO() != encrypted.Kvno {
+		// Older keys of the same encryption type are kept in the keytab after a rotation
+		for i := range kt.Entries {
+			entry := &kt.Entries[i]
+			if int32(entry.Key.Type) == encrypted.EType && entry.KVNO() == encrypted.Kvno && matchesPrincipal(entry, client, realm) {
+				index = i
+				break
+			}
+		}
+	}
+
+	entry := &kt.Entries[index]
+	plaintext, err := crypto.Decrypt(encrypted.EType, entry.Key.Key.Data, messages.KeyUsage_AS_REP_ENCPART, encrypted.Cipher)
+	if err != nil {
+		return -1, fmt.Errorf("error decrypting the AS-REP with keytab entry #%d (kvno %d, %s): %w", index, entry.KVNO(), entry.Key.Type.String(), err)
+	}
+	err = result.EncPart.FromBytes(plaintext)
+	if err != nil {
+		return -1, err
+	}
+	return index, nil
+}
+
+// encryptedTimestamp returns a PA-ENC-TIMESTAMP encrypted with the key of a keytab entry.
+func encryptedTimestamp(entry *keytab.KeytabEntry, now time.Time) (messages.PAData, error) {
+	timestamp := messages.PAEncTSEnc{
+		PATimestamp: now.Truncate(time.Second),
+		PAUsec:      int32(now.Nanosecond() / 1000),
+		HasPAUsec:   true,
+	}
+	plaintext, err := timestamp.ToBytes()
+	if err != nil {
+		return messages.PAData{}, err
+	}
+	etype := int32(entry.Key.Type)
+	cipher, err := crypto.Encrypt(etype, entry.Key.Key.Data, messages.KeyUsage_AS_REQ_PA_ENC_TIMESTAMP, plaintext)
+	if err != nil {
+		return messages.PAData{}, fmt.Errorf("error encrypting timestamp: %w", err)
+	}
+	encryptedData := messages.EncryptedData{EType: etype, Cipher: cipher}
+	value, err := encryptedData.ToBytes()
+	if err != nil {
+		return messages.PAData{}, err
+	}
+	return messages.PAData{PADataType: messages.PADataType_ENC_TIMESTAMP, PADataValue: value}, nil
+}
+
+// parseETypeInfo2 returns the PA-ETYPE-INFO2 of the METHOD-DATA of a pre-authentication error.
+func parseETypeInfo2(edata []byte) ([]messages.ETypeInfo2Entry, error) {
+	if len(edata) == 0 {
+		return nil, fmt.Errorf("the KDC requires pre-authentication but sent no METHOD-DATA")
+	}
+	methodData, err := messages.ParseMethodData(edata)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing METHOD-DATA: %w", err)
+	}
+	pa := messages.FindPAData(methodData, messages.PADataType_ETYPE_INFO2)
+	if pa == nil {
+		return nil, fmt.Errorf("the KDC requires pre-authentication but sent no PA-ETYPE-INFO2")
+	}
+	return messages.ParseETypeInfo2(pa.PADataValue)
+}
+
+// resolvePrincipal parses a principal, taking its realm from the keytab when absent.
+func resolvePrincipal(kt *keytab.Keytab, principal string) (messages.PrincipalName, string, error) {
+	name, realm, _ := strings.Cut(principal, "@")
+	components := strings.Split(name, "/")
+	client := messages.NewPrincipalName(messages.NameType_PRINCIPAL, components...)
+	if len(components) > 1 {
+		client.NameType = messages.NameType_SRV_INST
+	}
+	if len(name) == 0 {
+		return client, "", fmt.Errorf("invalid principal %q", principal)
+	}
+	if len(realm) != 0 {
+		return client, realm, nil
+	}
+
+	for i := range kt.Entries {
+		entryRealm := string(kt.Entries[i].Realm.Data)
+		if matchesPrincipal(&kt.Entries[i], client, entryRealm) {
+			return client, entryRealm, nil
+		}
+	}
+	return client, "", fmt.Errorf("principal %s is not in the keytab", principal)
+}
+
+// principalEntries returns the index of the keytab entry of the highest key version number
+// of a principal for each supported encryption type.
+func principalEntries(kt *keytab.Keytab, client messages.PrincipalName, realm string) map[int32]int {
+	keys := make(map[int32]int)
+	for i := range kt.Entries {
+		entry := &kt.Entries[i]
+		etype := int32(entry.Key.Type)
+		if !matchesPrincipal(entry, client, realm) || !crypto.IsSupported(etype) {
+			continue
+		}
+		if existing, ok := keys[etype]; ok && kt.Entries[existing].KVNO() >= entry.KVNO() {
+			continue
+		}
+		keys[etype] = i
+	}
+	return keys
+}
+
+// matchesPrincipal returns true if a keytab entry belongs to a principal. Realms are compared case-insensitively.
+func matchesPrincipal(entry *keytab.KeytabEntry, name messages.PrincipalName, realm string) bool {
+	if !strings.EqualFold(string(entry.Realm.Data), realm) || len(entry.Components) != len(name.NameString) {
+		return false
+	}
+	for i, component := range entry.Components {
+		if string(component.Data) != name.NameString[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// etypeNames returns the names of the encryption types of PA-ETYPE-INFO2 entries.
+func etypeNames(entries []messages.ETypeInfo2Entry) string {
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, keytab.EncryptionType(entry.EType).String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// toTimestamp converts an optional KerberosTime into a ccache timestamp.
+func toTimestamp(t time.Time) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint32(t.Unix())
+}
@@ -0,0 +1,120 @@
+package login
+
+import (
+	"bytes"
+	"errors"
+	"keytab/ccache"
+	"keytab/crypto"
+	"keytab/kdctest"
+	"keytab/keytab"
+	"keytab/messages"
+	"testing"
+)
+
+const testRealm = "TESTSEGMENT.LOCAL"
+
+// newTestKeytab returns a keytab holding two versions of the aes256 key of user and an rc4 key.
+func newTestKeytab(t *testing.T) *keytab.Keytab {
+	kt, err := kdctest.NewTestKeytab(testRealm,
+		kdctest.TestKey{Principal: "user", Kvno: 1, EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, Key: bytes.Repeat([]byte{0x01}, 32)},
+		kdctest.TestKey{Principal: "user", Kvno: 2, EncryptionType: keytab.EncryptionType_RC4_HMAC, Key: bytes.Repeat([]byte{0x03}, 16)},
+		kdctest.TestKey{Principal: "user", Kvno: 2, EncryptionType: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, Key: bytes.Repeat([]byte{0x02}, 32)},
+	)
+	if err != nil {
+		t.Fatalf("Error creating keytab: %v", err)
+	}
+	return kt
+}
+
+// newTestKDC returns an in-memory KDC using a copy of the test keytab as its database.
+func newTestKDC(t *testing.T) *kdctest.KDC {
+	kdc, err := kdctest.NewKDC(testRealm, newTestKeytab(t))
+	if err != nil {
+		t.Fatalf("Error creating KDC: %v", err)
+	}
+	return kdc
+}
+
+func Test_Login(t *testing.T) {
+	kdc := newTestKDC(t)
+	result, err := Login(newTestKeytab(t), "user", Options{Send: func(m []byte) ([]byte, error) { return kdc.HandleMessage(m), nil }})
+	if err != nil {
+		t.Fatalf("Error logging in: %v", err)
+	}
+	if result.EntryIndex != 2 || !result.PreAuthenticated {
+		t.Errorf("Expected the pre-authentication to use the kvno 2 aes256 entry #2, got #%d", result.EntryIndex)
+	}
+	if len(result.ETypeInfo) != 2 || result.ETypeInfo[0].EType != crypto.ETypeAES256CTSHMACSHA196 {
+		t.Errorf("Unexpected ETYPE-INFO2 %+v", result.ETypeInfo)
+	}
+
+	cc, err := result.CCache()
+	if err != nil {
+		t.Fatalf("Error building ccache: %v", err)
+	}
+	data, err := cc.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding ccache: %v", err)
+	}
+	parsed := &ccache.CCache{}
+	if err = parsed.FromBytes(data); err != nil {
+		t.Fatalf("Error parsing ccache: %v", err)
+	}
+	if parsed.DefaultPrincipal.String() != "user@"+testRealm || parsed.Credentials[0].Server.String() != "krbtgt/"+testRealm+"@"+testRealm {
+		t.Errorf("Unexpected ccache principals %s and %s", parsed.DefaultPrincipal.String(), parsed.Credentials[0].Server.String())
+	}
+}
+
+func Test_Login_ETypeNegotiation(t *testing.T) {
+	kdc := newTestKDC(t)
+	kdc.ETypes = []int32{crypto.ETypeRC4HMAC}
+	result, err := Login(newTestKeytab(t), "user@"+testRealm, Options{Send: func(m []byte) ([]byte, error) { return kdc.HandleMessage(m), nil }})
+	if err != nil {
+		t.Fatalf("Error logging in: %v", err)
+	}
+	if result.EntryIndex != 1 || result.EncPart.Key.KeyType != crypto.ETypeRC4HMAC {
+		t.Errorf("Expected the rc4 entry #1 to be used, got #%d", result.EntryIndex)
+	}
+
+	kdc.ETypes = []int32{crypto.ETypeAES128CTSHMACSHA196}
+	_, err = Login(newTestKeytab(t), "user@"+testRealm, Options{Send: func(m []byte) ([]byte, error) { return kdc.HandleMessage(m), nil }})
+	var krbError *messages.KRBError
+	if !errors.As(err, &krbError) || krbError.ErrorCode != messages.ErrorCode_KDC_ERR_ETYPE_NOSUPP {
+		t.Errorf("Expected KDC_ERR_ETYPE_NOSUPP, got %v", err)
+	}
+}
+
+func Test_Login_WrongKey(t *testing.T) {
+	kdc := newTestKDC(t)
+	kt := newTestKeytab(t)
+	kt.Entries[2].Key.Key.Data = bytes.Repeat([]byte{0x04}, 32)
+
+	_, err := Login(kt, "user", Options{Send: func(m []byte) ([]byte, error) { return kdc.HandleMessage(m), nil }})
+	var krbError *messages.KRBError
+	if !errors.As(err, &krbError) || krbError.ErrorCode != messages.ErrorCode_KDC_ERR_PREAUTH_FAILED {
+		t.Errorf("Expected KDC_ERR_PREAUTH_FAILED, got %v", err)
+	}
+
+	if _, err = Login(kt, "nobody", Options{}); err == nil {
+		t.Errorf("Expected an error for a principal missing from the keytab")
+	}
+}
+
+func Test_Login_Network(t *testing.T) {
+	kdc := newTestKDC(t)
+	if err := kdc.Start(); err != nil {
+		t.Fatalf("Error starting KDC: %v", err)
+	}
+	defer kdc.Close()
+
+	for _, transport := range []string{Transport_Auto, Transport_UDP, Transport_TCP} {
+		result, err := Login(newTestKeytab(t), "user", Options{KDC: kdc.Addr(), Transport: transport})
+		if err != nil {
+			t.Errorf("Error logging in over %q: %v", transport, err)
+			continue
+		}
+		if result.EntryIndex != 2 {
+			t.Errorf("Expected entry #2 over %q, got #%d", transport, result.EntryIndex)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package login
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Transports of the messages sent to a KDC.
+const (
+	Transport_Auto = ""
+	Transport_UDP  = "udp"
+	Transport_TCP  = "tcp"
+)
+
+// DefaultKDCPort is the port of the Kerberos KDC service.
+const DefaultKDCPort = "88"
+
+// DefaultTimeout is the default timeout of an exchange with a KDC.
+const DefaultTimeout = 10 * time.Second
+
+// maxUDPMessageSize is the size above which requests are sent over TCP in automatic mode, as MIT Kerberos does.
+const maxUDPMessageSize = 1465
+
+// maxMessageSize is the maximum size of a reply accepted over TCP.
+const maxMessageSize = 1 << 20
+
+// Exchange sends a message to a Kerberos server and returns its reply. Over TCP the messages
+// are preceded by their length on 4 bytes, as defined in RFC 4120 section 7.2.2.
+//
+// Parameters:
+//   - address (string): The address of the server, as "host" or "host:port".
+//   - defaultPort (string): The port used when the address has none.
+//   - transport (string): The transport, Transport_UDP, Transport_TCP or Transport_Auto to use UDP for small messages.
+//   - timeout (time.Duration): The timeout of the exchange.
+//   - message ([]byte): The message.
+//
+// Returns:
+//   - ([]byte, error): The reply and an error if the exchange failed.
+func Exchange(address, defaultPort, transport string, timeout time.Duration, message []byte) ([]byte, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultPort)
+	}
+	if transport == Transport_Auto {
+		transport = Transport_UDP
+		if len(message) > maxUDPMessageSize {
+			transport = Transport_TCP
+		}
+	}
+	if transport != Transport_UDP && transport != Transport_TCP {
+		return nil, fmt.Errorf("unsupported transport %q", transport)
+	}
+
+	conn, err := net.DialTimeout(transport, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if transport == Transport_UDP {
+		_, err = conn.Write(message)
+		if err != nil {
+			return nil, err
+		}
+		buffer := make([]byte, 65535)
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return nil, err
+		}
+		return buffer[:n], nil
+	}
+
+	framed := make([]byte, 4, 4+len(message))
+	binary.BigEndian.PutUint32(framed, uint32(len(message)))
+	_, err = conn.Write(append(framed, message...))
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 4)
+	_, err = io.ReadFull(conn, header)
+	if err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("reply of %d bytes is too large", length)
+	}
+	reply := make([]byte, length)
+	_, err = io.ReadFull(conn, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
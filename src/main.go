@@ -1,9 +1,25 @@
 package main
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"keytab/ad"
+	"keytab/ccache"
+	"keytab/crypto"
 	"keytab/keytab"
+	"keytab/kirbi"
+	"keytab/kpasswd"
+	"keytab/login"
+	"keytab/messages"
+	"keytab/pcap"
+	"keytab/render"
+	"keytab/utils"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/p0dalirius/goopts/subparser"
 )
@@ -12,14 +28,48 @@ var (
 	mode  string
 	debug bool
 
-	keytabFile string
-	principal  string
-	password   string
-	key        string
-	outputFile string
-	jsonOutput bool
-	txtOutput  bool
-	csvOutput  bool
+	keytabFile      string
+	ccacheFile      string
+	kirbiFile       string
+	inputFile       string
+	pcapFile        string
+	kdcAddress      string
+	kpasswdServer   string
+	transport       string
+	forwardable     bool
+	principal       string
+	targetPrincipal string
+	password        string
+	passwordStdin   bool
+	setPassword     bool
+	keep            int
+	kvno            int
+	salt            string
+	key             string
+	followSymlinks  bool
+	backups         int
+	backupName      string
+	restore         bool
+	format          string
+	failOn          string
+	noColor         bool
+	showKeys        bool
+	pepper          string
+	fingerprint     string
+	enctype         string
+	wordlist        string
+	workers         int
+	stateFile       string
+	realm           string
+	account         string
+	hostname        string
+	services        string
+	enctypes        string
+	perAccount      bool
+	outputFile      string
+	jsonOutput      bool
+	txtOutput       bool
+	csvOutput       bool
 )
 
 func parseArgs() {
@@ -34,6 +84,74 @@ func parseArgs() {
 	subparser_describe := asp.AddSubParser("describe", "Describe the content of a keytab file.")
 	subparser_describe.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
 	subparser_describe.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", false, "Path to the keytab file.")
+	subparser_describe.NewStringArgument(&format, "", "--format", "tree", false, "Output format, tree, table, json or yaml.")
+	subparser_describe.NewBoolArgument(&noColor, "", "--no-color", false, "Disable colors, which are also disabled when the output is not a terminal or NO_COLOR is set.")
+	subparser_describe.NewBoolArgument(&showKeys, "", "--show-keys", false, "Show the keys, which are masked by default.")
+
+	// describe-ccache mode ============================================================================================================
+	subparser_describe_ccache := asp.AddSubParser("describe-ccache", "Describe the content of a ccache file.")
+	subparser_describe_ccache.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_describe_ccache.NewStringArgument(&ccacheFile, "-c", "--ccache-file", "", true, "Path to the ccache file.")
+	subparser_describe_ccache.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", false, "Path to a keytab file, credentials of its principals are marked.")
+	subparser_describe_ccache.NewBoolArgument(&showKeys, "", "--show-keys", false, "Show the session keys, which are masked by default.")
+
+	// describe-kirbi mode ============================================================================================================
+	subparser_describe_kirbi := asp.AddSubParser("describe-kirbi", "Describe the content of a kirbi (KRB-CRED) file.")
+	subparser_describe_kirbi.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_describe_kirbi.NewStringArgument(&kirbiFile, "-k", "--kirbi-file", "", true, "Path to the kirbi file.")
+	subparser_describe_kirbi.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", false, "Path to a keytab file holding the key the enc-part is encrypted with.")
+	subparser_describe_kirbi.NewBoolArgument(&showKeys, "", "--show-keys", false, "Show the session keys, which are masked by default.")
+
+	// convert mode ============================================================================================================
+	subparser_convert := asp.AddSubParser("convert", "Convert a kirbi file to a ccache file and back.")
+	subparser_convert.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_convert.NewStringArgument(&inputFile, "-i", "--input-file", "", true, "Path to the kirbi or ccache file to convert.")
+	subparser_convert.NewStringArgument(&outputFile, "-o", "--output-file", "", true, "Path to the output file.")
+	subparser_convert.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", false, "Path to a keytab file holding the key the enc-part of the kirbi is encrypted with.")
+
+	// pcap mode ============================================================================================================
+	subparser_pcap := asp.AddSubParser("pcap", "Decrypt the Kerberos traffic of a pcap or pcapng capture.")
+	subparser_pcap.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_pcap.NewStringArgument(&pcapFile, "-r", "--pcap-file", "", true, "Path to the pcap or pcapng capture file.")
+	subparser_pcap.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", false, "Path to a keytab file holding the long-term keys used to decrypt the traffic.")
+	subparser_pcap.NewBoolArgument(&showKeys, "", "--show-keys", false, "Show the session keys and subkeys of the decrypted parts, which are masked by default.")
+
+	// login mode ============================================================================================================
+	subparser_login := asp.AddSubParser("login", "Request a TGT with the keys of a keytab file (kinit -k).")
+	subparser_login.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_login.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Path to the keytab file.")
+	subparser_login.NewStringArgument(&principal, "-p", "--principal", "", true, "Principal to authenticate as, the realm of the keytab entry is used when absent.")
+	subparser_login.NewStringArgument(&kdcAddress, "", "--kdc", "", true, "Address of the KDC, as host or host:port.")
+	subparser_login.NewStringArgument(&ccacheFile, "-c", "--ccache-file", "", false, "Path to the ccache file to write the TGT to. Defaults to <user>.ccache.")
+	subparser_login.NewStringArgument(&transport, "", "--transport", "", false, "Transport to reach the KDC, udp or tcp. Defaults to udp for small requests.")
+	subparser_login.NewBoolArgument(&forwardable, "", "--forwardable", false, "Request a forwardable TGT.")
+
+	// change-password mode ============================================================================================================
+	subparser_change_password := asp.AddSubParser("change-password", "Change the password of a principal with kpasswd and add its new keys to the keytab file.")
+	subparser_change_password.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_change_password.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Path to the keytab file.")
+	subparser_change_password.NewStringArgument(&principal, "-p", "--principal", "", true, "Principal to authenticate as, the realm of the keytab entry is used when absent.")
+	subparser_change_password.NewStringArgument(&kdcAddress, "", "--kdc", "", true, "Address of the KDC, as host or host:port.")
+	subparser_change_password.NewStringArgument(&kpasswdServer, "", "--kpasswd-server", "", false, "Address of the kpasswd server, as host or host:port. Defaults to the KDC host on port 464.")
+	subparser_change_password.NewStringArgument(&password, "", "--new-password", "", false, "New password of the principal.")
+	subparser_change_password.NewBoolArgument(&passwordStdin, "", "--password-stdin", false, "Read the new password from the first line of the standard input.")
+	subparser_change_password.NewStringArgument(&targetPrincipal, "-t", "--target", "", false, "Principal whose password is set instead of the authenticated one (set-password).")
+	subparser_change_password.NewBoolArgument(&setPassword, "", "--set", false, "Use the set-password request of Active Directory instead of change-password.")
+	subparser_change_password.NewStringArgument(&transport, "", "--transport", "", false, "Transport to reach the KDC and the kpasswd server, udp or tcp. Defaults to udp for small requests.")
+	subparser_change_password.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_change_password.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
+
+	// rotate mode ============================================================================================================
+	subparser_rotate := asp.AddSubParser("rotate", "Add the keys of a new password with the next kvno and remove the oldest kvnos.")
+	subparser_rotate.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_rotate.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Path to the keytab file.")
+	subparser_rotate.NewStringArgument(&principal, "-p", "--principal", "", true, "Principal to rotate, the realm of the keytab entry is used when absent.")
+	subparser_rotate.NewStringArgument(&password, "", "--password", "", false, "New password of the principal.")
+	subparser_rotate.NewBoolArgument(&passwordStdin, "", "--password-stdin", false, "Read the new password from the first line of the standard input.")
+	subparser_rotate.NewIntArgument(&keep, "", "--keep", 2, false, "Number of kvnos to keep, including the new one. 0 keeps all of them.")
+	subparser_rotate.NewStringArgument(&salt, "", "--salt", "", false, "Salt of the AES keys. Defaults to the realm followed by the components of the principal.")
+	subparser_rotate.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_rotate.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
 
 	// add mode ============================================================================================================
 	subparser_add := asp.AddSubParser("add", "Add a new key to the keytab file.")
@@ -42,18 +160,123 @@ func parseArgs() {
 	subparser_add.NewStringArgument(&principal, "-p", "--principal", "", false, "Principal to add to the keytab file.")
 	subparser_add.NewStringArgument(&password, "-k", "--key", "", false, "Key to add to the keytab file.")
 	subparser_add.NewStringArgument(&key, "-k", "--key", "", false, "Key to add to the keytab file.")
+	subparser_add.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_add.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
 
 	// delete mode ============================================================================================================
 	subparser_delete := asp.AddSubParser("delete", "Delete a key from the keytab file.")
 	subparser_delete.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
 	subparser_delete.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", false, "Path to the keytab file.")
 	subparser_delete.NewStringArgument(&principal, "-p", "--principal", "", false, "Principal to delete from the keytab file.")
+	subparser_delete.NewIntArgument(&kvno, "", "--kvno", 0, false, "Key version number to delete. 0 deletes all of them.")
+	subparser_delete.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_delete.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
+
+	// rollback mode ============================================================================================================
+	subparser_rollback := asp.AddSubParser("rollback", "List the backups of a keytab file, diff one against it and restore it.")
+	subparser_rollback.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_rollback.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Path to the keytab file.")
+	subparser_rollback.NewStringArgument(&backupName, "-b", "--backup", "", false, "Backup to diff against the keytab file, as its number in the list or its path. Backups are listed when absent.")
+	subparser_rollback.NewBoolArgument(&restore, "", "--restore", false, "Restore the backup over the keytab file.")
+	subparser_rollback.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_rollback.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
+
+	// lint mode ============================================================================================================
+	subparser_lint := asp.AddSubParser("lint", "Audit a keytab file for weak, inconsistent or corrupt content.")
+	subparser_lint.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_lint.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Path to the keytab file.")
+	subparser_lint.NewStringArgument(&format, "", "--format", "text", false, "Output format, text or json.")
+	subparser_lint.NewStringArgument(&failOn, "", "--fail-on", "error", false, "Exit with code 1 when a finding is at least this severe: info, warning, error or none.")
+
+	// fingerprint mode ============================================================================================================
+	subparser_fingerprint := asp.AddSubParser("fingerprint", "Print the fingerprints identifying the keys of a keytab file without revealing them.")
+	subparser_fingerprint.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_fingerprint.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Path to the keytab file.")
+	subparser_fingerprint.NewStringArgument(&pepper, "", "--pepper", "", false, "Secret mixed in the fingerprints with HMAC-SHA256. Defaults to the KEYTAB_FINGERPRINT_PEPPER environment variable.")
+	subparser_fingerprint.NewStringArgument(&format, "", "--format", "table", false, "Output format, table or json.")
+	subparser_fingerprint.NewBoolArgument(&noColor, "", "--no-color", false, "Disable colors, which are also disabled when the output is not a terminal or NO_COLOR is set.")
+
+	// search mode ============================================================================================================
+	subparser_search := asp.AddSubParser("search", "Search keytab files and directories for the entries holding a key.")
+	subparser_search.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_search.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Paths of the keytab files or directories to search recursively, separated by commas.")
+	subparser_search.NewStringArgument(&key, "", "--key", "", false, "Hexadecimal key to search for.")
+	subparser_search.NewStringArgument(&fingerprint, "", "--fingerprint", "", false, "Fingerprint of the key to search for, as printed by the fingerprint mode.")
+	subparser_search.NewStringArgument(&enctype, "", "--enctype", "", false, "Encryption type of the key, as a name such as aes256-cts-hmac-sha1-96 or a number. Any encryption type matches when absent.")
+	subparser_search.NewStringArgument(&pepper, "", "--pepper", "", false, "Secret mixed in the fingerprints with HMAC-SHA256. Defaults to the KEYTAB_FINGERPRINT_PEPPER environment variable.")
+
+	// audit-reuse mode ============================================================================================================
+	subparser_audit_reuse := asp.AddSubParser("audit-reuse", "Find the keys shared by several principals or keytab files, revealing reused passwords.")
+	subparser_audit_reuse.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_audit_reuse.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Paths of the keytab files or directories to audit recursively, separated by commas.")
+	subparser_audit_reuse.NewStringArgument(&format, "", "--format", "text", false, "Output format, text or json.")
+	subparser_audit_reuse.NewStringArgument(&pepper, "", "--pepper", "", false, "Secret mixed in the fingerprints with HMAC-SHA256. Defaults to the KEYTAB_FINGERPRINT_PEPPER environment variable.")
+
+	// audit-weak mode ============================================================================================================
+	subparser_audit_weak := asp.AddSubParser("audit-weak", "Find the keys of a keytab file derived from default passwords or the passwords of a wordlist.")
+	subparser_audit_weak.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_audit_weak.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", true, "Path to the keytab file.")
+	subparser_audit_weak.NewStringArgument(&wordlist, "-w", "--wordlist", "", true, "Path to the wordlist, holding one password per line.")
+	subparser_audit_weak.NewIntArgument(&workers, "", "--workers", 0, false, "Number of passwords tested in parallel. Defaults to the number of CPUs.")
+	subparser_audit_weak.NewStringArgument(&salt, "", "--salt", "", false, "Salt of the salted keys. Defaults to trying the default salt and the salt of Active Directory computer accounts.")
+	subparser_audit_weak.NewStringArgument(&stateFile, "", "--state-file", "", false, "File saving the progress of the audit, resumed from when it exists. It holds the passwords found.")
+	subparser_audit_weak.NewStringArgument(&format, "", "--format", "text", false, "Output format, text or json.")
+
+	// import-secretsdump mode ============================================================================================================
+	subparser_import_secretsdump := asp.AddSubParser("import-secretsdump", "Import the Kerberos keys and NT hashes printed by secretsdump or a DCSync into keytab files.")
+	subparser_import_secretsdump.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_import_secretsdump.NewStringArgument(&inputFile, "-i", "--input-file", "", true, "Path to the output of secretsdump, or - for the standard input.")
+	subparser_import_secretsdump.NewStringArgument(&outputFile, "-o", "--output-file", "", true, "Path to the keytab file the keys are added to, or to the directory of the keytab files with --per-account.")
+	subparser_import_secretsdump.NewStringArgument(&realm, "-r", "--realm", "", false, "Realm of the principals. Defaults to the upper-case domain of each account.")
+	subparser_import_secretsdump.NewIntArgument(&kvno, "", "--kvno", 1, false, "Key version number of the keys, which secretsdump does not print.")
+	subparser_import_secretsdump.NewBoolArgument(&perAccount, "", "--per-account", false, "Write the keys of each account to its own keytab file, named after the account, in the output directory.")
+	subparser_import_secretsdump.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_import_secretsdump.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
+
+	// import-supplemental mode ============================================================================================================
+	subparser_import_supplemental := asp.AddSubParser("import-supplemental", "Import the Kerberos keys of an Active Directory supplementalCredentials attribute into a keytab file.")
+	subparser_import_supplemental.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_import_supplemental.NewStringArgument(&inputFile, "-i", "--input", "", true, "Decrypted supplementalCredentials, or its Primary:Kerberos-Newer-Keys or Primary:Kerberos property, as a raw file, hexadecimal or base64.")
+	subparser_import_supplemental.NewStringArgument(&principal, "-p", "--principal", "", true, "Principal of the account, as name@REALM.")
+	subparser_import_supplemental.NewIntArgument(&kvno, "", "--kvno", 0, true, "Key version number of the current keys, the msDS-KeyVersionNumber of the account. The previous keys get the previous kvnos.")
+	subparser_import_supplemental.NewStringArgument(&outputFile, "-o", "--output-file", "", true, "Path to the keytab file the keys are added to.")
+	subparser_import_supplemental.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_import_supplemental.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
+
+	// gmsa mode ============================================================================================================
+	subparser_gmsa := asp.AddSubParser("gmsa", "Derive the keys of a group managed service account from its msDS-ManagedPassword attribute into a keytab file.")
+	subparser_gmsa.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_gmsa.NewStringArgument(&inputFile, "-i", "--input", "", true, "msDS-ManagedPassword attribute, a MSDS-MANAGEDPASSWORD_BLOB, as a raw file, hexadecimal or base64.")
+	subparser_gmsa.NewStringArgument(&account, "-a", "--account", "", true, "sAMAccountName of the group managed service account, such as svc_web$.")
+	subparser_gmsa.NewStringArgument(&realm, "-r", "--realm", "", true, "Realm of the account.")
+	subparser_gmsa.NewIntArgument(&kvno, "", "--kvno", 0, true, "Key version number of the current keys, the msDS-KeyVersionNumber of the account. The previous keys get the previous kvno.")
+	subparser_gmsa.NewStringArgument(&outputFile, "-o", "--output-file", "", true, "Path to the keytab file the keys are added to.")
+	subparser_gmsa.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_gmsa.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
+
+	// machine-keytab mode ============================================================================================================
+	subparser_machine_keytab := asp.AddSubParser("machine-keytab", "Create the keytab file of an Active Directory computer account from its host name and password.")
+	subparser_machine_keytab.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
+	subparser_machine_keytab.NewStringArgument(&hostname, "", "--hostname", "", true, "Short host name or fully qualified domain name of the computer.")
+	subparser_machine_keytab.NewStringArgument(&realm, "-d", "--domain", "", true, "DNS name of the Active Directory domain, the realm.")
+	subparser_machine_keytab.NewStringArgument(&password, "", "--password", "", false, "Password of the computer account.")
+	subparser_machine_keytab.NewBoolArgument(&passwordStdin, "", "--password-stdin", false, "Read the password from the first line of the standard input.")
+	subparser_machine_keytab.NewIntArgument(&kvno, "", "--kvno", 0, true, "Key version number of the keys, the msDS-KeyVersionNumber of the computer account.")
+	subparser_machine_keytab.NewStringArgument(&services, "", "--services", "", false, "Additional service classes, such as HTTP,cifs,nfs, separated by commas. The host and RestrictedKrbHost services are always added.")
+	subparser_machine_keytab.NewStringArgument(&enctypes, "", "--enctypes", "0x1c", false, "msDS-SupportedEncryptionTypes value of the computer account, selecting the encryption types of the keys.")
+	subparser_machine_keytab.NewStringArgument(&outputFile, "-o", "--output-file", "", true, "Path to the keytab file the keys are added to.")
+	subparser_machine_keytab.NewBoolArgument(&followSymlinks, "", "--follow-symlinks", false, "Replace the target of the keytab file if it is a symbolic link, instead of refusing to.")
+	subparser_machine_keytab.NewIntArgument(&backups, "", "--backups", 0, false, "Number of timestamped backups of the keytab file to keep, backing it up before replacing it. 0 disables backups.")
 
 	// export mode ============================================================================================================
 	subparser_export := asp.AddSubParser("export", "Export the keytab file to a file.")
 	subparser_export.NewBoolArgument(&debug, "", "--debug", false, "Enable debug mode.")
 	subparser_export.NewStringArgument(&keytabFile, "-f", "--keytab-file", "", false, "Path to the keytab file.")
-	subparser_export.NewStringArgument(&outputFile, "-o", "--output-file", "", false, "Path to the output file.")
+	subparser_export.NewStringArgument(&outputFile, "-o", "--output-file", "", false, "Path to the output file. The export is written to the standard output when absent or -.")
+	subparser_export.NewBoolArgument(&showKeys, "", "--show-keys", false, "Export the keys, which are masked by default. Required by the impacket, rubeus, wireshark and generic formats.")
+	subparser_export.NewStringArgument(&principal, "-p", "--principal", "", false, "Principals to export, separated by commas, in any realm when absent. All principals are exported by default.")
+	subparser_export.NewIntArgument(&kvno, "", "--kvno", 0, false, "Key version number to export. 0 exports all of them.")
+	subparser_export.NewStringArgument(&enctype, "", "--enctype", "", false, "Encryption type to export, as a name such as aes256-cts-hmac-sha1-96 or a number. All are exported by default.")
 	subparser_export_group_format, err := subparser_export.NewRequiredMutuallyExclusiveArgumentGroup("Format")
 	if err != nil {
 		fmt.Printf("[error] Error creating ArgumentGroup: %s\n", err)
@@ -61,13 +284,27 @@ func parseArgs() {
 		subparser_export_group_format.NewBoolArgument(&jsonOutput, "", "--json", false, "Export the keytab file in JSON format.")
 		subparser_export_group_format.NewBoolArgument(&txtOutput, "", "--txt", false, "Export the keytab file in TXT format.")
 		subparser_export_group_format.NewBoolArgument(&csvOutput, "", "--csv", false, "Export the keytab file in CSV format.")
+		subparser_export_group_format.NewStringArgument(&format, "", "--format", "", false, "Export format: json, txt, csv, impacket (-aesKey and -hashes arguments), rubeus (/aes256: and /rc4: arguments), wireshark (keytab file) or generic (principal:enctype:hex lines).")
 	}
 
+	if machineReadableOutput(os.Args[1:]) {
+		asp.Banner = ""
+	}
 	asp.Parse()
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// run runs the selected mode and returns the exit code of the program, once the deferred calls have run.
+func run() (exitCode int) {
 	parseArgs()
+	keytab.KeepRawBytes = debug
+	keytab.FingerprintPepper = []byte(os.Getenv("KEYTAB_FINGERPRINT_PEPPER"))
+	if len(pepper) != 0 {
+		keytab.FingerprintPepper = []byte(pepper)
+	}
 
 	if mode == "describe" {
 		if _, err := os.Stat(keytabFile); err == nil {
@@ -76,24 +313,275 @@ func main() {
 				fmt.Println("Error parsing keytab file:", err)
 				return
 			}
-			kt.Describe(0)
+			defer kt.Close()
+			outputFormat, err := render.ParseFormat(format)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			r := render.NewRenderer(os.Stdout, noColor)
+			r.ShowKeys = showKeys
+			err = kt.Render(r, outputFormat)
+			if err != nil {
+				fmt.Println("Error rendering keytab file:", err)
+				return
+			}
 		} else {
 			fmt.Println("Keytab file does not exist.")
 		}
-	} else if mode == "add" {
-		if _, err := os.Stat(keytabFile); err == nil {
-			kt, err := keytab.LoadKeytabFromFile(keytabFile)
+	} else if mode == "describe-ccache" {
+		if _, err := os.Stat(ccacheFile); err == nil {
+			cc, err := ccache.LoadCCacheFromFile(ccacheFile)
 			if err != nil {
-				fmt.Println("Error parsing keytab file:", err)
+				fmt.Println("Error parsing ccache file:", err)
 				return
 			}
 
-			kt.AddKey(principal, key, password)
+			var kt *keytab.Keytab
+			if len(keytabFile) != 0 {
+				kt, err = keytab.LoadKeytabFromFile(keytabFile)
+				if err != nil {
+					fmt.Println("Error parsing keytab file:", err)
+					return
+				}
+				defer kt.Close()
+			}
 
-			kt.SaveToFile(keytabFile)
+			cc.Describe(0, kt, showKeys)
 		} else {
-			fmt.Println("Keytab file does not exist.")
+			fmt.Println("CCache file does not exist.")
 		}
+	} else if mode == "describe-kirbi" {
+		if _, err := os.Stat(kirbiFile); err == nil {
+			k, err := kirbi.LoadKirbiFromFile(kirbiFile)
+			if err != nil {
+				fmt.Println("Error parsing kirbi file:", err)
+				return
+			}
+
+			if len(keytabFile) != 0 && !k.Decrypted {
+				kt, err := keytab.LoadKeytabFromFile(keytabFile)
+				if err != nil {
+					fmt.Println("Error parsing keytab file:", err)
+					return
+				}
+				defer kt.Close()
+				err = k.Decrypt(kt)
+				if err != nil {
+					fmt.Println("Error decrypting kirbi enc-part:", err)
+				}
+			}
+
+			k.Describe(0, showKeys)
+		} else {
+			fmt.Println("Kirbi file does not exist.")
+		}
+	} else if mode == "convert" {
+		data, err := os.ReadFile(inputFile)
+		if err != nil {
+			fmt.Println("Error reading input file:", err)
+			return
+		}
+
+		if len(data) >= 2 && data[0] == 0x05 {
+			// ccache files start with the file format version 0x05XX
+			cc := &ccache.CCache{}
+			err = cc.FromBytes(data)
+			if err != nil {
+				fmt.Println("Error parsing ccache file:", err)
+				return
+			}
+			k, err := kirbi.FromCCache(cc)
+			if err != nil {
+				fmt.Println("Error converting ccache to kirbi:", err)
+				return
+			}
+			err = k.SaveToFile(outputFile)
+			if err != nil {
+				fmt.Println("Error writing kirbi file:", err)
+				return
+			}
+			fmt.Printf("[+] Converted ccache %s to kirbi %s (%d tickets)\n", inputFile, outputFile, len(k.Cred.Tickets))
+		} else {
+			k := &kirbi.Kirbi{}
+			err = k.FromBytes(data)
+			if err != nil {
+				fmt.Println("Error parsing kirbi file:", err)
+				return
+			}
+			if len(keytabFile) != 0 && !k.Decrypted {
+				kt, err := keytab.LoadKeytabFromFile(keytabFile)
+				if err != nil {
+					fmt.Println("Error parsing keytab file:", err)
+					return
+				}
+				defer kt.Close()
+				err = k.Decrypt(kt)
+				if err != nil {
+					fmt.Println("Error decrypting kirbi enc-part:", err)
+					return
+				}
+			}
+			cc, err := k.ToCCache()
+			if err != nil {
+				fmt.Println("Error converting kirbi to ccache:", err)
+				return
+			}
+			err = cc.SaveToFile(outputFile)
+			if err != nil {
+				fmt.Println("Error writing ccache file:", err)
+				return
+			}
+			fmt.Printf("[+] Converted kirbi %s to ccache %s (%d tickets)\n", inputFile, outputFile, len(cc.Credentials))
+		}
+	} else if mode == "pcap" {
+		if _, err := os.Stat(pcapFile); err == nil {
+			packets, err := pcap.LoadPacketsFromFile(pcapFile)
+			if err != nil {
+				fmt.Println("Error parsing capture file:", err)
+				if len(packets) == 0 {
+					return
+				}
+			}
+
+			timeline := pcap.NewTimeline(pcap.ExtractMessages(packets))
+			if len(keytabFile) != 0 {
+				kt, err := keytab.LoadKeytabFromFile(keytabFile)
+				if err != nil {
+					fmt.Println("Error parsing keytab file:", err)
+					return
+				}
+				defer kt.Close()
+				timeline.AddKeytab(kt)
+				timeline.ShowKeys = showKeys
+				timeline.Decrypt()
+			}
+
+			timeline.Describe(0)
+		} else {
+			fmt.Println("Capture file does not exist.")
+		}
+	} else if mode == "login" {
+		kt, err := keytab.LoadKeytabFromFile(keytabFile)
+		if err != nil {
+			fmt.Println("Error parsing keytab file:", err)
+			return
+		}
+		defer kt.Close()
+
+		result, err := login.Login(kt, principal, login.Options{
+			KDC:         kdcAddress,
+			Transport:   strings.ToLower(transport),
+			Forwardable: forwardable,
+		})
+		if err != nil {
+			fmt.Println("Error requesting TGT:", err)
+			return
+		}
+		fmt.Printf("[+] Authenticated as %s@%s with keytab entry #%d (%s, kvno %d, %s)\n",
+			result.Reply.CName.String(), result.Reply.CRealm, result.EntryIndex, result.Entry.Principal(),
+			result.Entry.KVNO(), result.Entry.Key.Type.String())
+
+		cc, err := result.CCache()
+		if err != nil {
+			fmt.Println("Error building ccache:", err)
+			return
+		}
+		if len(ccacheFile) == 0 {
+			ccacheFile = strings.Join(result.Reply.CName.NameString, "_") + ".ccache"
+		}
+		err = cc.SaveToFile(ccacheFile)
+		if err != nil {
+			fmt.Println("Error writing ccache file:", err)
+			return
+		}
+		fmt.Printf("[+] TGT valid until %s saved to %s\n", result.EncPart.EndTime.Local().Format("2006-01-02 15:04:05"), ccacheFile)
+	} else if mode == "change-password" {
+		password, err := newPassword("--new-password")
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		kt, err := keytab.LoadKeytabFromFile(keytabFile)
+		if err != nil {
+			fmt.Println("Error parsing keytab file:", err)
+			return
+		}
+		defer kt.Close()
+
+		result, err := kpasswd.ChangePassword(kt, principal, password, kpasswd.Options{
+			Login:       login.Options{KDC: kdcAddress, Transport: strings.ToLower(transport)},
+			Server:      kpasswdServer,
+			Target:      targetPrincipal,
+			SetPassword: setPassword,
+		})
+		if err != nil {
+			fmt.Println("Error changing password:", err)
+			return
+		}
+		fmt.Printf("[+] Password of %s changed (authenticated with keytab entry #%d, kvno %d, %s)\n",
+			result.Principal, result.Login.EntryIndex, result.Login.Entry.KVNO(), result.Login.Entry.Key.Type.String())
+
+		// Only the salts of the authenticated principal are known
+		name, realm, _ := strings.Cut(result.Principal, "@")
+		salts := result.Salts()
+		if result.Principal != result.Login.Reply.CName.String()+"@"+result.Login.Reply.CRealm {
+			salts = nil
+		}
+		kvno, err := kt.Rekey(realm, strings.Split(name, "/"), password, salts)
+		if err != nil {
+			fmt.Println("[!] The keytab was not updated:", err)
+			return
+		}
+		err = kt.SaveToFileWithOptions(keytabFile, saveOptions())
+		if err != nil {
+			fmt.Println("Error writing keytab file:", err)
+			return
+		}
+		fmt.Printf("[+] Keys of kvno %d added to %s, previous keys kept\n", kvno, keytabFile)
+	} else if mode == "rotate" {
+		password, err := newPassword("--password")
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		kt, err := keytab.LoadKeytabFromFile(keytabFile)
+		if err != nil {
+			fmt.Println("Error parsing keytab file:", err)
+			return
+		}
+		defer kt.Close()
+
+		var salts map[keytab.EncryptionType]string
+		if len(salt) != 0 {
+			salts = make(map[keytab.EncryptionType]string)
+			for _, etype := range []keytab.EncryptionType{
+				keytab.EncryptionType_AES128_CTS_HMAC_SHA1_96,
+				keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96,
+				keytab.EncryptionType_AES128_CTS_HMAC_SHA256_128,
+				keytab.EncryptionType_AES256_CTS_HMAC_SHA384_192,
+			} {
+				salts[etype] = salt
+			}
+		}
+		kvno, removed, err := kt.Rotate(principal, password, keep, salts)
+		if err != nil {
+			fmt.Println("Error rotating keys:", err)
+			return
+		}
+		err = kt.SaveToFileWithOptions(keytabFile, saveOptions())
+		if err != nil {
+			fmt.Println("Error writing keytab file:", err)
+			return
+		}
+		fmt.Printf("[+] Keys of kvno %d added to %s\n", kvno, keytabFile)
+		for _, removedKvno := range removed {
+			fmt.Printf("[+] Keys of kvno %d removed\n", removedKvno)
+		}
+	} else if mode == "add" {
+		// Keytab.AddKey does not add keys yet, so the keytab file is not rewritten
+		fmt.Println("Error: adding a key is not supported yet, use the rotate mode to add the keys of a password")
+		return 1
 	} else if mode == "delete" {
 		if _, err := os.Stat(keytabFile); err == nil {
 			kt, err := keytab.LoadKeytabFromFile(keytabFile)
@@ -101,13 +589,483 @@ func main() {
 				fmt.Println("Error parsing keytab file:", err)
 				return
 			}
+			defer kt.Close()
 
-			kt.DeleteKey(principal)
-
-			kt.SaveToFile(keytabFile)
+			deleted := kt.DeleteKey(principal, uint32(kvno))
+			if deleted == 0 {
+				fmt.Printf("Error: no entries of %s to delete, the keytab file is left untouched\n", principal)
+				return 1
+			}
+			err = kt.SaveToFileWithOptions(keytabFile, saveOptions())
+			if err != nil {
+				fmt.Println("Error writing keytab file:", err)
+				return
+			}
+			fmt.Printf("[+] %d entries deleted\n", deleted)
 		} else {
 			fmt.Println("Keytab file does not exist.")
 		}
+	} else if mode == "rollback" {
+		path := keytabFile
+		if followSymlinks {
+			if resolved, err := filepath.EvalSymlinks(path); err == nil {
+				path = resolved
+			}
+		}
+		backupList, err := keytab.ListBackups(path)
+		if err != nil {
+			fmt.Println("Error listing backups:", err)
+			return
+		}
+
+		if len(backupName) == 0 {
+			if len(backupList) == 0 {
+				fmt.Printf("[+] No backups of %s\n", keytabFile)
+				return
+			}
+			for i, backup := range backupList {
+				entries := "invalid keytab"
+				if kt, err := keytab.LoadKeytabFromFile(backup.Path); err == nil {
+					entries = fmt.Sprintf("%d entries", len(kt.Entries))
+					kt.Close()
+				}
+				fmt.Printf("[%d] %s  %s (%s)\n", i+1, backup.Time.Local().Format("2006-01-02 15:04:05"), backup.Path, entries)
+			}
+			return
+		}
+
+		backupPath := backupName
+		if number, err := strconv.Atoi(backupName); err == nil {
+			if number < 1 || number > len(backupList) {
+				fmt.Printf("Error: no backup #%d, %s has %d backups\n", number, keytabFile, len(backupList))
+				return
+			}
+			backupPath = backupList[number-1].Path
+		}
+		backupKeytab, err := keytab.LoadKeytabFromFile(backupPath)
+		if err != nil {
+			fmt.Println("Error parsing backup file:", err)
+			return
+		}
+		defer backupKeytab.Close()
+		currentKeytab, err := keytab.LoadKeytabFromFile(keytabFile)
+		if err != nil {
+			// A missing or corrupt keytab file can still be restored, all the entries of the backup are then added
+			fmt.Printf("[!] Could not load %s, all the entries of the backup are shown as added: %v\n", keytabFile, err)
+			currentKeytab = &keytab.Keytab{}
+		}
+		defer currentKeytab.Close()
+
+		removed, added := currentKeytab.Diff(backupKeytab)
+		fmt.Printf("[+] Changes restoring %s over %s:\n", backupPath, keytabFile)
+		for _, entry := range removed {
+			fmt.Printf("  - %s kvno %d %s key %s\n", entry.Principal(), entry.KVNO(), entry.Key.Type.String(), entry.Key.Fingerprint())
+		}
+		for _, entry := range added {
+			fmt.Printf("  + %s kvno %d %s key %s\n", entry.Principal(), entry.KVNO(), entry.Key.Type.String(), entry.Key.Fingerprint())
+		}
+		if len(removed) == 0 && len(added) == 0 {
+			fmt.Println("  (none, the entries are identical)")
+		}
+
+		if restore {
+			err = keytab.RestoreBackup(keytabFile, backupPath, saveOptions())
+			if err != nil {
+				fmt.Println("Error restoring backup:", err)
+				return
+			}
+			fmt.Printf("[+] %s restored from %s\n", keytabFile, backupPath)
+		}
+	} else if mode == "lint" {
+		threshold := keytab.Severity(-1)
+		if !strings.EqualFold(failOn, "none") {
+			var err error
+			threshold, err = keytab.ParseSeverity(failOn)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return 1
+			}
+		}
+		if format != "text" && format != "json" {
+			fmt.Printf("Error: unknown format %q, expected text or json\n", format)
+			return 1
+		}
+		kt, err := keytab.LoadKeytabFromFile(keytabFile)
+		if err != nil {
+			fmt.Println("Error parsing keytab file:", err)
+			return 1
+		}
+		defer kt.Close()
+
+		findings := kt.Lint()
+		counts := make(map[keytab.Severity]int)
+		failed := false
+		for _, finding := range findings {
+			counts[finding.Severity]++
+			if threshold >= 0 && finding.Severity >= threshold {
+				failed = true
+			}
+		}
+		if format == "json" {
+			data, err := json.MarshalIndent(findings, "", "  ")
+			if err != nil {
+				fmt.Println("Error encoding findings:", err)
+				return 1
+			}
+			fmt.Println(string(data))
+		} else {
+			for _, finding := range findings {
+				fmt.Println(finding.String())
+			}
+			fmt.Printf("[+] %d findings in %s (%d errors, %d warnings, %d infos)\n", len(findings), keytabFile, counts[keytab.Severity_Error], counts[keytab.Severity_Warning], counts[keytab.Severity_Info])
+		}
+		if failed {
+			return 1
+		}
+	} else if mode == "fingerprint" {
+		outputFormat, err := render.ParseFormat(format)
+		if err != nil || (outputFormat != render.Format_Table && outputFormat != render.Format_JSON) {
+			fmt.Printf("Error: unknown format %q, expected table or json\n", format)
+			return
+		}
+		kt, err := keytab.LoadKeytabFromFile(keytabFile)
+		if err != nil {
+			fmt.Println("Error parsing keytab file:", err)
+			return
+		}
+		defer kt.Close()
+
+		r := render.NewRenderer(os.Stdout, noColor)
+		if outputFormat == render.Format_JSON {
+			err = r.JSON(kt.Fingerprints())
+			if err != nil {
+				fmt.Println("Error encoding fingerprints:", err)
+			}
+			return
+		}
+		rows := make([][]string, 0, len(kt.Entries))
+		for _, fingerprint := range kt.Fingerprints() {
+			rows = append(rows, []string{fingerprint.Principal, fmt.Sprintf("%d", fingerprint.Kvno), fingerprint.EncryptionType, fingerprint.Fingerprint})
+		}
+		r.Table([]string{"PRINCIPAL", "KVNO", "ENCTYPE", "FINGERPRINT"}, rows)
+	} else if mode == "search" {
+		if (len(key) == 0) == (len(fingerprint) == 0) {
+			fmt.Println("Error: give either --key or --fingerprint")
+			return 1
+		}
+		etype := keytab.EncryptionType_NULL
+		if len(enctype) != 0 {
+			var err error
+			etype, err = keytab.ParseEncryptionType(enctype)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return 1
+			}
+		}
+		keyBytes, err := hex.DecodeString(key)
+		if err != nil {
+			fmt.Println("Error: invalid hexadecimal key:", err)
+			return 1
+		}
+		defer clear(keyBytes)
+
+		matches := 0
+		err = keytab.WalkKeytabs(strings.Split(keytabFile, ","), func(path string, kt *keytab.Keytab, err error) error {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Skipping %s: %v\n", path, err)
+				return nil
+			}
+			var entries []*keytab.KeytabEntry
+			if len(fingerprint) != 0 {
+				entries, err = kt.SearchFingerprint(fingerprint, etype)
+				if err != nil {
+					return err
+				}
+			} else {
+				entries = kt.SearchKey(keyBytes, etype)
+			}
+			for _, entry := range entries {
+				fmt.Printf("[+] %s: %s (kvno %d, %s)\n", path, entry.Principal(), entry.KVNO(), entry.Key.Type.String())
+			}
+			matches += len(entries)
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+		fmt.Printf("[+] %d matching entries\n", matches)
+		if matches == 0 {
+			return 1
+		}
+	} else if mode == "audit-reuse" {
+		if format != "text" && format != "json" {
+			fmt.Printf("Error: unknown format %q, expected text or json\n", format)
+			return 1
+		}
+		auditor := keytab.NewReuseAuditor()
+		files := 0
+		err := keytab.WalkKeytabs(strings.Split(keytabFile, ","), func(path string, kt *keytab.Keytab, err error) error {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Skipping %s: %v\n", path, err)
+				return nil
+			}
+			auditor.Add(path, kt)
+			files++
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+
+		groups := auditor.Groups()
+		if format == "json" {
+			data, err := json.MarshalIndent(groups, "", "  ")
+			if err != nil {
+				fmt.Println("Error encoding groups:", err)
+				return 1
+			}
+			fmt.Println(string(data))
+		} else {
+			for _, group := range groups {
+				fmt.Println(group.String())
+				for _, location := range group.Locations {
+					fmt.Printf("  - %s: %s (kvno %d, entry #%d)\n", location.Path, location.Principal, location.Kvno, location.Entry)
+				}
+			}
+			fmt.Printf("[+] %d reused keys in %d keytab files\n", len(groups), files)
+		}
+		if len(groups) != 0 {
+			return 1
+		}
+	} else if mode == "audit-weak" {
+		if format != "text" && format != "json" {
+			fmt.Printf("Error: unknown format %q, expected text or json\n", format)
+			return 1
+		}
+		kt, err := keytab.LoadKeytabFromFile(keytabFile)
+		if err != nil {
+			fmt.Println("Error parsing keytab file:", err)
+			return 1
+		}
+		defer kt.Close()
+
+		options := keytab.WeakAuditOptions{Workers: workers, Salt: salt}
+		if len(stateFile) != 0 {
+			if _, err := os.Stat(stateFile); err == nil {
+				options.State, err = keytab.LoadWeakAuditState(stateFile)
+				if err != nil {
+					fmt.Println("Error:", err)
+					return 1
+				}
+				fmt.Fprintf(os.Stderr, "[+] Resuming from phase %d, line %d of %s\n", options.State.Phase, options.State.Line, stateFile)
+			}
+		}
+		phases := map[int]string{keytab.WeakPhase_Unsalted: "unsalted keys", keytab.WeakPhase_Salted: "salted keys"}
+		lastProgress := time.Time{}
+		options.Progress = func(state keytab.WeakAuditState) {
+			if time.Since(lastProgress) < time.Second && state.Line != state.Lines {
+				return
+			}
+			lastProgress = time.Now()
+			if len(stateFile) != 0 {
+				if err := state.SaveToFile(stateFile); err != nil {
+					fmt.Fprintf(os.Stderr, "[!] Error saving state file: %v\n", err)
+				}
+			}
+			if state.Phase < keytab.WeakPhase_Done && state.Lines != 0 {
+				fmt.Fprintf(os.Stderr, "[>] Phase %d/2 (%s): %d/%d lines (%.1f%%), %d weak keys found\n", state.Phase, phases[state.Phase], state.Line, state.Lines, 100*float64(state.Line)/float64(state.Lines), len(state.Found))
+			}
+		}
+
+		found, err := kt.AuditWeak(wordlist, options)
+		if err != nil {
+			fmt.Println("Error auditing keytab file:", err)
+			return 1
+		}
+		if format == "json" {
+			data, err := json.MarshalIndent(found, "", "  ")
+			if err != nil {
+				fmt.Println("Error encoding weak keys:", err)
+				return 1
+			}
+			fmt.Println(string(data))
+		} else {
+			for _, key := range found {
+				fmt.Printf("[!] %s (kvno %d, %s, entry #%d): password %q from the %s passwords\n", key.Principal, key.Kvno, key.EncryptionType, key.Entry, key.Password, key.Source)
+			}
+			fmt.Printf("[+] %d of %d keys derived from weak passwords in %s\n", len(found), len(kt.Entries), keytabFile)
+		}
+		if len(found) != 0 {
+			return 1
+		}
+	} else if mode == "import-secretsdump" {
+		input := os.Stdin
+		if inputFile != "-" {
+			file, err := os.Open(inputFile)
+			if err != nil {
+				fmt.Println("Error opening input file:", err)
+				return
+			}
+			defer file.Close()
+			input = file
+		}
+		entries, warnings, err := keytab.ParseSecretsDump(input, realm, uint32(kvno))
+		if err != nil {
+			fmt.Println("Error parsing secretsdump output:", err)
+			return
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "[!] %s\n", warning)
+		}
+		if len(entries) == 0 {
+			fmt.Println("Error: no keys found in", inputFile)
+			return
+		}
+
+		// The entries of each keytab file, in the order of their first account
+		paths := []string{}
+		keytabs := make(map[string][]keytab.KeytabEntry)
+		for _, entry := range entries {
+			path := outputFile
+			if perAccount {
+				name := strings.NewReplacer("/", "_", "\\", "_").Replace(string(entry.Components[0].Data))
+				path = filepath.Join(outputFile, name+".keytab")
+			}
+			if _, exists := keytabs[path]; !exists {
+				paths = append(paths, path)
+			}
+			keytabs[path] = append(keytabs[path], entry)
+		}
+		if perAccount {
+			if err = os.MkdirAll(outputFile, 0700); err != nil {
+				fmt.Println("Error creating output directory:", err)
+				return
+			}
+		}
+		for _, path := range paths {
+			added, err := addEntries(path, keytabs[path])
+			if err != nil {
+				fmt.Printf("Error writing keytab file %s: %v\n", path, err)
+				return
+			}
+			fmt.Printf("[+] Added %d keys to %s\n", added, path)
+		}
+	} else if mode == "import-supplemental" {
+		name, principalRealm, found := strings.Cut(principal, "@")
+		if !found || len(name) == 0 || len(principalRealm) == 0 {
+			fmt.Printf("Error: invalid principal %q, expected name@REALM\n", principal)
+			return
+		}
+		data, err := utils.ReadBlob(inputFile)
+		if err != nil {
+			fmt.Println("Error reading supplemental credentials:", err)
+			return
+		}
+		credential, err := ad.ParseSupplementalCredentials(data)
+		clear(data)
+		if err != nil {
+			fmt.Println("Error parsing supplemental credentials:", err)
+			return
+		}
+		defer credential.Wipe()
+
+		fmt.Printf("[+] Revision %d credentials, salt %q, %d iterations\n", credential.Revision, credential.DefaultSalt, credential.DefaultIterationCount)
+		entries, err := credential.KeytabEntries(principalRealm, strings.Split(name, "/"), messages.NameType_PRINCIPAL, uint32(kvno))
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		for _, entry := range entries {
+			fmt.Printf("  - %s (kvno %d, %s): %s\n", entry.Principal(), entry.KVNO(), entry.Key.Type.String(), entry.Key.Masked())
+		}
+		added, err := addEntries(outputFile, entries)
+		if err != nil {
+			fmt.Printf("Error writing keytab file %s: %v\n", outputFile, err)
+			return
+		}
+		fmt.Printf("[+] Added %d keys to %s\n", added, outputFile)
+	} else if mode == "gmsa" {
+		data, err := utils.ReadBlob(inputFile)
+		if err != nil {
+			fmt.Println("Error reading managed password:", err)
+			return
+		}
+		managedPassword, err := ad.ParseManagedPassword(data)
+		clear(data)
+		if err != nil {
+			fmt.Println("Error parsing managed password:", err)
+			return
+		}
+		defer managedPassword.Wipe()
+
+		fmt.Printf("[+] Query the password again within %s, unchanged for %s\n", managedPassword.QueryPasswordInterval, managedPassword.UnchangedPasswordInterval)
+		if len(managedPassword.PreviousPassword) == 0 {
+			fmt.Println("[+] No previous password")
+		}
+		entries, err := managedPassword.KeytabEntries(realm, account, uint32(kvno))
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		for _, entry := range entries {
+			fmt.Printf("  - %s (kvno %d, %s): %s\n", entry.Principal(), entry.KVNO(), entry.Key.Type.String(), entry.Key.Masked())
+		}
+		added, err := addEntries(outputFile, entries)
+		if err != nil {
+			fmt.Printf("Error writing keytab file %s: %v\n", outputFile, err)
+			return
+		}
+		fmt.Printf("[+] Added %d keys to %s\n", added, outputFile)
+	} else if mode == "machine-keytab" {
+		machine, err := ad.NewMachineAccount(realm, hostname)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		value, err := strconv.ParseUint(enctypes, 0, 32)
+		if err != nil {
+			fmt.Printf("Error: invalid msDS-SupportedEncryptionTypes value %q\n", enctypes)
+			return
+		}
+		etypes := []keytab.EncryptionType{}
+		for _, etype := range ad.SupportedEncryptionTypes(uint32(value)) {
+			if !crypto.IsSupported(int32(etype)) {
+				fmt.Fprintf(os.Stderr, "[!] Skipping the %s keys, which can not be derived\n", etype.String())
+				continue
+			}
+			etypes = append(etypes, etype)
+		}
+		if len(etypes) == 0 {
+			fmt.Printf("Error: no supported encryption type in %s\n", enctypes)
+			return
+		}
+		password, err := newPassword("--password")
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		serviceClasses := []string{}
+		if len(services) != 0 {
+			serviceClasses = strings.Split(services, ",")
+		}
+		entries, err := machine.KeytabEntries(password, uint32(kvno), etypes, serviceClasses)
+		if err != nil {
+			fmt.Println("Error deriving keys:", err)
+			return
+		}
+		fmt.Printf("[+] Keys of %s@%s salted with %q\n", machine.Account, machine.Realm, machine.Salt())
+		for _, components := range machine.Principals(serviceClasses) {
+			fmt.Printf("  - %s@%s\n", strings.Join(components, "/"), machine.Realm)
+		}
+		added, err := addEntries(outputFile, entries)
+		if err != nil {
+			fmt.Printf("Error writing keytab file %s: %v\n", outputFile, err)
+			return
+		}
+		fmt.Printf("[+] Added %d keys to %s\n", added, outputFile)
 	} else if mode == "export" {
 		if _, err := os.Stat(keytabFile); err == nil {
 			kt, err := keytab.LoadKeytabFromFile(keytabFile)
@@ -115,10 +1073,139 @@ func main() {
 				fmt.Println("Error parsing keytab file:", err)
 				return
 			}
+			defer kt.Close()
+
+			exportFormat := keytab.ExportFormat_JSON
+			if txtOutput {
+				exportFormat = keytab.ExportFormat_TXT
+			} else if csvOutput {
+				exportFormat = keytab.ExportFormat_CSV
+			} else if !jsonOutput {
+				exportFormat, err = keytab.ParseExportFormat(format)
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+			}
+			filter := keytab.Filter{Kvno: uint32(kvno)}
+			if len(enctype) != 0 {
+				filter.EncryptionType, err = keytab.ParseEncryptionType(enctype)
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+			}
+			filters := []keytab.Filter{filter}
+			if len(principal) != 0 {
+				filters = nil
+				for _, name := range strings.Split(principal, ",") {
+					filters = append(filters, keytab.Filter{Principal: name, Kvno: filter.Kvno, EncryptionType: filter.EncryptionType})
+				}
+			}
+			subset := kt.Subset(filters)
+			defer subset.Close()
+			if len(subset.Entries) == 0 {
+				fmt.Println("Error: no entry matches the filters")
+				return
+			}
 
-			kt.Export(outputFile, jsonOutput, txtOutput, csvOutput)
+			if len(outputFile) == 0 || outputFile == "-" {
+				err = subset.ExportTo(os.Stdout, exportFormat, showKeys)
+			} else {
+				err = subset.Export(outputFile, exportFormat, showKeys)
+			}
+			if err != nil {
+				fmt.Println("Error exporting keytab file:", err)
+				return
+			}
+			if len(outputFile) != 0 && outputFile != "-" {
+				fmt.Printf("[+] %d entries exported to %s\n", len(subset.Entries), outputFile)
+			}
 		} else {
 			fmt.Println("Keytab file does not exist.")
 		}
 	}
+	return exitCode
+}
+
+// newPassword returns the new password given with the flag or, with --password-stdin, read from the standard input.
+func newPassword(flag string) (string, error) {
+	value := password
+	if passwordStdin {
+		var err error
+		value, err = utils.ReadPassword(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(value) == 0 {
+		return "", fmt.Errorf("no new password, use %s or --password-stdin", flag)
+	}
+	return value, nil
+}
+
+// saveOptions returns the options of the saves of keytab files given with the flags.
+func saveOptions() keytab.SaveOptions {
+	return keytab.SaveOptions{FollowSymlinks: followSymlinks, Backups: backups}
+}
+
+// addEntries adds entries to a keytab file, created when it does not exist, skipping the entries it already holds.
+func addEntries(path string, entries []keytab.KeytabEntry) (int, error) {
+	kt := &keytab.Keytab{FileFormatVersion: 0x502}
+	if _, err := os.Stat(path); err == nil {
+		kt, err = keytab.LoadKeytabFromFile(path)
+		if err != nil {
+			return 0, err
+		}
+	}
+	defer kt.Close()
+
+	added := 0
+	for _, entry := range entries {
+		duplicate := false
+		for i := range kt.Entries {
+			if kt.Entries[i].Principal() == entry.Principal() && kt.Entries[i].KVNO() == entry.KVNO() && kt.Entries[i].Key.Equal(entry.Key) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kt.Entries = append(kt.Entries, entry)
+			added++
+		}
+	}
+	kt.InvalidateIndex()
+	return added, kt.SaveToFileWithOptions(path, saveOptions())
+}
+
+// machineReadableOutput returns whether the arguments select an output meant to be parsed or piped,
+// such as the JSON and YAML formats or an export to the standard output, which the banner would corrupt.
+func machineReadableOutput(args []string) bool {
+	if len(args) != 0 && strings.EqualFold(args[0], "export") {
+		toStdout := true
+		for i, arg := range args {
+			if (arg == "-o" || arg == "--output-file") && i+1 < len(args) {
+				toStdout = args[i+1] == "-"
+			} else if value, found := strings.CutPrefix(arg, "--output-file="); found {
+				toStdout = value == "-"
+			}
+		}
+		if toStdout {
+			return true
+		}
+	}
+	for i, arg := range args {
+		value, found := strings.CutPrefix(arg, "--format=")
+		if !found && arg == "--format" && i+1 < len(args) {
+			value, found = args[i+1], true
+		}
+		if found {
+			switch strings.ToLower(value) {
+			case "", "text", "tree", "table":
+			default:
+				return true
+			}
+		}
+	}
+	return false
 }
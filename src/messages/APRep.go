@@ -0,0 +1,134 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// APRep represents an AP-REP message, as defined in RFC 4120 section 5.5.2.
+//
+// Attributes:
+//   - Pvno (int32): The protocol version number, always 5.
+//   - MsgType (int32): The message type, always 15.
+//   - EncPart (EncryptedData): The EncAPRepPart, encrypted in the session key of the ticket.
+type APRep struct {
+	Pvno    int32
+	MsgType int32
+	EncPart EncryptedData
+}
+
+// EncAPRepPart represents the encrypted part of an AP-REP message.
+//
+// Attributes:
+//   - CTime (time.Time): The client time of the authenticator being answered.
+//   - Cusec (int32): The microseconds part of the client time.
+//   - SubKey (*EncryptionKey): The sub-session key chosen by the server, may be nil.
+//   - SeqNumber (uint32): The initial sequence number of the server, if HasSeqNumber is set.
+//   - HasSeqNumber (bool): Whether the sequence number is present.
+type EncAPRepPart struct {
+	CTime        time.Time
+	Cusec        int32
+	SubKey       *EncryptionKey
+	SeqNumber    uint32
+	HasSeqNumber bool
+}
+
+// FromBytes parses the DER encoding of an AP-REP.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded message.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (a *APRep) FromBytes(data []byte) error {
+	fields, err := parseFields(data, MsgType_AP_REP)
+	if err != nil {
+		return fmt.Errorf("error parsing AP-REP: %w", err)
+	}
+
+	pvno, err := requireInt(fields, 0, "pvno")
+	if err != nil {
+		return err
+	}
+	a.Pvno = int32(pvno)
+
+	msgType, err := requireInt(fields, 1, "msg-type")
+	if err != nil {
+		return err
+	}
+	a.MsgType = int32(msgType)
+
+	encPart, ok := fields[2]
+	if !ok {
+		return fmt.Errorf("missing field enc-part")
+	}
+	return a.EncPart.fromElement(encPart)
+}
+
+// ToBytes converts an APRep to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (a *APRep) ToBytes() ([]byte, error) {
+	return der.Application(MsgType_AP_REP, der.Sequence(
+		der.Explicit(0, der.Integer(int64(a.Pvno))),
+		der.Explicit(1, der.Integer(int64(a.MsgType))),
+		der.Explicit(2, a.EncPart.marshal()),
+	)), nil
+}
+
+// FromBytes parses the DER encoding of an EncAPRepPart.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded decrypted part.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (e *EncAPRepPart) FromBytes(data []byte) error {
+	fields, err := parseFields(data, 27)
+	if err != nil {
+		return fmt.Errorf("error parsing EncAPRepPart: %w", err)
+	}
+
+	if e.CTime, err = requireTime(fields, 0, "ctime"); err != nil {
+		return err
+	}
+
+	cusec, err := requireInt(fields, 1, "cusec")
+	if err != nil {
+		return err
+	}
+	e.Cusec = int32(cusec)
+
+	if subkey, ok := fields[2]; ok {
+		e.SubKey = &EncryptionKey{}
+		if err = e.SubKey.fromElement(subkey); err != nil {
+			return err
+		}
+	}
+
+	seqNumber, hasSeqNumber, err := optionalInt(fields, 3, "seq-number")
+	e.SeqNumber, e.HasSeqNumber = uint32(seqNumber), hasSeqNumber
+	return err
+}
+
+// ToBytes converts an EncAPRepPart to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (e *EncAPRepPart) ToBytes() ([]byte, error) {
+	var subkey, seqNumber []byte
+	if e.SubKey != nil {
+		subkey = der.Explicit(2, e.SubKey.marshal())
+	}
+	if e.HasSeqNumber {
+		seqNumber = der.Explicit(3, der.Integer(int64(e.SeqNumber)))
+	}
+	return der.Application(27, der.Sequence(
+		der.Explicit(0, der.GeneralizedTime(e.CTime)),
+		der.Explicit(1, der.Integer(int64(e.Cusec))),
+		subkey,
+		seqNumber,
+	)), nil
+}
@@ -0,0 +1,206 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// AP options, as defined in RFC 4120 section 5.5.1, in the KerberosFlags bit order.
+const (
+	APOption_UseSessionKey  uint32 = 0x40000000
+	APOption_MutualRequired uint32 = 0x20000000
+)
+
+// APReq represents an AP-REQ message, as defined in RFC 4120 section 5.5.1.
+//
+// Attributes:
+//   - Pvno (int32): The protocol version number, always 5.
+//   - MsgType (int32): The message type, always 14.
+//   - APOptions (uint32): The AP options.
+//   - Ticket (Ticket): The ticket for the server.
+//   - Authenticator (EncryptedData): The Authenticator, encrypted in the session key of the ticket.
+type APReq struct {
+	Pvno          int32
+	MsgType       int32
+	APOptions     uint32
+	Ticket        Ticket
+	Authenticator EncryptedData
+}
+
+// Authenticator represents a Kerberos Authenticator, as defined in RFC 4120 section 5.5.1.
+//
+// Attributes:
+//   - AuthenticatorVno (int32): The version number of the authenticator format, always 5.
+//   - CRealm (string): The realm of the client.
+//   - CName (PrincipalName): The name of the client.
+//   - Cksum (*Checksum): The application checksum, may be nil.
+//   - Cusec (int32): The microseconds part of the client time.
+//   - CTime (time.Time): The client time.
+//   - SubKey (*EncryptionKey): The sub-session key chosen by the client, may be nil.
+//   - SeqNumber (uint32): The initial sequence number, if HasSeqNumber is set.
+//   - HasSeqNumber (bool): Whether the sequence number is present.
+//   - AuthorizationData ([]AuthorizationDataEntry): The authorization data.
+type Authenticator struct {
+	AuthenticatorVno  int32
+	CRealm            string
+	CName             PrincipalName
+	Cksum             *Checksum
+	Cusec             int32
+	CTime             time.Time
+	SubKey            *EncryptionKey
+	SeqNumber         uint32
+	HasSeqNumber      bool
+	AuthorizationData []AuthorizationDataEntry
+}
+
+// FromBytes parses the DER encoding of an AP-REQ.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded message.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (a *APReq) FromBytes(data []byte) error {
+	fields, err := parseFields(data, MsgType_AP_REQ)
+	if err != nil {
+		return fmt.Errorf("error parsing AP-REQ: %w", err)
+	}
+
+	pvno, err := requireInt(fields, 0, "pvno")
+	if err != nil {
+		return err
+	}
+	a.Pvno = int32(pvno)
+
+	msgType, err := requireInt(fields, 1, "msg-type")
+	if err != nil {
+		return err
+	}
+	a.MsgType = int32(msgType)
+
+	if a.APOptions, err = optionalFlags(fields, 2, "ap-options"); err != nil {
+		return err
+	}
+
+	ticket, ok := fields[3]
+	if !ok {
+		return fmt.Errorf("missing field ticket")
+	}
+	if err = a.Ticket.fromElement(ticket); err != nil {
+		return err
+	}
+
+	authenticator, ok := fields[4]
+	if !ok {
+		return fmt.Errorf("missing field authenticator")
+	}
+	return a.Authenticator.fromElement(authenticator)
+}
+
+// ToBytes converts an APReq to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (a *APReq) ToBytes() ([]byte, error) {
+	return der.Application(MsgType_AP_REQ, der.Sequence(
+		der.Explicit(0, der.Integer(int64(a.Pvno))),
+		der.Explicit(1, der.Integer(int64(a.MsgType))),
+		der.Explicit(2, der.BitString(a.APOptions)),
+		der.Explicit(3, a.Ticket.marshal()),
+		der.Explicit(4, a.Authenticator.marshal()),
+	)), nil
+}
+
+// FromBytes parses the DER encoding of an Authenticator.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded decrypted authenticator.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (a *Authenticator) FromBytes(data []byte) error {
+	fields, err := parseFields(data, 2)
+	if err != nil {
+		return fmt.Errorf("error parsing Authenticator: %w", err)
+	}
+
+	vno, err := requireInt(fields, 0, "authenticator-vno")
+	if err != nil {
+		return err
+	}
+	a.AuthenticatorVno = int32(vno)
+
+	if a.CRealm, err = requireText(fields, 1, "crealm"); err != nil {
+		return err
+	}
+
+	cname, ok := fields[2]
+	if !ok {
+		return fmt.Errorf("missing field cname")
+	}
+	if err = a.CName.fromElement(cname); err != nil {
+		return err
+	}
+
+	if cksum, ok := fields[3]; ok {
+		a.Cksum = &Checksum{}
+		if err = a.Cksum.fromElement(cksum); err != nil {
+			return err
+		}
+	}
+
+	cusec, err := requireInt(fields, 4, "cusec")
+	if err != nil {
+		return err
+	}
+	a.Cusec = int32(cusec)
+
+	if a.CTime, err = requireTime(fields, 5, "ctime"); err != nil {
+		return err
+	}
+
+	if subkey, ok := fields[6]; ok {
+		a.SubKey = &EncryptionKey{}
+		if err = a.SubKey.fromElement(subkey); err != nil {
+			return err
+		}
+	}
+
+	seqNumber, hasSeqNumber, err := optionalInt(fields, 7, "seq-number")
+	if err != nil {
+		return err
+	}
+	a.SeqNumber, a.HasSeqNumber = uint32(seqNumber), hasSeqNumber
+
+	a.AuthorizationData, err = parseAuthorizationData(fields, 8, "authorization-data")
+	return err
+}
+
+// ToBytes converts an Authenticator to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (a *Authenticator) ToBytes() ([]byte, error) {
+	var cksum, subkey, seqNumber []byte
+	if a.Cksum != nil {
+		cksum = der.Explicit(3, a.Cksum.marshal())
+	}
+	if a.SubKey != nil {
+		subkey = der.Explicit(6, a.SubKey.marshal())
+	}
+	if a.HasSeqNumber {
+		seqNumber = der.Explicit(7, der.Integer(int64(a.SeqNumber)))
+	}
+	return der.Application(2, der.Sequence(
+		der.Explicit(0, der.Integer(int64(a.AuthenticatorVno))),
+		der.Explicit(1, der.GeneralString(a.CRealm)),
+		der.Explicit(2, a.CName.marshal()),
+		cksum,
+		der.Explicit(4, der.Integer(int64(a.Cusec))),
+		der.Explicit(5, der.GeneralizedTime(a.CTime)),
+		subkey,
+		seqNumber,
+		der.Explicit(8, MarshalAuthorizationData(a.AuthorizationData)),
+	)), nil
+}
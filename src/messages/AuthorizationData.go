@@ -0,0 +1,95 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+)
+
+// AuthorizationDataEntry represents a single element of a Kerberos AuthorizationData,
+// as defined in RFC 4120 section 5.2.6.
+//
+// Attributes:
+//   - ADType (int32): The type of the authorization data.
+//   - ADData ([]byte): The authorization data.
+type AuthorizationDataEntry struct {
+	ADType int32
+	ADData []byte
+}
+
+// Authorization data types, as defined in RFC 4120 section 7.5.4 and MS-PAC.
+const (
+	ADType_IF_RELEVANT = 1
+	ADType_WIN2K_PAC   = 128
+)
+
+// parseAuthorizationData parses an optional AuthorizationData field.
+func parseAuthorizationData(fields map[int]der.Element, tag int, name string) ([]AuthorizationDataEntry, error) {
+	elements, err := sequenceOf(fields, tag, name)
+	if err != nil || elements == nil {
+		return nil, err
+	}
+	return authorizationDataFromElements(elements)
+}
+
+// ParseAuthorizationData parses the DER encoding of an AuthorizationData, for example the
+// content of an AD-IF-RELEVANT element.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded authorization data.
+//
+// Returns:
+//   - ([]AuthorizationDataEntry, error): The authorization data elements and an error if the parsing failed.
+func ParseAuthorizationData(data []byte) ([]AuthorizationDataEntry, error) {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := element.Children()
+	if err != nil {
+		return nil, err
+	}
+	return authorizationDataFromElements(elements)
+}
+
+// authorizationDataFromElements parses the elements of an AuthorizationData sequence.
+func authorizationDataFromElements(elements []der.Element) ([]AuthorizationDataEntry, error) {
+	entries := make([]AuthorizationDataEntry, 0, len(elements))
+	for _, element := range elements {
+		fields, err := element.Fields()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing authorization data: %w", err)
+		}
+		adType, err := requireInt(fields, 0, "ad-type")
+		if err != nil {
+			return nil, err
+		}
+		adData, err := requireBytes(fields, 1, "ad-data")
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, AuthorizationDataEntry{ADType: int32(adType), ADData: adData})
+	}
+	return entries, nil
+}
+
+// MarshalAuthorizationData returns the DER encoding of an AuthorizationData, or nil if it is empty.
+//
+// Parameters:
+//   - entries ([]AuthorizationDataEntry): The authorization data elements.
+//
+// Returns:
+//   - []byte: The DER encoded authorization data.
+func MarshalAuthorizationData(entries []AuthorizationDataEntry) []byte {
+	if len(entries) == 0 {
+		return nil
+	}
+	encoded := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		encoded = append(encoded, der.Sequence(
+			der.Explicit(0, der.Integer(int64(entry.ADType))),
+			der.Explicit(1, der.OctetString(entry.ADData)),
+		))
+	}
+	return der.Sequence(encoded...)
+}
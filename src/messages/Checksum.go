@@ -0,0 +1,41 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+)
+
+// Checksum represents a Kerberos Checksum, as defined in RFC 4120 section 5.2.9.
+//
+// Attributes:
+//   - CksumType (int32): The type of the checksum.
+//   - Checksum ([]byte): The checksum.
+type Checksum struct {
+	CksumType int32
+	Checksum  []byte
+}
+
+// fromElement parses an already decoded Checksum element.
+func (c *Checksum) fromElement(element der.Element) error {
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing checksum: %w", err)
+	}
+
+	cksumType, err := requireInt(fields, 0, "cksumtype")
+	if err != nil {
+		return err
+	}
+	c.CksumType = int32(cksumType)
+
+	c.Checksum, err = requireBytes(fields, 1, "checksum")
+	return err
+}
+
+// marshal returns the DER encoding of the Checksum.
+func (c *Checksum) marshal() []byte {
+	return der.Sequence(
+		der.Explicit(0, der.Integer(int64(c.CksumType))),
+		der.Explicit(1, der.OctetString(c.Checksum)),
+	)
+}
@@ -0,0 +1,57 @@
+package messages
+
+// Protocol version number of Kerberos 5.
+const PVNO = 5
+
+// Message types, as defined in RFC 4120 section 5.10.
+const (
+	MsgType_AS_REQ    = 10
+	MsgType_AS_REP    = 11
+	MsgType_TGS_REQ   = 12
+	MsgType_TGS_REP   = 13
+	MsgType_AP_REQ    = 14
+	MsgType_AP_REP    = 15
+	MsgType_KRB_SAFE  = 20
+	MsgType_KRB_PRIV  = 21
+	MsgType_KRB_CRED  = 22
+	MsgType_KRB_ERROR = 30
+)
+
+// Principal name types, as defined in RFC 4120 section 6.2.
+const (
+	NameType_UNKNOWN        = 0
+	NameType_PRINCIPAL      = 1
+	NameType_SRV_INST       = 2
+	NameType_SRV_HST        = 3
+	NameType_SRV_XHST       = 4
+	NameType_UID            = 5
+	NameType_X500_PRINCIPAL = 6
+	NameType_SMTP_NAME      = 7
+	NameType_ENTERPRISE     = 10
+)
+
+// Key usage numbers, as defined in RFC 4120 section 7.5.1.
+const (
+	KeyUsage_AS_REQ_PA_ENC_TIMESTAMP        = 1
+	KeyUsage_KDC_REP_TICKET                 = 2
+	KeyUsage_AS_REP_ENCPART                 = 3
+	KeyUsage_TGS_REQ_AUTHDATA_SESSION_KEY   = 4
+	KeyUsage_TGS_REQ_AUTHDATA_SUB_KEY       = 5
+	KeyUsage_TGS_REQ_PA_AUTHENTICATOR_CKSUM = 6
+	KeyUsage_TGS_REQ_PA_AUTHENTICATOR       = 7
+	KeyUsage_TGS_REP_ENCPART_SESSION_KEY    = 8
+	KeyUsage_TGS_REP_ENCPART_SUB_KEY        = 9
+	KeyUsage_AP_REQ_AUTHENTICATOR_CKSUM     = 10
+	KeyUsage_AP_REQ_AUTHENTICATOR           = 11
+	KeyUsage_AP_REP_ENCPART                 = 12
+	KeyUsage_KRB_PRIV_ENCPART               = 13
+	KeyUsage_KRB_CRED_ENCPART               = 14
+	KeyUsage_KRB_SAFE_CHKSUM                = 15
+)
+
+// Host address types, as defined in RFC 4120 section 7.5.3.
+const (
+	AddrType_IPv4        = 2
+	AddrType_DIRECTIONAL = 3
+	AddrType_IPv6        = 24
+)
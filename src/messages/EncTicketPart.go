@@ -0,0 +1,136 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// EncTicketPart represents the encrypted part of a Ticket, as defined in RFC 4120 section 5.3.
+//
+// Attributes:
+//   - Flags (uint32): The ticket flags.
+//   - Key (EncryptionKey): The session key of the ticket.
+//   - CRealm (string): The realm of the client.
+//   - CName (PrincipalName): The name of the client.
+//   - TransitedType (int32): The type of the transited realms encoding.
+//   - TransitedContents ([]byte): The transited realms.
+//   - AuthTime (time.Time): The time of the initial authentication.
+//   - StartTime (time.Time): The time after which the ticket is valid, zero if absent.
+//   - EndTime (time.Time): The time after which the ticket is no longer valid.
+//   - RenewTill (time.Time): The maximum end time of the ticket when renewing it, zero if absent.
+//   - CAddr ([]HostAddress): The addresses the ticket is valid for.
+//   - AuthorizationData ([]AuthorizationDataEntry): The authorization data, holding the PAC in Active Directory.
+type EncTicketPart struct {
+	Flags             uint32
+	Key               EncryptionKey
+	CRealm            string
+	CName             PrincipalName
+	TransitedType     int32
+	TransitedContents []byte
+	AuthTime          time.Time
+	StartTime         time.Time
+	EndTime           time.Time
+	RenewTill         time.Time
+	CAddr             []HostAddress
+	AuthorizationData []AuthorizationDataEntry
+}
+
+// FromBytes parses the DER encoding of an EncTicketPart.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded decrypted part of a ticket.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (e *EncTicketPart) FromBytes(data []byte) error {
+	fields, err := parseFields(data, 3)
+	if err != nil {
+		return fmt.Errorf("error parsing EncTicketPart: %w", err)
+	}
+
+	if e.Flags, err = optionalFlags(fields, 0, "flags"); err != nil {
+		return err
+	}
+
+	key, ok := fields[1]
+	if !ok {
+		return fmt.Errorf("missing field key")
+	}
+	if err = e.Key.fromElement(key); err != nil {
+		return err
+	}
+
+	if e.CRealm, err = requireText(fields, 2, "crealm"); err != nil {
+		return err
+	}
+
+	cname, ok := fields[3]
+	if !ok {
+		return fmt.Errorf("missing field cname")
+	}
+	if err = e.CName.fromElement(cname); err != nil {
+		return err
+	}
+
+	if transited, ok := fields[4]; ok {
+		transitedFields, err := transited.Fields()
+		if err != nil {
+			return fmt.Errorf("error parsing transited: %w", err)
+		}
+		trType, err := requireInt(transitedFields, 0, "tr-type")
+		if err != nil {
+			return err
+		}
+		e.TransitedType = int32(trType)
+		if e.TransitedContents, err = requireBytes(transitedFields, 1, "contents"); err != nil {
+			return err
+		}
+	}
+
+	if e.AuthTime, err = requireTime(fields, 5, "authtime"); err != nil {
+		return err
+	}
+	if e.StartTime, err = optionalTime(fields, 6, "starttime"); err != nil {
+		return err
+	}
+	if e.EndTime, err = requireTime(fields, 7, "endtime"); err != nil {
+		return err
+	}
+	if e.RenewTill, err = optionalTime(fields, 8, "renew-till"); err != nil {
+		return err
+	}
+	if e.CAddr, err = parseHostAddresses(fields, 9, "caddr"); err != nil {
+		return err
+	}
+
+	e.AuthorizationData, err = parseAuthorizationData(fields, 10, "authorization-data")
+	return err
+}
+
+// ToBytes converts an EncTicketPart to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (e *EncTicketPart) ToBytes() ([]byte, error) {
+	transitedContents := e.TransitedContents
+	if transitedContents == nil {
+		transitedContents = []byte{}
+	}
+	return der.Application(3, der.Sequence(
+		der.Explicit(0, der.BitString(e.Flags)),
+		der.Explicit(1, e.Key.marshal()),
+		der.Explicit(2, der.GeneralString(e.CRealm)),
+		der.Explicit(3, e.CName.marshal()),
+		der.Explicit(4, der.Sequence(
+			der.Explicit(0, der.Integer(int64(e.TransitedType))),
+			der.Explicit(1, der.OctetString(transitedContents)),
+		)),
+		der.Explicit(5, der.GeneralizedTime(e.AuthTime)),
+		der.Explicit(6, encodeTime(e.StartTime)),
+		der.Explicit(7, der.GeneralizedTime(e.EndTime)),
+		der.Explicit(8, encodeTime(e.RenewTill)),
+		der.Explicit(9, marshalHostAddresses(e.CAddr)),
+		der.Explicit(10, MarshalAuthorizationData(e.AuthorizationData)),
+	)), nil
+}
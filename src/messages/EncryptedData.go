@@ -0,0 +1,80 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+)
+
+// EncryptedData represents a Kerberos EncryptedData, as defined in RFC 4120 section 5.2.9.
+//
+// Attributes:
+//   - EType (int32): The encryption type used to encrypt the cipher.
+//   - Kvno (uint32): The version number of the key used to encrypt the cipher, if HasKvno is set.
+//   - HasKvno (bool): Whether the key version number is present.
+//   - Cipher ([]byte): The encrypted data.
+type EncryptedData struct {
+	EType   int32
+	Kvno    uint32
+	HasKvno bool
+	Cipher  []byte
+}
+
+// FromBytes parses the DER encoding of an EncryptedData.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded encrypted data.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (e *EncryptedData) FromBytes(data []byte) error {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	return e.fromElement(element)
+}
+
+// fromElement parses an already decoded EncryptedData element.
+func (e *EncryptedData) fromElement(element der.Element) error {
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing encrypted data: %w", err)
+	}
+
+	etype, err := requireInt(fields, 0, "etype")
+	if err != nil {
+		return err
+	}
+	e.EType = int32(etype)
+
+	kvno, hasKvno, err := optionalInt(fields, 1, "kvno")
+	if err != nil {
+		return err
+	}
+	e.Kvno, e.HasKvno = uint32(kvno), hasKvno
+
+	e.Cipher, err = requireBytes(fields, 2, "cipher")
+	return err
+}
+
+// ToBytes converts an EncryptedData to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (e *EncryptedData) ToBytes() ([]byte, error) {
+	return e.marshal(), nil
+}
+
+// marshal returns the DER encoding of the EncryptedData.
+func (e *EncryptedData) marshal() []byte {
+	var kvno []byte
+	if e.HasKvno {
+		kvno = der.Explicit(1, der.Integer(int64(e.Kvno)))
+	}
+	return der.Sequence(
+		der.Explicit(0, der.Integer(int64(e.EType))),
+		kvno,
+		der.Explicit(2, der.OctetString(e.Cipher)),
+	)
+}
@@ -0,0 +1,65 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+)
+
+// EncryptionKey represents a Kerberos EncryptionKey, as defined in RFC 4120 section 5.2.9.
+//
+// Attributes:
+//   - KeyType (int32): The encryption type of the key.
+//   - KeyValue ([]byte): The key.
+type EncryptionKey struct {
+	KeyType  int32
+	KeyValue []byte
+}
+
+// FromBytes parses the DER encoding of an EncryptionKey.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded key.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *EncryptionKey) FromBytes(data []byte) error {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	return k.fromElement(element)
+}
+
+// fromElement parses an already decoded EncryptionKey element.
+func (k *EncryptionKey) fromElement(element der.Element) error {
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing encryption key: %w", err)
+	}
+
+	keyType, err := requireInt(fields, 0, "keytype")
+	if err != nil {
+		return err
+	}
+	k.KeyType = int32(keyType)
+
+	k.KeyValue, err = requireBytes(fields, 1, "keyvalue")
+	return err
+}
+
+// ToBytes converts an EncryptionKey to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (k *EncryptionKey) ToBytes() ([]byte, error) {
+	return k.marshal(), nil
+}
+
+// marshal returns the DER encoding of the EncryptionKey.
+func (k *EncryptionKey) marshal() []byte {
+	return der.Sequence(
+		der.Explicit(0, der.Integer(int64(k.KeyType))),
+		der.Explicit(1, der.OctetString(k.KeyValue)),
+	)
+}
@@ -0,0 +1,71 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+)
+
+// HostAddress represents a Kerberos HostAddress, as defined in RFC 4120 section 5.2.5.
+//
+// Attributes:
+//   - AddrType (int32): The type of the address.
+//   - Address ([]byte): The address.
+type HostAddress struct {
+	AddrType int32
+	Address  []byte
+}
+
+// fromElement parses an already decoded HostAddress element.
+func (h *HostAddress) fromElement(element der.Element) error {
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing host address: %w", err)
+	}
+
+	addrType, err := requireInt(fields, 0, "addr-type")
+	if err != nil {
+		return err
+	}
+	h.AddrType = int32(addrType)
+
+	h.Address, err = requireBytes(fields, 1, "address")
+	return err
+}
+
+// marshal returns the DER encoding of the HostAddress.
+func (h *HostAddress) marshal() []byte {
+	return der.Sequence(
+		der.Explicit(0, der.Integer(int64(h.AddrType))),
+		der.Explicit(1, der.OctetString(h.Address)),
+	)
+}
+
+// parseHostAddresses parses an optional HostAddresses field.
+func parseHostAddresses(fields map[int]der.Element, tag int, name string) ([]HostAddress, error) {
+	elements, err := sequenceOf(fields, tag, name)
+	if err != nil || elements == nil {
+		return nil, err
+	}
+	addresses := make([]HostAddress, 0, len(elements))
+	for _, element := range elements {
+		address := HostAddress{}
+		err := address.fromElement(element)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// marshalHostAddresses encodes an optional HostAddresses field, returning nil if there are no addresses.
+func marshalHostAddresses(addresses []HostAddress) []byte {
+	if len(addresses) == 0 {
+		return nil
+	}
+	encoded := make([][]byte, 0, len(addresses))
+	for i := range addresses {
+		encoded = append(encoded, addresses[i].marshal())
+	}
+	return der.Sequence(encoded...)
+}
@@ -0,0 +1,302 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// Application tags of the encrypted parts of KDC replies.
+const (
+	ApplicationTag_EncASRepPart  = 25
+	ApplicationTag_EncTGSRepPart = 26
+)
+
+// KDCRep represents an AS-REP or a TGS-REP message, as defined in RFC 4120 section 5.4.2.
+//
+// Attributes:
+//   - Pvno (int32): The protocol version number, always 5.
+//   - MsgType (int32): The message type, 11 for AS-REP and 13 for TGS-REP.
+//   - PAData ([]PAData): The pre-authentication data.
+//   - CRealm (string): The realm of the client.
+//   - CName (PrincipalName): The name of the client.
+//   - Ticket (Ticket): The issued ticket.
+//   - EncPart (EncryptedData): The encrypted EncKDCRepPart.
+type KDCRep struct {
+	Pvno    int32
+	MsgType int32
+	PAData  []PAData
+	CRealm  string
+	CName   PrincipalName
+	Ticket  Ticket
+	EncPart EncryptedData
+}
+
+// LastReqEntry represents a single element of a Kerberos LastReq.
+//
+// Attributes:
+//   - LRType (int32): The type of the entry.
+//   - LRValue (time.Time): The time of the entry.
+type LastReqEntry struct {
+	LRType  int32
+	LRValue time.Time
+}
+
+// EncKDCRepPart represents the encrypted part of a KDC reply, as defined in RFC 4120 section 5.4.2.
+//
+// Attributes:
+//   - ApplicationTag (int): The application tag, 25 for EncASRepPart and 26 for EncTGSRepPart.
+//   - Key (EncryptionKey): The session key of the ticket.
+//   - LastReq ([]LastReqEntry): The times of the last requests of the client.
+//   - Nonce (uint32): The nonce of the request.
+//   - KeyExpiration (time.Time): The expiration time of the key of the client, zero if absent.
+//   - Flags (uint32): The ticket flags.
+//   - AuthTime (time.Time): The time of the initial authentication.
+//   - StartTime (time.Time): The time after which the ticket is valid, zero if absent.
+//   - EndTime (time.Time): The time after which the ticket is no longer valid.
+//   - RenewTill (time.Time): The maximum end time of the ticket when renewing it, zero if absent.
+//   - SRealm (string): The realm of the server.
+//   - SName (PrincipalName): The name of the server.
+//   - CAddr ([]HostAddress): The addresses the ticket is valid for.
+//   - EncPAData ([]PAData): The encrypted pre-authentication data.
+type EncKDCRepPart struct {
+	ApplicationTag int
+	Key            EncryptionKey
+	LastReq        []LastReqEntry
+	Nonce          uint32
+	KeyExpiration  time.Time
+	Flags          uint32
+	AuthTime       time.Time
+	StartTime      time.Time
+	EndTime        time.Time
+	RenewTill      time.Time
+	SRealm         string
+	SName          PrincipalName
+	CAddr          []HostAddress
+	EncPAData      []PAData
+}
+
+// FromBytes parses the DER encoding of an AS-REP or a TGS-REP.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded message.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *KDCRep) FromBytes(data []byte) error {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	if element.Class != der.ClassApplication || (element.Tag != MsgType_AS_REP && element.Tag != MsgType_TGS_REP) {
+		return fmt.Errorf("message is not an AS-REP or a TGS-REP")
+	}
+
+	fields, err := elementFields(element, element.Tag)
+	if err != nil {
+		return fmt.Errorf("error parsing KDC-REP: %w", err)
+	}
+
+	pvno, err := requireInt(fields, 0, "pvno")
+	if err != nil {
+		return err
+	}
+	k.Pvno = int32(pvno)
+
+	msgType, err := requireInt(fields, 1, "msg-type")
+	if err != nil {
+		return err
+	}
+	k.MsgType = int32(msgType)
+
+	k.PAData, err = parsePAData(fields, 2, "padata")
+	if err != nil {
+		return err
+	}
+
+	k.CRealm, err = requireText(fields, 3, "crealm")
+	if err != nil {
+		return err
+	}
+
+	cname, ok := fields[4]
+	if !ok {
+		return fmt.Errorf("missing field cname")
+	}
+	err = k.CName.fromElement(cname)
+	if err != nil {
+		return err
+	}
+
+	ticket, ok := fields[5]
+	if !ok {
+		return fmt.Errorf("missing field ticket")
+	}
+	err = k.Ticket.fromElement(ticket)
+	if err != nil {
+		return err
+	}
+
+	encPart, ok := fields[6]
+	if !ok {
+		return fmt.Errorf("missing field enc-part")
+	}
+	return k.EncPart.fromElement(encPart)
+}
+
+// ToBytes converts a KDCRep to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (k *KDCRep) ToBytes() ([]byte, error) {
+	if k.MsgType != MsgType_AS_REP && k.MsgType != MsgType_TGS_REP {
+		return nil, fmt.Errorf("invalid KDC-REP message type %d", k.MsgType)
+	}
+	return der.Application(int(k.MsgType), der.Sequence(
+		der.Explicit(0, der.Integer(int64(k.Pvno))),
+		der.Explicit(1, der.Integer(int64(k.MsgType))),
+		der.Explicit(2, marshalPAData(k.PAData)),
+		der.Explicit(3, der.GeneralString(k.CRealm)),
+		der.Explicit(4, k.CName.marshal()),
+		der.Explicit(5, k.Ticket.marshal()),
+		der.Explicit(6, k.EncPart.marshal()),
+	)), nil
+}
+
+// FromBytes parses the DER encoding of an EncASRepPart or an EncTGSRepPart.
+// Both application tags are accepted for either reply, as some KDCs use the
+// EncTGSRepPart tag in AS replies.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded decrypted part.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (e *EncKDCRepPart) FromBytes(data []byte) error {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	if element.Class != der.ClassApplication || (element.Tag != ApplicationTag_EncASRepPart && element.Tag != ApplicationTag_EncTGSRepPart) {
+		return fmt.Errorf("data is not an EncASRepPart or an EncTGSRepPart")
+	}
+	e.ApplicationTag = element.Tag
+
+	fields, err := elementFields(element, element.Tag)
+	if err != nil {
+		return fmt.Errorf("error parsing EncKDCRepPart: %w", err)
+	}
+
+	key, ok := fields[0]
+	if !ok {
+		return fmt.Errorf("missing field key")
+	}
+	err = e.Key.fromElement(key)
+	if err != nil {
+		return err
+	}
+
+	lastReqs, err := sequenceOf(fields, 1, "last-req")
+	if err != nil {
+		return err
+	}
+	e.LastReq = make([]LastReqEntry, 0, len(lastReqs))
+	for _, lastReq := range lastReqs {
+		lrFields, err := lastReq.Fields()
+		if err != nil {
+			return fmt.Errorf("error parsing last-req: %w", err)
+		}
+		lrType, err := requireInt(lrFields, 0, "lr-type")
+		if err != nil {
+			return err
+		}
+		lrValue, err := requireTime(lrFields, 1, "lr-value")
+		if err != nil {
+			return err
+		}
+		e.LastReq = append(e.LastReq, LastReqEntry{LRType: int32(lrType), LRValue: lrValue})
+	}
+
+	nonce, err := requireInt(fields, 2, "nonce")
+	if err != nil {
+		return err
+	}
+	e.Nonce = uint32(nonce)
+
+	if e.KeyExpiration, err = optionalTime(fields, 3, "key-expiration"); err != nil {
+		return err
+	}
+	if e.Flags, err = optionalFlags(fields, 4, "flags"); err != nil {
+		return err
+	}
+	if e.AuthTime, err = requireTime(fields, 5, "authtime"); err != nil {
+		return err
+	}
+	if e.StartTime, err = optionalTime(fields, 6, "starttime"); err != nil {
+		return err
+	}
+	if e.EndTime, err = requireTime(fields, 7, "endtime"); err != nil {
+		return err
+	}
+	if e.RenewTill, err = optionalTime(fields, 8, "renew-till"); err != nil {
+		return err
+	}
+
+	e.SRealm, err = requireText(fields, 9, "srealm")
+	if err != nil {
+		return err
+	}
+
+	sname, ok := fields[10]
+	if !ok {
+		return fmt.Errorf("missing field sname")
+	}
+	err = e.SName.fromElement(sname)
+	if err != nil {
+		return err
+	}
+
+	e.CAddr, err = parseHostAddresses(fields, 11, "caddr")
+	if err != nil {
+		return err
+	}
+
+	e.EncPAData, err = parsePAData(fields, 12, "encrypted-pa-data")
+	return err
+}
+
+// ToBytes converts an EncKDCRepPart to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (e *EncKDCRepPart) ToBytes() ([]byte, error) {
+	if e.ApplicationTag != ApplicationTag_EncASRepPart && e.ApplicationTag != ApplicationTag_EncTGSRepPart {
+		return nil, fmt.Errorf("invalid EncKDCRepPart application tag %d", e.ApplicationTag)
+	}
+
+	lastReqs := make([][]byte, 0, len(e.LastReq))
+	for _, lastReq := range e.LastReq {
+		lastReqs = append(lastReqs, der.Sequence(
+			der.Explicit(0, der.Integer(int64(lastReq.LRType))),
+			der.Explicit(1, der.GeneralizedTime(lastReq.LRValue)),
+		))
+	}
+
+	return der.Application(e.ApplicationTag, der.Sequence(
+		der.Explicit(0, e.Key.marshal()),
+		der.Explicit(1, der.Sequence(lastReqs...)),
+		der.Explicit(2, der.Integer(int64(e.Nonce))),
+		der.Explicit(3, encodeTime(e.KeyExpiration)),
+		der.Explicit(4, der.BitString(e.Flags)),
+		der.Explicit(5, der.GeneralizedTime(e.AuthTime)),
+		der.Explicit(6, encodeTime(e.StartTime)),
+		der.Explicit(7, der.GeneralizedTime(e.EndTime)),
+		der.Explicit(8, encodeTime(e.RenewTill)),
+		der.Explicit(9, der.GeneralString(e.SRealm)),
+		der.Explicit(10, e.SName.marshal()),
+		der.Explicit(11, marshalHostAddresses(e.CAddr)),
+		der.Explicit(12, marshalPAData(e.EncPAData)),
+	)), nil
+}
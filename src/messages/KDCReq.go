@@ -0,0 +1,269 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// KDC options, as defined in RFC 4120 section 5.4.1, in the KerberosFlags bit order.
+const (
+	KDCOption_Forwardable           uint32 = 0x40000000
+	KDCOption_Forwarded             uint32 = 0x20000000
+	KDCOption_Proxiable             uint32 = 0x10000000
+	KDCOption_Proxy                 uint32 = 0x08000000
+	KDCOption_AllowPostdate         uint32 = 0x04000000
+	KDCOption_Postdated             uint32 = 0x02000000
+	KDCOption_Renewable             uint32 = 0x00800000
+	KDCOption_Canonicalize          uint32 = 0x00010000
+	KDCOption_DisableTransitedCheck uint32 = 0x00000020
+	KDCOption_RenewableOK           uint32 = 0x00000010
+	KDCOption_EncTktInSkey          uint32 = 0x00000008
+	KDCOption_Renew                 uint32 = 0x00000002
+	KDCOption_Validate              uint32 = 0x00000001
+)
+
+// KDCReq represents an AS-REQ or a TGS-REQ message, as defined in RFC 4120 section 5.4.1.
+//
+// Attributes:
+//   - Pvno (int32): The protocol version number, always 5.
+//   - MsgType (int32): The message type, 10 for AS-REQ and 12 for TGS-REQ.
+//   - PAData ([]PAData): The pre-authentication data.
+//   - ReqBody (KDCReqBody): The body of the request.
+type KDCReq struct {
+	Pvno    int32
+	MsgType int32
+	PAData  []PAData
+	ReqBody KDCReqBody
+}
+
+// KDCReqBody represents the body of a KDC request.
+//
+// Attributes:
+//   - KDCOptions (uint32): The KDC options.
+//   - CName (PrincipalName): The name of the client, only present in AS-REQ.
+//   - Realm (string): The realm of the server.
+//   - SName (PrincipalName): The name of the server.
+//   - From (time.Time): The requested start time, zero if absent.
+//   - Till (time.Time): The requested end time.
+//   - RTime (time.Time): The requested renew-till time, zero if absent.
+//   - Nonce (uint32): A random number used to match the reply to the request.
+//   - EType ([]int32): The encryption types supported by the client, in order of preference.
+//   - Addresses ([]HostAddress): The addresses the ticket should be valid for.
+//   - EncAuthorizationData (*EncryptedData): The encrypted authorization data, may be nil.
+//   - AdditionalTickets ([]Ticket): The additional tickets.
+//   - RawBytes ([]byte): The DER encoding of the body as received, used to verify checksums.
+type KDCReqBody struct {
+	KDCOptions           uint32
+	CName                PrincipalName
+	Realm                string
+	SName                PrincipalName
+	From                 time.Time
+	Till                 time.Time
+	RTime                time.Time
+	Nonce                uint32
+	EType                []int32
+	Addresses            []HostAddress
+	EncAuthorizationData *EncryptedData
+	AdditionalTickets    []Ticket
+	// Internal
+	RawBytes []byte
+}
+
+// FromBytes parses the DER encoding of an AS-REQ or a TGS-REQ.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded message.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *KDCReq) FromBytes(data []byte) error {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	if element.Class != der.ClassApplication || (element.Tag != MsgType_AS_REQ && element.Tag != MsgType_TGS_REQ) {
+		return fmt.Errorf("message is not an AS-REQ or a TGS-REQ")
+	}
+
+	fields, err := elementFields(element, element.Tag)
+	if err != nil {
+		return fmt.Errorf("error parsing KDC-REQ: %w", err)
+	}
+
+	pvno, err := requireInt(fields, 1, "pvno")
+	if err != nil {
+		return err
+	}
+	k.Pvno = int32(pvno)
+
+	msgType, err := requireInt(fields, 2, "msg-type")
+	if err != nil {
+		return err
+	}
+	k.MsgType = int32(msgType)
+
+	k.PAData, err = parsePAData(fields, 3, "padata")
+	if err != nil {
+		return err
+	}
+
+	body, ok := fields[4]
+	if !ok {
+		return fmt.Errorf("missing field req-body")
+	}
+	return k.ReqBody.fromElement(body)
+}
+
+// ToBytes converts a KDCReq to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (k *KDCReq) ToBytes() ([]byte, error) {
+	if k.MsgType != MsgType_AS_REQ && k.MsgType != MsgType_TGS_REQ {
+		return nil, fmt.Errorf("invalid KDC-REQ message type %d", k.MsgType)
+	}
+	return der.Application(int(k.MsgType), der.Sequence(
+		der.Explicit(1, der.Integer(int64(k.Pvno))),
+		der.Explicit(2, der.Integer(int64(k.MsgType))),
+		der.Explicit(3, marshalPAData(k.PAData)),
+		der.Explicit(4, k.ReqBody.Marshal()),
+	)), nil
+}
+
+// fromElement parses an already decoded KDC-REQ-BODY element.
+func (b *KDCReqBody) fromElement(element der.Element) error {
+	b.RawBytes = append([]byte{}, element.RawBytes...)
+
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing KDC-REQ-BODY: %w", err)
+	}
+
+	b.KDCOptions, err = optionalFlags(fields, 0, "kdc-options")
+	if err != nil {
+		return err
+	}
+
+	if cname, ok := fields[1]; ok {
+		err = b.CName.fromElement(cname)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.Realm, err = requireText(fields, 2, "realm")
+	if err != nil {
+		return err
+	}
+
+	if sname, ok := fields[3]; ok {
+		err = b.SName.fromElement(sname)
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.From, err = optionalTime(fields, 4, "from"); err != nil {
+		return err
+	}
+	if b.Till, err = optionalTime(fields, 5, "till"); err != nil {
+		return err
+	}
+	if b.RTime, err = optionalTime(fields, 6, "rtime"); err != nil {
+		return err
+	}
+
+	nonce, err := requireInt(fields, 7, "nonce")
+	if err != nil {
+		return err
+	}
+	b.Nonce = uint32(nonce)
+
+	etypes, err := sequenceOf(fields, 8, "etype")
+	if err != nil {
+		return err
+	}
+	b.EType = make([]int32, 0, len(etypes))
+	for _, etype := range etypes {
+		value, err := etype.Int()
+		if err != nil {
+			return fmt.Errorf("error parsing field etype: %w", err)
+		}
+		b.EType = append(b.EType, int32(value))
+	}
+
+	b.Addresses, err = parseHostAddresses(fields, 9, "addresses")
+	if err != nil {
+		return err
+	}
+
+	if encAuthorizationData, ok := fields[10]; ok {
+		b.EncAuthorizationData = &EncryptedData{}
+		err = b.EncAuthorizationData.fromElement(encAuthorizationData)
+		if err != nil {
+			return err
+		}
+	}
+
+	tickets, err := sequenceOf(fields, 11, "additional-tickets")
+	if err != nil {
+		return err
+	}
+	for _, element := range tickets {
+		ticket := Ticket{}
+		err := ticket.fromElement(element)
+		if err != nil {
+			return err
+		}
+		b.AdditionalTickets = append(b.AdditionalTickets, ticket)
+	}
+
+	return nil
+}
+
+// Marshal returns the DER encoding of the KDCReqBody, over which the checksum of
+// the authenticator of a TGS-REQ is computed.
+//
+// Returns:
+//   - []byte: The DER encoding of the body.
+func (b *KDCReqBody) Marshal() []byte {
+	var cname, sname, encAuthorizationData, tickets []byte
+	if len(b.CName.NameString) != 0 {
+		cname = der.Explicit(1, b.CName.marshal())
+	}
+	if len(b.SName.NameString) != 0 {
+		sname = der.Explicit(3, b.SName.marshal())
+	}
+	if b.EncAuthorizationData != nil {
+		encAuthorizationData = der.Explicit(10, b.EncAuthorizationData.marshal())
+	}
+	if len(b.AdditionalTickets) != 0 {
+		encoded := make([][]byte, 0, len(b.AdditionalTickets))
+		for i := range b.AdditionalTickets {
+			encoded = append(encoded, b.AdditionalTickets[i].marshal())
+		}
+		tickets = der.Explicit(11, der.Sequence(encoded...))
+	}
+
+	etypes := make([][]byte, 0, len(b.EType))
+	for _, etype := range b.EType {
+		etypes = append(etypes, der.Integer(int64(etype)))
+	}
+
+	return der.Sequence(
+		der.Explicit(0, der.BitString(b.KDCOptions)),
+		cname,
+		der.Explicit(2, der.GeneralString(b.Realm)),
+		sname,
+		der.Explicit(4, encodeTime(b.From)),
+		der.Explicit(5, encodeTime(b.Till)),
+		der.Explicit(6, encodeTime(b.RTime)),
+		der.Explicit(7, der.Integer(int64(b.Nonce))),
+		der.Explicit(8, der.Sequence(etypes...)),
+		der.Explicit(9, marshalHostAddresses(b.Addresses)),
+		encAuthorizationData,
+		tickets,
+	)
+}
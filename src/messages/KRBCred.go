@@ -0,0 +1,289 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// KRBCred represents a KRB-CRED message, as defined in RFC 4120 section 5.8.1.
+// It is the format of the .kirbi files produced by Windows tooling.
+//
+// Attributes:
+//   - Pvno (int32): The protocol version number, always 5.
+//   - MsgType (int32): The message type, always 22.
+//   - Tickets ([]Ticket): The tickets being transferred.
+//   - EncPart (EncryptedData): The encrypted EncKrbCredPart, holding the session keys of the tickets.
+type KRBCred struct {
+	Pvno    int32
+	MsgType int32
+	Tickets []Ticket
+	EncPart EncryptedData
+}
+
+// EncKrbCredPart represents the decrypted part of a KRB-CRED message.
+//
+// Attributes:
+//   - TicketInfo ([]KrbCredInfo): The information about each ticket, in the same order as the tickets.
+//   - Nonce (uint32): The nonce of the message, if HasNonce is set.
+//   - HasNonce (bool): Whether the nonce is present.
+//   - Timestamp (time.Time): The time the message was generated, zero if absent.
+//   - Usec (int32): The microseconds part of the timestamp.
+type EncKrbCredPart struct {
+	TicketInfo []KrbCredInfo
+	Nonce      uint32
+	HasNonce   bool
+	Timestamp  time.Time
+	Usec       int32
+}
+
+// KrbCredInfo represents the information about a single ticket in an EncKrbCredPart.
+//
+// Attributes:
+//   - Key (EncryptionKey): The session key of the ticket.
+//   - PRealm (string): The realm of the client.
+//   - PName (PrincipalName): The name of the client.
+//   - Flags (uint32): The ticket flags.
+//   - AuthTime (time.Time): The time of the initial authentication.
+//   - StartTime (time.Time): The time after which the ticket is valid.
+//   - EndTime (time.Time): The time after which the ticket is no longer valid.
+//   - RenewTill (time.Time): The maximum end time of the ticket when renewing it.
+//   - SRealm (string): The realm of the server.
+//   - SName (PrincipalName): The name of the server.
+//   - CAddr ([]HostAddress): The addresses the ticket is valid for.
+type KrbCredInfo struct {
+	Key       EncryptionKey
+	PRealm    string
+	PName     PrincipalName
+	Flags     uint32
+	AuthTime  time.Time
+	StartTime time.Time
+	EndTime   time.Time
+	RenewTill time.Time
+	SRealm    string
+	SName     PrincipalName
+	CAddr     []HostAddress
+}
+
+// FromBytes parses the DER encoding of a KRB-CRED message.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded message.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *KRBCred) FromBytes(data []byte) error {
+	fields, err := parseFields(data, MsgType_KRB_CRED)
+	if err != nil {
+		return fmt.Errorf("error parsing KRB-CRED: %w", err)
+	}
+
+	pvno, err := requireInt(fields, 0, "pvno")
+	if err != nil {
+		return err
+	}
+	k.Pvno = int32(pvno)
+
+	msgType, err := requireInt(fields, 1, "msg-type")
+	if err != nil {
+		return err
+	}
+	k.MsgType = int32(msgType)
+	if k.MsgType != MsgType_KRB_CRED {
+		return fmt.Errorf("unexpected message type %d, expected %d", k.MsgType, MsgType_KRB_CRED)
+	}
+
+	tickets, err := sequenceOf(fields, 2, "tickets")
+	if err != nil {
+		return err
+	}
+	k.Tickets = make([]Ticket, 0, len(tickets))
+	for _, element := range tickets {
+		ticket := Ticket{}
+		err := ticket.fromElement(element)
+		if err != nil {
+			return err
+		}
+		k.Tickets = append(k.Tickets, ticket)
+	}
+
+	encPart, ok := fields[3]
+	if !ok {
+		return fmt.Errorf("missing field enc-part")
+	}
+	return k.EncPart.fromElement(encPart)
+}
+
+// ToBytes converts a KRB-CRED message to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (k *KRBCred) ToBytes() ([]byte, error) {
+	tickets := make([][]byte, 0, len(k.Tickets))
+	for i := range k.Tickets {
+		tickets = append(tickets, k.Tickets[i].marshal())
+	}
+	return der.Application(MsgType_KRB_CRED, der.Sequence(
+		der.Explicit(0, der.Integer(int64(k.Pvno))),
+		der.Explicit(1, der.Integer(int64(k.MsgType))),
+		der.Explicit(2, der.Sequence(tickets...)),
+		der.Explicit(3, k.EncPart.marshal()),
+	)), nil
+}
+
+// FromBytes parses the DER encoding of an EncKrbCredPart.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded decrypted part.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (e *EncKrbCredPart) FromBytes(data []byte) error {
+	fields, err := parseFields(data, 29)
+	if err != nil {
+		return fmt.Errorf("error parsing EncKrbCredPart: %w", err)
+	}
+
+	infos, err := sequenceOf(fields, 0, "ticket-info")
+	if err != nil {
+		return err
+	}
+	e.TicketInfo = make([]KrbCredInfo, 0, len(infos))
+	for _, element := range infos {
+		info := KrbCredInfo{}
+		err := info.fromElement(element)
+		if err != nil {
+			return err
+		}
+		e.TicketInfo = append(e.TicketInfo, info)
+	}
+
+	nonce, hasNonce, err := optionalInt(fields, 1, "nonce")
+	if err != nil {
+		return err
+	}
+	e.Nonce, e.HasNonce = uint32(nonce), hasNonce
+
+	e.Timestamp, err = optionalTime(fields, 2, "timestamp")
+	if err != nil {
+		return err
+	}
+
+	usec, _, err := optionalInt(fields, 3, "usec")
+	e.Usec = int32(usec)
+	return err
+}
+
+// ToBytes converts an EncKrbCredPart to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (e *EncKrbCredPart) ToBytes() ([]byte, error) {
+	infos := make([][]byte, 0, len(e.TicketInfo))
+	for i := range e.TicketInfo {
+		infos = append(infos, e.TicketInfo[i].marshal())
+	}
+
+	var nonce, usec []byte
+	if e.HasNonce {
+		nonce = der.Explicit(1, der.Integer(int64(e.Nonce)))
+	}
+	if !e.Timestamp.IsZero() {
+		usec = der.Explicit(3, der.Integer(int64(e.Usec)))
+	}
+
+	return der.Application(29, der.Sequence(
+		der.Explicit(0, der.Sequence(infos...)),
+		nonce,
+		der.Explicit(2, encodeTime(e.Timestamp)),
+		usec,
+	)), nil
+}
+
+// fromElement parses an already decoded KrbCredInfo element.
+func (k *KrbCredInfo) fromElement(element der.Element) error {
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing KrbCredInfo: %w", err)
+	}
+
+	key, ok := fields[0]
+	if !ok {
+		return fmt.Errorf("missing field key")
+	}
+	err = k.Key.fromElement(key)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := fields[1]; ok {
+		k.PRealm, err = requireText(fields, 1, "prealm")
+		if err != nil {
+			return err
+		}
+	}
+	if pname, ok := fields[2]; ok {
+		err = k.PName.fromElement(pname)
+		if err != nil {
+			return err
+		}
+	}
+
+	k.Flags, err = optionalFlags(fields, 3, "flags")
+	if err != nil {
+		return err
+	}
+
+	if k.AuthTime, err = optionalTime(fields, 4, "authtime"); err != nil {
+		return err
+	}
+	if k.StartTime, err = optionalTime(fields, 5, "starttime"); err != nil {
+		return err
+	}
+	if k.EndTime, err = optionalTime(fields, 6, "endtime"); err != nil {
+		return err
+	}
+	if k.RenewTill, err = optionalTime(fields, 7, "renew-till"); err != nil {
+		return err
+	}
+
+	if _, ok := fields[8]; ok {
+		k.SRealm, err = requireText(fields, 8, "srealm")
+		if err != nil {
+			return err
+		}
+	}
+	if sname, ok := fields[9]; ok {
+		err = k.SName.fromElement(sname)
+		if err != nil {
+			return err
+		}
+	}
+
+	k.CAddr, err = parseHostAddresses(fields, 10, "caddr")
+	return err
+}
+
+// marshal returns the DER encoding of the KrbCredInfo.
+func (k *KrbCredInfo) marshal() []byte {
+	var pname, sname []byte
+	if len(k.PName.NameString) != 0 {
+		pname = der.Explicit(2, k.PName.marshal())
+	}
+	if len(k.SName.NameString) != 0 {
+		sname = der.Explicit(9, k.SName.marshal())
+	}
+	return der.Sequence(
+		der.Explicit(0, k.Key.marshal()),
+		der.Explicit(1, encodeOptionalString(k.PRealm)),
+		pname,
+		der.Explicit(3, der.BitString(k.Flags)),
+		der.Explicit(4, encodeTime(k.AuthTime)),
+		der.Explicit(5, encodeTime(k.StartTime)),
+		der.Explicit(6, encodeTime(k.EndTime)),
+		der.Explicit(7, encodeTime(k.RenewTill)),
+		der.Explicit(8, encodeOptionalString(k.SRealm)),
+		sname,
+		der.Explicit(10, marshalHostAddresses(k.CAddr)),
+	)
+}
@@ -0,0 +1,265 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// Error codes, as defined in RFC 4120 section 7.5.9.
+const (
+	ErrorCode_KDC_ERR_NONE                 = 0
+	ErrorCode_KDC_ERR_C_PRINCIPAL_UNKNOWN  = 6
+	ErrorCode_KDC_ERR_S_PRINCIPAL_UNKNOWN  = 7
+	ErrorCode_KDC_ERR_BADOPTION            = 13
+	ErrorCode_KDC_ERR_ETYPE_NOSUPP         = 14
+	ErrorCode_KDC_ERR_PREAUTH_FAILED       = 24
+	ErrorCode_KDC_ERR_PREAUTH_REQUIRED     = 25
+	ErrorCode_KRB_AP_ERR_BAD_INTEGRITY     = 31
+	ErrorCode_KRB_AP_ERR_TKT_EXPIRED       = 32
+	ErrorCode_KRB_AP_ERR_TKT_NYV           = 33
+	ErrorCode_KRB_AP_ERR_REPEAT            = 34
+	ErrorCode_KRB_AP_ERR_NOT_US            = 35
+	ErrorCode_KRB_AP_ERR_BADMATCH          = 36
+	ErrorCode_KRB_AP_ERR_SKEW              = 37
+	ErrorCode_KRB_AP_ERR_BADADDR           = 38
+	ErrorCode_KRB_AP_ERR_BADVERSION        = 39
+	ErrorCode_KRB_AP_ERR_MSG_TYPE          = 40
+	ErrorCode_KRB_AP_ERR_MODIFIED          = 41
+	ErrorCode_KRB_AP_ERR_BADKEYVER         = 44
+	ErrorCode_KRB_AP_ERR_NOKEY             = 45
+	ErrorCode_KRB_AP_ERR_INAPP_CKSUM       = 50
+	ErrorCode_KRB_ERR_GENERIC              = 60
+	ErrorCode_KRB_ERR_RESPONSE_TOO_BIG     = 52
+	ErrorCode_KDC_ERR_WRONG_REALM          = 68
+	ErrorCode_KDC_ERR_CLIENT_REVOKED       = 18
+	ErrorCode_KDC_ERR_KEY_EXPIRED          = 23
+	ErrorCode_KDC_ERR_SERVICE_NOTYET       = 22
+	ErrorCode_KDC_ERR_CANNOT_POSTDATE      = 10
+	ErrorCode_KDC_ERR_NEVER_VALID          = 11
+	ErrorCode_KDC_ERR_POLICY               = 12
+	ErrorCode_KDC_ERR_SUMTYPE_NOSUPP       = 15
+	ErrorCode_KDC_ERR_PADATA_TYPE_NOSUPP   = 16
+	ErrorCode_KDC_ERR_TRTYPE_NOSUPP        = 17
+	ErrorCode_KDC_ERR_SERVICE_EXP          = 20
+	ErrorCode_KDC_ERR_C_OLD_MAST_KVNO      = 4
+	ErrorCode_KDC_ERR_S_OLD_MAST_KVNO      = 5
+	ErrorCode_KDC_ERR_PRINCIPAL_NOT_UNIQUE = 8
+	ErrorCode_KDC_ERR_NULL_KEY             = 9
+)
+
+// errorCodeNames maps the error codes to their names.
+var errorCodeNames = map[int32]string{
+	ErrorCode_KDC_ERR_NONE:                 "KDC_ERR_NONE",
+	ErrorCode_KDC_ERR_C_OLD_MAST_KVNO:      "KDC_ERR_C_OLD_MAST_KVNO",
+	ErrorCode_KDC_ERR_S_OLD_MAST_KVNO:      "KDC_ERR_S_OLD_MAST_KVNO",
+	ErrorCode_KDC_ERR_C_PRINCIPAL_UNKNOWN:  "KDC_ERR_C_PRINCIPAL_UNKNOWN",
+	ErrorCode_KDC_ERR_S_PRINCIPAL_UNKNOWN:  "KDC_ERR_S_PRINCIPAL_UNKNOWN",
+	ErrorCode_KDC_ERR_PRINCIPAL_NOT_UNIQUE: "KDC_ERR_PRINCIPAL_NOT_UNIQUE",
+	ErrorCode_KDC_ERR_NULL_KEY:             "KDC_ERR_NULL_KEY",
+	ErrorCode_KDC_ERR_CANNOT_POSTDATE:      "KDC_ERR_CANNOT_POSTDATE",
+	ErrorCode_KDC_ERR_NEVER_VALID:          "KDC_ERR_NEVER_VALID",
+	ErrorCode_KDC_ERR_POLICY:               "KDC_ERR_POLICY",
+	ErrorCode_KDC_ERR_BADOPTION:            "KDC_ERR_BADOPTION",
+	ErrorCode_KDC_ERR_ETYPE_NOSUPP:         "KDC_ERR_ETYPE_NOSUPP",
+	ErrorCode_KDC_ERR_SUMTYPE_NOSUPP:       "KDC_ERR_SUMTYPE_NOSUPP",
+	ErrorCode_KDC_ERR_PADATA_TYPE_NOSUPP:   "KDC_ERR_PADATA_TYPE_NOSUPP",
+	ErrorCode_KDC_ERR_TRTYPE_NOSUPP:        "KDC_ERR_TRTYPE_NOSUPP",
+	ErrorCode_KDC_ERR_CLIENT_REVOKED:       "KDC_ERR_CLIENT_REVOKED",
+	ErrorCode_KDC_ERR_SERVICE_EXP:          "KDC_ERR_SERVICE_EXP",
+	ErrorCode_KDC_ERR_SERVICE_NOTYET:       "KDC_ERR_SERVICE_NOTYET",
+	ErrorCode_KDC_ERR_KEY_EXPIRED:          "KDC_ERR_KEY_EXPIRED",
+	ErrorCode_KDC_ERR_PREAUTH_FAILED:       "KDC_ERR_PREAUTH_FAILED",
+	ErrorCode_KDC_ERR_PREAUTH_REQUIRED:     "KDC_ERR_PREAUTH_REQUIRED",
+	ErrorCode_KRB_AP_ERR_BAD_INTEGRITY:     "KRB_AP_ERR_BAD_INTEGRITY",
+	ErrorCode_KRB_AP_ERR_TKT_EXPIRED:       "KRB_AP_ERR_TKT_EXPIRED",
+	ErrorCode_KRB_AP_ERR_TKT_NYV:           "KRB_AP_ERR_TKT_NYV",
+	ErrorCode_KRB_AP_ERR_REPEAT:            "KRB_AP_ERR_REPEAT",
+	ErrorCode_KRB_AP_ERR_NOT_US:            "KRB_AP_ERR_NOT_US",
+	ErrorCode_KRB_AP_ERR_BADMATCH:          "KRB_AP_ERR_BADMATCH",
+	ErrorCode_KRB_AP_ERR_SKEW:              "KRB_AP_ERR_SKEW",
+	ErrorCode_KRB_AP_ERR_BADADDR:           "KRB_AP_ERR_BADADDR",
+	ErrorCode_KRB_AP_ERR_BADVERSION:        "KRB_AP_ERR_BADVERSION",
+	ErrorCode_KRB_AP_ERR_MSG_TYPE:          "KRB_AP_ERR_MSG_TYPE",
+	ErrorCode_KRB_AP_ERR_MODIFIED:          "KRB_AP_ERR_MODIFIED",
+	ErrorCode_KRB_AP_ERR_BADKEYVER:         "KRB_AP_ERR_BADKEYVER",
+	ErrorCode_KRB_AP_ERR_NOKEY:             "KRB_AP_ERR_NOKEY",
+	ErrorCode_KRB_AP_ERR_INAPP_CKSUM:       "KRB_AP_ERR_INAPP_CKSUM",
+	ErrorCode_KRB_ERR_RESPONSE_TOO_BIG:     "KRB_ERR_RESPONSE_TOO_BIG",
+	ErrorCode_KRB_ERR_GENERIC:              "KRB_ERR_GENERIC",
+	ErrorCode_KDC_ERR_WRONG_REALM:          "KDC_ERR_WRONG_REALM",
+}
+
+// ErrorCodeToString returns the name of a Kerberos error code.
+//
+// Parameters:
+//   - code (int32): The error code.
+//
+// Returns:
+//   - string: The name of the error code.
+func ErrorCodeToString(code int32) string {
+	if name, ok := errorCodeNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("KRB_ERROR_%d", code)
+}
+
+// KRBError represents a KRB-ERROR message, as defined in RFC 4120 section 5.9.1.
+//
+// Attributes:
+//   - Pvno (int32): The protocol version number, always 5.
+//   - MsgType (int32): The message type, always 30.
+//   - CTime (time.Time): The client time of the request, zero if absent.
+//   - Cusec (int32): The microseconds part of the client time.
+//   - STime (time.Time): The server time.
+//   - Susec (int32): The microseconds part of the server time.
+//   - ErrorCode (int32): The error code.
+//   - CRealm (string): The realm of the client, may be empty.
+//   - CName (PrincipalName): The name of the client, may be empty.
+//   - Realm (string): The realm of the server.
+//   - SName (PrincipalName): The name of the server.
+//   - EText (string): The error text, may be empty.
+//   - EData ([]byte): The error data, such as a METHOD-DATA, may be nil.
+type KRBError struct {
+	Pvno      int32
+	MsgType   int32
+	CTime     time.Time
+	Cusec     int32
+	STime     time.Time
+	Susec     int32
+	ErrorCode int32
+	CRealm    string
+	CName     PrincipalName
+	Realm     string
+	SName     PrincipalName
+	EText     string
+	EData     []byte
+}
+
+// FromBytes parses the DER encoding of a KRB-ERROR.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded message.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *KRBError) FromBytes(data []byte) error {
+	fields, err := parseFields(data, MsgType_KRB_ERROR)
+	if err != nil {
+		return fmt.Errorf("error parsing KRB-ERROR: %w", err)
+	}
+
+	pvno, err := requireInt(fields, 0, "pvno")
+	if err != nil {
+		return err
+	}
+	k.Pvno = int32(pvno)
+
+	msgType, err := requireInt(fields, 1, "msg-type")
+	if err != nil {
+		return err
+	}
+	k.MsgType = int32(msgType)
+
+	if k.CTime, err = optionalTime(fields, 2, "ctime"); err != nil {
+		return err
+	}
+	cusec, _, err := optionalInt(fields, 3, "cusec")
+	if err != nil {
+		return err
+	}
+	k.Cusec = int32(cusec)
+
+	if k.STime, err = requireTime(fields, 4, "stime"); err != nil {
+		return err
+	}
+	susec, err := requireInt(fields, 5, "susec")
+	if err != nil {
+		return err
+	}
+	k.Susec = int32(susec)
+
+	errorCode, err := requireInt(fields, 6, "error-code")
+	if err != nil {
+		return err
+	}
+	k.ErrorCode = int32(errorCode)
+
+	if _, ok := fields[7]; ok {
+		if k.CRealm, err = requireText(fields, 7, "crealm"); err != nil {
+			return err
+		}
+	}
+	if cname, ok := fields[8]; ok {
+		if err = k.CName.fromElement(cname); err != nil {
+			return err
+		}
+	}
+
+	if k.Realm, err = requireText(fields, 9, "realm"); err != nil {
+		return err
+	}
+	sname, ok := fields[10]
+	if !ok {
+		return fmt.Errorf("missing field sname")
+	}
+	if err = k.SName.fromElement(sname); err != nil {
+		return err
+	}
+
+	if _, ok := fields[11]; ok {
+		if k.EText, err = requireText(fields, 11, "e-text"); err != nil {
+			return err
+		}
+	}
+	if _, ok := fields[12]; ok {
+		if k.EData, err = requireBytes(fields, 12, "e-data"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ToBytes converts a KRBError to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (k *KRBError) ToBytes() ([]byte, error) {
+	var cusec, cname, edata []byte
+	if !k.CTime.IsZero() {
+		cusec = der.Explicit(3, der.Integer(int64(k.Cusec)))
+	}
+	if len(k.CName.NameString) != 0 {
+		cname = der.Explicit(8, k.CName.marshal())
+	}
+	if k.EData != nil {
+		edata = der.Explicit(12, der.OctetString(k.EData))
+	}
+	return der.Application(MsgType_KRB_ERROR, der.Sequence(
+		der.Explicit(0, der.Integer(int64(k.Pvno))),
+		der.Explicit(1, der.Integer(int64(k.MsgType))),
+		der.Explicit(2, encodeTime(k.CTime)),
+		cusec,
+		der.Explicit(4, der.GeneralizedTime(k.STime)),
+		der.Explicit(5, der.Integer(int64(k.Susec))),
+		der.Explicit(6, der.Integer(int64(k.ErrorCode))),
+		der.Explicit(7, encodeOptionalString(k.CRealm)),
+		cname,
+		der.Explicit(9, der.GeneralString(k.Realm)),
+		der.Explicit(10, k.SName.marshal()),
+		der.Explicit(11, encodeOptionalString(k.EText)),
+		edata,
+	)), nil
+}
+
+// Error returns a human readable description of the KRB-ERROR, so that it can be used as an error.
+//
+// Returns:
+//   - string: The description of the error.
+func (k *KRBError) Error() string {
+	if len(k.EText) != 0 {
+		return fmt.Sprintf("%s (%d): %s", ErrorCodeToString(k.ErrorCode), k.ErrorCode, k.EText)
+	}
+	return fmt.Sprintf("%s (%d)", ErrorCodeToString(k.ErrorCode), k.ErrorCode)
+}
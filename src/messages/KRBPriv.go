@@ -0,0 +1,223 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// KRBPriv represents a KRB-PRIV message, as defined in RFC 4120 section 5.7.1.
+// It carries the requests and replies of the kpasswd protocol.
+//
+// Attributes:
+//   - Pvno (int32): The protocol version number, always 5.
+//   - MsgType (int32): The message type, always 21.
+//   - EncPart (EncryptedData): The encrypted EncKrbPrivPart.
+type KRBPriv struct {
+	Pvno    int32
+	MsgType int32
+	EncPart EncryptedData
+}
+
+// EncKrbPrivPart represents the decrypted part of a KRB-PRIV message.
+//
+// Attributes:
+//   - UserData ([]byte): The application data.
+//   - Timestamp (time.Time): The time the message was generated, zero if absent.
+//   - Usec (int32): The microseconds part of the timestamp.
+//   - SeqNumber (uint32): The sequence number of the message, if HasSeqNumber is set.
+//   - HasSeqNumber (bool): Whether the sequence number is present.
+//   - SAddress (*HostAddress): The address of the sender, may be nil.
+//   - RAddress (*HostAddress): The address of the recipient, may be nil.
+type EncKrbPrivPart struct {
+	UserData     []byte
+	Timestamp    time.Time
+	Usec         int32
+	SeqNumber    uint32
+	HasSeqNumber bool
+	SAddress     *HostAddress
+	RAddress     *HostAddress
+}
+
+// ChangePasswdData represents the user data of a set-password request, as defined in
+// RFC 3244 section 2.
+//
+// Attributes:
+//   - NewPasswd ([]byte): The new password.
+//   - TargName (*PrincipalName): The principal whose password is set, nil for the requesting principal.
+//   - TargRealm (string): The realm of the target principal, empty for the realm of the requester.
+type ChangePasswdData struct {
+	NewPasswd []byte
+	TargName  *PrincipalName
+	TargRealm string
+}
+
+// FromBytes parses the DER encoding of a KRB-PRIV message.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded message.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *KRBPriv) FromBytes(data []byte) error {
+	fields, err := parseFields(data, MsgType_KRB_PRIV)
+	if err != nil {
+		return fmt.Errorf("error parsing KRB-PRIV: %w", err)
+	}
+
+	pvno, err := requireInt(fields, 0, "pvno")
+	if err != nil {
+		return err
+	}
+	k.Pvno = int32(pvno)
+
+	msgType, err := requireInt(fields, 1, "msg-type")
+	if err != nil {
+		return err
+	}
+	k.MsgType = int32(msgType)
+
+	encPart, ok := fields[3]
+	if !ok {
+		return fmt.Errorf("missing field enc-part")
+	}
+	return k.EncPart.fromElement(encPart)
+}
+
+// ToBytes converts a KRBPriv to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (k *KRBPriv) ToBytes() ([]byte, error) {
+	return der.Application(MsgType_KRB_PRIV, der.Sequence(
+		der.Explicit(0, der.Integer(int64(k.Pvno))),
+		der.Explicit(1, der.Integer(int64(k.MsgType))),
+		der.Explicit(3, k.EncPart.marshal()),
+	)), nil
+}
+
+// FromBytes parses the DER encoding of an EncKrbPrivPart.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded decrypted part.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (e *EncKrbPrivPart) FromBytes(data []byte) error {
+	fields, err := parseFields(data, 28)
+	if err != nil {
+		return fmt.Errorf("error parsing EncKrbPrivPart: %w", err)
+	}
+
+	if e.UserData, err = requireBytes(fields, 0, "user-data"); err != nil {
+		return err
+	}
+
+	if e.Timestamp, err = optionalTime(fields, 1, "timestamp"); err != nil {
+		return err
+	}
+
+	usec, _, err := optionalInt(fields, 2, "usec")
+	if err != nil {
+		return err
+	}
+	e.Usec = int32(usec)
+
+	seqNumber, hasSeqNumber, err := optionalInt(fields, 3, "seq-number")
+	if err != nil {
+		return err
+	}
+	e.SeqNumber, e.HasSeqNumber = uint32(seqNumber), hasSeqNumber
+
+	if address, ok := fields[4]; ok {
+		e.SAddress = &HostAddress{}
+		if err = e.SAddress.fromElement(address); err != nil {
+			return err
+		}
+	}
+	if address, ok := fields[5]; ok {
+		e.RAddress = &HostAddress{}
+		if err = e.RAddress.fromElement(address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToBytes converts an EncKrbPrivPart to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (e *EncKrbPrivPart) ToBytes() ([]byte, error) {
+	var timestamp, usec, seqNumber, sAddress, rAddress []byte
+	if !e.Timestamp.IsZero() {
+		timestamp = der.Explicit(1, der.GeneralizedTime(e.Timestamp))
+		usec = der.Explicit(2, der.Integer(int64(e.Usec)))
+	}
+	if e.HasSeqNumber {
+		seqNumber = der.Explicit(3, der.Integer(int64(e.SeqNumber)))
+	}
+	if e.SAddress != nil {
+		sAddress = der.Explicit(4, e.SAddress.marshal())
+	}
+	if e.RAddress != nil {
+		rAddress = der.Explicit(5, e.RAddress.marshal())
+	}
+	return der.Application(28, der.Sequence(
+		der.Explicit(0, der.OctetString(e.UserData)),
+		timestamp,
+		usec,
+		seqNumber,
+		sAddress,
+		rAddress,
+	)), nil
+}
+
+// FromBytes parses the DER encoding of a ChangePasswdData.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded user data of a set-password request.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (c *ChangePasswdData) FromBytes(data []byte) error {
+	fields, err := parseFields(data, -1)
+	if err != nil {
+		return fmt.Errorf("error parsing ChangePasswdData: %w", err)
+	}
+
+	if c.NewPasswd, err = requireBytes(fields, 0, "newpasswd"); err != nil {
+		return err
+	}
+
+	if name, ok := fields[1]; ok {
+		c.TargName = &PrincipalName{}
+		if err = c.TargName.fromElement(name); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := fields[2]; ok {
+		c.TargRealm, err = requireText(fields, 2, "targrealm")
+	}
+	return err
+}
+
+// ToBytes converts a ChangePasswdData to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (c *ChangePasswdData) ToBytes() ([]byte, error) {
+	var targName, targRealm []byte
+	if c.TargName != nil {
+		targName = der.Explicit(1, c.TargName.marshal())
+	}
+	if c.TargRealm != "" {
+		targRealm = der.Explicit(2, der.GeneralString(c.TargRealm))
+	}
+	return der.Sequence(
+		der.Explicit(0, der.OctetString(c.NewPasswd)),
+		targName,
+		targRealm,
+	), nil
+}
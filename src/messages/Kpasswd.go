@@ -0,0 +1,132 @@
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Protocol versions of kpasswd requests, as defined in RFC 3244 section 2.
+const (
+	KpasswdVersion_ChangePassword = 0x0001
+	KpasswdVersion_SetPassword    = 0xff80
+)
+
+// Result codes of kpasswd replies, as defined in RFC 3244 section 2.
+const (
+	KpasswdResult_SUCCESS             = 0
+	KpasswdResult_MALFORMED           = 1
+	KpasswdResult_HARDERROR           = 2
+	KpasswdResult_AUTHERROR           = 3
+	KpasswdResult_SOFTERROR           = 4
+	KpasswdResult_ACCESSDENIED        = 5
+	KpasswdResult_BAD_VERSION         = 6
+	KpasswdResult_INITIAL_FLAG_NEEDED = 7
+)
+
+var kpasswdResultNames = map[uint16]string{
+	KpasswdResult_SUCCESS:             "KRB5_KPASSWD_SUCCESS",
+	KpasswdResult_MALFORMED:           "KRB5_KPASSWD_MALFORMED",
+	KpasswdResult_HARDERROR:           "KRB5_KPASSWD_HARDERROR",
+	KpasswdResult_AUTHERROR:           "KRB5_KPASSWD_AUTHERROR",
+	KpasswdResult_SOFTERROR:           "KRB5_KPASSWD_SOFTERROR",
+	KpasswdResult_ACCESSDENIED:        "KRB5_KPASSWD_ACCESSDENIED",
+	KpasswdResult_BAD_VERSION:         "KRB5_KPASSWD_BAD_VERSION",
+	KpasswdResult_INITIAL_FLAG_NEEDED: "KRB5_KPASSWD_INITIAL_FLAG_NEEDED",
+}
+
+// KpasswdResultToString returns the name of a kpasswd result code.
+//
+// Parameters:
+//   - code (uint16): The result code.
+//
+// Returns:
+//   - string: The name of the result code, or "UNKNOWN" if it is not known.
+func KpasswdResultToString(code uint16) string {
+	if name, ok := kpasswdResultNames[code]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// KpasswdMessage represents a kpasswd request or reply, as defined in RFC 3244 section 2.
+// Both are made of a header followed by an AP message and a KRB-PRIV; a reply carries a
+// KRB-ERROR and no AP-REP when the request could not be authenticated.
+//
+// Attributes:
+//   - Version (uint16): The protocol version, KpasswdVersion_ChangePassword or KpasswdVersion_SetPassword in requests and 1 in replies.
+//   - AP ([]byte): The DER encoded AP-REQ of a request or AP-REP of a reply, empty in error replies.
+//   - Data ([]byte): The DER encoded KRB-PRIV, or KRB-ERROR in error replies.
+type KpasswdMessage struct {
+	Version uint16
+	AP      []byte
+	Data    []byte
+}
+
+// FromBytes parses a kpasswd message.
+//
+// Parameters:
+//   - data ([]byte): The message, without the TCP length prefix.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (k *KpasswdMessage) FromBytes(data []byte) error {
+	if len(data) < 6 {
+		return fmt.Errorf("kpasswd message of %d bytes is too short", len(data))
+	}
+	length := int(binary.BigEndian.Uint16(data[0:2]))
+	if length != len(data) {
+		return fmt.Errorf("kpasswd message length %d does not match its size %d", length, len(data))
+	}
+	k.Version = binary.BigEndian.Uint16(data[2:4])
+	apLength := int(binary.BigEndian.Uint16(data[4:6]))
+	if 6+apLength > len(data) {
+		return fmt.Errorf("kpasswd AP message length %d exceeds the message", apLength)
+	}
+	k.AP = append([]byte{}, data[6:6+apLength]...)
+	k.Data = append([]byte{}, data[6+apLength:]...)
+	return nil
+}
+
+// ToBytes converts a KpasswdMessage to its wire encoding.
+//
+// Returns:
+//   - ([]byte, error): The encoded message and an error if it is too large.
+func (k *KpasswdMessage) ToBytes() ([]byte, error) {
+	length := 6 + len(k.AP) + len(k.Data)
+	if length > 0xffff {
+		return nil, fmt.Errorf("kpasswd message of %d bytes is too large", length)
+	}
+	data := make([]byte, 6, length)
+	binary.BigEndian.PutUint16(data[0:2], uint16(length))
+	binary.BigEndian.PutUint16(data[2:4], k.Version)
+	binary.BigEndian.PutUint16(data[4:6], uint16(len(k.AP)))
+	data = append(data, k.AP...)
+	return append(data, k.Data...), nil
+}
+
+// ParseKpasswdResult parses the user data of a kpasswd reply.
+//
+// Parameters:
+//   - data ([]byte): The user data of the KRB-PRIV of the reply.
+//
+// Returns:
+//   - (uint16, []byte, error): The result code, the result string and an error if the parsing failed.
+//     The result string is usually UTF-8 text, but Active Directory sends a binary password policy with KpasswdResult_SOFTERROR.
+func ParseKpasswdResult(data []byte) (uint16, []byte, error) {
+	if len(data) < 2 {
+		return 0, nil, fmt.Errorf("kpasswd result of %d bytes is too short", len(data))
+	}
+	return binary.BigEndian.Uint16(data[0:2]), append([]byte{}, data[2:]...), nil
+}
+
+// MarshalKpasswdResult encodes the user data of a kpasswd reply.
+//
+// Parameters:
+//   - code (uint16): The result code.
+//   - text (string): The result string.
+//
+// Returns:
+//   - []byte: The user data.
+func MarshalKpasswdResult(code uint16, text string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, code), text...)
+}
@@ -0,0 +1,57 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+)
+
+// messageTypeNames maps the message types to their names.
+var messageTypeNames = map[int]string{
+	MsgType_AS_REQ:    "AS-REQ",
+	MsgType_AS_REP:    "AS-REP",
+	MsgType_TGS_REQ:   "TGS-REQ",
+	MsgType_TGS_REP:   "TGS-REP",
+	MsgType_AP_REQ:    "AP-REQ",
+	MsgType_AP_REP:    "AP-REP",
+	MsgType_KRB_SAFE:  "KRB-SAFE",
+	MsgType_KRB_PRIV:  "KRB-PRIV",
+	MsgType_KRB_CRED:  "KRB-CRED",
+	MsgType_KRB_ERROR: "KRB-ERROR",
+}
+
+// MessageTypeToString returns the name of a Kerberos message type.
+//
+// Parameters:
+//   - msgType (int): The message type.
+//
+// Returns:
+//   - string: The name of the message type.
+func MessageTypeToString(msgType int) string {
+	if name, ok := messageTypeNames[msgType]; ok {
+		return name
+	}
+	return fmt.Sprintf("MSG-TYPE-%d", msgType)
+}
+
+// GetMessageType returns the message type of a DER encoded Kerberos message,
+// which is the application tag of its outermost element.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded message.
+//
+// Returns:
+//   - (int, error): The message type and an error if the data is not a Kerberos message.
+func GetMessageType(data []byte) (int, error) {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return 0, err
+	}
+	if element.Class != der.ClassApplication || !element.Constructed {
+		return 0, fmt.Errorf("data is not a Kerberos message")
+	}
+	if _, ok := messageTypeNames[element.Tag]; !ok {
+		return 0, fmt.Errorf("unknown Kerberos message type %d", element.Tag)
+	}
+	return element.Tag, nil
+}
@@ -0,0 +1,122 @@
+package messages
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func Test_Message_APReqInvolution(t *testing.T) {
+	apReq1 := APReq{
+		Pvno:      PVNO,
+		MsgType:   MsgType_AP_REQ,
+		APOptions: APOption_MutualRequired,
+		Ticket: Ticket{
+			TktVno:  PVNO,
+			Realm:   "TESTSEGMENT.LOCAL",
+			SName:   NewPrincipalName(NameType_SRV_INST, "HTTP", "web01.testsegment.local"),
+			EncPart: EncryptedData{EType: 18, Kvno: 2, HasKvno: true, Cipher: bytes.Repeat([]byte{0x11}, 48)},
+		},
+		Authenticator: EncryptedData{EType: 18, Cipher: bytes.Repeat([]byte{0x22}, 48)},
+	}
+
+	data, err := apReq1.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding AP-REQ: %v", err)
+	}
+	msgType, err := GetMessageType(data)
+	if err != nil || msgType != MsgType_AP_REQ {
+		t.Fatalf("Expected message type AP-REQ, got %d (%v)", msgType, err)
+	}
+
+	apReq2 := APReq{}
+	if err := apReq2.FromBytes(data); err != nil {
+		t.Fatalf("Error parsing AP-REQ: %v", err)
+	}
+	data2, _ := apReq2.ToBytes()
+	if !bytes.Equal(data, data2) {
+		t.Errorf("AP-REQ mismatch after encoding and parsing")
+	}
+	if apReq2.APOptions != APOption_MutualRequired || !apReq2.Ticket.SName.Equal(apReq1.Ticket.SName) {
+		t.Errorf("AP-REQ fields mismatch after encoding and parsing")
+	}
+}
+
+func Test_Message_KRBErrorInvolution(t *testing.T) {
+	krbError1 := KRBError{
+		Pvno:      PVNO,
+		MsgType:   MsgType_KRB_ERROR,
+		STime:     time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		ErrorCode: ErrorCode_KDC_ERR_PREAUTH_REQUIRED,
+		Realm:     "TESTSEGMENT.LOCAL",
+		SName:     NewPrincipalName(NameType_SRV_INST, "krbtgt", "TESTSEGMENT.LOCAL"),
+		EData:     []byte{0x30, 0x00},
+	}
+
+	data, err := krbError1.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding KRB-ERROR: %v", err)
+	}
+
+	krbError2 := KRBError{}
+	if err := krbError2.FromBytes(data); err != nil {
+		t.Fatalf("Error parsing KRB-ERROR: %v", err)
+	}
+	if krbError2.ErrorCode != krbError1.ErrorCode || !krbError2.STime.Equal(krbError1.STime) || !bytes.Equal(krbError2.EData, krbError1.EData) {
+		t.Errorf("KRB-ERROR fields mismatch after encoding and parsing")
+	}
+	if krbError2.Error() != "KDC_ERR_PREAUTH_REQUIRED (25)" {
+		t.Errorf("Unexpected error string %q", krbError2.Error())
+	}
+}
+
+func Test_Message_KRBPrivInvolution(t *testing.T) {
+	targName := NewPrincipalName(NameType_PRINCIPAL, "svc_web")
+	request1 := ChangePasswdData{NewPasswd: []byte("N3w-P4ssw0rd"), TargName: &targName, TargRealm: "TESTSEGMENT.LOCAL"}
+	userData, err := request1.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding ChangePasswdData: %v", err)
+	}
+
+	part1 := EncKrbPrivPart{
+		UserData:     userData,
+		Timestamp:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Usec:         42,
+		SeqNumber:    1234,
+		HasSeqNumber: true,
+		SAddress:     &HostAddress{AddrType: AddrType_IPv4, Address: []byte{127, 0, 0, 1}},
+	}
+	data, err := part1.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding EncKrbPrivPart: %v", err)
+	}
+
+	part2 := EncKrbPrivPart{}
+	if err := part2.FromBytes(data); err != nil {
+		t.Fatalf("Error parsing EncKrbPrivPart: %v", err)
+	}
+	if part2.SeqNumber != 1234 || part2.Usec != 42 || part2.SAddress == nil || part2.RAddress != nil || !part2.Timestamp.Equal(part1.Timestamp) {
+		t.Errorf("EncKrbPrivPart fields mismatch after encoding and parsing")
+	}
+
+	request2 := ChangePasswdData{}
+	if err := request2.FromBytes(part2.UserData); err != nil {
+		t.Fatalf("Error parsing ChangePasswdData: %v", err)
+	}
+	if string(request2.NewPasswd) != "N3w-P4ssw0rd" || request2.TargName == nil || !request2.TargName.Equal(targName) || request2.TargRealm != "TESTSEGMENT.LOCAL" {
+		t.Errorf("ChangePasswdData fields mismatch after encoding and parsing")
+	}
+
+	priv1 := KRBPriv{Pvno: PVNO, MsgType: MsgType_KRB_PRIV, EncPart: EncryptedData{EType: 18, Cipher: bytes.Repeat([]byte{0x33}, 48)}}
+	data, err = priv1.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding KRB-PRIV: %v", err)
+	}
+	priv2 := KRBPriv{}
+	if err := priv2.FromBytes(data); err != nil {
+		t.Fatalf("Error parsing KRB-PRIV: %v", err)
+	}
+	if priv2.MsgType != MsgType_KRB_PRIV || !bytes.Equal(priv2.EncPart.Cipher, priv1.EncPart.Cipher) {
+		t.Errorf("KRB-PRIV fields mismatch after encoding and parsing")
+	}
+}
@@ -0,0 +1,100 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+)
+
+// Pre-authentication data types, as defined in RFC 4120 section 7.5.2 and RFC 6113.
+const (
+	PADataType_TGS_REQ        = 1
+	PADataType_ENC_TIMESTAMP  = 2
+	PADataType_PW_SALT        = 3
+	PADataType_ETYPE_INFO     = 11
+	PADataType_ETYPE_INFO2    = 19
+	PADataType_PAC_REQUEST    = 128
+	PADataType_FX_FAST        = 136
+	PADataType_ENCRYPTED_DATA = 138
+)
+
+// PAData represents a Kerberos PA-DATA, as defined in RFC 4120 section 5.2.7.
+//
+// Attributes:
+//   - PADataType (int32): The type of the pre-authentication data.
+//   - PADataValue ([]byte): The value of the pre-authentication data, usually DER encoded.
+type PAData struct {
+	PADataType  int32
+	PADataValue []byte
+}
+
+// fromElement parses an already decoded PAData element.
+func (p *PAData) fromElement(element der.Element) error {
+	fields, err := element.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing PA-DATA: %w", err)
+	}
+
+	padataType, err := requireInt(fields, 1, "padata-type")
+	if err != nil {
+		return err
+	}
+	p.PADataType = int32(padataType)
+
+	p.PADataValue, err = requireBytes(fields, 2, "padata-value")
+	return err
+}
+
+// marshal returns the DER encoding of the PAData.
+func (p *PAData) marshal() []byte {
+	return der.Sequence(
+		der.Explicit(1, der.Integer(int64(p.PADataType))),
+		der.Explicit(2, der.OctetString(p.PADataValue)),
+	)
+}
+
+// parsePAData parses an optional SEQUENCE OF PA-DATA field.
+func parsePAData(fields map[int]der.Element, tag int, name string) ([]PAData, error) {
+	elements, err := sequenceOf(fields, tag, name)
+	if err != nil || elements == nil {
+		return nil, err
+	}
+	padata := make([]PAData, 0, len(elements))
+	for _, element := range elements {
+		p := PAData{}
+		err := p.fromElement(element)
+		if err != nil {
+			return nil, err
+		}
+		padata = append(padata, p)
+	}
+	return padata, nil
+}
+
+// marshalPAData encodes an optional SEQUENCE OF PA-DATA field, returning nil if it is empty.
+func marshalPAData(padata []PAData) []byte {
+	if len(padata) == 0 {
+		return nil
+	}
+	encoded := make([][]byte, 0, len(padata))
+	for i := range padata {
+		encoded = append(encoded, padata[i].marshal())
+	}
+	return der.Sequence(encoded...)
+}
+
+// FindPAData returns the first PA-DATA of the given type, or nil if there is none.
+//
+// Parameters:
+//   - padata ([]PAData): The pre-authentication data to search.
+//   - padataType (int32): The type of pre-authentication data to find.
+//
+// Returns:
+//   - *PAData: The pre-authentication data, or nil if not found.
+func FindPAData(padata []PAData, padataType int32) *PAData {
+	for i := range padata {
+		if padata[i].PADataType == padataType {
+			return &padata[i]
+		}
+	}
+	return nil
+}
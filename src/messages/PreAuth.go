@@ -0,0 +1,179 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// PAEncTSEnc represents a PA-ENC-TS-ENC, the plaintext of the encrypted timestamp
+// pre-authentication, as defined in RFC 4120 section 5.2.7.2.
+//
+// Attributes:
+//   - PATimestamp (time.Time): The client time.
+//   - PAUsec (int32): The microseconds part of the client time, if HasPAUsec is set.
+//   - HasPAUsec (bool): Whether the microseconds are present.
+type PAEncTSEnc struct {
+	PATimestamp time.Time
+	PAUsec      int32
+	HasPAUsec   bool
+}
+
+// ETypeInfo2Entry represents a single element of a PA-ETYPE-INFO2, telling the client
+// how to derive its key for an encryption type, as defined in RFC 4120 section 5.2.7.5.
+//
+// Attributes:
+//   - EType (int32): The encryption type.
+//   - Salt (string): The salt, if HasSalt is set. The default salt is used otherwise.
+//   - HasSalt (bool): Whether the salt is present.
+//   - S2KParams ([]byte): The string-to-key parameters, may be nil.
+type ETypeInfo2Entry struct {
+	EType     int32
+	Salt      string
+	HasSalt   bool
+	S2KParams []byte
+}
+
+// FromBytes parses the DER encoding of a PA-ENC-TS-ENC.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded timestamp, as decrypted from the PA-DATA.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (p *PAEncTSEnc) FromBytes(data []byte) error {
+	fields, err := parseFields(data, -1)
+	if err != nil {
+		return fmt.Errorf("error parsing PA-ENC-TS-ENC: %w", err)
+	}
+
+	p.PATimestamp, err = requireTime(fields, 0, "patimestamp")
+	if err != nil {
+		return err
+	}
+
+	usec, ok, err := optionalInt(fields, 1, "pausec")
+	if err != nil {
+		return err
+	}
+	p.PAUsec, p.HasPAUsec = int32(usec), ok
+	return nil
+}
+
+// ToBytes converts a PAEncTSEnc to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (p *PAEncTSEnc) ToBytes() ([]byte, error) {
+	var usec []byte
+	if p.HasPAUsec {
+		usec = der.Explicit(1, der.Integer(int64(p.PAUsec)))
+	}
+	return der.Sequence(
+		der.Explicit(0, der.GeneralizedTime(p.PATimestamp)),
+		usec,
+	), nil
+}
+
+// ParseETypeInfo2 parses the DER encoding of a PA-ETYPE-INFO2.
+//
+// Parameters:
+//   - data ([]byte): The value of the PA-DATA.
+//
+// Returns:
+//   - ([]ETypeInfo2Entry, error): The entries, in order of preference of the KDC, and an error if the parsing failed.
+func ParseETypeInfo2(data []byte) ([]ETypeInfo2Entry, error) {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	children, err := element.Children()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ETYPE-INFO2: %w", err)
+	}
+
+	entries := make([]ETypeInfo2Entry, 0, len(children))
+	for _, child := range children {
+		fields, err := child.Fields()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ETYPE-INFO2-ENTRY: %w", err)
+		}
+		entry := ETypeInfo2Entry{}
+		etype, err := requireInt(fields, 0, "etype")
+		if err != nil {
+			return nil, err
+		}
+		entry.EType = int32(etype)
+		if salt, ok := fields[1]; ok {
+			if entry.Salt, err = salt.Text(); err != nil {
+				return nil, fmt.Errorf("error parsing field salt: %w", err)
+			}
+			entry.HasSalt = true
+		}
+		if _, ok := fields[2]; ok {
+			if entry.S2KParams, err = requireBytes(fields, 2, "s2kparams"); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// MarshalETypeInfo2 returns the DER encoding of a PA-ETYPE-INFO2.
+//
+// Parameters:
+//   - entries ([]ETypeInfo2Entry): The entries, in order of preference.
+//
+// Returns:
+//   - []byte: The DER encoded PA-ETYPE-INFO2.
+func MarshalETypeInfo2(entries []ETypeInfo2Entry) []byte {
+	encoded := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		var salt, s2kParams []byte
+		if entry.HasSalt {
+			salt = der.Explicit(1, der.GeneralString(entry.Salt))
+		}
+		if entry.S2KParams != nil {
+			s2kParams = der.Explicit(2, der.OctetString(entry.S2KParams))
+		}
+		encoded = append(encoded, der.Sequence(
+			der.Explicit(0, der.Integer(int64(entry.EType))),
+			salt,
+			s2kParams,
+		))
+	}
+	return der.Sequence(encoded...)
+}
+
+// ParseMethodData parses the DER encoding of a METHOD-DATA, the e-data of the
+// KDC_ERR_PREAUTH_REQUIRED and KDC_ERR_PREAUTH_FAILED errors.
+//
+// Parameters:
+//   - data ([]byte): The e-data of the error.
+//
+// Returns:
+//   - ([]PAData, error): The pre-authentication data and an error if the parsing failed.
+func ParseMethodData(data []byte) ([]PAData, error) {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return parsePAData(map[int]der.Element{0: element}, 0, "method-data")
+}
+
+// MarshalMethodData returns the DER encoding of a METHOD-DATA.
+//
+// Parameters:
+//   - padata ([]PAData): The pre-authentication data.
+//
+// Returns:
+//   - []byte: The DER encoded METHOD-DATA.
+func MarshalMethodData(padata []PAData) []byte {
+	if len(padata) == 0 {
+		return der.Sequence()
+	}
+	return marshalPAData(padata)
+}
@@ -0,0 +1,164 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"strings"
+)
+
+// PrincipalName represents a Kerberos PrincipalName, as defined in RFC 4120 section 5.2.2.
+//
+// Attributes:
+//   - NameType (int32): The type of the name.
+//   - NameString ([]string): The components of the name.
+type PrincipalName struct {
+	NameType   int32
+	NameString []string
+}
+
+// NewPrincipalName creates a PrincipalName from a name type and components.
+//
+// Parameters:
+//   - nameType (int32): The type of the name.
+//   - components (...string): The components of the name.
+//
+// Returns:
+//   - PrincipalName: The principal name.
+func NewPrincipalName(nameType int32, components ...string) PrincipalName {
+	return PrincipalName{NameType: nameType, NameString: components}
+}
+
+// ParsePrincipal splits a principal in the form "component1/component2@REALM" into
+// its components and realm. Escaped separators ("\/" and "\@") are supported.
+//
+// Parameters:
+//   - principal (string): The principal to parse.
+//
+// Returns:
+//   - []string: The components of the principal.
+//   - string: The realm of the principal, empty if there is none.
+func ParsePrincipal(principal string) ([]string, string) {
+	components := make([]string, 0)
+	realm := ""
+	current := strings.Builder{}
+	inRealm := false
+
+	for i := 0; i < len(principal); i++ {
+		c := principal[i]
+		switch {
+		case c == '\\' && i+1 < len(principal):
+			i++
+			current.WriteByte(principal[i])
+		case c == '/' && !inRealm:
+			components = append(components, current.String())
+			current.Reset()
+		case c == '@' && !inRealm:
+			components = append(components, current.String())
+			current.Reset()
+			inRealm = true
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	if inRealm {
+		realm = current.String()
+	} else {
+		components = append(components, current.String())
+	}
+
+	return components, realm
+}
+
+// FromBytes parses the DER encoding of a PrincipalName.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded principal name.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (p *PrincipalName) FromBytes(data []byte) error {
+	e := der.Element{}
+	err := e.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	return p.fromElement(e)
+}
+
+// fromElement parses an already decoded PrincipalName element.
+func (p *PrincipalName) fromElement(e der.Element) error {
+	fields, err := e.Fields()
+	if err != nil {
+		return fmt.Errorf("error parsing principal name: %w", err)
+	}
+
+	nameType, err := requireInt(fields, 0, "name-type")
+	if err != nil {
+		return err
+	}
+	p.NameType = int32(nameType)
+
+	names, err := sequenceOf(fields, 1, "name-string")
+	if err != nil {
+		return err
+	}
+	p.NameString = make([]string, 0, len(names))
+	for _, name := range names {
+		value, err := name.Text()
+		if err != nil {
+			return fmt.Errorf("error parsing name-string: %w", err)
+		}
+		p.NameString = append(p.NameString, value)
+	}
+
+	return nil
+}
+
+// ToBytes converts a PrincipalName to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (p *PrincipalName) ToBytes() ([]byte, error) {
+	return p.marshal(), nil
+}
+
+// marshal returns the DER encoding of the PrincipalName.
+func (p *PrincipalName) marshal() []byte {
+	names := make([][]byte, 0, len(p.NameString))
+	for _, name := range p.NameString {
+		names = append(names, der.GeneralString(name))
+	}
+	return der.Sequence(
+		der.Explicit(0, der.Integer(int64(p.NameType))),
+		der.Explicit(1, der.Sequence(names...)),
+	)
+}
+
+// String returns the components of the PrincipalName joined with "/".
+//
+// Returns:
+//   - string: The string representation of the principal name.
+func (p *PrincipalName) String() string {
+	return strings.Join(p.NameString, "/")
+}
+
+// Equal checks if two PrincipalName structs hold the same components.
+// The name type is not compared, as RFC 4120 states it is only a hint.
+//
+// Parameters:
+//   - p2 (PrincipalName): The PrincipalName to compare to.
+//
+// Returns:
+//   - bool: True if the PrincipalName structs are equal, false otherwise.
+func (p *PrincipalName) Equal(p2 PrincipalName) bool {
+	if len(p.NameString) != len(p2.NameString) {
+		return false
+	}
+	for i := range p.NameString {
+		if p.NameString[i] != p2.NameString[i] {
+			return false
+		}
+	}
+	return true
+}
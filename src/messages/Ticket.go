@@ -0,0 +1,88 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+)
+
+// Ticket represents a Kerberos Ticket, as defined in RFC 4120 section 5.3.
+//
+// Attributes:
+//   - TktVno (int32): The version number of the ticket format, always 5.
+//   - Realm (string): The realm of the server.
+//   - SName (PrincipalName): The name of the server.
+//   - EncPart (EncryptedData): The encrypted part of the ticket, an EncTicketPart encrypted in the server key.
+type Ticket struct {
+	TktVno  int32
+	Realm   string
+	SName   PrincipalName
+	EncPart EncryptedData
+}
+
+// FromBytes parses the DER encoding of a Ticket.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded ticket.
+//
+// Returns:
+//   - error: An error if the parsing failed.
+func (t *Ticket) FromBytes(data []byte) error {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	return t.fromElement(element)
+}
+
+// fromElement parses an already decoded Ticket element.
+func (t *Ticket) fromElement(element der.Element) error {
+	fields, err := elementFields(element, 1)
+	if err != nil {
+		return fmt.Errorf("error parsing ticket: %w", err)
+	}
+
+	tktVno, err := requireInt(fields, 0, "tkt-vno")
+	if err != nil {
+		return err
+	}
+	t.TktVno = int32(tktVno)
+
+	t.Realm, err = requireText(fields, 1, "realm")
+	if err != nil {
+		return err
+	}
+
+	sname, ok := fields[2]
+	if !ok {
+		return fmt.Errorf("missing field sname")
+	}
+	err = t.SName.fromElement(sname)
+	if err != nil {
+		return err
+	}
+
+	encPart, ok := fields[3]
+	if !ok {
+		return fmt.Errorf("missing field enc-part")
+	}
+	return t.EncPart.fromElement(encPart)
+}
+
+// ToBytes converts a Ticket to its DER encoding.
+//
+// Returns:
+//   - ([]byte, error): The DER encoding and an error if the conversion failed.
+func (t *Ticket) ToBytes() ([]byte, error) {
+	return t.marshal(), nil
+}
+
+// marshal returns the DER encoding of the Ticket.
+func (t *Ticket) marshal() []byte {
+	return der.Application(1, der.Sequence(
+		der.Explicit(0, der.Integer(int64(t.TktVno))),
+		der.Explicit(1, der.GeneralString(t.Realm)),
+		der.Explicit(2, t.SName.marshal()),
+		der.Explicit(3, t.EncPart.marshal()),
+	))
+}
@@ -0,0 +1,152 @@
+package messages
+
+import (
+	"fmt"
+	"keytab/der"
+	"time"
+)
+
+// parseFields parses the DER encoding of a Kerberos structure, optionally wrapped
+// in an application tag, and returns its context tagged fields.
+//
+// Parameters:
+//   - data ([]byte): The DER encoded structure.
+//   - application (int): The expected application tag, or -1 if the structure is not wrapped.
+//
+// Returns:
+//   - (map[int]der.Element, error): The fields and an error if the parsing failed.
+func parseFields(data []byte, application int) (map[int]der.Element, error) {
+	e := der.Element{}
+	err := e.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return elementFields(e, application)
+}
+
+// elementFields returns the context tagged fields of an already parsed structure,
+// optionally wrapped in an application tag.
+func elementFields(e der.Element, application int) (map[int]der.Element, error) {
+	if application >= 0 {
+		inner, err := e.Unwrap(der.ClassApplication, application)
+		if err != nil {
+			return nil, err
+		}
+		e = inner
+	}
+	return e.Fields()
+}
+
+// requireInt returns the integer value of a mandatory field.
+func requireInt(fields map[int]der.Element, tag int, name string) (int64, error) {
+	field, ok := fields[tag]
+	if !ok {
+		return 0, fmt.Errorf("missing field %s", name)
+	}
+	value, err := field.Int()
+	if err != nil {
+		return 0, fmt.Errorf("error parsing field %s: %w", name, err)
+	}
+	return value, nil
+}
+
+// requireBytes returns the octet string value of a mandatory field.
+func requireBytes(fields map[int]der.Element, tag int, name string) ([]byte, error) {
+	field, ok := fields[tag]
+	if !ok {
+		return nil, fmt.Errorf("missing field %s", name)
+	}
+	value, err := field.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing field %s: %w", name, err)
+	}
+	return append([]byte{}, value...), nil
+}
+
+// requireText returns the string value of a mandatory field.
+func requireText(fields map[int]der.Element, tag int, name string) (string, error) {
+	field, ok := fields[tag]
+	if !ok {
+		return "", fmt.Errorf("missing field %s", name)
+	}
+	value, err := field.Text()
+	if err != nil {
+		return "", fmt.Errorf("error parsing field %s: %w", name, err)
+	}
+	return value, nil
+}
+
+// requireTime returns the time value of a mandatory field.
+func requireTime(fields map[int]der.Element, tag int, name string) (time.Time, error) {
+	field, ok := fields[tag]
+	if !ok {
+		return time.Time{}, fmt.Errorf("missing field %s", name)
+	}
+	value, err := field.Time()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing field %s: %w", name, err)
+	}
+	return value, nil
+}
+
+// optionalTime returns the time value of an optional field, or the zero time if it is absent.
+func optionalTime(fields map[int]der.Element, tag int, name string) (time.Time, error) {
+	if _, ok := fields[tag]; !ok {
+		return time.Time{}, nil
+	}
+	return requireTime(fields, tag, name)
+}
+
+// optionalInt returns the integer value of an optional field, and whether it is present.
+func optionalInt(fields map[int]der.Element, tag int, name string) (int64, bool, error) {
+	if _, ok := fields[tag]; !ok {
+		return 0, false, nil
+	}
+	value, err := requireInt(fields, tag, name)
+	return value, err == nil, err
+}
+
+// optionalFlags returns the KerberosFlags value of an optional field, or 0 if it is absent.
+func optionalFlags(fields map[int]der.Element, tag int, name string) (uint32, error) {
+	field, ok := fields[tag]
+	if !ok {
+		return 0, nil
+	}
+	value, err := field.BitString()
+	if err != nil {
+		return 0, fmt.Errorf("error parsing field %s: %w", name, err)
+	}
+	return value, nil
+}
+
+// sequenceOf returns the elements of a SEQUENCE OF field, or nil if the field is absent.
+func sequenceOf(fields map[int]der.Element, tag int, name string) ([]der.Element, error) {
+	field, ok := fields[tag]
+	if !ok {
+		return nil, nil
+	}
+	if !field.Is(der.ClassUniversal, der.TagSequence) {
+		return nil, fmt.Errorf("field %s is not a sequence", name)
+	}
+	children, err := field.Children()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing field %s: %w", name, err)
+	}
+	return children, nil
+}
+
+// encodeTime encodes a KerberosTime, or returns nil for the zero time so that optional times are omitted.
+func encodeTime(value time.Time) []byte {
+	if value.IsZero() {
+		return nil
+	}
+	return der.GeneralizedTime(value)
+}
+
+// encodeOptionalString encodes a KerberosString, or returns nil for an empty string.
+func encodeOptionalString(value string) []byte {
+	if len(value) == 0 {
+		return nil
+	}
+	return der.GeneralString(value)
+}
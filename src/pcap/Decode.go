@@ -0,0 +1,179 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Transport protocols carried by a segment.
+const (
+	Transport_TCP = "tcp"
+	Transport_UDP = "udp"
+)
+
+// TCP flags used during reassembly.
+const (
+	tcpFlag_FIN = 0x01
+	tcpFlag_SYN = 0x02
+	tcpFlag_RST = 0x04
+)
+
+// segment represents the transport layer payload of a decoded packet.
+type segment struct {
+	Timestamp time.Time
+	Transport string
+	SrcIP     net.IP
+	DstIP     net.IP
+	SrcPort   uint16
+	DstPort   uint16
+	Seq       uint32
+	Flags     uint8
+	Payload   []byte
+}
+
+// Source returns the source endpoint of the segment as "ip:port".
+func (s *segment) Source() string {
+	return net.JoinHostPort(s.SrcIP.String(), strconv.Itoa(int(s.SrcPort)))
+}
+
+// Destination returns the destination endpoint of the segment as "ip:port".
+func (s *segment) Destination() string {
+	return net.JoinHostPort(s.DstIP.String(), strconv.Itoa(int(s.DstPort)))
+}
+
+// decodePacket decodes the link, network and transport layers of a packet.
+// It returns false for packets that are not TCP or UDP over IPv4 or IPv6, and
+// for IPv4 fragments other than the first one.
+func decodePacket(packet Packet) (segment, bool) {
+	data := packet.Data
+	etherType := uint16(0)
+
+	switch packet.LinkType {
+	case LinkType_Ethernet:
+		if len(data) < 14 {
+			return segment{}, false
+		}
+		etherType = binary.BigEndian.Uint16(data[12:14])
+		data = data[14:]
+		// 802.1Q and 802.1ad VLAN tags
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(data) >= 4 {
+			etherType = binary.BigEndian.Uint16(data[2:4])
+			data = data[4:]
+		}
+	case LinkType_Null, LinkType_Loop:
+		if len(data) < 4 {
+			return segment{}, false
+		}
+		data = data[4:]
+	case LinkType_Raw:
+	case LinkType_SLL:
+		if len(data) < 16 {
+			return segment{}, false
+		}
+		etherType = binary.BigEndian.Uint16(data[14:16])
+		data = data[16:]
+	case LinkType_SLL2:
+		if len(data) < 20 {
+			return segment{}, false
+		}
+		etherType = binary.BigEndian.Uint16(data[0:2])
+		data = data[20:]
+	default:
+		return segment{}, false
+	}
+
+	if etherType != 0 && etherType != 0x0800 && etherType != 0x86dd {
+		return segment{}, false
+	}
+	if len(data) == 0 {
+		return segment{}, false
+	}
+
+	s := segment{Timestamp: packet.Timestamp}
+	protocol := uint8(0)
+
+	switch data[0] >> 4 {
+	case 4:
+		if len(data) < 20 {
+			return segment{}, false
+		}
+		headerLength := int(data[0]&0x0f) * 4
+		totalLength := int(binary.BigEndian.Uint16(data[2:4]))
+		if headerLength < 20 || totalLength < headerLength || len(data) < headerLength {
+			return segment{}, false
+		}
+		if totalLength < len(data) {
+			// Strip the Ethernet padding
+			data = data[:totalLength]
+		}
+		if binary.BigEndian.Uint16(data[6:8])&0x1fff != 0 {
+			return segment{}, false
+		}
+		protocol = data[9]
+		s.SrcIP = net.IP(data[12:16])
+		s.DstIP = net.IP(data[16:20])
+		data = data[headerLength:]
+
+	case 6:
+		if len(data) < 40 {
+			return segment{}, false
+		}
+		payloadLength := int(binary.BigEndian.Uint16(data[4:6]))
+		protocol = data[6]
+		s.SrcIP = net.IP(data[8:24])
+		s.DstIP = net.IP(data[24:40])
+		data = data[40:]
+		if payloadLength < len(data) {
+			data = data[:payloadLength]
+		}
+		// Skip the hop-by-hop, routing and destination options extension headers
+		for (protocol == 0 || protocol == 43 || protocol == 60) && len(data) >= 8 {
+			length := (int(data[1]) + 1) * 8
+			if length > len(data) {
+				return segment{}, false
+			}
+			protocol = data[0]
+			data = data[length:]
+		}
+
+	default:
+		return segment{}, false
+	}
+
+	switch protocol {
+	case 6:
+		if len(data) < 20 {
+			return segment{}, false
+		}
+		dataOffset := int(data[12]>>4) * 4
+		if dataOffset < 20 || dataOffset > len(data) {
+			return segment{}, false
+		}
+		s.Transport = Transport_TCP
+		s.SrcPort = binary.BigEndian.Uint16(data[0:2])
+		s.DstPort = binary.BigEndian.Uint16(data[2:4])
+		s.Seq = binary.BigEndian.Uint32(data[4:8])
+		s.Flags = data[13]
+		s.Payload = data[dataOffset:]
+
+	case 17:
+		if len(data) < 8 {
+			return segment{}, false
+		}
+		s.Transport = Transport_UDP
+		s.SrcPort = binary.BigEndian.Uint16(data[0:2])
+		s.DstPort = binary.BigEndian.Uint16(data[2:4])
+		length := int(binary.BigEndian.Uint16(data[4:6]))
+		s.Payload = data[8:]
+		if length >= 8 && length-8 < len(s.Payload) {
+			s.Payload = s.Payload[:length-8]
+		}
+
+	default:
+		return segment{}, false
+	}
+
+	return s, true
+}
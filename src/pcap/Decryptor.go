@@ -0,0 +1,303 @@
+package pcap
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"keytab/crypto"
+	"keytab/keytab"
+	"keytab/messages"
+	"strings"
+)
+
+// ringKey represents a key known to the decryptor.
+//
+// Attributes:
+//   - EType (int32): The encryption type of the key.
+//   - Value ([]byte): The key.
+//   - Origin (string): A description of where the key comes from.
+//   - LongTerm (bool): Whether the key is a long-term key from a keytab, rather than a session key or a subkey.
+//   - Principal (string): The principal owning a long-term key.
+type ringKey struct {
+	EType     int32
+	Value     []byte
+	Origin    string
+	LongTerm  bool
+	Principal string
+}
+
+// Part represents an encrypted part of a message.
+//
+// Attributes:
+//   - Name (string): The name of the part, such as "ticket" or "enc-part".
+//   - EncryptedData (messages.EncryptedData): The encrypted data.
+//   - Decrypted (bool): Whether the part was decrypted.
+//   - KeyOrigin (string): The origin of the key that decrypted the part.
+//   - Details ([]string): Human readable "name: value" lines describing the decrypted content.
+type Part struct {
+	Name          string
+	EncryptedData messages.EncryptedData
+	Decrypted     bool
+	KeyOrigin     string
+	Details       []string
+	// Internal
+	usages    []uint32
+	longTerm  bool
+	principal string
+	ticket    []byte
+	tried     map[*ringKey]bool
+	handler   func(plaintext []byte) error
+}
+
+// AddKeytab adds the long-term keys of a keytab to the keys used to decrypt the messages.
+//
+// Parameters:
+//   - kt (*keytab.Keytab): The keytab.
+func (t *Timeline) AddKeytab(kt *keytab.Keytab) {
+	for i := range kt.Entries {
+		entry := &kt.Entries[i]
+		t.addKey(&ringKey{
+			EType:     int32(entry.Key.Type),
+			Value:     entry.Key.Key.Data,
+			Origin:    fmt.Sprintf("keytab entry %s (kvno %d)", entry.Principal(), entry.KVNO()),
+			LongTerm:  true,
+			Principal: entry.Principal(),
+		})
+	}
+}
+
+// addKey adds a key to the key ring, unless it is already known.
+func (t *Timeline) addKey(key *ringKey) *ringKey {
+	id := fmt.Sprintf("%d:%x", key.EType, key.Value)
+	if known, ok := t.keyIndex[id]; ok {
+		return known
+	}
+	t.keyIndex[id] = key
+	t.keys = append(t.keys, key)
+	return key
+}
+
+// addSessionKey registers a session key or a subkey, optionally bound to the ticket it belongs to.
+func (t *Timeline) addSessionKey(key messages.EncryptionKey, origin string, ticketCipher []byte) {
+	if len(key.KeyValue) == 0 {
+		return
+	}
+	rk := t.addKey(&ringKey{EType: key.KeyType, Value: key.KeyValue, Origin: origin})
+	if ticketCipher != nil {
+		t.ticketKeys[hex.EncodeToString(ticketCipher)] = rk
+	}
+}
+
+// keyString returns a session key or a subkey in hexadecimal if keys are shown, or its masked representation otherwise.
+func (t *Timeline) keyString(key messages.EncryptionKey) string {
+	return keytab.NewKeyBlock(keytab.EncryptionType(key.KeyType), key.KeyValue).KeyString(t.ShowKeys)
+}
+
+// Decrypt decrypts every encrypted part for which a key is known. Decrypting a part may
+// reveal new keys (session keys in AS-REP and TGS-REP enc-parts and in tickets, subkeys in
+// authenticators), so the parts are retried until no further progress is made.
+//
+// Returns:
+//   - int: The number of decrypted parts.
+func (t *Timeline) Decrypt() int {
+	count := 0
+	for {
+		progress := false
+		for _, event := range t.Events {
+			for _, part := range event.Parts {
+				if part.Decrypted {
+					continue
+				}
+				if t.decryptPart(part) {
+					progress = true
+					count++
+				}
+			}
+		}
+		if !progress {
+			return count
+		}
+	}
+}
+
+// decryptPart tries the candidate keys of a part that were not tried yet.
+func (t *Timeline) decryptPart(part *Part) bool {
+	for _, key := range t.candidates(part) {
+		if part.tried[key] {
+			continue
+		}
+		part.tried[key] = true
+
+		for _, usage := range part.usages {
+			plaintext, err := crypto.Decrypt(key.EType, key.Value, usage, part.EncryptedData.Cipher)
+			if errors.Is(err, crypto.ErrIntegrity) {
+				continue
+			} else if err != nil {
+				return false
+			}
+
+			// A parse failure means the checksum matched by chance, keep looking
+			part.Details = nil
+			if part.handler(plaintext) != nil {
+				continue
+			}
+			part.Decrypted = true
+			part.KeyOrigin = key.Origin
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the keys that may decrypt a part, most likely ones first:
+// long-term keys of the expected principal or the session key bound to the ticket.
+func (t *Timeline) candidates(part *Part) []*ringKey {
+	etype := part.EncryptedData.EType
+	preferred := make([]*ringKey, 0)
+	others := make([]*ringKey, 0)
+
+	if !part.longTerm && part.ticket != nil {
+		if key, ok := t.ticketKeys[hex.EncodeToString(part.ticket)]; ok && key.EType == etype {
+			preferred = append(preferred, key)
+		}
+	}
+
+	for _, key := range t.keys {
+		if key.EType != etype || key.LongTerm != part.longTerm {
+			continue
+		}
+		if part.longTerm && strings.EqualFold(key.Principal, part.principal) {
+			preferred = append(preferred, key)
+		} else {
+			others = append(others, key)
+		}
+	}
+
+	return append(preferred, others...)
+}
+
+// newPart creates an encrypted part.
+func newPart(name string, data messages.EncryptedData, usages []uint32, handler func(plaintext []byte) error) *Part {
+	return &Part{
+		Name:          name,
+		EncryptedData: data,
+		usages:        usages,
+		tried:         make(map[*ringKey]bool),
+		handler:       handler,
+	}
+}
+
+// ticketPart creates the part of a ticket, encrypted in the long-term key of its server.
+func (t *Timeline) ticketPart(event *Event, ticket *messages.Ticket) *Part {
+	part := newPart("ticket", ticket.EncPart, []uint32{messages.KeyUsage_KDC_REP_TICKET}, nil)
+	part.longTerm = true
+	part.principal = ticket.SName.String() + "@" + ticket.Realm
+	part.handler = func(plaintext []byte) error {
+		encPart := messages.EncTicketPart{}
+		err := encPart.FromBytes(plaintext)
+		if err != nil {
+			return err
+		}
+		part.Details = append(part.Details,
+			fmt.Sprintf("Client: %s@%s", encPart.CName.String(), encPart.CRealm),
+			fmt.Sprintf("SessionKey: %s (%s)", etypeName(encPart.Key.KeyType), t.keyString(encPart.Key)),
+			fmt.Sprintf("Validity: %s -> %s", formatTime(encPart.AuthTime), formatTime(encPart.EndTime)),
+		)
+		for _, ad := range encPart.AuthorizationData {
+			if ad.ADType == messages.ADType_IF_RELEVANT {
+				part.Details = append(part.Details, fmt.Sprintf("AuthorizationData: AD-IF-RELEVANT (%d bytes)", len(ad.ADData)))
+			}
+		}
+		t.addSessionKey(encPart.Key, fmt.Sprintf("session key of ticket %s (#%d)", part.principal, event.Index), ticket.EncPart.Cipher)
+		return nil
+	}
+	return part
+}
+
+// kdcRepPart creates the enc-part of an AS-REP or a TGS-REP.
+func (t *Timeline) kdcRepPart(event *Event, rep *messages.KDCRep) *Part {
+	part := newPart("enc-part", rep.EncPart, nil, nil)
+	if rep.MsgType == messages.MsgType_AS_REP {
+		// Some KDCs encrypt the AS-REP enc-part with the TGS-REP key usage
+		part.usages = []uint32{messages.KeyUsage_AS_REP_ENCPART, messages.KeyUsage_TGS_REP_ENCPART_SESSION_KEY}
+		part.longTerm = true
+		part.principal = rep.CName.String() + "@" + rep.CRealm
+	} else {
+		part.usages = []uint32{messages.KeyUsage_TGS_REP_ENCPART_SUB_KEY, messages.KeyUsage_TGS_REP_ENCPART_SESSION_KEY}
+	}
+	part.handler = func(plaintext []byte) error {
+		encPart := messages.EncKDCRepPart{}
+		err := encPart.FromBytes(plaintext)
+		if err != nil {
+			return err
+		}
+		server := encPart.SName.String() + "@" + encPart.SRealm
+		part.Details = append(part.Details,
+			fmt.Sprintf("Server: %s", server),
+			fmt.Sprintf("SessionKey: %s (%s)", etypeName(encPart.Key.KeyType), t.keyString(encPart.Key)),
+			fmt.Sprintf("Nonce: %d", encPart.Nonce),
+			fmt.Sprintf("Validity: %s -> %s", formatTime(encPart.AuthTime), formatTime(encPart.EndTime)),
+		)
+		t.addSessionKey(encPart.Key, fmt.Sprintf("session key of %s (#%d)", server, event.Index), rep.Ticket.EncPart.Cipher)
+		return nil
+	}
+	return part
+}
+
+// authenticatorPart creates the authenticator part of an AP-REQ, encrypted in the session key of its ticket.
+func (t *Timeline) authenticatorPart(event *Event, apReq *messages.APReq, usage uint32) *Part {
+	part := newPart("authenticator", apReq.Authenticator, []uint32{usage}, nil)
+	part.ticket = apReq.Ticket.EncPart.Cipher
+	part.handler = func(plaintext []byte) error {
+		authenticator := messages.Authenticator{}
+		err := authenticator.FromBytes(plaintext)
+		if err != nil {
+			return err
+		}
+		part.Details = append(part.Details,
+			fmt.Sprintf("Client: %s@%s", authenticator.CName.String(), authenticator.CRealm),
+			fmt.Sprintf("CTime: %s", formatTime(authenticator.CTime)),
+		)
+		if authenticator.SubKey != nil {
+			part.Details = append(part.Details, fmt.Sprintf("SubKey: %s (%s)", etypeName(authenticator.SubKey.KeyType), t.keyString(*authenticator.SubKey)))
+			t.addSessionKey(*authenticator.SubKey, fmt.Sprintf("subkey of authenticator (#%d)", event.Index), nil)
+		}
+		return nil
+	}
+	return part
+}
+
+// apRepPart creates the enc-part of an AP-REP, encrypted in the session key of the ticket being answered.
+func (t *Timeline) apRepPart(event *Event, apRep *messages.APRep) *Part {
+	part := newPart("enc-part", apRep.EncPart, []uint32{messages.KeyUsage_AP_REP_ENCPART}, nil)
+	part.handler = func(plaintext []byte) error {
+		encPart := messages.EncAPRepPart{}
+		err := encPart.FromBytes(plaintext)
+		if err != nil {
+			return err
+		}
+		part.Details = append(part.Details, fmt.Sprintf("CTime: %s", formatTime(encPart.CTime)))
+		if encPart.SubKey != nil {
+			part.Details = append(part.Details, fmt.Sprintf("SubKey: %s (%s)", etypeName(encPart.SubKey.KeyType), t.keyString(*encPart.SubKey)))
+			t.addSessionKey(*encPart.SubKey, fmt.Sprintf("subkey of AP-REP (#%d)", event.Index), nil)
+		}
+		return nil
+	}
+	return part
+}
+
+// encTimestampPart creates the PA-ENC-TIMESTAMP part of an AS-REQ, encrypted in the long-term key of the client.
+func (t *Timeline) encTimestampPart(req *messages.KDCReq, data messages.EncryptedData) *Part {
+	part := newPart("PA-ENC-TIMESTAMP", data, []uint32{messages.KeyUsage_AS_REQ_PA_ENC_TIMESTAMP}, nil)
+	part.longTerm = true
+	part.principal = req.ReqBody.CName.String() + "@" + req.ReqBody.Realm
+	part.handler = func(plaintext []byte) error {
+		timestamp, err := parseEncTimestamp(plaintext)
+		if err != nil {
+			return err
+		}
+		part.Details = append(part.Details, fmt.Sprintf("Timestamp: %s", formatTime(timestamp)))
+		return nil
+	}
+	return part
+}
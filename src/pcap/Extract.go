@@ -0,0 +1,253 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"keytab/der"
+	"keytab/messages"
+	"sort"
+	"time"
+)
+
+// Carriers of the Kerberos messages found in a capture.
+const (
+	Carrier_Kerberos = "kerberos"
+	Carrier_SMB      = "smb"
+	Carrier_HTTP     = "http"
+	Carrier_LDAP     = "ldap"
+	Carrier_GSSAPI   = "gss-api"
+)
+
+// KerberosPort is the port of the Kerberos KDC, over both TCP and UDP.
+const KerberosPort = 88
+
+// gssKerberosOIDs holds the DER encodings of the Kerberos 5 mechanism OID (1.2.840.113554.1.2.2)
+// and of the variant with a truncated arc (1.2.840.48018.1.2.2) sent by older Windows versions.
+var gssKerberosOIDs = [][]byte{
+	{0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x12, 0x01, 0x02, 0x02},
+	{0x06, 0x09, 0x2a, 0x86, 0x48, 0x82, 0xf7, 0x12, 0x01, 0x02, 0x02},
+}
+
+// gssTokenTypes maps the RFC 4121 TOK_ID of a Kerberos GSS-API token to the message it carries.
+var gssTokenTypes = map[uint16]int{
+	0x0100: messages.MsgType_AP_REQ,
+	0x0200: messages.MsgType_AP_REP,
+	0x0300: messages.MsgType_KRB_ERROR,
+}
+
+// Message represents a Kerberos message extracted from a capture.
+//
+// Attributes:
+//   - Timestamp (time.Time): The capture time of the packet holding the start of the message.
+//   - Source (string): The sending endpoint, as "ip:port".
+//   - Destination (string): The receiving endpoint, as "ip:port".
+//   - Transport (string): The transport protocol, "tcp" or "udp".
+//   - Carrier (string): The protocol carrying the message, "kerberos" for KDC traffic.
+//   - MsgType (int): The Kerberos message type.
+//   - Data ([]byte): The DER encoding of the message.
+type Message struct {
+	Timestamp   time.Time
+	Source      string
+	Destination string
+	Transport   string
+	Carrier     string
+	MsgType     int
+	Data        []byte
+}
+
+// ExtractMessages finds the Kerberos messages of a capture. Messages exchanged with a
+// KDC over TCP or UDP port 88 are extracted from the reassembled traffic, and AP-REQ,
+// AP-REP and KRB-ERROR tokens embedded in other TCP protocols (SMB, HTTP Negotiate,
+// LDAP SASL) are found by looking for the GSS-API Kerberos mechanism.
+//
+// Parameters:
+//   - packets ([]Packet): The packets of the capture.
+//
+// Returns:
+//   - []Message: The messages, ordered by capture time.
+func ExtractMessages(packets []Packet) []Message {
+	segments := make([]segment, 0, len(packets))
+	for _, packet := range packets {
+		seg, ok := decodePacket(packet)
+		if ok {
+			segments = append(segments, seg)
+		}
+	}
+
+	result := make([]Message, 0)
+
+	for i := range segments {
+		seg := &segments[i]
+		if seg.Transport != Transport_UDP || (seg.SrcPort != KerberosPort && seg.DstPort != KerberosPort) {
+			continue
+		}
+		msgType, err := messages.GetMessageType(seg.Payload)
+		if err != nil {
+			continue
+		}
+		result = append(result, Message{
+			Timestamp:   seg.Timestamp,
+			Source:      seg.Source(),
+			Destination: seg.Destination(),
+			Transport:   Transport_UDP,
+			Carrier:     Carrier_Kerberos,
+			MsgType:     msgType,
+			Data:        seg.Payload,
+		})
+	}
+
+	for _, s := range reassembleTCP(segments) {
+		if s.SrcPort == KerberosPort || s.DstPort == KerberosPort {
+			result = append(result, extractKerberosTCP(s)...)
+		} else {
+			result = append(result, extractEmbedded(s)...)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+
+	return result
+}
+
+// extractKerberosTCP splits a KDC TCP stream into its messages, each prefixed by its 4 bytes length.
+func extractKerberosTCP(s *stream) []Message {
+	result := make([]Message, 0)
+	offset := 0
+	for offset+4 <= len(s.Data) {
+		// The high bit of the length is reserved
+		length := int(binary.BigEndian.Uint32(s.Data[offset:offset+4]) & 0x7fffffff)
+		if length > len(s.Data)-offset-4 {
+			break
+		}
+		data := s.Data[offset+4 : offset+4+length]
+		if msgType, err := messages.GetMessageType(data); err == nil {
+			result = append(result, Message{
+				Timestamp:   s.TimeAt(offset),
+				Source:      s.Source,
+				Destination: s.Destination,
+				Transport:   Transport_TCP,
+				Carrier:     Carrier_Kerberos,
+				MsgType:     msgType,
+				Data:        data,
+			})
+		}
+		offset += 4 + length
+	}
+	return result
+}
+
+// extractEmbedded finds the Kerberos GSS-API tokens of a non-KDC TCP stream, either in
+// binary form (SMB, LDAP) or base64 encoded in HTTP Negotiate headers.
+func extractEmbedded(s *stream) []Message {
+	carrier := Carrier_GSSAPI
+	switch {
+	case s.SrcPort == 445 || s.DstPort == 445 || s.SrcPort == 139 || s.DstPort == 139:
+		carrier = Carrier_SMB
+	case s.SrcPort == 389 || s.DstPort == 389 || s.SrcPort == 3268 || s.DstPort == 3268:
+		carrier = Carrier_LDAP
+	}
+
+	result := make([]Message, 0)
+	newMessage := func(offset int, msgType int, data []byte, carrier string) Message {
+		return Message{
+			Timestamp:   s.TimeAt(offset),
+			Source:      s.Source,
+			Destination: s.Destination,
+			Transport:   Transport_TCP,
+			Carrier:     carrier,
+			MsgType:     msgType,
+			Data:        data,
+		}
+	}
+
+	for _, token := range findGSSTokens(s.Data) {
+		result = append(result, newMessage(token.offset, token.msgType, token.data, carrier))
+	}
+
+	for _, encoded := range findNegotiateHeaders(s.Data) {
+		decoded, err := base64.StdEncoding.DecodeString(string(encoded.data))
+		if err != nil {
+			decoded, err = base64.RawStdEncoding.DecodeString(string(encoded.data))
+			if err != nil {
+				continue
+			}
+		}
+		for _, token := range findGSSTokens(decoded) {
+			result = append(result, newMessage(encoded.offset, token.msgType, token.data, Carrier_HTTP))
+		}
+	}
+
+	return result
+}
+
+// gssToken is a Kerberos message found in a byte array.
+type gssToken struct {
+	offset  int
+	msgType int
+	data    []byte
+}
+
+// findGSSTokens finds the Kerberos messages following a GSS-API Kerberos mechanism OID and a TOK_ID.
+func findGSSTokens(data []byte) []gssToken {
+	tokens := make([]gssToken, 0)
+	for _, oid := range gssKerberosOIDs {
+		offset := 0
+		for {
+			index := bytes.Index(data[offset:], oid)
+			if index < 0 {
+				break
+			}
+			start := offset + index + len(oid)
+			offset = start
+			if start+2 > len(data) {
+				break
+			}
+			msgType, ok := gssTokenTypes[binary.BigEndian.Uint16(data[start:start+2])]
+			if !ok {
+				continue
+			}
+			element := der.Element{}
+			if element.FromBytes(data[start+2:]) != nil {
+				continue
+			}
+			if element.Class != der.ClassApplication || element.Tag != msgType {
+				continue
+			}
+			tokens = append(tokens, gssToken{offset: start - len(oid), msgType: msgType, data: element.RawBytes})
+			offset = start + 2 + int(element.RawBytesSize)
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].offset < tokens[j].offset })
+	return tokens
+}
+
+// findNegotiateHeaders finds the base64 tokens of the HTTP "Negotiate" authentication scheme.
+func findNegotiateHeaders(data []byte) []gssToken {
+	marker := []byte("Negotiate ")
+	tokens := make([]gssToken, 0)
+	offset := 0
+	for {
+		index := bytes.Index(data[offset:], marker)
+		if index < 0 {
+			break
+		}
+		start := offset + index + len(marker)
+		end := start
+		for end < len(data) && isBase64Char(data[end]) {
+			end++
+		}
+		if end > start {
+			tokens = append(tokens, gssToken{offset: offset + index, data: data[start:end]})
+		}
+		offset = end
+	}
+	return tokens
+}
+
+// isBase64Char returns true if c belongs to the standard base64 alphabet or is padding.
+func isBase64Char(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/' || c == '='
+}
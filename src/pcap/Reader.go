@@ -0,0 +1,260 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Link types, as registered at https://www.tcpdump.org/linktypes.html.
+const (
+	LinkType_Null     = 0
+	LinkType_Ethernet = 1
+	LinkType_Raw      = 101
+	LinkType_Loop     = 108
+	LinkType_SLL      = 113
+	LinkType_SLL2     = 276
+)
+
+// Magic numbers of the capture file formats.
+const (
+	magicPcapMicroseconds = 0xa1b2c3d4
+	magicPcapNanoseconds  = 0xa1b23c4d
+	magicPcapng           = 0x0a0d0d0a
+	magicPcapngByteOrder  = 0x1a2b3c4d
+)
+
+// pcapng block types, as defined in the pcapng specification.
+const (
+	blockType_InterfaceDescription = 0x00000001
+	blockType_SimplePacket         = 0x00000003
+	blockType_EnhancedPacket       = 0x00000006
+	blockType_SectionHeader        = 0x0a0d0d0a
+)
+
+// Packet represents a single captured frame.
+//
+// Attributes:
+//   - Timestamp (time.Time): The capture time of the frame.
+//   - LinkType (uint32): The link layer type of the frame.
+//   - Data ([]byte): The captured bytes of the frame.
+type Packet struct {
+	Timestamp time.Time
+	LinkType  uint32
+	Data      []byte
+}
+
+// pcapngInterface holds the properties of a pcapng interface needed to read its packets.
+type pcapngInterface struct {
+	linkType   uint32
+	resolution float64
+}
+
+// ReadPackets parses a pcap or pcapng capture into its packets.
+//
+// Parameters:
+//   - data ([]byte): The content of the capture file.
+//
+// Returns:
+//   - ([]Packet, error): The packets and an error if the capture could not be parsed.
+func ReadPackets(data []byte) ([]Packet, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("capture is too short")
+	}
+
+	if binary.LittleEndian.Uint32(data[0:4]) == magicPcapng {
+		return readPcapng(data)
+	}
+	return readPcap(data)
+}
+
+// LoadPacketsFromFile reads the packets of a pcap or pcapng capture file.
+//
+// Parameters:
+//   - path (string): The path to the capture file.
+//
+// Returns:
+//   - ([]Packet, error): The packets and an error if the file could not be read.
+func LoadPacketsFromFile(path string) ([]Packet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadPackets(data)
+}
+
+// readPcap parses a classic libpcap capture, in either byte order and timestamp resolution.
+func readPcap(data []byte) ([]Packet, error) {
+	if len(data) < 24 {
+		return nil, fmt.Errorf("pcap header is too short")
+	}
+
+	var order binary.ByteOrder
+	nanoseconds := false
+	switch {
+	case binary.BigEndian.Uint32(data[0:4]) == magicPcapMicroseconds:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(data[0:4]) == magicPcapMicroseconds:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(data[0:4]) == magicPcapNanoseconds:
+		order, nanoseconds = binary.BigEndian, true
+	case binary.LittleEndian.Uint32(data[0:4]) == magicPcapNanoseconds:
+		order, nanoseconds = binary.LittleEndian, true
+	default:
+		return nil, fmt.Errorf("not a pcap or pcapng capture")
+	}
+
+	linkType := order.Uint32(data[20:24]) & 0x0fffffff
+
+	packets := make([]Packet, 0)
+	offset := 24
+	for offset+16 <= len(data) {
+		seconds := order.Uint32(data[offset : offset+4])
+		fraction := order.Uint32(data[offset+4 : offset+8])
+		capturedLength := int(order.Uint32(data[offset+8 : offset+12]))
+		offset += 16
+
+		if capturedLength > len(data)-offset {
+			return packets, fmt.Errorf("truncated packet record at offset %d", offset-16)
+		}
+
+		nsec := int64(fraction) * 1000
+		if nanoseconds {
+			nsec = int64(fraction)
+		}
+		packets = append(packets, Packet{
+			Timestamp: time.Unix(int64(seconds), nsec).UTC(),
+			LinkType:  linkType,
+			Data:      data[offset : offset+capturedLength],
+		})
+		offset += capturedLength
+	}
+
+	return packets, nil
+}
+
+// readPcapng parses a pcapng capture, which may contain several sections and interfaces.
+func readPcapng(data []byte) ([]Packet, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	interfaces := make([]pcapngInterface, 0)
+	packets := make([]Packet, 0)
+
+	offset := 0
+	for offset+12 <= len(data) {
+		blockType := order.Uint32(data[offset : offset+4])
+
+		if blockType == blockType_SectionHeader {
+			// The byte order of a section is given by the magic of its header
+			if offset+12 > len(data) {
+				break
+			}
+			switch {
+			case binary.LittleEndian.Uint32(data[offset+8:offset+12]) == magicPcapngByteOrder:
+				order = binary.LittleEndian
+			case binary.BigEndian.Uint32(data[offset+8:offset+12]) == magicPcapngByteOrder:
+				order = binary.BigEndian
+			default:
+				return packets, fmt.Errorf("invalid pcapng byte order magic at offset %d", offset)
+			}
+			interfaces = interfaces[:0]
+		}
+
+		blockLength := int(order.Uint32(data[offset+4 : offset+8]))
+		if blockLength < 12 || blockLength > len(data)-offset {
+			return packets, fmt.Errorf("invalid pcapng block length %d at offset %d", blockLength, offset)
+		}
+		body := data[offset+8 : offset+blockLength-4]
+
+		switch blockType {
+		case blockType_InterfaceDescription:
+			if len(body) < 8 {
+				return packets, fmt.Errorf("interface description block is too short")
+			}
+			iface := pcapngInterface{
+				linkType:   uint32(order.Uint16(body[0:2])),
+				resolution: 1e-6,
+			}
+			parseInterfaceOptions(&iface, body[8:], order)
+			interfaces = append(interfaces, iface)
+
+		case blockType_EnhancedPacket:
+			if len(body) < 20 {
+				return packets, fmt.Errorf("enhanced packet block is too short")
+			}
+			interfaceID := int(order.Uint32(body[0:4]))
+			if interfaceID >= len(interfaces) {
+				return packets, fmt.Errorf("packet references unknown interface %d", interfaceID)
+			}
+			timestamp := uint64(order.Uint32(body[4:8]))<<32 | uint64(order.Uint32(body[8:12]))
+			capturedLength := int(order.Uint32(body[12:16]))
+			if capturedLength > len(body)-20 {
+				return packets, fmt.Errorf("truncated enhanced packet block at offset %d", offset)
+			}
+			iface := interfaces[interfaceID]
+			packets = append(packets, Packet{
+				Timestamp: convertTimestamp(timestamp, iface.resolution),
+				LinkType:  iface.linkType,
+				Data:      body[20 : 20+capturedLength],
+			})
+
+		case blockType_SimplePacket:
+			if len(body) < 4 || len(interfaces) == 0 {
+				return packets, fmt.Errorf("invalid simple packet block at offset %d", offset)
+			}
+			capturedLength := int(order.Uint32(body[0:4]))
+			if capturedLength > len(body)-4 {
+				capturedLength = len(body) - 4
+			}
+			packets = append(packets, Packet{
+				LinkType: interfaces[0].linkType,
+				Data:     body[4 : 4+capturedLength],
+			})
+		}
+
+		offset += blockLength
+	}
+
+	return packets, nil
+}
+
+// parseInterfaceOptions reads the timestamp resolution (if_tsresol) of an interface description block.
+func parseInterfaceOptions(iface *pcapngInterface, options []byte, order binary.ByteOrder) {
+	for len(options) >= 4 {
+		code := order.Uint16(options[0:2])
+		length := int(order.Uint16(options[2:4]))
+		if code == 0 || 4+length > len(options) {
+			return
+		}
+		if code == 9 && length >= 1 {
+			value := options[4]
+			if value&0x80 != 0 {
+				iface.resolution = math.Pow(2, -float64(value&0x7f))
+			} else {
+				iface.resolution = math.Pow(10, -float64(value))
+			}
+		}
+		options = options[4+((length+3)&^3):]
+	}
+}
+
+// convertTimestamp converts a pcapng timestamp in units of resolution seconds to a time.
+func convertTimestamp(timestamp uint64, resolution float64) time.Time {
+	unitsPerSecond := uint64(math.Round(1 / resolution))
+	if unitsPerSecond == 0 {
+		return time.Unix(0, 0).UTC()
+	}
+	seconds := timestamp / unitsPerSecond
+	remainder := timestamp % unitsPerSecond
+	nsec := int64(float64(remainder) * resolution * 1e9)
+	return time.Unix(int64(seconds), nsec).UTC()
+}
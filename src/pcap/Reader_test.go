@@ -0,0 +1,126 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// buildFrame builds an Ethernet/IPv4 frame carrying a TCP segment or a UDP datagram.
+func buildFrame(transport string, src, dst string, srcPort, dstPort uint16, seq uint32, flags uint8, payload []byte) []byte {
+	var l4 []byte
+	if transport == Transport_TCP {
+		l4 = make([]byte, 20)
+		binary.BigEndian.PutUint16(l4[0:2], srcPort)
+		binary.BigEndian.PutUint16(l4[2:4], dstPort)
+		binary.BigEndian.PutUint32(l4[4:8], seq)
+		l4[12] = 5 << 4
+		l4[13] = flags
+	} else {
+		l4 = make([]byte, 8)
+		binary.BigEndian.PutUint16(l4[0:2], srcPort)
+		binary.BigEndian.PutUint16(l4[2:4], dstPort)
+		binary.BigEndian.PutUint16(l4[4:6], uint16(8+len(payload)))
+	}
+	l4 = append(l4, payload...)
+
+	ip := make([]byte, 20)
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(l4)))
+	ip[8] = 64
+	if transport == Transport_TCP {
+		ip[9] = 6
+	} else {
+		ip[9] = 17
+	}
+	copy(ip[12:16], net.ParseIP(src).To4())
+	copy(ip[16:20], net.ParseIP(dst).To4())
+
+	frame := make([]byte, 14)
+	binary.BigEndian.PutUint16(frame[12:14], 0x0800)
+	frame = append(frame, ip...)
+	return append(frame, l4...)
+}
+
+// buildPcap builds a little endian microseconds pcap capture of Ethernet frames.
+func buildPcap(packets []Packet) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, []uint32{magicPcapMicroseconds, 0x00040002, 0, 0, 65535, LinkType_Ethernet})
+	for _, p := range packets {
+		binary.Write(buf, binary.LittleEndian, []uint32{
+			uint32(p.Timestamp.Unix()), uint32(p.Timestamp.Nanosecond() / 1000), uint32(len(p.Data)), uint32(len(p.Data)),
+		})
+		buf.Write(p.Data)
+	}
+	return buf.Bytes()
+}
+
+// buildPcapng builds a big endian pcapng capture of Ethernet frames with a nanoseconds resolution.
+func buildPcapng(packets []Packet) []byte {
+	order := binary.BigEndian
+	buf := new(bytes.Buffer)
+	block := func(blockType uint32, body []byte) {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		binary.Write(buf, order, []uint32{blockType, uint32(12 + len(body))})
+		buf.Write(body)
+		binary.Write(buf, order, uint32(12+len(body)))
+	}
+
+	shb := new(bytes.Buffer)
+	binary.Write(shb, order, uint32(magicPcapngByteOrder))
+	binary.Write(shb, order, []uint16{1, 0})
+	binary.Write(shb, order, int64(-1))
+	block(blockType_SectionHeader, shb.Bytes())
+
+	idb := new(bytes.Buffer)
+	binary.Write(idb, order, []uint16{LinkType_Ethernet, 0})
+	binary.Write(idb, order, uint32(65535))
+	binary.Write(idb, order, []uint16{9, 1})
+	idb.Write([]byte{9, 0, 0, 0})
+	binary.Write(idb, order, []uint16{0, 0})
+	block(blockType_InterfaceDescription, idb.Bytes())
+
+	for _, p := range packets {
+		timestamp := uint64(p.Timestamp.UnixNano())
+		epb := new(bytes.Buffer)
+		binary.Write(epb, order, []uint32{0, uint32(timestamp >> 32), uint32(timestamp), uint32(len(p.Data)), uint32(len(p.Data))})
+		epb.Write(p.Data)
+		block(blockType_EnhancedPacket, epb.Bytes())
+	}
+	return buf.Bytes()
+}
+
+func Test_ReadPackets_PcapAndPcapng(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	packets := []Packet{
+		{Timestamp: base, LinkType: LinkType_Ethernet, Data: buildFrame(Transport_UDP, "10.0.0.5", "10.0.0.1", 50000, 88, 0, 0, []byte("hello"))},
+		{Timestamp: base.Add(1500 * time.Microsecond), LinkType: LinkType_Ethernet, Data: buildFrame(Transport_TCP, "10.0.0.5", "10.0.0.1", 50001, 88, 1000, 0, []byte("world"))},
+	}
+
+	for name, data := range map[string][]byte{"pcap": buildPcap(packets), "pcapng": buildPcapng(packets)} {
+		read, err := ReadPackets(data)
+		if err != nil {
+			t.Fatalf("%s: error reading packets: %v", name, err)
+		}
+		if len(read) != len(packets) {
+			t.Fatalf("%s: expected %d packets, got %d", name, len(packets), len(read))
+		}
+		for i := range packets {
+			if !read[i].Timestamp.Equal(packets[i].Timestamp) {
+				t.Errorf("%s: packet %d timestamp mismatch: %s != %s", name, i, read[i].Timestamp, packets[i].Timestamp)
+			}
+			if read[i].LinkType != LinkType_Ethernet || !bytes.Equal(read[i].Data, packets[i].Data) {
+				t.Errorf("%s: packet %d content mismatch", name, i)
+			}
+		}
+
+		seg, ok := decodePacket(read[1])
+		if !ok || seg.Transport != Transport_TCP || seg.Source() != "10.0.0.5:50001" || seg.Seq != 1000 || string(seg.Payload) != "world" {
+			t.Errorf("%s: unexpected decoded segment %+v", name, seg)
+		}
+	}
+}
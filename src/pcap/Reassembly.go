@@ -0,0 +1,133 @@
+package pcap
+
+import (
+	"sort"
+	"time"
+)
+
+// stream represents one direction of a TCP connection, reassembled in sequence order.
+//
+// Attributes:
+//   - Source (string): The sending endpoint, as "ip:port".
+//   - Destination (string): The receiving endpoint, as "ip:port".
+//   - SrcPort (uint16): The source port.
+//   - DstPort (uint16): The destination port.
+//   - Data ([]byte): The reassembled payload.
+//   - Gaps (int): The number of holes in the sequence space, caused by missing segments.
+type stream struct {
+	Source      string
+	Destination string
+	SrcPort     uint16
+	DstPort     uint16
+	Data        []byte
+	Gaps        int
+	// Internal
+	chunks   []chunk
+	segments []segment
+	base     uint32
+	hasBase  bool
+}
+
+// chunk records the capture time of the payload starting at an offset of a stream.
+type chunk struct {
+	offset    int
+	timestamp time.Time
+}
+
+// TimeAt returns the capture time of the segment holding the byte at offset.
+//
+// Parameters:
+//   - offset (int): The offset in the reassembled payload.
+//
+// Returns:
+//   - time.Time: The capture time of the segment.
+func (s *stream) TimeAt(offset int) time.Time {
+	index := sort.Search(len(s.chunks), func(i int) bool { return s.chunks[i].offset > offset })
+	if index == 0 {
+		if len(s.chunks) == 0 {
+			return time.Time{}
+		}
+		return s.chunks[0].timestamp
+	}
+	return s.chunks[index-1].timestamp
+}
+
+// add records a segment of the stream, using the first SYN or segment to anchor the sequence numbers.
+func (s *stream) add(seg segment) {
+	if seg.Flags&tcpFlag_SYN != 0 {
+		s.base, s.hasBase = seg.Seq+1, true
+	}
+	if len(seg.Payload) == 0 {
+		return
+	}
+	if !s.hasBase {
+		s.base, s.hasBase = seg.Seq, true
+	}
+	s.segments = append(s.segments, seg)
+}
+
+// assemble orders the segments by sequence number, drops retransmitted bytes and builds the payload.
+func (s *stream) assemble() {
+	// Relative sequence numbers handle wrap-around of the 32 bits sequence space
+	sort.SliceStable(s.segments, func(i, j int) bool {
+		return int32(s.segments[i].Seq-s.base) < int32(s.segments[j].Seq-s.base)
+	})
+
+	next := 0
+	for _, seg := range s.segments {
+		start := int(int32(seg.Seq - s.base))
+		end := start + len(seg.Payload)
+		if end <= next || start < 0 && end <= 0 {
+			continue
+		}
+		payload := seg.Payload
+		if start < next {
+			payload = payload[next-start:]
+		} else if start > next {
+			s.Gaps++
+		}
+		s.chunks = append(s.chunks, chunk{offset: len(s.Data), timestamp: seg.Timestamp})
+		s.Data = append(s.Data, payload...)
+		next = end
+	}
+	s.segments = nil
+}
+
+// reassembleTCP groups TCP segments into streams, one per direction of each connection,
+// ordered by the time of their first segment. A new SYN on a 4-tuple that already
+// carried data starts a new connection.
+func reassembleTCP(segments []segment) []*stream {
+	active := make(map[string]*stream)
+	streams := make([]*stream, 0)
+
+	for _, seg := range segments {
+		if seg.Transport != Transport_TCP {
+			continue
+		}
+		key := seg.Source() + ">" + seg.Destination()
+		s, ok := active[key]
+		if ok && seg.Flags&tcpFlag_SYN != 0 && len(s.segments) != 0 {
+			ok = false
+		}
+		if !ok {
+			s = &stream{
+				Source:      seg.Source(),
+				Destination: seg.Destination(),
+				SrcPort:     seg.SrcPort,
+				DstPort:     seg.DstPort,
+			}
+			active[key] = s
+			streams = append(streams, s)
+		}
+		s.add(seg)
+	}
+
+	result := make([]*stream, 0, len(streams))
+	for _, s := range streams {
+		s.assemble()
+		if len(s.Data) != 0 {
+			result = append(result, s)
+		}
+	}
+	return result
+}
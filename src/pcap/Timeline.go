@@ -0,0 +1,269 @@
+package pcap
+
+import (
+	"fmt"
+	"keytab/der"
+	"keytab/keytab"
+	"keytab/messages"
+	"strings"
+	"time"
+)
+
+// Event represents a Kerberos message of the timeline.
+//
+// Attributes:
+//   - Index (int): The position of the event in the timeline.
+//   - Message (Message): The extracted message.
+//   - Client (string): The client principal, when known from the clear text part.
+//   - Server (string): The server principal, when known from the clear text part.
+//   - Info ([]string): Human readable "name: value" lines describing the clear text part.
+//   - Parts ([]*Part): The encrypted parts of the message.
+//   - ParseError (error): The error raised while parsing the message, if any.
+type Event struct {
+	Index      int
+	Message    Message
+	Client     string
+	Server     string
+	Info       []string
+	Parts      []*Part
+	ParseError error
+}
+
+// Timeline represents the Kerberos messages of a capture, in capture order, along with
+// the keys used to decrypt them.
+//
+// Attributes:
+//   - Events ([]*Event): The events of the timeline.
+//   - ShowKeys (bool): Whether the details of the decrypted parts show the session keys and subkeys, which are masked otherwise. Set it before Decrypt.
+type Timeline struct {
+	Events   []*Event
+	ShowKeys bool
+	// Internal
+	keys       []*ringKey
+	keyIndex   map[string]*ringKey
+	ticketKeys map[string]*ringKey
+}
+
+// NewTimeline parses the extracted messages into a timeline.
+//
+// Parameters:
+//   - msgs ([]Message): The messages, as returned by ExtractMessages.
+//
+// Returns:
+//   - *Timeline: The timeline.
+func NewTimeline(msgs []Message) *Timeline {
+	t := &Timeline{
+		Events:     make([]*Event, 0, len(msgs)),
+		keys:       make([]*ringKey, 0),
+		keyIndex:   make(map[string]*ringKey),
+		ticketKeys: make(map[string]*ringKey),
+	}
+	for i, msg := range msgs {
+		event := &Event{Index: i, Message: msg}
+		event.ParseError = t.parseEvent(event)
+		t.Events = append(t.Events, event)
+	}
+	return t
+}
+
+// parseEvent parses the clear text part of a message and registers its encrypted parts.
+func (t *Timeline) parseEvent(event *Event) error {
+	data := event.Message.Data
+
+	switch event.Message.MsgType {
+	case messages.MsgType_AS_REQ, messages.MsgType_TGS_REQ:
+		req := messages.KDCReq{}
+		err := req.FromBytes(data)
+		if err != nil {
+			return err
+		}
+		body := &req.ReqBody
+		if len(body.CName.NameString) != 0 {
+			event.Client = body.CName.String() + "@" + body.Realm
+		}
+		event.Server = body.SName.String() + "@" + body.Realm
+		etypes := make([]string, 0, len(body.EType))
+		for _, etype := range body.EType {
+			etypes = append(etypes, etypeName(etype))
+		}
+		event.Info = append(event.Info, fmt.Sprintf("ETypes: %s", strings.Join(etypes, ", ")))
+
+		if pa := messages.FindPAData(req.PAData, messages.PADataType_ENC_TIMESTAMP); pa != nil {
+			encTimestamp := messages.EncryptedData{}
+			if encTimestamp.FromBytes(pa.PADataValue) == nil {
+				event.Parts = append(event.Parts, t.encTimestampPart(&req, encTimestamp))
+			}
+		}
+		if pa := messages.FindPAData(req.PAData, messages.PADataType_TGS_REQ); pa != nil {
+			apReq := messages.APReq{}
+			err = apReq.FromBytes(pa.PADataValue)
+			if err != nil {
+				return fmt.Errorf("error parsing PA-TGS-REQ: %w", err)
+			}
+			event.Info = append(event.Info, fmt.Sprintf("TGT: %s@%s", apReq.Ticket.SName.String(), apReq.Ticket.Realm))
+			event.Parts = append(event.Parts,
+				t.ticketPart(event, &apReq.Ticket),
+				t.authenticatorPart(event, &apReq, messages.KeyUsage_TGS_REQ_PA_AUTHENTICATOR),
+			)
+		}
+
+	case messages.MsgType_AS_REP, messages.MsgType_TGS_REP:
+		rep := messages.KDCRep{}
+		err := rep.FromBytes(data)
+		if err != nil {
+			return err
+		}
+		event.Client = rep.CName.String() + "@" + rep.CRealm
+		event.Server = rep.Ticket.SName.String() + "@" + rep.Ticket.Realm
+		event.Parts = append(event.Parts, t.ticketPart(event, &rep.Ticket), t.kdcRepPart(event, &rep))
+
+	case messages.MsgType_AP_REQ:
+		apReq := messages.APReq{}
+		err := apReq.FromBytes(data)
+		if err != nil {
+			return err
+		}
+		event.Server = apReq.Ticket.SName.String() + "@" + apReq.Ticket.Realm
+		if apReq.APOptions&messages.APOption_MutualRequired != 0 {
+			event.Info = append(event.Info, "APOptions: mutual-required")
+		}
+		event.Parts = append(event.Parts,
+			t.ticketPart(event, &apReq.Ticket),
+			t.authenticatorPart(event, &apReq, messages.KeyUsage_AP_REQ_AUTHENTICATOR),
+		)
+
+	case messages.MsgType_AP_REP:
+		apRep := messages.APRep{}
+		err := apRep.FromBytes(data)
+		if err != nil {
+			return err
+		}
+		event.Parts = append(event.Parts, t.apRepPart(event, &apRep))
+
+	case messages.MsgType_KRB_ERROR:
+		krbError := messages.KRBError{}
+		err := krbError.FromBytes(data)
+		if err != nil {
+			return err
+		}
+		if len(krbError.CName.NameString) != 0 {
+			event.Client = krbError.CName.String() + "@" + krbError.CRealm
+		}
+		event.Server = krbError.SName.String() + "@" + krbError.Realm
+		event.Info = append(event.Info, fmt.Sprintf("Error: %s", krbError.Error()))
+	}
+
+	return nil
+}
+
+// Describe prints the timeline, one event per message with its decrypted parts.
+//
+// Parameters:
+//   - indent (int): The indentation level for formatting the output.
+func (t *Timeline) Describe(indent int) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+
+	total, decrypted := 0, 0
+	for _, event := range t.Events {
+		for _, part := range event.Parts {
+			total++
+			if part.Decrypted {
+				decrypted++
+			}
+		}
+	}
+
+	fmt.Printf("%s<Timeline>\n", indentPrompt)
+	fmt.Printf("%s │ \x1b[93mMessages\x1b[0m  : \x1b[96m%d\x1b[0m\n", indentPrompt, len(t.Events))
+	fmt.Printf("%s │ \x1b[93mDecrypted\x1b[0m : \x1b[96m%d\x1b[0m / \x1b[96m%d\x1b[0m encrypted parts\n", indentPrompt, decrypted, total)
+	for _, event := range t.Events {
+		event.Describe(indent + 1)
+	}
+	fmt.Printf("%s └─\n", indentPrompt)
+}
+
+// Describe prints the event and its encrypted parts.
+//
+// Parameters:
+//   - indent (int): The indentation level for formatting the output.
+func (e *Event) Describe(indent int) {
+	indentPrompt := strings.Repeat(" │ ", indent)
+	msg := &e.Message
+
+	fmt.Printf("%s<Event #%d> \x1b[94m%s\x1b[0m \x1b[96m%s\x1b[0m -> \x1b[96m%s\x1b[0m (%s/%s) \x1b[1m%s\x1b[0m\n",
+		indentPrompt, e.Index, msg.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z"), msg.Source, msg.Destination,
+		msg.Transport, msg.Carrier, messages.MessageTypeToString(msg.MsgType))
+	if e.ParseError != nil {
+		fmt.Printf("%s │ \x1b[91mError parsing message: %s\x1b[0m\n", indentPrompt, e.ParseError)
+	}
+	if len(e.Client) != 0 {
+		fmt.Printf("%s │ \x1b[93mClient\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, e.Client)
+	}
+	if len(e.Server) != 0 {
+		fmt.Printf("%s │ \x1b[93mServer\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, e.Server)
+	}
+	for _, line := range e.Info {
+		describeLine(indentPrompt+" │ ", line)
+	}
+
+	for _, part := range e.Parts {
+		fmt.Printf("%s │ <%s> \x1b[96m%s\x1b[0m", indentPrompt, part.Name, etypeName(part.EncryptedData.EType))
+		if part.EncryptedData.HasKvno {
+			fmt.Printf(" (\x1b[94mkvno %d\x1b[0m)", part.EncryptedData.Kvno)
+		}
+		if part.Decrypted {
+			fmt.Printf(" \x1b[92mdecrypted\x1b[0m with %s\n", part.KeyOrigin)
+			for _, line := range part.Details {
+				describeLine(indentPrompt+" │  │ ", line)
+			}
+		} else {
+			fmt.Printf(" \x1b[91mencrypted\x1b[0m (no key)\n")
+		}
+	}
+	fmt.Printf("%s └─\n", indentPrompt)
+}
+
+// describeLine prints a "name: value" line with the colors of the Describe methods.
+func describeLine(indentPrompt, line string) {
+	name, value, found := strings.Cut(line, ": ")
+	if !found {
+		fmt.Printf("%s%s\n", indentPrompt, line)
+		return
+	}
+	fmt.Printf("%s\x1b[93m%s\x1b[0m : \x1b[96m%s\x1b[0m\n", indentPrompt, name, value)
+}
+
+// parseEncTimestamp parses a PA-ENC-TS-ENC, as defined in RFC 4120 section 5.2.7.2.
+func parseEncTimestamp(data []byte) (time.Time, error) {
+	element := der.Element{}
+	err := element.FromBytes(data)
+	if err != nil {
+		return time.Time{}, err
+	}
+	fields, err := element.Fields()
+	if err != nil {
+		return time.Time{}, err
+	}
+	timestamp, ok := fields[0]
+	if !ok {
+		return time.Time{}, fmt.Errorf("missing field patimestamp")
+	}
+	return timestamp.Time()
+}
+
+// etypeName returns the name of an encryption type, or its number when unknown.
+func etypeName(etype int32) string {
+	name := keytab.EncryptionType(etype).String()
+	if len(name) == 0 {
+		return fmt.Sprintf("etype %d", etype)
+	}
+	return name
+}
+
+// formatTime formats an optional time, printing unset times as "-".
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,243 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"keytab/crypto"
+	"keytab/der"
+	"keytab/keytab"
+	"keytab/messages"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRealm = "TESTSEGMENT.LOCAL"
+
+// encrypt encrypts a message part with an aes256-cts-hmac-sha1-96 key.
+func encrypt(t *testing.T, key []byte, usage uint32, plaintext []byte) messages.EncryptedData {
+	cipher, err := crypto.Encrypt(crypto.ETypeAES256CTSHMACSHA196, key, usage, plaintext)
+	if err != nil {
+		t.Fatalf("Error encrypting: %v", err)
+	}
+	return messages.EncryptedData{EType: crypto.ETypeAES256CTSHMACSHA196, Cipher: cipher}
+}
+
+// mustBytes returns the DER encoding of a message, failing the test on error.
+func mustBytes(t *testing.T, m interface{ ToBytes() ([]byte, error) }) []byte {
+	data, err := m.ToBytes()
+	if err != nil {
+		t.Fatalf("Error encoding message: %v", err)
+	}
+	return data
+}
+
+// gssWrap wraps a Kerberos message in a GSS-API InitialContextToken.
+func gssWrap(tokID []byte, message []byte) []byte {
+	content := append(append(append([]byte{}, gssKerberosOIDs[0]...), tokID...), message...)
+	return der.Encode(der.ClassApplication, true, 0, content)
+}
+
+// buildTestCapture builds a capture of a full Kerberos exchange: an AS exchange over UDP,
+// a TGS exchange over TCP with out of order segments, and an HTTP Negotiate authentication.
+// Only the long-term key of the user is needed to decrypt every part but the tickets.
+func buildTestCapture(t *testing.T, userKey []byte) []byte {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	krbtgtKey := bytes.Repeat([]byte{0x01}, 32)
+	serviceKey := bytes.Repeat([]byte{0x02}, 32)
+	tgtSessionKey := messages.EncryptionKey{KeyType: 18, KeyValue: bytes.Repeat([]byte{0x03}, 32)}
+	tgsSubKey := messages.EncryptionKey{KeyType: 18, KeyValue: bytes.Repeat([]byte{0x04}, 32)}
+	serviceSessionKey := messages.EncryptionKey{KeyType: 18, KeyValue: bytes.Repeat([]byte{0x05}, 32)}
+	user := messages.NewPrincipalName(messages.NameType_PRINCIPAL, "user")
+	krbtgt := messages.NewPrincipalName(messages.NameType_SRV_INST, "krbtgt", testRealm)
+	service := messages.NewPrincipalName(messages.NameType_SRV_INST, "HTTP", "web01.testsegment.local")
+
+	newTicket := func(sname messages.PrincipalName, key []byte, sessionKey messages.EncryptionKey) messages.Ticket {
+		encPart := &messages.EncTicketPart{Key: sessionKey, CRealm: testRealm, CName: user, AuthTime: now, EndTime: now.Add(10 * time.Hour)}
+		return messages.Ticket{
+			TktVno:  messages.PVNO,
+			Realm:   testRealm,
+			SName:   sname,
+			EncPart: encrypt(t, key, messages.KeyUsage_KDC_REP_TICKET, mustBytes(t, encPart)),
+		}
+	}
+	newEncRepPart := func(tag int, sname messages.PrincipalName, sessionKey messages.EncryptionKey) []byte {
+		return mustBytes(t, &messages.EncKDCRepPart{
+			ApplicationTag: tag, Key: sessionKey, LastReq: []messages.LastReqEntry{{LRValue: now}}, Nonce: 42,
+			AuthTime: now, EndTime: now.Add(10 * time.Hour), SRealm: testRealm, SName: sname,
+		})
+	}
+	newAPReq := func(ticket messages.Ticket, key messages.EncryptionKey, usage uint32, subkey *messages.EncryptionKey) *messages.APReq {
+		authenticator := &messages.Authenticator{AuthenticatorVno: messages.PVNO, CRealm: testRealm, CName: user, CTime: now, SubKey: subkey}
+		return &messages.APReq{
+			Pvno: messages.PVNO, MsgType: messages.MsgType_AP_REQ, Ticket: ticket,
+			Authenticator: encrypt(t, key.KeyValue, usage, mustBytes(t, authenticator)),
+		}
+	}
+
+	tgt := newTicket(krbtgt, krbtgtKey, tgtSessionKey)
+	serviceTicket := newTicket(service, serviceKey, serviceSessionKey)
+
+	encTimestamp := encrypt(t, userKey, messages.KeyUsage_AS_REQ_PA_ENC_TIMESTAMP, der.Sequence(der.Explicit(0, der.GeneralizedTime(now))))
+	asReq := mustBytes(t, &messages.KDCReq{
+		Pvno: messages.PVNO, MsgType: messages.MsgType_AS_REQ,
+		PAData:  []messages.PAData{{PADataType: messages.PADataType_ENC_TIMESTAMP, PADataValue: mustBytes(t, &encTimestamp)}},
+		ReqBody: messages.KDCReqBody{CName: user, Realm: testRealm, SName: krbtgt, Till: now.Add(10 * time.Hour), Nonce: 42, EType: []int32{18, 23}},
+	})
+	asRep := mustBytes(t, &messages.KDCRep{
+		Pvno: messages.PVNO, MsgType: messages.MsgType_AS_REP, CRealm: testRealm, CName: user, Ticket: tgt,
+		EncPart: encrypt(t, userKey, messages.KeyUsage_AS_REP_ENCPART, newEncRepPart(messages.ApplicationTag_EncASRepPart, krbtgt, tgtSessionKey)),
+	})
+
+	tgsReq := mustBytes(t, &messages.KDCReq{
+		Pvno: messages.PVNO, MsgType: messages.MsgType_TGS_REQ,
+		PAData: []messages.PAData{{
+			PADataType:  messages.PADataType_TGS_REQ,
+			PADataValue: mustBytes(t, newAPReq(tgt, tgtSessionKey, messages.KeyUsage_TGS_REQ_PA_AUTHENTICATOR, &tgsSubKey)),
+		}},
+		ReqBody: messages.KDCReqBody{Realm: testRealm, SName: service, Till: now.Add(10 * time.Hour), Nonce: 42, EType: []int32{18}},
+	})
+	tgsRep := mustBytes(t, &messages.KDCRep{
+		Pvno: messages.PVNO, MsgType: messages.MsgType_TGS_REP, CRealm: testRealm, CName: user, Ticket: serviceTicket,
+		EncPart: encrypt(t, tgsSubKey.KeyValue, messages.KeyUsage_TGS_REP_ENCPART_SUB_KEY, newEncRepPart(messages.ApplicationTag_EncTGSRepPart, service, serviceSessionKey)),
+	})
+
+	apReq := mustBytes(t, newAPReq(serviceTicket, serviceSessionKey, messages.KeyUsage_AP_REQ_AUTHENTICATOR, nil))
+	encAPRepPart := mustBytes(t, &messages.EncAPRepPart{CTime: now})
+	apRep := mustBytes(t, &messages.APRep{
+		Pvno: messages.PVNO, MsgType: messages.MsgType_AP_REP,
+		EncPart: encrypt(t, serviceSessionKey.KeyValue, messages.KeyUsage_AP_REP_ENCPART, encAPRepPart),
+	})
+
+	framed := func(message []byte) []byte {
+		data := make([]byte, 4, 4+len(message))
+		binary.BigEndian.PutUint32(data, uint32(len(message)))
+		return append(data, message...)
+	}
+	httpRequest := []byte("GET / HTTP/1.1\r\nHost: web01\r\nAuthorization: Negotiate " +
+		base64.StdEncoding.EncodeToString(gssWrap([]byte{0x01, 0x00}, apReq)) + "\r\n\r\n")
+	httpResponse := []byte("HTTP/1.1 200 OK\r\nWWW-Authenticate: Negotiate " +
+		base64.StdEncoding.EncodeToString(gssWrap([]byte{0x02, 0x00}, apRep)) + "\r\n\r\n")
+
+	client, kdc, web := "10.0.0.5", "10.0.0.1", "10.0.0.80"
+	tgsReqFramed := framed(tgsReq)
+	half := len(tgsReqFramed) / 2
+	frames := [][]byte{
+		buildFrame(Transport_UDP, client, kdc, 50000, 88, 0, 0, asReq),
+		buildFrame(Transport_UDP, kdc, client, 88, 50000, 0, 0, asRep),
+		buildFrame(Transport_TCP, client, kdc, 50001, 88, 999, tcpFlag_SYN, nil),
+		// Out of order segments, the second one being retransmitted
+		buildFrame(Transport_TCP, client, kdc, 50001, 88, 1000+uint32(half), 0, tgsReqFramed[half:]),
+		buildFrame(Transport_TCP, client, kdc, 50001, 88, 1000, 0, tgsReqFramed[:half]),
+		buildFrame(Transport_TCP, client, kdc, 50001, 88, 1000+uint32(half), 0, tgsReqFramed[half:]),
+		buildFrame(Transport_TCP, kdc, client, 88, 50001, 5000, 0, framed(tgsRep)),
+		buildFrame(Transport_TCP, client, web, 50002, 80, 1, 0, httpRequest),
+		buildFrame(Transport_TCP, web, client, 80, 50002, 1, 0, httpResponse),
+	}
+
+	packets := make([]Packet, 0, len(frames))
+	for i, frame := range frames {
+		packets = append(packets, Packet{Timestamp: now.Add(time.Duration(i) * time.Millisecond), LinkType: LinkType_Ethernet, Data: frame})
+	}
+	return buildPcapng(packets)
+}
+
+func Test_Timeline_DecryptChain(t *testing.T) {
+	userKey := bytes.Repeat([]byte{0x42}, 32)
+	packets, err := ReadPackets(buildTestCapture(t, userKey))
+	if err != nil {
+		t.Fatalf("Error reading capture: %v", err)
+	}
+
+	timeline := NewTimeline(ExtractMessages(packets))
+	expectedTypes := []int{
+		messages.MsgType_AS_REQ, messages.MsgType_AS_REP, messages.MsgType_TGS_REQ,
+		messages.MsgType_TGS_REP, messages.MsgType_AP_REQ, messages.MsgType_AP_REP,
+	}
+	if len(timeline.Events) != len(expectedTypes) {
+		t.Fatalf("Expected %d events, got %d", len(expectedTypes), len(timeline.Events))
+	}
+	for i, event := range timeline.Events {
+		if event.Message.MsgType != expectedTypes[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, messages.MessageTypeToString(expectedTypes[i]), messages.MessageTypeToString(event.Message.MsgType))
+		}
+		if event.ParseError != nil {
+			t.Errorf("Event %d: unexpected parse error: %v", i, event.ParseError)
+		}
+	}
+	if timeline.Events[4].Message.Carrier != Carrier_HTTP {
+		t.Errorf("Expected the AP-REQ to be carried by HTTP, got %s", timeline.Events[4].Message.Carrier)
+	}
+
+	timeline.AddKeytab(&keytab.Keytab{
+		FileFormatVersion: 0x502,
+		Entries: []keytab.KeytabEntry{
+			{
+				NumComponents: 1,
+				Realm:         keytab.CountedOctetString{Length: uint16(len(testRealm)), Data: []byte(testRealm)},
+				Components:    []keytab.CountedOctetString{{Length: 4, Data: []byte("user")}},
+				Vno:           1,
+				Key: keytab.KeyBlock{
+					Type: keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96,
+					Key:  keytab.CountedOctetString{Length: 32, Data: userKey},
+				},
+			},
+		},
+	})
+
+	// PA-ENC-TIMESTAMP, AS-REP enc-part, TGS-REQ authenticator, TGS-REP enc-part, AP-REQ authenticator, AP-REP enc-part
+	if count := timeline.Decrypt(); count != 6 {
+		t.Errorf("Expected 6 decrypted parts, got %d", count)
+	}
+	for _, event := range timeline.Events {
+		for _, part := range event.Parts {
+			if part.Name == "ticket" && part.Decrypted {
+				t.Errorf("Event %d: ticket decrypted without the service key", event.Index)
+			}
+			if part.Name != "ticket" && !part.Decrypted {
+				t.Errorf("Event %d: %s was not decrypted", event.Index, part.Name)
+			}
+		}
+	}
+}
+
+func Test_Timeline_ShowKeys(t *testing.T) {
+	userKey := bytes.Repeat([]byte{0x42}, 32)
+	packets, err := ReadPackets(buildTestCapture(t, userKey))
+	if err != nil {
+		t.Fatalf("Error reading capture: %v", err)
+	}
+	entry, err := keytab.NewKeytabEntry(testRealm, []string{"user"}, messages.NameType_PRINCIPAL, 1, keytab.EncryptionType_AES256_CTS_HMAC_SHA1_96, userKey)
+	if err != nil {
+		t.Fatalf("Error creating keytab entry: %v", err)
+	}
+	kt := &keytab.Keytab{FileFormatVersion: 0x502, Entries: []keytab.KeytabEntry{entry}}
+
+	// The TGT session key is 0x03 repeated, the subkey of the TGS-REQ 0x04 repeated
+	for _, showKeys := range []bool{false, true} {
+		timeline := NewTimeline(ExtractMessages(packets))
+		timeline.ShowKeys = showKeys
+		timeline.AddKeytab(kt)
+		timeline.Decrypt()
+
+		keys := 0
+		for _, event := range timeline.Events {
+			for _, part := range event.Parts {
+				for _, detail := range part.Details {
+					if !strings.HasPrefix(detail, "SessionKey:") && !strings.HasPrefix(detail, "SubKey:") {
+						continue
+					}
+					keys++
+					revealed := strings.Contains(detail, strings.Repeat("03", 32)) || strings.Contains(detail, strings.Repeat("04", 32)) || strings.Contains(detail, strings.Repeat("05", 32))
+					if revealed != showKeys || strings.Contains(detail, "hidden, 32 bytes, fingerprint ") == showKeys {
+						t.Errorf("Unexpected key detail with ShowKeys %v: %s", showKeys, detail)
+					}
+				}
+			}
+		}
+		if keys != 3 {
+			t.Errorf("Expected the 2 session keys and the subkey in the details, got %d", keys)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format is an output format of a Renderer.
+type Format string
+
+// Output formats.
+const (
+	Format_Tree  Format = "tree"
+	Format_Table Format = "table"
+	Format_JSON  Format = "json"
+	Format_YAML  Format = "yaml"
+)
+
+// Formats is the list of the output formats.
+var Formats = []Format{Format_Tree, Format_Table, Format_JSON, Format_YAML}
+
+// ParseFormat returns the Format of a name.
+//
+// Parameters:
+//   - name (string): The name of the format, as "tree", "table", "json" or "yaml".
+//
+// Returns:
+//   - (Format, error): The Format and an error if the name is unknown.
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q, expected tree, table, json or yaml", name)
+}
+
+// ansiEscape matches the ANSI color escape sequences.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Renderer writes the output of the describe views to a writer, with or without ANSI colors.
+//
+// Attributes:
+//   - Writer (io.Writer): The writer the output is written to.
+//   - Color (bool): Whether ANSI colors are written.
+//   - ShowKeys (bool): Whether key material is written, instead of being masked.
+type Renderer struct {
+	Writer   io.Writer
+	Color    bool
+	ShowKeys bool
+}
+
+// NewRenderer returns a Renderer writing to a writer. Colors are enabled only when the writer
+// is a terminal, unless disabled with noColor or the NO_COLOR environment variable. Keys are
+// masked until ShowKeys is set.
+//
+// Parameters:
+//   - writer (io.Writer): The writer the output is written to.
+//   - noColor (bool): Whether colors are disabled.
+//
+// Returns:
+//   - *Renderer: The Renderer.
+func NewRenderer(writer io.Writer, noColor bool) *Renderer {
+	return &Renderer{
+		Writer: writer,
+		Color:  !noColor && len(os.Getenv("NO_COLOR")) == 0 && IsTerminal(writer),
+	}
+}
+
+// IsTerminal returns true if the writer is a file opened on a terminal.
+//
+// Parameters:
+//   - writer (io.Writer): The writer.
+//
+// Returns:
+//   - bool: True if the writer is a terminal.
+func IsTerminal(writer io.Writer) bool {
+	file, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Printf writes a formatted string. The ANSI color escape sequences of the format are
+// removed when colors are disabled, those of the arguments are kept.
+//
+// Parameters:
+//   - format (string): The format, as in fmt.Printf.
+//   - args (...interface{}): The arguments of the format.
+func (r *Renderer) Printf(format string, args ...interface{}) {
+	if !r.Color {
+		format = ansiEscape.ReplaceAllString(format, "")
+	}
+	fmt.Fprintf(r.Writer, format, args...)
+}
+
+// Table writes rows as a compact table, with columns aligned and separated by two spaces.
+//
+// Parameters:
+//   - headers ([]string): The headers of the columns.
+//   - rows ([][]string): The rows, with a cell per column.
+func (r *Renderer) Table(headers []string, rows [][]string) {
+	widths := make([]int, len(headers))
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+			}
+		}
+	}
+
+	writeRow := func(row []string, color string) {
+		line := strings.Builder{}
+		for i, cell := range row {
+			if i >= len(widths) {
+				break
+			}
+			if i == len(row)-1 || i == len(widths)-1 {
+				line.WriteString(cell)
+			} else {
+				line.WriteString(cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		if r.Color && len(color) != 0 {
+			fmt.Fprintf(r.Writer, "%s%s\x1b[0m\n", color, line.String())
+		} else {
+			fmt.Fprintln(r.Writer, line.String())
+		}
+	}
+
+	writeRow(headers, "\x1b[93m")
+	for _, row := range rows {
+		writeRow(row, "")
+	}
+}
+
+// JSON writes a value as indented JSON.
+//
+// Parameters:
+//   - value (interface{}): The value, encoded as with encoding/json.
+//
+// Returns:
+//   - error: An error if the value could not be encoded.
+func (r *Renderer) JSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(r.Writer, string(data))
+	return err
+}
+
+// YAML writes a value as YAML, see MarshalYAML.
+//
+// Parameters:
+//   - value (interface{}): The value.
+//
+// Returns:
+//   - error: An error if the value could not be encoded.
+func (r *Renderer) YAML(value interface{}) error {
+	data, err := MarshalYAML(value)
+	if err != nil {
+		return err
+	}
+	_, err = r.Writer.Write(data)
+	return err
+}
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func Test_Renderer_Printf(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	r := NewRenderer(buffer, false)
+	if r.Color {
+		t.Errorf("Expected colors to be disabled when not writing to a terminal")
+	}
+	r.Printf("\x1b[93m%s\x1b[0m : \x1b[96m%d\x1b[0m\n", "Size", 4)
+	if buffer.String() != "Size : 4\n" {
+		t.Errorf("Unexpected output %q", buffer.String())
+	}
+
+	buffer.Reset()
+	r.Color = true
+	r.Printf("\x1b[93m%s\x1b[0m\n", "Size")
+	if buffer.String() != "\x1b[93mSize\x1b[0m\n" {
+		t.Errorf("Unexpected colored output %q", buffer.String())
+	}
+}
+
+func Test_Renderer_Table(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	r := &Renderer{Writer: buffer}
+	r.Table([]string{"#", "PRINCIPAL", "KVNO"}, [][]string{{"0", "svc_web@TESTSEGMENT.LOCAL", "2"}, {"10", "é@R", "13"}})
+	expected := "" +
+		"#   PRINCIPAL                  KVNO\n" +
+		"0   svc_web@TESTSEGMENT.LOCAL  2\n" +
+		"10  é@R                        13\n"
+	if buffer.String() != expected {
+		t.Errorf("Unexpected table:\n%s", buffer.String())
+	}
+}
+
+func Test_MarshalYAML(t *testing.T) {
+	type entry struct {
+		Name    string    `json:"name"`
+		Kvno    uint32    `json:"kvno"`
+		Tags    []string  `json:"tags"`
+		Skipped string    `json:"-"`
+		Empty   string    `json:"empty,omitempty"`
+		Time    time.Time `json:"time"`
+	}
+	value := map[string]interface{}{
+		"entries": []entry{{Name: "svc_web@TESTSEGMENT.LOCAL", Kvno: 2, Tags: []string{"yes", "a b"}, Skipped: "x", Time: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)}},
+		"count":   1,
+		"none":    nil,
+		"empty":   []int{},
+	}
+	data, err := MarshalYAML(value)
+	if err != nil {
+		t.Fatalf("Error encoding YAML: %v", err)
+	}
+	expected := "" +
+		"count: 1\n" +
+		"empty: []\n" +
+		"entries:\n" +
+		"  - name: svc_web@TESTSEGMENT.LOCAL\n" +
+		"    kvno: 2\n" +
+		"    tags:\n" +
+		"      - \"yes\"\n" +
+		"      - \"a b\"\n" +
+		"    time: \"2026-10-17T12:00:00Z\"\n" +
+		"none: null\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected YAML:\n%s", data)
+	}
+}
+
+func Test_ParseFormat(t *testing.T) {
+	if format, err := ParseFormat("YAML"); err != nil || format != Format_YAML {
+		t.Errorf("Expected the yaml format, got %q (%v)", format, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,193 @@
+package render
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// plainScalar matches the strings written without quotes in YAML.
+var plainScalar = regexp.MustCompile(`^[A-Za-z_/.@][A-Za-z0-9_./@+\-]*$`)
+
+// reservedScalars are the plain strings YAML parsers read as booleans or null.
+var reservedScalars = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true, "y": true, "n": true, "null": true, ".inf": true, ".nan": true,
+}
+
+// MarshalYAML encodes a value as a YAML document, following the encoding/json conventions:
+// struct fields are named by their json tags, fields tagged "-" and empty fields tagged
+// "omitempty" are skipped, values implementing encoding.TextMarshaler are written as strings
+// and byte slices in base64. Maps are written with their keys sorted.
+//
+// Parameters:
+//   - value (interface{}): The value to encode.
+//
+// Returns:
+//   - ([]byte, error): The YAML document and an error if the value could not be encoded.
+func MarshalYAML(value interface{}) ([]byte, error) {
+	builder := &strings.Builder{}
+	err := writeYAML(builder, reflect.ValueOf(value), 0)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(builder.String()), nil
+}
+
+// writeYAML writes a value at an indentation. Scalars are written on the current line,
+// mappings and sequences on the following lines.
+func writeYAML(builder *strings.Builder, value reflect.Value, indent int) error {
+	if scalar, isScalar, err := yamlScalar(value); err != nil {
+		return err
+	} else if isScalar {
+		builder.WriteString(scalar + "\n")
+		return nil
+	}
+
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	prefix := strings.Repeat(" ", indent)
+	switch value.Kind() {
+	case reflect.Struct:
+		keys, values := structFields(value)
+		return writeMapping(builder, keys, values, indent)
+	case reflect.Map:
+		keys := make([]string, 0, value.Len())
+		byKey := make(map[string]reflect.Value)
+		for _, key := range value.MapKeys() {
+			name := fmt.Sprint(key.Interface())
+			keys = append(keys, name)
+			byKey[name] = value.MapIndex(key)
+		}
+		sort.Strings(keys)
+		values := make([]reflect.Value, len(keys))
+		for i, key := range keys {
+			values[i] = byKey[key]
+		}
+		return writeMapping(builder, keys, values, indent)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			item := &strings.Builder{}
+			err := writeYAML(item, value.Index(i), indent+2)
+			if err != nil {
+				return err
+			}
+			// Items start on the line of their dash, the indentation of their first line is replaced by it
+			builder.WriteString(prefix + "- " + strings.TrimPrefix(item.String(), prefix+"  "))
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported type %s", value.Type().String())
+}
+
+// writeMapping writes the keys and values of a mapping at an indentation.
+func writeMapping(builder *strings.Builder, keys []string, values []reflect.Value, indent int) error {
+	prefix := strings.Repeat(" ", indent)
+	for i, key := range keys {
+		builder.WriteString(prefix + quoteYAML(key) + ":")
+		scalar, isScalar, err := yamlScalar(values[i])
+		if err != nil {
+			return err
+		}
+		if isScalar {
+			builder.WriteString(" " + scalar + "\n")
+			continue
+		}
+		builder.WriteString("\n")
+		err = writeYAML(builder, values[i], indent+2)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlScalar returns the representation of a value if it is a scalar, including empty mappings and sequences.
+func yamlScalar(value reflect.Value) (string, bool, error) {
+	if !value.IsValid() {
+		return "null", true, nil
+	}
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok && (value.Kind() != reflect.Pointer || !value.IsNil()) {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return "", false, err
+		}
+		return quoteYAML(string(text)), true, nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return "null", true, nil
+		}
+		return yamlScalar(value.Elem())
+	case reflect.String:
+		return quoteYAML(value.String()), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64), true, nil
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return quoteYAML(base64.StdEncoding.EncodeToString(value.Bytes())), true, nil
+		}
+		if value.Len() == 0 {
+			return "[]", true, nil
+		}
+	case reflect.Array:
+		if value.Len() == 0 {
+			return "[]", true, nil
+		}
+	case reflect.Map:
+		if value.Len() == 0 {
+			return "{}", true, nil
+		}
+	case reflect.Struct:
+		if keys, _ := structFields(value); len(keys) == 0 {
+			return "{}", true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// structFields returns the names and values of the fields of a struct encoded as with encoding/json.
+func structFields(value reflect.Value) ([]string, []reflect.Value) {
+	keys := make([]string, 0)
+	values := make([]reflect.Value, 0)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && len(options) == 0 {
+			continue
+		}
+		if strings.Contains(","+options+",", ",omitempty,") && value.Field(i).IsZero() {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		keys = append(keys, name)
+		values = append(values, value.Field(i))
+	}
+	return keys, values
+}
+
+// quoteYAML returns a string as a YAML scalar, quoted unless it is plain and cannot be read as another type.
+func quoteYAML(value string) string {
+	if plainScalar.MatchString(value) && !reservedScalars[strings.ToLower(value)] {
+		return value
+	}
+	return strconv.Quote(value)
+}
@@ -1,6 +1,14 @@
 package utils
 
-import "fmt"
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
 
 // BytesToPrintableString takes a byte slice and returns a string with only the printable characters.
 // Non-printable characters are represented in the form of \x00.
@@ -17,3 +25,35 @@ func BytesToPrintableString(data []byte) string {
 
 	return result
 }
+
+// ReadPassword reads a password from the first line of a reader, such as the standard input,
+// without its line terminator.
+func ReadPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return "", fmt.Errorf("error reading password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// ReadBlob reads binary data given as the path of a raw file, as a hexadecimal string or as a
+// base64 string, tried in this order. Whitespace is ignored in the strings.
+func ReadBlob(input string) ([]byte, error) {
+	if info, err := os.Stat(input); err == nil && info.Mode().IsRegular() {
+		return os.ReadFile(input)
+	}
+	compact := strings.Join(strings.Fields(input), "")
+	if len(compact) == 0 {
+		return nil, fmt.Errorf("empty blob")
+	}
+	if data, err := hex.DecodeString(compact); err == nil {
+		return data, nil
+	}
+	if data, err := base64.StdEncoding.DecodeString(compact); err == nil {
+		return data, nil
+	}
+	if data, err := base64.RawStdEncoding.DecodeString(compact); err == nil {
+		return data, nil
+	}
+	return nil, fmt.Errorf("the blob is neither a file, hexadecimal nor base64")
+}